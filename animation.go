@@ -0,0 +1,607 @@
+package cast
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ----------------------- //
+//        ANIMATION        //
+// ----------------------- //
+
+// Animation wraps an animation node with helpers for its curves and notification tracks
+type Animation struct {
+	*CastNode
+}
+
+// AsAnimation returns the node as an [Animation], nil if it is not an animation node
+func AsAnimation(n *CastNode) *Animation {
+	if n == nil || n.id != NodeIdAnimation {
+		return nil
+	}
+	return &Animation{n}
+}
+
+// Framerate returns the framerate of the animation, 0 if it is not set
+func (a *Animation) Framerate() float32 {
+	return GetPropertyValueOr(a.CastNode, PropNameFramerate, float32(0))
+}
+
+// Curves returns the curves of the animation
+func (a *Animation) Curves() []*Curve {
+	var curves []*Curve
+	for _, c := range a.GetChildrenOfType(NodeIdCurve) {
+		curves = append(curves, AsCurve(c))
+	}
+	return curves
+}
+
+// FrameRange returns the first and the last keyframe across all curves and notification tracks of the
+// animation, both are 0 if there are no keyframes
+func (a *Animation) FrameRange() (start, end float64) {
+	first := true
+	for _, c := range a.childNodes {
+		if c.id != NodeIdCurve && c.id != NodeIdNotificationTrack {
+			continue
+		}
+
+		p, ok := c.GetProperty(PropNameKeyFrameBuffer)
+		if !ok {
+			continue
+		}
+		frames, err := numericValues(p)
+		if err != nil {
+			continue
+		}
+
+		for i := range frames.len {
+			f := frames.at(i)
+			if first {
+				start, end, first = f, f, false
+				continue
+			}
+			start, end = min(start, f), max(end, f)
+		}
+	}
+	return start, end
+}
+
+// Duration returns the time between the first and the last keyframe of the animation at its framerate,
+// 0 if the framerate is not set
+func (a *Animation) Duration() time.Duration {
+	rate := a.Framerate()
+	if rate <= 0 {
+		return 0
+	}
+
+	start, end := a.FrameRange()
+	return time.Duration((end - start) / float64(rate) * float64(time.Second))
+}
+
+// Resample bakes every curve of the animation to one keyframe per frame at the given framerate, covering the
+// same time span as before, and updates the framerate of the animation. The keyframes of the notification
+// tracks are moved to the nearest frame at the new framerate.
+func (a *Animation) Resample(fps float32) error {
+	if fps <= 0 {
+		return fmt.Errorf("cast: invalid framerate: %v", fps)
+	}
+
+	rate := a.Framerate()
+	if rate <= 0 {
+		return fmt.Errorf("cast: animation has no framerate")
+	}
+	scale := float64(fps) / float64(rate)
+
+	for _, curve := range a.Curves() {
+		if err := curve.resample(scale); err != nil {
+			return fmt.Errorf("cast: resample curve %s.%s: %w", curve.NodeName(), curve.KeyProperty(), err)
+		}
+	}
+
+	if err := a.scaleNotificationTracks(scale); err != nil {
+		return err
+	}
+
+	_, err := CreateProperty(a.CastNode, PropNameFramerate, PropFloat, fps)
+	return err
+}
+
+// ConvertFramerate changes the framerate of the animation to the given one, keeping its timing. With resample
+// set, the curves are baked to one keyframe per frame at the new framerate, see [Animation.Resample].
+// Otherwise only the keyframes are moved to the nearest frame at the new framerate, keeping their values;
+// when keyframes fall onto the same frame, the first one is kept. The keyframes of the notification tracks
+// are moved to the nearest frame at the new framerate in both cases.
+func (a *Animation) ConvertFramerate(fps float32, resample bool) error {
+	if resample {
+		return a.Resample(fps)
+	}
+
+	if fps <= 0 {
+		return fmt.Errorf("cast: invalid framerate: %v", fps)
+	}
+
+	rate := a.Framerate()
+	if rate <= 0 {
+		return fmt.Errorf("cast: animation has no framerate")
+	}
+	scale := float64(fps) / float64(rate)
+
+	for _, curve := range a.Curves() {
+		if err := curve.rescale(scale); err != nil {
+			return fmt.Errorf("cast: rescale curve %s.%s: %w", curve.NodeName(), curve.KeyProperty(), err)
+		}
+	}
+
+	if err := a.scaleNotificationTracks(scale); err != nil {
+		return err
+	}
+
+	_, err := CreateProperty(a.CastNode, PropNameFramerate, PropFloat, fps)
+	return err
+}
+
+// scaleNotificationTracks moves the keyframes of the notification tracks to the nearest frame after scaling
+// their time by the given factor
+func (a *Animation) scaleNotificationTracks(scale float64) error {
+	for _, track := range a.GetChildrenOfType(NodeIdNotificationTrack) {
+		frames, err := GetPropertyValuesAsUint32(track, PropNameKeyFrameBuffer)
+		if err != nil {
+			continue
+		}
+
+		var last uint32
+		for i, f := range frames {
+			frames[i] = uint32(math.Round(float64(f) * scale))
+			last = max(last, frames[i])
+		}
+		if err := setIndexValues(track, PropNameKeyFrameBuffer, indexPropertyId(int(last)), frames); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resample bakes the curve to one keyframe per frame after scaling its time by the given factor
+func (c *Curve) resample(scale float64) error {
+	keyframes, err := c.KeyFrames()
+	if err != nil {
+		return err
+	}
+	if len(keyframes) == 0 {
+		return nil
+	}
+
+	first := int(math.Round(float64(keyframes[0]) * scale))
+	last := int(math.Round(float64(keyframes[len(keyframes)-1]) * scale))
+
+	frames := make([]uint32, 0, last-first+1)
+	for f := first; f <= last; f++ {
+		frames = append(frames, uint32(f))
+	}
+
+	if c.KeyProperty() == KeyPropertyRotation {
+		values := make([]Vec4, len(frames))
+		for i, f := range frames {
+			if values[i], err = c.EvaluateRotation(float64(f) / scale); err != nil {
+				return err
+			}
+		}
+		if _, err := CreateProperty(c.CastNode, PropNameKeyValueBuffer, PropVector4, values...); err != nil {
+			return err
+		}
+	} else {
+		values := make([]float64, len(frames))
+		for i, f := range frames {
+			if values[i], err = c.Evaluate(float64(f) / scale); err != nil {
+				return err
+			}
+		}
+		p, _ := c.GetProperty(PropNameKeyValueBuffer)
+		if err := setNumericValues(c.CastNode, PropNameKeyValueBuffer, p.Id(), values); err != nil {
+			return err
+		}
+	}
+
+	return setIndexValues(c.CastNode, PropNameKeyFrameBuffer, indexPropertyId(last), frames)
+}
+
+// rescale moves the keyframes of the curve to the nearest frame after scaling its time by the given factor,
+// keeping the first of the keyframes falling onto the same frame
+func (c *Curve) rescale(scale float64) error {
+	keyframes, err := c.KeyFrames()
+	if err != nil {
+		return err
+	}
+	if len(keyframes) == 0 {
+		return nil
+	}
+	if kv, ok := c.GetProperty(PropNameKeyValueBuffer); !ok || kv.Count() != len(keyframes) {
+		return fmt.Errorf("cast: curve has %d keyframes but %d values", len(keyframes), propertyCount(c.CastNode, PropNameKeyValueBuffer))
+	}
+
+	keep := make([]int, 0, len(keyframes))
+	frames := make([]uint32, 0, len(keyframes))
+	for i, f := range keyframes {
+		scaled := uint32(math.Round(float64(f) * scale))
+		if len(frames) > 0 && frames[len(frames)-1] == scaled {
+			continue
+		}
+		keep = append(keep, i)
+		frames = append(frames, scaled)
+	}
+	return c.keepKeyframes(keep, frames, indexPropertyId(int(frames[len(frames)-1])))
+}
+
+// Trim clips the curves and notification tracks of the animation to the frames from start to end and moves
+// the start to frame 0, e.g. to split a long motion capture take into clips. Curves are keyed at the start
+// and the end of the range with their values there if they have no keyframe at these frames, so the clip
+// plays exactly as the range did before. Curves without keyframes in the range keep a single keyframe
+// holding their value at the start. Notification tracks without keyframes in the range are removed.
+func (a *Animation) Trim(start, end float64) error {
+	if start < 0 || end < start {
+		return fmt.Errorf("cast: invalid frame range from %v to %v", start, end)
+	}
+
+	for _, curve := range a.Curves() {
+		if err := curve.trim(start, end); err != nil {
+			return fmt.Errorf("cast: trim curve %s.%s: %w", curve.NodeName(), curve.KeyProperty(), err)
+		}
+	}
+
+	for _, track := range a.GetChildrenOfType(NodeIdNotificationTrack) {
+		frames, err := GetPropertyValuesAsUint32(track, PropNameKeyFrameBuffer)
+		if err != nil {
+			continue
+		}
+
+		var kept []uint32
+		for _, f := range frames {
+			if float64(f) >= start && float64(f) <= end {
+				kept = append(kept, uint32(math.Round(float64(f)-start)))
+			}
+		}
+		if len(kept) == 0 {
+			track.Remove()
+			continue
+		}
+		if err := setIndexValues(track, PropNameKeyFrameBuffer, indexPropertyId(int(slices.Max(kept))), kept); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trim clips the curve to the frames from start to end and moves the start to frame 0, see [Animation.Trim]
+func (c *Curve) trim(start, end float64) error {
+	keyframes, err := c.KeyFrames()
+	if err != nil {
+		return err
+	}
+	if len(keyframes) == 0 {
+		return nil
+	}
+
+	// the times of the new keyframes in the frames of the untrimmed curve
+	first, last := float64(keyframes[0]), float64(keyframes[len(keyframes)-1])
+	isKey := func(t float64) bool {
+		return t == math.Trunc(t) && t <= math.MaxUint32 && slices.Contains(keyframes, uint32(t))
+	}
+
+	var times []float64
+	if start > first && !isKey(start) {
+		times = append(times, start)
+	}
+	for _, f := range keyframes {
+		if float64(f) >= start && float64(f) <= end {
+			times = append(times, float64(f))
+		}
+	}
+	if end < last && end != start && !isKey(end) || len(times) == 0 {
+		times = append(times, end)
+	}
+
+	frames := make([]uint32, 0, len(times))
+	kept := times[:0]
+	for _, t := range times {
+		f := uint32(math.Round(t - start))
+		if len(frames) > 0 && frames[len(frames)-1] == f {
+			continue
+		}
+		frames = append(frames, f)
+		kept = append(kept, t)
+	}
+	times = kept
+
+	if c.KeyProperty() == KeyPropertyRotation {
+		values := make([]Vec4, len(times))
+		for i, t := range times {
+			if values[i], err = c.EvaluateRotation(t); err != nil {
+				return err
+			}
+		}
+		if _, err := CreateProperty(c.CastNode, PropNameKeyValueBuffer, PropVector4, values...); err != nil {
+			return err
+		}
+	} else {
+		values := make([]float64, len(times))
+		for i, t := range times {
+			if values[i], err = c.Evaluate(t); err != nil {
+				return err
+			}
+		}
+		p, _ := c.GetProperty(PropNameKeyValueBuffer)
+		if err := setNumericValues(c.CastNode, PropNameKeyValueBuffer, p.Id(), values); err != nil {
+			return err
+		}
+	}
+
+	return setIndexValues(c.CastNode, PropNameKeyFrameBuffer, indexPropertyId(int(frames[len(frames)-1])), frames)
+}
+
+// ApplyAdditive returns a copy of the animation with the additive curves of the given animation applied on top
+// of it, weighted by their additive blend weight. Translations are offset, rotations are applied after the base
+// rotation and scales multiply the base scale. Curves of the other animation that are not additive replace the
+// curves of the same channel. Channels without a curve in the animation start from the rest pose of the bone
+// with the same name in the given skeleton, it may be nil if every channel has a base curve. Both animations
+// must have the same framerate, see [Animation.Resample]. The copy is assigned fresh hashes and does not belong
+// to a file until it is attached with [CastNode.MoveTo].
+func (a *Animation) ApplyAdditive(additive *Animation, skeleton *CastNode) (*Animation, error) {
+	if a.Framerate() != additive.Framerate() {
+		return nil, fmt.Errorf("cast: framerate %v does not match %v", additive.Framerate(), a.Framerate())
+	}
+
+	combined := AsAnimation(a.Clone(false))
+	for _, curve := range additive.Curves() {
+		base := combined.curve(curve.NodeName(), curve.KeyProperty())
+		mode, err := curve.Mode()
+		if err != nil {
+			return nil, err
+		}
+
+		if mode != CurveModeAdditive {
+			if base != nil {
+				base.Remove()
+			}
+			if err := curve.Clone(false).MoveTo(combined.CastNode); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if base == nil {
+			rest, err := restCurve(curve, skeleton)
+			if err != nil {
+				return nil, err
+			}
+			if err := rest.MoveTo(combined.CastNode); err != nil {
+				return nil, err
+			}
+			base = AsCurve(rest)
+		}
+
+		if err := base.applyAdditive(curve); err != nil {
+			return nil, fmt.Errorf("cast: apply additive curve %s.%s: %w", curve.NodeName(), curve.KeyProperty(), err)
+		}
+	}
+	return combined, nil
+}
+
+// MergeCurves copies the curves of the other animation into the animation, e.g. to assemble the facial and
+// body animations of a split export. Both animations must have the same framerate, see [Animation.Resample].
+// If both animations have a curve animating the same property of the same node, [ErrCurveConflict] is
+// returned listing all of them and the animation is left unchanged. The copies are assigned fresh hashes of
+// the file of the animation, the other animation is left untouched.
+func (a *Animation) MergeCurves(other *Animation) error {
+	if a.Framerate() != other.Framerate() {
+		return fmt.Errorf("cast: framerate %v does not match %v", other.Framerate(), a.Framerate())
+	}
+
+	curves := other.Curves()
+	var conflicts []string
+	for _, curve := range curves {
+		if a.curve(curve.NodeName(), curve.KeyProperty()) != nil {
+			conflicts = append(conflicts, curve.NodeName()+"."+curve.KeyProperty())
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrCurveConflict, strings.Join(conflicts, ", "))
+	}
+
+	for _, curve := range curves {
+		c := a.CreateChild(NodeIdCurve)
+		for _, p := range curve.properties {
+			c.properties = append(c.properties, p.clone())
+		}
+	}
+	return nil
+}
+
+// ExtractRootMotion moves the motion of the root bone with the given name into a separate animation, as most
+// engines expect root motion to be delivered apart from the body animation. The returned animation holds copies
+// of the translation and rotation curves of the bone along with the properties of the animation, e.g. its
+// framerate. The curves in the animation keep their keyframes but are zeroed: translations become 0 and
+// rotations the identity. The copy is assigned fresh hashes and does not belong to a file until it is attached
+// with [CastNode.MoveTo]. Returns an error if no translation or rotation curve animates the bone.
+func (a *Animation) ExtractRootMotion(rootBone string) (*Animation, error) {
+	var curves []*Curve
+	for _, c := range a.Curves() {
+		if c.NodeName() == rootBone && isRootMotionProperty(c.KeyProperty()) {
+			curves = append(curves, c)
+		}
+	}
+	if len(curves) == 0 {
+		return nil, fmt.Errorf("cast: no translation or rotation curves animate bone %q", rootBone)
+	}
+
+	motion := AsAnimation(a.Clone(false))
+	for _, c := range slices.Clone(motion.childNodes) {
+		if curve := AsCurve(c); curve == nil || curve.NodeName() != rootBone || !isRootMotionProperty(curve.KeyProperty()) {
+			c.Remove()
+		}
+	}
+
+	for _, c := range curves {
+		if err := c.zero(); err != nil {
+			return nil, fmt.Errorf("cast: zero curve %s.%s: %w", c.NodeName(), c.KeyProperty(), err)
+		}
+	}
+	return motion, nil
+}
+
+// isRootMotionProperty reports whether the key property is a translation or the rotation
+func isRootMotionProperty(kp string) bool {
+	switch kp {
+	case KeyPropertyTranslationX, KeyPropertyTranslationY, KeyPropertyTranslationZ, KeyPropertyRotation:
+		return true
+	}
+	return false
+}
+
+// zero replaces the values of the curve with 0, or the identity for rotations, keeping its keyframes
+func (c *Curve) zero() error {
+	if c.KeyProperty() == KeyPropertyRotation {
+		values, err := GetPropertyValues[Vec4](c.CastNode, PropNameKeyValueBuffer)
+		if err != nil {
+			return err
+		}
+		identity := make([]Vec4, len(values))
+		for i := range identity {
+			identity[i] = Vec4{W: 1}
+		}
+		_, err = CreateProperty(c.CastNode, PropNameKeyValueBuffer, PropVector4, identity...)
+		return err
+	}
+
+	values, err := c.scalarValues()
+	if err != nil {
+		return err
+	}
+	p, _ := c.GetProperty(PropNameKeyValueBuffer)
+	return setNumericValues(c.CastNode, PropNameKeyValueBuffer, p.Id(), make([]float64, values.len))
+}
+
+// curve returns the curve animating the given property of the node with the given name, nil if there is none
+func (a *Animation) curve(nodeName, keyProperty string) *Curve {
+	for _, c := range a.Curves() {
+		if c.NodeName() == nodeName && c.KeyProperty() == keyProperty {
+			return c
+		}
+	}
+	return nil
+}
+
+// restCurve returns a detached curve holding the rest pose value of the channel of the given curve, taken
+// from the bone of the skeleton with the name of the animated node
+func restCurve(curve *Curve, skeleton *CastNode) (*CastNode, error) {
+	if skeleton == nil {
+		return nil, fmt.Errorf("cast: no base curve or skeleton for %s.%s", curve.NodeName(), curve.KeyProperty())
+	}
+
+	bones := skeleton.Find(AllOf(ByType(NodeIdBone), ByName(curve.NodeName())))
+	if len(bones) == 0 {
+		return nil, fmt.Errorf("cast: bone %s not found", curve.NodeName())
+	}
+	bone := bones[0]
+
+	rest := newCastNode(NodeIdCurve, nil)
+	CreateProperty(rest, PropNameNodeName, PropString, curve.NodeName())
+	CreateProperty(rest, PropNameKeyProperty, PropString, curve.KeyProperty())
+	CreateProperty(rest, PropNameKeyFrameBuffer, PropByte, byte(0))
+
+	kp := curve.KeyProperty()
+	switch kp {
+	case KeyPropertyRotation:
+		q := GetPropertyValueOr(bone, PropNameLocalRotation, Vec4{W: 1})
+		CreateProperty(rest, PropNameKeyValueBuffer, PropVector4, q)
+	case KeyPropertyTranslationX, KeyPropertyTranslationY, KeyPropertyTranslationZ:
+		v := GetPropertyValueOr(bone, PropNameLocalPosition, Vec3{})
+		CreateProperty(rest, PropNameKeyValueBuffer, PropFloat, vec3Component(v, kp[1]))
+	case KeyPropertyScaleX, KeyPropertyScaleY, KeyPropertyScaleZ:
+		v := GetPropertyValueOr(bone, PropNameScale, Vec3{1, 1, 1})
+		CreateProperty(rest, PropNameKeyValueBuffer, PropFloat, vec3Component(v, kp[1]))
+	default:
+		return nil, fmt.Errorf("cast: no rest pose for key property %q", kp)
+	}
+	return rest, nil
+}
+
+// vec3Component returns the component of the vector with the given axis name ('x', 'y' or 'z')
+func vec3Component(v Vec3, axis byte) float32 {
+	switch axis {
+	case 'x':
+		return v.X
+	case 'y':
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+// applyAdditive combines the additive curve into the curve, keyed at the union of the keyframes of both
+func (c *Curve) applyAdditive(additive *Curve) error {
+	weight := GetPropertyValueOr(additive.CastNode, PropNameAdditiveBlendWeight, float32(1))
+
+	baseFrames, err := c.KeyFrames()
+	if err != nil {
+		return err
+	}
+	additiveFrames, err := additive.KeyFrames()
+	if err != nil {
+		return err
+	}
+	frames := slices.Concat(baseFrames, additiveFrames)
+	slices.Sort(frames)
+	frames = slices.Compact(frames)
+	if len(frames) == 0 {
+		return nil
+	}
+
+	switch kp := c.KeyProperty(); kp {
+	case KeyPropertyRotation:
+		values := make([]Vec4, len(frames))
+		for i, f := range frames {
+			base, err := c.EvaluateRotation(float64(f))
+			if err != nil {
+				return err
+			}
+			q, err := additive.EvaluateRotation(float64(f))
+			if err != nil {
+				return err
+			}
+			values[i] = Vec4(Quat(base).Mul(QuatIdent().Slerp(Quat(q), weight)))
+		}
+		if _, err := CreateProperty(c.CastNode, PropNameKeyValueBuffer, PropVector4, values...); err != nil {
+			return err
+		}
+	default:
+		scale := kp == KeyPropertyScaleX || kp == KeyPropertyScaleY || kp == KeyPropertyScaleZ
+		values := make([]float64, len(frames))
+		for i, f := range frames {
+			base, err := c.Evaluate(float64(f))
+			if err != nil {
+				return err
+			}
+			v, err := additive.Evaluate(float64(f))
+			if err != nil {
+				return err
+			}
+			if scale {
+				values[i] = base * (1 + float64(weight)*(v-1))
+			} else {
+				values[i] = base + float64(weight)*v
+			}
+		}
+		p, _ := c.GetProperty(PropNameKeyValueBuffer)
+		if err := setNumericValues(c.CastNode, PropNameKeyValueBuffer, p.Id(), values); err != nil {
+			return err
+		}
+	}
+
+	if err := setIndexValues(c.CastNode, PropNameKeyFrameBuffer, indexPropertyId(int(frames[len(frames)-1])), frames); err != nil {
+		return err
+	}
+	return c.SetMode(CurveModeAbsolute)
+}
@@ -0,0 +1,255 @@
+package cast
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAnimationResample(t *testing.T) {
+	animation := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation))
+	CreateProperty(animation.CastNode, PropNameFramerate, PropFloat, float32(30))
+	translation := createCurve(animation.CastNode, "j_root", KeyPropertyTranslationX, PropFloat, []uint16{0, 30}, float32(0), float32(1))
+	rotation := createCurve(animation.CastNode, "j_root", KeyPropertyRotation, PropVector4, []uint16{10, 20}, Vec4{W: 1}, Vec4{W: 1})
+	visibility := createCurve(animation.CastNode, "j_root", KeyPropertyVisibility, PropByte, []uint16{0, 15}, byte(1), byte(0))
+	track := animation.CreateChild(NodeIdNotificationTrack)
+	CreateProperty(track, PropNameKeyFrameBuffer, PropByte, byte(15))
+
+	if err := animation.Resample(60); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, animation.Framerate(), 60)
+
+	frames := must(translation.KeyFrames())
+	assertEqual(t, len(frames), 61)
+	assertEqual(t, frames[60], 60)
+	assertEqual(t, must(GetPropertyValues[float32](translation.CastNode, PropNameKeyValueBuffer))[30], 0.5)
+
+	frames = must(rotation.KeyFrames())
+	assertEqual(t, len(frames), 21)
+	assertEqual(t, frames[0], 20)
+	assertEqual(t, len(must(GetPropertyValues[Vec4](rotation.CastNode, PropNameKeyValueBuffer))), 21)
+
+	values := must(GetPropertyValues[byte](visibility.CastNode, PropNameKeyValueBuffer))
+	assertEqual(t, values[29], 1)
+	assertEqual(t, values[30], 0)
+
+	assertEqual(t, must(GetPropertyValues[byte](track, PropNameKeyFrameBuffer))[0], 30)
+
+	if err := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation)).Resample(30); err == nil {
+		t.Error("expected error for an animation without framerate")
+	}
+}
+
+func TestAnimationApplyAdditive(t *testing.T) {
+	root := New().CreateRoot()
+	skeleton := root.CreateChild(NodeIdSkeleton)
+	bone := skeleton.CreateChild(NodeIdBone)
+	CreateProperty(bone, PropNameName, PropString, "j_spine")
+	CreateProperty(bone, PropNameLocalPosition, PropVector3, Vec3{X: 1, Y: 2, Z: 3})
+
+	base := AsAnimation(root.CreateChild(NodeIdAnimation))
+	CreateProperty(base.CastNode, PropNameFramerate, PropFloat, float32(30))
+	createCurve(base.CastNode, "j_spine", KeyPropertyTranslationX, PropFloat, []uint16{0, 10}, float32(0), float32(10))
+	createCurve(base.CastNode, "j_spine", KeyPropertyScaleX, PropFloat, []uint16{0}, float32(2))
+
+	additive := AsAnimation(root.CreateChild(NodeIdAnimation))
+	CreateProperty(additive.CastNode, PropNameFramerate, PropFloat, float32(30))
+	tx := createCurve(additive.CastNode, "j_spine", KeyPropertyTranslationX, PropFloat, []uint16{5}, float32(4))
+	tx.SetMode(CurveModeAdditive)
+	CreateProperty(tx.CastNode, PropNameAdditiveBlendWeight, PropFloat, float32(0.5))
+	ty := createCurve(additive.CastNode, "j_spine", KeyPropertyTranslationY, PropFloat, []uint16{0}, float32(1))
+	ty.SetMode(CurveModeAdditive)
+	sx := createCurve(additive.CastNode, "j_spine", KeyPropertyScaleX, PropFloat, []uint16{0}, float32(3))
+	sx.SetMode(CurveModeAdditive)
+	createCurve(additive.CastNode, "j_spine", KeyPropertyTranslationZ, PropFloat, []uint16{0}, float32(7))
+
+	combined, err := base.ApplyAdditive(additive, skeleton)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, combined.GetParentNode(), nil)
+	assertEqual(t, len(combined.Curves()), 4)
+
+	curve := combined.curve("j_spine", KeyPropertyTranslationX)
+	assertEqual(t, len(must(curve.KeyFrames())), 3)
+	assertEqual(t, must(curve.Evaluate(5)), 7)
+	assertEqual(t, must(curve.Evaluate(10)), 12)
+	assertEqual(t, must(curve.Mode()), CurveModeAbsolute)
+
+	assertEqual(t, must(combined.curve("j_spine", KeyPropertyTranslationY).Evaluate(0)), 3)
+	assertEqual(t, must(combined.curve("j_spine", KeyPropertyScaleX).Evaluate(0)), 6)
+	assertEqual(t, must(combined.curve("j_spine", KeyPropertyTranslationZ).Evaluate(0)), 7)
+
+	// the base animation is left untouched
+	assertEqual(t, len(base.Curves()), 2)
+	assertEqual(t, len(must(base.curve("j_spine", KeyPropertyTranslationX).KeyFrames())), 2)
+
+	if _, err := base.ApplyAdditive(additive, nil); err == nil {
+		t.Error("expected error for a missing base channel without skeleton")
+	}
+}
+
+func TestAnimationMergeCurves(t *testing.T) {
+	body := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation))
+	CreateProperty(body.CastNode, PropNameFramerate, PropFloat, float32(30))
+	createCurve(body.CastNode, "j_spine", KeyPropertyRotation, PropVector4, []uint16{0}, Vec4{W: 1})
+
+	face := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation))
+	CreateProperty(face.CastNode, PropNameFramerate, PropFloat, float32(30))
+	createCurve(face.CastNode, "j_jaw", KeyPropertyRotation, PropVector4, []uint16{0, 5}, Vec4{W: 1}, Vec4{X: 1})
+	createCurve(face.CastNode, "j_spine", KeyPropertyTranslationX, PropFloat, []uint16{0}, float32(1))
+
+	if err := body.MergeCurves(face); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(body.Curves()), 3)
+	assertEqual(t, len(face.Curves()), 2)
+	jaw := body.curve("j_jaw", KeyPropertyRotation)
+	assertEqual(t, jaw.GetParentNode(), body.CastNode)
+	assertEqual(t, len(must(jaw.KeyFrames())), 2)
+	assertEqual(t, body.file.FindByHash(jaw.hash), jaw.CastNode)
+
+	// curves are copied
+	SetProperty(face.curve("j_jaw", KeyPropertyRotation).CastNode, PropNameNodeName, "j_head")
+	assertEqual(t, jaw.NodeName(), "j_jaw")
+
+	err := body.MergeCurves(face)
+	assertEqual(t, errors.Is(err, ErrCurveConflict), true)
+	assertEqual(t, err.Error(), "cast: curves animate the same property: j_spine.tx")
+	assertEqual(t, len(body.Curves()), 3)
+
+	CreateProperty(face.CastNode, PropNameFramerate, PropFloat, float32(60))
+	assertEqual(t, body.MergeCurves(face) != nil, true)
+}
+
+func TestAnimationTrim(t *testing.T) {
+	animation := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation))
+	CreateProperty(animation.CastNode, PropNameFramerate, PropFloat, float32(30))
+	translation := createCurve(animation.CastNode, "j_root", KeyPropertyTranslationX, PropFloat, []uint16{0, 30}, float32(0), float32(3))
+	rotation := createCurve(animation.CastNode, "j_root", KeyPropertyRotation, PropVector4, []uint16{10, 20}, Vec4{W: 1}, Vec4{X: 1})
+	visibility := createCurve(animation.CastNode, "j_root", KeyPropertyVisibility, PropByte, []uint16{0, 15}, byte(1), byte(0))
+	before := createCurve(animation.CastNode, "j_root", KeyPropertyScaleX, PropFloat, []uint16{0, 2}, float32(1), float32(2))
+	track := animation.CreateChild(NodeIdNotificationTrack)
+	CreateProperty(track, PropNameKeyFrameBuffer, PropByte, []byte{5, 15, 25}...)
+	empty := animation.CreateChild(NodeIdNotificationTrack)
+	CreateProperty(empty, PropNameKeyFrameBuffer, PropByte, byte(1))
+
+	if err := animation.Trim(10, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, [2]uint32(must(translation.KeyFrames())), [2]uint32{0, 10})
+	assertEqual(t, [2]float32(must(GetPropertyValues[float32](translation.CastNode, PropNameKeyValueBuffer))), [2]float32{1, 2})
+
+	assertEqual(t, [2]uint32(must(rotation.KeyFrames())), [2]uint32{0, 10})
+	assertEqual(t, must(GetPropertyValues[Vec4](rotation.CastNode, PropNameKeyValueBuffer))[1], Vec4{X: 1})
+
+	assertEqual(t, [2]uint32(must(visibility.KeyFrames())), [2]uint32{0, 5})
+	assertEqual(t, [2]byte(must(GetPropertyValues[byte](visibility.CastNode, PropNameKeyValueBuffer))), [2]byte{1, 0})
+
+	assertEqual(t, [1]uint32(must(before.KeyFrames())), [1]uint32{0})
+	assertEqual(t, must(GetPropertyValues[float32](before.CastNode, PropNameKeyValueBuffer))[0], 2)
+
+	assertEqual(t, [1]byte(must(GetPropertyValues[byte](track, PropNameKeyFrameBuffer))), [1]byte{5})
+	assertEqual(t, empty.GetParentNode() == nil, true)
+	assertEqual(t, len(animation.GetChildrenOfType(NodeIdNotificationTrack)), 1)
+
+	start, end := animation.FrameRange()
+	assertEqual(t, start, 0)
+	assertEqual(t, end, 10)
+
+	// fractional ranges are rounded after rebasing
+	if err := animation.Trim(2.5, 7.5); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, [2]uint32(must(translation.KeyFrames())), [2]uint32{0, 5})
+	assertEqual(t, [2]float32(must(GetPropertyValues[float32](translation.CastNode, PropNameKeyValueBuffer))), [2]float32{1.25, 1.75})
+
+	assertEqual(t, animation.Trim(5, 4) != nil, true)
+	assertEqual(t, animation.Trim(-1, 4) != nil, true)
+}
+
+func TestAnimationConvertFramerate(t *testing.T) {
+	animation := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation))
+	CreateProperty(animation.CastNode, PropNameFramerate, PropFloat, float32(60))
+	translation := createCurve(animation.CastNode, "j_root", KeyPropertyTranslationX, PropShort, []uint16{0, 1, 2, 601}, uint16(0), uint16(1), uint16(2), uint16(3))
+	rotation := createCurve(animation.CastNode, "j_root", KeyPropertyRotation, PropVector4, []uint16{0, 30}, Vec4{W: 1}, Vec4{X: 1})
+	track := animation.CreateChild(NodeIdNotificationTrack)
+	CreateProperty(track, PropNameKeyFrameBuffer, PropShort, []uint16{3, 600}...)
+
+	if err := animation.ConvertFramerate(30, false); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, animation.Framerate(), 30)
+
+	// keyframes falling onto the same frame keep the first one
+	assertEqual(t, [3]uint32(must(translation.KeyFrames())), [3]uint32{0, 1, 301})
+	assertEqual(t, [3]uint16(must(GetPropertyValues[uint16](translation.CastNode, PropNameKeyValueBuffer))), [3]uint16{0, 1, 3})
+
+	assertEqual(t, [2]uint32(must(rotation.KeyFrames())), [2]uint32{0, 15})
+	assertEqual(t, must(GetPropertyValues[Vec4](rotation.CastNode, PropNameKeyValueBuffer))[1], Vec4{X: 1})
+	assertEqual(t, [2]uint32(must(GetPropertyValuesAsUint32(track, PropNameKeyFrameBuffer))), [2]uint32{2, 300})
+
+	if err := animation.ConvertFramerate(60, true); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, animation.Framerate(), 60)
+	assertEqual(t, len(must(rotation.KeyFrames())), 31)
+	assertEqual(t, [2]uint32(must(GetPropertyValuesAsUint32(track, PropNameKeyFrameBuffer))), [2]uint32{4, 600})
+
+	assertEqual(t, animation.ConvertFramerate(0, false) != nil, true)
+	CreateProperty(translation.CastNode, PropNameKeyValueBuffer, PropShort, uint16(1))
+	assertEqual(t, animation.ConvertFramerate(30, false) != nil, true)
+}
+
+func TestAnimationExtractRootMotion(t *testing.T) {
+	animation := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation))
+	CreateProperty(animation.CastNode, PropNameFramerate, PropFloat, float32(30))
+	tx := createCurve(animation.CastNode, "j_root", KeyPropertyTranslationX, PropShort, []uint16{0, 10}, uint16(0), uint16(50))
+	rq := createCurve(animation.CastNode, "j_root", KeyPropertyRotation, PropVector4, []uint16{0, 10}, Vec4{W: 1}, Vec4{Z: 1})
+	sx := createCurve(animation.CastNode, "j_root", KeyPropertyScaleX, PropFloat, []uint16{0}, float32(2))
+	spine := createCurve(animation.CastNode, "j_spine", KeyPropertyTranslationX, PropFloat, []uint16{0}, float32(3))
+	animation.CreateChild(NodeIdNotificationTrack)
+
+	motion, err := animation.ExtractRootMotion("j_root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, motion.GetParentNode() == nil, true)
+	assertEqual(t, motion.Framerate(), 30)
+	assertEqual(t, len(motion.childNodes), 2)
+	assertEqual(t, [2]uint16(must(GetPropertyValues[uint16](motion.curve("j_root", KeyPropertyTranslationX).CastNode, PropNameKeyValueBuffer))), [2]uint16{0, 50})
+	assertEqual(t, must(GetPropertyValues[Vec4](motion.curve("j_root", KeyPropertyRotation).CastNode, PropNameKeyValueBuffer))[1], Vec4{Z: 1})
+	assertEqual(t, animation.file.FindByHash(motion.hash) == nil, true)
+
+	// the body keeps its keyframes with zeroed values
+	assertEqual(t, [2]uint32(must(tx.KeyFrames())), [2]uint32{0, 10})
+	assertEqual(t, [2]uint16(must(GetPropertyValues[uint16](tx.CastNode, PropNameKeyValueBuffer))), [2]uint16{0, 0})
+	assertEqual(t, [2]Vec4(must(GetPropertyValues[Vec4](rq.CastNode, PropNameKeyValueBuffer))), [2]Vec4{{W: 1}, {W: 1}})
+	assertEqual(t, must(GetPropertyValues[float32](sx.CastNode, PropNameKeyValueBuffer))[0], 2)
+	assertEqual(t, must(GetPropertyValues[float32](spine.CastNode, PropNameKeyValueBuffer))[0], 3)
+	assertEqual(t, len(animation.childNodes), 5)
+
+	_, err = animation.ExtractRootMotion("j_hips")
+	assertEqual(t, err != nil, true)
+}
+
+func TestAnimationFrameRange(t *testing.T) {
+	animation := AsAnimation(New().CreateRoot().CreateChild(NodeIdAnimation))
+	start, end := animation.FrameRange()
+	assertEqual(t, start, 0)
+	assertEqual(t, end, 0)
+	assertEqual(t, animation.Duration(), 0)
+
+	CreateProperty(animation.CastNode, PropNameFramerate, PropFloat, float32(30))
+	createCurve(animation.CastNode, "j_root", KeyPropertyTranslationX, PropFloat, []uint16{15, 45}, float32(0), float32(1))
+	createCurve(animation.CastNode, "j_root", KeyPropertyRotation, PropVector4, []uint16{10, 20}, Vec4{W: 1}, Vec4{W: 1})
+	track := animation.CreateChild(NodeIdNotificationTrack)
+	CreateProperty(track, PropNameKeyFrameBuffer, PropInteger32, uint32(100))
+
+	start, end = animation.FrameRange()
+	assertEqual(t, start, 10)
+	assertEqual(t, end, 100)
+	assertEqual(t, animation.Duration(), 3*time.Second)
+}
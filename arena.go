@@ -0,0 +1,174 @@
+package cast
+
+import "sync"
+
+// ----------------------- //
+//          ARENA          //
+// ----------------------- //
+
+const (
+	arenaSlabSize    = 1024 // number of elements of each slab
+	arenaSmallValues = 256  // maximum number of values of a property allocated from the arena
+)
+
+// arenaPool holds the arenas of released files for reuse by later loads
+var arenaPool = sync.Pool{
+	New: func() any {
+		return new(arena)
+	},
+}
+
+// WithArena makes loading allocate the nodes, properties and small property buffers of the file from an arena
+// of large slabs. Once the file is no longer needed, [CastFile.Release] hands the slabs to later loads, which
+// greatly reduces the pressure on the garbage collector when processing many files in a row.
+func WithArena() LoadOption {
+	return func(d *decoder) {
+		d.arena = arenaPool.Get().(*arena)
+	}
+}
+
+// Release releases the memory of a file loaded with [WithArena] for reuse by later loads and empties the file.
+// Neither the file nor any of its nodes, properties or values may be used afterwards, even if they were moved
+// to another file. It only empties files loaded without an arena.
+func (n *CastFile) Release() {
+	n.rootNodes = nil
+	n.index = nil
+	if n.arena != nil {
+		n.arena.release()
+		n.arena = nil
+	}
+}
+
+// slab hands out slices of large chunks of elements
+type slab[T any] struct {
+	chunks [][]T
+	chunk  int // index of the current chunk
+	used   int // number of used elements of the current chunk
+}
+
+// alloc returns a slice of n elements, n must not exceed [arenaSlabSize]. The capacity of the slice is limited
+// to its length, so appending to it does not overwrite other slices.
+func (s *slab[T]) alloc(n int) []T {
+	for {
+		if s.chunk == len(s.chunks) {
+			s.chunks = append(s.chunks, make([]T, arenaSlabSize))
+		}
+
+		if s.used+n <= arenaSlabSize {
+			b := s.chunks[s.chunk][s.used : s.used+n : s.used+n]
+			s.used += n
+			return b
+		}
+		s.chunk++
+		s.used = 0
+	}
+}
+
+// reset zeroes the used chunks, so they do not keep their contents alive, and makes them available again
+func (s *slab[T]) reset() {
+	for _, c := range s.chunks[:min(s.chunk+1, len(s.chunks))] {
+		clear(c)
+	}
+	s.chunk = 0
+	s.used = 0
+}
+
+// typedArena holds the slabs of the properties and their values of a single type
+type typedArena[T CastPropertyValueType] struct {
+	properties slab[CastProperty[T]]
+	values     slab[T]
+}
+
+// newProperty allocates a property with the given number of values from the arena. Values exceeding
+// [arenaSmallValues] are allocated on the heap.
+func (a *typedArena[T]) newProperty(id CastPropertyId, name CastPropertyName, size uint32) *CastProperty[T] {
+	p := &a.properties.alloc(1)[0]
+	p.id = id
+	p.name = name
+	if size <= arenaSmallValues {
+		p.values = a.values.alloc(int(size))
+	} else {
+		p.values = make([]T, size)
+	}
+	return p
+}
+
+// reset resets the slabs
+func (a *typedArena[T]) reset() {
+	a.properties.reset()
+	a.values.reset()
+}
+
+// arena holds the slabs the nodes and properties of a loaded file are allocated from
+type arena struct {
+	nodes      slab[CastNode]
+	nodeRefs   slab[*CastNode]
+	properties slab[iCastProperty]
+
+	bytes    typedArena[byte]
+	shorts   typedArena[uint16]
+	integers typedArena[uint32]
+	longs    typedArena[uint64]
+	floats   typedArena[float32]
+	doubles  typedArena[float64]
+	strings  typedArena[string]
+	vec2s    typedArena[Vec2]
+	vec3s    typedArena[Vec3]
+	vec4s    typedArena[Vec4]
+}
+
+// newNode allocates a node with room for the given number of properties and children
+func (a *arena) newNode(header castNodeHeader) *CastNode {
+	n := &a.nodes.alloc(1)[0]
+	n.id = header.Id
+	n.hash = header.NodeHash
+	n.properties = a.properties.alloc(capacity(header.PropertyCount))[:0]
+	n.childNodes = a.nodeRefs.alloc(capacity(header.ChildCount))[:0]
+	return n
+}
+
+// newProperty allocates a property with the given type, name and number of values
+func (a *arena) newProperty(id CastPropertyId, name CastPropertyName, size uint32) (iCastProperty, error) {
+	switch id {
+	case PropByte:
+		return a.bytes.newProperty(id, name, size), nil
+	case PropShort:
+		return a.shorts.newProperty(id, name, size), nil
+	case PropInteger32:
+		return a.integers.newProperty(id, name, size), nil
+	case PropInteger64:
+		return a.longs.newProperty(id, name, size), nil
+	case PropFloat:
+		return a.floats.newProperty(id, name, size), nil
+	case PropDouble:
+		return a.doubles.newProperty(id, name, size), nil
+	case PropString:
+		return a.strings.newProperty(id, name, size), nil
+	case PropVector2:
+		return a.vec2s.newProperty(id, name, size), nil
+	case PropVector3:
+		return a.vec3s.newProperty(id, name, size), nil
+	case PropVector4:
+		return a.vec4s.newProperty(id, name, size), nil
+	default:
+		return newCastProperty(id, name, size)
+	}
+}
+
+// release resets the slabs and returns the arena to the pool
+func (a *arena) release() {
+	a.nodes.reset()
+	a.nodeRefs.reset()
+	a.properties.reset()
+	a.bytes.reset()
+	a.shorts.reset()
+	a.integers.reset()
+	a.longs.reset()
+	a.floats.reset()
+	a.doubles.reset()
+	a.strings.reset()
+	a.vec2s.reset()
+	a.vec3s.reset()
+	a.vec4s.reset()
+	arenaPool.Put(a)
+}
@@ -0,0 +1,78 @@
+package cast
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestArena(t *testing.T) {
+	for _, f := range []string{"cube.cast", "cast_ik.cast", "pilot_medium_bangalore_LOD0.cast"} {
+		data, err := os.ReadFile("testdata/" + f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want bytes.Buffer
+		if err := must(Load(bytes.NewReader(data))).Write(&want); err != nil {
+			t.Fatal(err)
+		}
+
+		// load twice so the second load reuses the released arena
+		for range 2 {
+			castFile, err := Load(bytes.NewReader(data), WithArena())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got bytes.Buffer
+			if err := castFile.Write(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("%s: output differs when loaded with an arena", f)
+			}
+
+			castFile.Release()
+			assertEqual(t, len(castFile.Roots()), 0)
+		}
+	}
+
+	if _, err := Load(bytes.NewReader([]byte("invalid")), WithArena()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestArenaSlab(t *testing.T) {
+	var s slab[uint32]
+	a := s.alloc(2)
+	b := s.alloc(2)
+	a = append(a, 7)
+	assertEqual(t, b[0], 0)
+
+	c := s.alloc(arenaSlabSize)
+	assertEqual(t, len(c), arenaSlabSize)
+	assertEqual(t, len(s.chunks), 2)
+
+	c[0] = 1
+	s.reset()
+	assertEqual(t, s.chunks[1][0], 0)
+	assertEqual(t, len(s.alloc(1)), 1)
+	assertEqual(t, len(s.chunks), 2)
+}
+
+func BenchmarkLoadArena(b *testing.B) {
+	data, err := os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		castFile, err := Load(bytes.NewReader(data), WithArena())
+		if err != nil {
+			b.Fatal(err)
+		}
+		castFile.Release()
+	}
+}
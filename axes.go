@@ -0,0 +1,197 @@
+package cast
+
+import "fmt"
+
+// ----------------------- //
+//          AXES           //
+// ----------------------- //
+
+// AxisConvention is the orientation of the coordinate system of a file
+type AxisConvention int
+
+const (
+	YUpRightHanded AxisConvention = iota // X right, Y up, Z towards the viewer
+	ZUpRightHanded                       // X right, Z up, Y away from the viewer
+	YUpLeftHanded                        // X right, Y up, Z away from the viewer
+	ZUpLeftHanded                        // X right, Z up, Y towards the viewer
+)
+
+// axisConventionBases holds the matrices converting from [YUpRightHanded] to each convention
+var axisConventionBases = map[AxisConvention]Mat4{
+	YUpRightHanded: Ident4(),
+	ZUpRightHanded: {
+		1, 0, 0, 0,
+		0, 0, 1, 0,
+		0, -1, 0, 0,
+		0, 0, 0, 1,
+	},
+	YUpLeftHanded: {
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, -1, 0,
+		0, 0, 0, 1,
+	},
+	ZUpLeftHanded: {
+		1, 0, 0, 0,
+		0, 0, 1, 0,
+		0, 1, 0, 0,
+		0, 0, 0, 1,
+	},
+}
+
+// ConvertAxes converts the file from one axis convention to another. Vertex positions, normals and tangents
+// of all meshes, bone and instance transforms and translation, rotation and scale curves are converted. When
+// the handedness changes the winding order of the faces is reversed.
+func ConvertAxes(file *CastFile, from, to AxisConvention) error {
+	fromBasis, ok := axisConventionBases[from]
+	if !ok {
+		return fmt.Errorf("cast: invalid axis convention: %d", from)
+	}
+	toBasis, ok := axisConventionBases[to]
+	if !ok {
+		return fmt.Errorf("cast: invalid axis convention: %d", to)
+	}
+	if from == to {
+		return nil
+	}
+
+	// the bases are signed permutations, so their inverse is their transpose
+	c := toBasis.Mul(transpose(fromBasis))
+
+	for n := range file.AllNodes() {
+		var err error
+		switch n.id {
+		case NodeIdMesh:
+			err = AsMesh(n).BakeTransform(c)
+		case NodeIdBone:
+			convertTransform(n, c, PropNameLocalPosition, PropNameLocalRotation, PropNameScale)
+			convertTransform(n, c, PropNameWorldPosition, PropNameWorldRotation, "")
+		case NodeIdInstance:
+			convertTransform(n, c, PropNamePosition, PropNameRotation, PropNameScale)
+		case NodeIdCurve:
+			err = convertCurve(AsCurve(n), c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertTransform converts the position, rotation and scale properties with the given names by the change of basis
+func convertTransform(n *CastNode, c Mat4, position, rotation, scale CastPropertyName) {
+	if values, err := GetPropertyValues[Vec3](n, position); err == nil {
+		for i, v := range values {
+			values[i] = c.TransformDirection(v)
+		}
+	}
+
+	if values, err := GetPropertyValues[Vec4](n, rotation); err == nil {
+		for i, q := range values {
+			values[i] = convertRotation(q, c)
+		}
+	}
+
+	if scale == "" {
+		return
+	}
+	if values, err := GetPropertyValues[Vec3](n, scale); err == nil {
+		a := absolute(c)
+		for i, v := range values {
+			values[i] = a.TransformDirection(v)
+		}
+	}
+}
+
+// convertRotation converts a rotation quaternion by the change of basis. The rotation axis is transformed
+// as an axial vector, so it is negated by a change of handedness while the angle stays the same.
+func convertRotation(q Vec4, c Mat4) Vec4 {
+	axis := c.TransformDirection(Vec3{q.X, q.Y, q.Z})
+	if c.det3() < 0 {
+		axis = Vec3{-axis.X, -axis.Y, -axis.Z}
+	}
+	return Vec4{axis.X, axis.Y, axis.Z, q.W}
+}
+
+// convertCurve converts the keyframe values of the curve by the change of basis. Curves animating a single
+// component of a translation or scale are moved to the component they map to.
+func convertCurve(curve *Curve, c Mat4) error {
+	switch kp := curve.KeyProperty(); kp {
+	case KeyPropertyRotation:
+		values, err := GetPropertyValues[Vec4](curve.CastNode, PropNameKeyValueBuffer)
+		if err != nil {
+			return err
+		}
+		for i, q := range values {
+			values[i] = convertRotation(q, c)
+		}
+	case KeyPropertyTranslationX, KeyPropertyTranslationY, KeyPropertyTranslationZ,
+		KeyPropertyScaleX, KeyPropertyScaleY, KeyPropertyScaleZ:
+		src := int(kp[1] - 'x')
+		for dst := range 3 {
+			factor := c.At(dst, src)
+			if factor == 0 {
+				continue
+			}
+			if kp[0] == 's' {
+				factor = 1
+			}
+
+			if err := scaleKeyValues(curve, factor); err != nil {
+				return err
+			}
+			_, err := CreateProperty(curve.CastNode, PropNameKeyProperty, PropString, string([]byte{kp[0], byte('x' + dst)}))
+			return err
+		}
+	}
+	return nil
+}
+
+// scaleKeyValues multiplies the keyframe values of the curve by the given factor
+func scaleKeyValues(curve *Curve, factor float32) error {
+	if factor == 1 {
+		return nil
+	}
+
+	p, ok := curve.GetProperty(PropNameKeyValueBuffer)
+	if !ok {
+		return fmt.Errorf(`cast: property %s not found`, PropNameKeyValueBuffer)
+	}
+
+	switch p := p.(type) {
+	case *CastProperty[float32]:
+		values := p.GetValues()
+		for i := range values {
+			values[i] *= factor
+		}
+	case *CastProperty[float64]:
+		values := p.GetValues()
+		for i := range values {
+			values[i] *= float64(factor)
+		}
+	default:
+		return fmt.Errorf("cast: key value buffer has a type of %T", p)
+	}
+	return nil
+}
+
+// transpose returns the transpose of the matrix
+func transpose(m Mat4) Mat4 {
+	var t Mat4
+	for r := range 4 {
+		for c := range 4 {
+			t[c*4+r] = m[r*4+c]
+		}
+	}
+	return t
+}
+
+// absolute returns the matrix with the absolute values of its elements
+func absolute(m Mat4) Mat4 {
+	for i, v := range m {
+		if v < 0 {
+			m[i] = -v
+		}
+	}
+	return m
+}
@@ -0,0 +1,62 @@
+package cast
+
+import "testing"
+
+func TestConvertAxes(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	mesh := AsMesh(root.CreateChild(NodeIdModel).CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, Vec3{}, Vec3{X: 1}, Vec3{Y: 1})
+	mesh.SetFaces(0, 1, 2)
+
+	bone := root.CreateChild(NodeIdBone)
+	CreateProperty(bone, PropNameLocalPosition, PropVector3, Vec3{X: 1, Y: 2, Z: 3})
+	CreateProperty(bone, PropNameLocalRotation, PropVector4, Vec4{X: 0.5, Y: 0.5, Z: 0.5, W: 0.5})
+	CreateProperty(bone, PropNameScale, PropVector3, Vec3{X: 1, Y: 2, Z: 3})
+
+	animation := root.CreateChild(NodeIdAnimation)
+	curve := animation.CreateChild(NodeIdCurve)
+	CreateProperty(curve, PropNameKeyProperty, PropString, KeyPropertyTranslationY)
+	CreateProperty(curve, PropNameKeyValueBuffer, PropFloat, float32(1), float32(2))
+
+	if err := ConvertAxes(castFile, YUpRightHanded, ZUpRightHanded); err != nil {
+		t.Fatal(err)
+	}
+
+	positions, _ := GetPropertyValues[Vec3](mesh.CastNode, PropNameVertexPositionBuffer)
+	assertEqual(t, positions[2], Vec3{Z: 1})
+
+	lp, _ := GetPropertyValue[Vec3](bone, PropNameLocalPosition)
+	assertEqual(t, *lp, Vec3{X: 1, Y: -3, Z: 2})
+	lr, _ := GetPropertyValue[Vec4](bone, PropNameLocalRotation)
+	assertEqual(t, *lr, Vec4{X: 0.5, Y: -0.5, Z: 0.5, W: 0.5})
+	s, _ := GetPropertyValue[Vec3](bone, PropNameScale)
+	assertEqual(t, *s, Vec3{X: 1, Y: 3, Z: 2})
+
+	assertEqual(t, AsCurve(curve).KeyProperty(), KeyPropertyTranslationZ)
+	values, _ := GetPropertyValues[float32](curve, PropNameKeyValueBuffer)
+	assertEqual(t, values[1], 2)
+
+	// a change of handedness mirrors the data and reverses the winding order
+	if err := ConvertAxes(castFile, ZUpRightHanded, ZUpLeftHanded); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, *lp, Vec3{X: 1, Y: 3, Z: 2})
+	assertEqual(t, *lr, Vec4{X: -0.5, Y: -0.5, Z: -0.5, W: 0.5})
+	faces, _ := mesh.Faces()
+	assertEqual(t, [3]uint32(faces), [3]uint32{0, 2, 1})
+	assertEqual(t, AsCurve(curve).KeyProperty(), KeyPropertyTranslationZ)
+
+	if err := ConvertAxes(castFile, ZUpLeftHanded, YUpRightHanded); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, *lp, Vec3{X: 1, Y: 2, Z: 3})
+	assertEqual(t, *lr, Vec4{X: 0.5, Y: 0.5, Z: 0.5, W: 0.5})
+	assertEqual(t, AsCurve(curve).KeyProperty(), KeyPropertyTranslationY)
+	faces, _ = mesh.Faces()
+	assertEqual(t, [3]uint32(faces), [3]uint32{0, 1, 2})
+
+	if err := ConvertAxes(castFile, YUpRightHanded, AxisConvention(42)); err == nil {
+		t.Error("expected error for an invalid convention")
+	}
+}
@@ -0,0 +1,175 @@
+package cast
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ----------------------- //
+//       BLEND SHAPE       //
+// ----------------------- //
+
+// BlendShape wraps a blend shape node deforming a base mesh towards target meshes
+type BlendShape struct {
+	*CastNode
+}
+
+// AsBlendShape returns the node as a [BlendShape], nil if it is not a blend shape node
+func AsBlendShape(n *CastNode) *BlendShape {
+	if n == nil || n.id != NodeIdBlendShape {
+		return nil
+	}
+	return &BlendShape{n}
+}
+
+// BlendShapes returns the blend shapes of the model
+func (m *Model) BlendShapes() []*BlendShape {
+	var shapes []*BlendShape
+	for _, c := range m.GetChildrenOfType(NodeIdBlendShape) {
+		shapes = append(shapes, AsBlendShape(c))
+	}
+	return shapes
+}
+
+// Name returns the name of the blend shape
+func (b *BlendShape) Name() string {
+	return GetPropertyValueOr(b.CastNode, PropNameName, "")
+}
+
+// BaseShape returns the mesh deformed by the blend shape, nil if it is not found
+func (b *BlendShape) BaseShape() *Mesh {
+	return AsMesh(b.ResolveReference(PropNameBaseShape))
+}
+
+// SetBaseShape sets the mesh deformed by the blend shape
+func (b *BlendShape) SetBaseShape(mesh *Mesh) error {
+	_, err := CreateProperty(b.CastNode, PropNameBaseShape, PropInteger64, mesh.hash)
+	return err
+}
+
+// BlendShapeTarget is a target shape of a [BlendShape] along with the scale of its weight
+type BlendShapeTarget struct {
+	Hash  uint64  // Hash is the hash of the target mesh
+	Mesh  *Mesh   // Mesh is the target mesh, nil if the hash is not found
+	Scale float32 // Scale is the scale of the weight of the target
+}
+
+// Targets returns the target shapes of the blend shape paired with the scales of their weights, which default
+// to 1 for targets without a scale
+func (b *BlendShape) Targets() []BlendShapeTarget {
+	hashes, err := GetPropertyValues[uint64](b.CastNode, PropNameTargetShape)
+	if err != nil {
+		return nil
+	}
+	scales, _ := GetPropertyValues[float32](b.CastNode, PropNameTargetWeightScale)
+
+	nodes := b.resolveHashes(hashes...)
+	targets := make([]BlendShapeTarget, len(hashes))
+	for i, hash := range hashes {
+		targets[i] = BlendShapeTarget{Hash: hash, Mesh: AsMesh(nodes[i]), Scale: 1}
+		if i < len(scales) {
+			targets[i].Scale = scales[i]
+		}
+	}
+	return targets
+}
+
+// AddTarget adds the mesh as a target shape of the blend shape with the given scale of its weight. The
+// scales of the existing targets are filled in with 1 if they are missing.
+func (b *BlendShape) AddTarget(mesh *Mesh, scale float32) error {
+	hashes, _ := GetPropertyValues[uint64](b.CastNode, PropNameTargetShape)
+	scales := b.targetScales(len(hashes))
+
+	if _, err := CreateProperty(b.CastNode, PropNameTargetShape, PropInteger64, append(slices.Clip(hashes), mesh.hash)...); err != nil {
+		return err
+	}
+	_, err := CreateProperty(b.CastNode, PropNameTargetWeightScale, PropFloat, append(scales, scale)...)
+	return err
+}
+
+// SetTargetScale sets the scale of the weight of the given target shape. Returns an error if the mesh is not
+// a target of the blend shape.
+func (b *BlendShape) SetTargetScale(mesh *Mesh, scale float32) error {
+	hashes, _ := GetPropertyValues[uint64](b.CastNode, PropNameTargetShape)
+	i := slices.Index(hashes, mesh.hash)
+	if i < 0 {
+		return fmt.Errorf("cast: mesh %#x is not a target of blend shape %q", mesh.hash, b.Name())
+	}
+
+	scales := b.targetScales(len(hashes))
+	scales[i] = scale
+	_, err := CreateProperty(b.CastNode, PropNameTargetWeightScale, PropFloat, scales...)
+	return err
+}
+
+// targetScales returns a copy of the target weight scales with the given length, missing scales are 1
+func (b *BlendShape) targetScales(count int) []float32 {
+	stored, _ := GetPropertyValues[float32](b.CastNode, PropNameTargetWeightScale)
+	scales := make([]float32, count, count+1)
+	for i := range scales {
+		scales[i] = 1
+		if i < len(stored) {
+			scales[i] = stored[i]
+		}
+	}
+	return scales
+}
+
+// VertexDelta is the offset of a vertex of a target shape from the base shape
+type VertexDelta struct {
+	Index uint32 // Index is the index of the vertex
+	Delta Vec3   // Delta is the offset of the target position from the base position
+}
+
+// ShapeDeltas returns the offsets of the vertex positions of the target shape from the ones of the base shape,
+// for formats storing morph targets as offsets instead of absolute geometry. Both meshes must have the same
+// number of vertices. Deltas whose components are all within the tolerance are left out, a negative tolerance
+// keeps every delta and a tolerance of 0 leaves out the vertices that did not move.
+func ShapeDeltas(base, target *Mesh, tolerance float32) ([]VertexDelta, error) {
+	basePositions, err := GetPropertyValues[Vec3](base.CastNode, PropNameVertexPositionBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPositions, err := GetPropertyValues[Vec3](target.CastNode, PropNameVertexPositionBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(basePositions) != len(targetPositions) {
+		return nil, fmt.Errorf("cast: target shape has %d vertices instead of %d", len(targetPositions), len(basePositions))
+	}
+
+	var deltas []VertexDelta
+	for i := range basePositions {
+		d := subVec3(targetPositions[i], basePositions[i])
+		if tolerance >= 0 && max(d.X, -d.X, d.Y, -d.Y, d.Z, -d.Z) <= tolerance {
+			continue
+		}
+		deltas = append(deltas, VertexDelta{Index: uint32(i), Delta: d})
+	}
+	return deltas, nil
+}
+
+// TargetDeltas returns the vertex deltas of every target shape from the base shape in the order of the
+// targets, see [ShapeDeltas]. Returns an error if the base shape or a target shape is not found.
+func (b *BlendShape) TargetDeltas(tolerance float32) ([][]VertexDelta, error) {
+	base := b.BaseShape()
+	if base == nil {
+		return nil, fmt.Errorf("%w: base shape of blend shape %q", ErrUnresolved, b.Name())
+	}
+
+	targets := b.Targets()
+	deltas := make([][]VertexDelta, len(targets))
+	for i, target := range targets {
+		if target.Mesh == nil {
+			return nil, fmt.Errorf("%w: target shape %#x of blend shape %q", ErrUnresolved, target.Hash, b.Name())
+		}
+
+		var err error
+		if deltas[i], err = ShapeDeltas(base, target.Mesh, tolerance); err != nil {
+			return nil, err
+		}
+	}
+	return deltas, nil
+}
@@ -0,0 +1,77 @@
+package cast
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBlendShape(t *testing.T) {
+	model := AsModel(New().CreateRoot().CreateChild(NodeIdModel))
+	base := AsMesh(model.CreateChild(NodeIdMesh))
+	smile := AsMesh(model.CreateChild(NodeIdMesh))
+	blink := AsMesh(model.CreateChild(NodeIdMesh))
+
+	shape := AsBlendShape(model.CreateChild(NodeIdBlendShape))
+	CreateProperty(shape.CastNode, PropNameName, PropString, "face")
+	if err := shape.SetBaseShape(base); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, shape.BaseShape().CastNode, base.CastNode)
+	assertEqual(t, len(shape.Targets()), 0)
+
+	// targets without scales default to 1
+	CreateProperty(shape.CastNode, PropNameTargetShape, PropInteger64, smile.Hash(), 42)
+	targets := shape.Targets()
+	assertEqual(t, len(targets), 2)
+	assertEqual(t, targets[0].Mesh.CastNode, smile.CastNode)
+	assertEqual(t, targets[0].Scale, 1)
+	assertEqual(t, targets[1].Mesh == nil, true)
+	assertEqual(t, targets[1].Hash, 42)
+
+	if err := shape.AddTarget(blink, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := shape.SetTargetScale(smile, 2); err != nil {
+		t.Fatal(err)
+	}
+	targets = shape.Targets()
+	assertEqual(t, len(targets), 3)
+	assertEqual(t, targets[2].Mesh.CastNode, blink.CastNode)
+	assertEqual(t, [3]float32(must(GetPropertyValues[float32](shape.CastNode, PropNameTargetWeightScale))), [3]float32{2, 1, 0.5})
+
+	assertEqual(t, shape.SetTargetScale(base, 1) != nil, true)
+	assertEqual(t, len(model.BlendShapes()), 1)
+	if AsBlendShape(base.CastNode) != nil {
+		t.Error("expected nil for a non blend shape node")
+	}
+}
+
+func TestShapeDeltas(t *testing.T) {
+	model := AsModel(New().CreateRoot().CreateChild(NodeIdModel))
+	base := AsMesh(model.CreateChild(NodeIdMesh))
+	CreateProperty(base.CastNode, PropNameVertexPositionBuffer, PropVector3, Vec3{}, Vec3{X: 1}, Vec3{Y: 1})
+	target := AsMesh(model.CreateChild(NodeIdMesh))
+	CreateProperty(target.CastNode, PropNameVertexPositionBuffer, PropVector3, Vec3{}, Vec3{X: 1, Z: 0.5}, Vec3{Y: 1.0001})
+
+	deltas := must(ShapeDeltas(base, target, 0))
+	assertEqual(t, len(deltas), 2)
+	assertEqual(t, deltas[0], VertexDelta{Index: 1, Delta: Vec3{Z: 0.5}})
+	assertEqual(t, deltas[1].Index, 2)
+
+	assertEqual(t, len(must(ShapeDeltas(base, target, 0.001))), 1)
+	assertEqual(t, len(must(ShapeDeltas(base, target, -1))), 3)
+
+	shape := AsBlendShape(model.CreateChild(NodeIdBlendShape))
+	_, err := shape.TargetDeltas(0)
+	assertEqual(t, errors.Is(err, ErrUnresolved), true)
+
+	shape.SetBaseShape(base)
+	shape.AddTarget(target, 1)
+	targetDeltas := must(shape.TargetDeltas(0.001))
+	assertEqual(t, len(targetDeltas), 1)
+	assertEqual(t, targetDeltas[0][0].Index, 1)
+
+	CreateProperty(target.CastNode, PropNameVertexPositionBuffer, PropVector3, Vec3{})
+	_, err = ShapeDeltas(base, target, 0)
+	assertEqual(t, err != nil, true)
+}
@@ -1,20 +1,33 @@
 package cast
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"runtime"
+	"slices"
+	"strings"
 )
 
 const (
-	castMagic uint32 = 0x74736163
+	castMagic    uint32 = 0x74736163
+	castHashBase uint64 = 0x534E495752545250
 )
 
 var (
-	castHashBase uint64 = 0x534E495752545250
-
-	ErrEmptyValues = errors.New("cast: empty values")
+	defaultHashGenerator = NewSequentialHashGenerator(castHashBase)
+
+	ErrEmptyValues   = errors.New("cast: empty values")
+	ErrMergeSelf     = errors.New("cast: cannot merge a file into itself")
+	ErrHashInUse     = errors.New("cast: hash is already in use")
+	ErrInvalidMove   = errors.New("cast: cannot move a node into its own subtree or to a nil parent")
+	ErrNotRoot       = errors.New("cast: node is not a root node of the file")
+	ErrUnresolved    = errors.New("cast: file could not be resolved")
+	ErrLimitExceeded = errors.New("cast: load limit exceeded")
+	ErrSizeOverflow  = errors.New("cast: size exceeds the limits of the format")
+	ErrCurveConflict = errors.New("cast: curves animate the same property")
 )
 
 // ----------------------- //
@@ -29,46 +42,113 @@ type castHeader struct {
 	Flags     uint32
 }
 
+// CastFileFlag is a bit of the flags field of the file header. The specification reserves the field and
+// currently defines no bits, files written by the reference implementation store [FlagNone]. Other bits are
+// kept as they are when loading and writing a file.
+type CastFileFlag uint32
+
+const (
+	FlagNone CastFileFlag = 0 // No flags set
+)
+
 // CastFile holds data of a cast file
 type CastFile struct {
-	flags     uint32
-	version   uint32
-	rootNodes []*CastNode
+	flags         uint32
+	version       uint32
+	rootNodes     []*CastNode
+	index         map[uint64]*CastNode
+	hashGenerator HashGenerator
+	contentHashes bool
+	warnings      []Warning
+	arena         *arena
 }
 
-// New creates a new [CastFile]
-func New() *CastFile {
-	return &CastFile{
-		flags:     0,
-		version:   0x1,
-		rootNodes: make([]*CastNode, 0),
+// Option configures a [CastFile] created by [New]
+type Option func(f *CastFile)
+
+// WithHashGenerator sets the generator used to assign hashes to the nodes created in the file
+func WithHashGenerator(g HashGenerator) Option {
+	return func(f *CastFile) {
+		f.hashGenerator = g
 	}
 }
 
-// Load loads a [castFile] from the given [io.Reader]
-func Load(r io.Reader) (*CastFile, error) {
-	var header castHeader
-	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return nil, err
+// WithContentHashes makes [CastFile.Write] assign content based hashes to all nodes before writing,
+// see [CastFile.AssignContentHashes]
+func WithContentHashes() Option {
+	return func(f *CastFile) {
+		f.contentHashes = true
 	}
+}
 
-	if header.Magic != castMagic {
-		return nil, fmt.Errorf("invalid cast file magic: %#x", header.Magic)
+// New creates a new [CastFile]. Unless a different generator is given, node hashes are assigned by a
+// sequential counter owned by the file, so files can be built concurrently from different goroutines.
+func New(opts ...Option) *CastFile {
+	castFile := &CastFile{
+		flags:         0,
+		version:       0x1,
+		rootNodes:     make([]*CastNode, 0),
+		hashGenerator: NewSequentialHashGenerator(castHashBase),
 	}
 
-	castFile := &CastFile{
-		flags:     header.Flags,
-		version:   header.Version,
-		rootNodes: make([]*CastNode, header.RootNodes),
+	for _, opt := range opts {
+		opt(castFile)
 	}
+	return castFile
+}
+
+// Load loads a [castFile] from the given [io.Reader]. Failures are reported as a [*LoadError]
+// describing where in the file the failure occurred. Lenient options like [WithResyncNodes] allow
+// loading damaged files, the degraded data is reported by [CastFile.Warnings]. Inconsistencies that are
+// kept as they are, like mismatching UV layer counts, are reported there as well.
+func Load(r io.Reader, opts ...LoadOption) (*CastFile, error) {
+	d := decoderPool.Get().(*decoder)
+	defer decoderPool.Put(d)
+	return d.load(r, opts)
+}
+
+// LoadPartial loads a [CastFile] from the given [io.Reader] like [Load], but on failure returns the tree
+// decoded so far along with the error. The node that failed to load and its ancestors hold the properties
+// and children decoded before the failure, the property that failed is left out. The returned file is nil
+// only if the file header could not be read.
+func LoadPartial(r io.Reader, opts ...LoadOption) (*CastFile, error) {
+	d := decoderPool.Get().(*decoder)
+	defer decoderPool.Put(d)
+	return d.loadPartial(r, opts)
+}
 
-	for i := range castFile.rootNodes {
-		castFile.rootNodes[i] = &CastNode{}
-		if err := castFile.rootNodes[i].load(r); err != nil {
-			return nil, err
+// Header holds the fields of a file header, see [ReadHeader]
+type Header struct {
+	Magic     uint32 // Magic identifying cast files
+	Version   uint32 // Version of the format
+	RootNodes uint32 // RootNodes is the number of root nodes
+	Flags     uint32 // Flags of the file, see [CastFileFlag]
+}
+
+// ReadHeader reads only the file header from the given [io.Reader], leaving the remaining data unread. It
+// does not fail on an invalid magic, so the header of any data can be inspected, see [Header.Valid].
+func ReadHeader(r io.Reader) (Header, error) {
+	var b [castHeaderSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
 		}
+		return Header{}, fmt.Errorf("cast: reading header: %w", err)
 	}
-	return castFile, nil
+
+	var header castHeader
+	header.decode(b[:])
+	return Header(header), nil
+}
+
+// Valid reports whether the header holds the magic of cast files
+func (h Header) Valid() bool {
+	return h.Magic == castMagic
+}
+
+// HasFlag reports whether all bits of the given flag are set
+func (h Header) HasFlag(flag CastFileFlag) bool {
+	return h.Flags&uint32(flag) == uint32(flag)
 }
 
 // Flags returns the flags
@@ -82,6 +162,23 @@ func (n *CastFile) SetFlags(flags uint32) *CastFile {
 	return n
 }
 
+// HasFlag reports whether all bits of the given flag are set
+func (n *CastFile) HasFlag(flag CastFileFlag) bool {
+	return n.flags&uint32(flag) == uint32(flag)
+}
+
+// AddFlag sets the bits of the given flag
+func (n *CastFile) AddFlag(flag CastFileFlag) *CastFile {
+	n.flags |= uint32(flag)
+	return n
+}
+
+// ClearFlag clears the bits of the given flag
+func (n *CastFile) ClearFlag(flag CastFileFlag) *CastFile {
+	n.flags &^= uint32(flag)
+	return n
+}
+
 // Version returns the version
 func (n *CastFile) Version() uint32 {
 	return n.version
@@ -93,35 +190,149 @@ func (n *CastFile) SetVersion(version uint32) *CastFile {
 	return n
 }
 
-// Roots returns the root nodes
+// SetHashGenerator sets the generator used to assign hashes to the nodes created in the file
+func (n *CastFile) SetHashGenerator(g HashGenerator) *CastFile {
+	n.hashGenerator = g
+	return n
+}
+
+// Roots returns the root nodes. The returned slice is owned by the file and must not be modified,
+// use [CastFile.CreateRoot], [CastFile.RemoveRoot] and [CastFile.ReplaceRoot] instead.
 func (n *CastFile) Roots() []*CastNode {
 	return n.rootNodes
 }
 
+// GetNodesOfType returns every node of the file with the given type in depth-first order
+func (n *CastFile) GetNodesOfType(id CastNodeId) []*CastNode {
+	return n.Find(ByType(id))
+}
+
 // CreateRoot creates a root node
 func (n *CastFile) CreateRoot() *CastNode {
-	root := newCastNode(NodeIdRoot)
+	root := newCastNode(NodeIdRoot, n)
 	n.rootNodes = append(n.rootNodes, root)
+	n.adopt(root)
 	return root
 }
 
-// Write writes the file to the given [io.Writer]
+// Write writes the file to the given [io.Writer]. Files with several root nodes have their roots encoded
+// concurrently into separate buffers, which are written in order, so the output is the same as when writing
+// them one after another. The tree must not be modified while it is written. Nothing is written if a value
+// does not fit its header field, e.g. a node exceeding 4 GiB, which is reported as an [*OverflowError].
 func (n *CastFile) Write(w io.Writer) error {
-	if err := binary.Write(w, binary.LittleEndian, castHeader{
+	if n.contentHashes {
+		n.AssignContentHashes()
+	}
+
+	if err := n.checkSizes(); err != nil {
+		return err
+	}
+
+	header := castHeader{
 		Magic:     castMagic,
 		Version:   n.version,
 		RootNodes: uint32(len(n.rootNodes)),
 		Flags:     n.flags,
-	}); err != nil {
+	}
+	if _, err := w.Write(header.append(make([]byte, 0, castHeaderSize))); err != nil {
+		return err
+	}
+
+	if len(n.rootNodes) > 1 && runtime.GOMAXPROCS(0) > 1 {
+		return writeNodesParallel(w, n.rootNodes)
+	}
+	return writeNodes(w, n.rootNodes)
+}
+
+// MarshalBinary returns the encoding of the file as written by [CastFile.Write], implementing
+// [encoding.BinaryMarshaler]
+func (n *CastFile) MarshalBinary() ([]byte, error) {
+	size := castHeaderSize
+	for _, root := range n.rootNodes {
+		size += root.len()
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	if err := n.Write(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the contents of the file with the file decoded from the given data like [Load],
+// implementing [encoding.BinaryUnmarshaler]. The hash generator of the file and [WithContentHashes] are kept,
+// a zero file gets the generator of a loaded file. The previous contents are released with [CastFile.Release].
+// The file is left unchanged if the data cannot be decoded.
+func (n *CastFile) UnmarshalBinary(data []byte) error {
+	f, err := Load(bytes.NewReader(data))
+	if err != nil {
 		return err
 	}
 
-	for _, rootNode := range n.rootNodes {
-		if err := rootNode.write(w); err != nil {
+	n.Release()
+	n.flags = f.flags
+	n.version = f.version
+	n.rootNodes = f.rootNodes
+	n.warnings = f.warnings
+	if n.hashGenerator == nil {
+		n.hashGenerator = f.hashGenerator
+	}
+	for _, root := range n.rootNodes {
+		n.adopt(root)
+	}
+	return nil
+}
+
+// writeNodes writes the nodes one after another
+func writeNodes(w io.Writer, nodes []*CastNode) error {
+	for _, node := range nodes {
+		if err := node.write(w); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// writeNodesParallel encodes the nodes into separate buffers concurrently and writes the buffers in order as
+// soon as they are complete
+func writeNodesParallel(w io.Writer, nodes []*CastNode) error {
+	buffers := make([]bytes.Buffer, len(nodes))
+	errs := make([]error, len(nodes))
+	done := make([]chan struct{}, len(nodes))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+	go func() {
+		for i, node := range nodes {
+			workers <- struct{}{}
+			go func() {
+				defer func() {
+					<-workers
+					close(done[i])
+				}()
+				buffers[i].Grow(node.len())
+				errs[i] = node.write(&buffers[i])
+			}()
+		}
+	}()
 
+	for i := range nodes {
+		<-done[i]
+		err := errs[i]
+		if err == nil {
+			_, err = buffers[i].WriteTo(w)
+		}
+		if err != nil {
+			// wait for the remaining encoders, the nodes must not be in use once writing returns
+			for _, d := range done[i+1:] {
+				<-d
+			}
+			return err
+		}
+		buffers[i] = bytes.Buffer{}
+	}
 	return nil
 }
 
@@ -147,8 +358,47 @@ const (
 	NodeIdMaterial          CastNodeId = 0x6C74616D
 	NodeIdFile              CastNodeId = 0x656C6966
 	NodeIdInstance          CastNodeId = 0x74736E69
+	NodeIdMetadata          CastNodeId = 0x6174656D
 )
 
+// String returns the four character tag of the node id
+func (id CastNodeId) String() string {
+	return string([]byte{byte(id), byte(id >> 8), byte(id >> 16), byte(id >> 24)})
+}
+
+// castNodeIdNames maps the descriptive names of the node ids to the ids
+var castNodeIdNames = map[string]CastNodeId{
+	"root":              NodeIdRoot,
+	"model":             NodeIdModel,
+	"mesh":              NodeIdMesh,
+	"blendshape":        NodeIdBlendShape,
+	"skeleton":          NodeIdSkeleton,
+	"bone":              NodeIdBone,
+	"ikhandle":          NodeIdIKHandle,
+	"constraint":        NodeIdConstraint,
+	"animation":         NodeIdAnimation,
+	"curve":             NodeIdCurve,
+	"notificationtrack": NodeIdNotificationTrack,
+	"material":          NodeIdMaterial,
+	"file":              NodeIdFile,
+	"instance":          NodeIdInstance,
+	"metadata":          NodeIdMetadata,
+}
+
+// ParseCastNodeId parses a node id from its four character tag (e.g. "modl") or its
+// case-insensitive descriptive name (e.g. "model")
+func ParseCastNodeId(s string) (CastNodeId, error) {
+	if id, ok := castNodeIdNames[strings.ToLower(s)]; ok {
+		return id, nil
+	}
+
+	if len(s) == 4 {
+		return CastNodeId(binary.LittleEndian.Uint32([]byte(s))), nil
+	}
+
+	return 0, fmt.Errorf("cast: invalid node id: %q", s)
+}
+
 // castNodeHeader hold header data of a node
 type castNodeHeader struct {
 	Id            CastNodeId
@@ -162,16 +412,28 @@ type castNodeHeader struct {
 type CastNode struct {
 	id         CastNodeId
 	hash       uint64
-	properties map[CastPropertyName]iCastProperty
+	properties []iCastProperty
 	childNodes []*CastNode
 	parentNode *CastNode
+	file       *CastFile
 }
 
-func newCastNode(id CastNodeId) *CastNode {
+// newCastNode creates a new node, the hash is generated by and unique within the given file if it is not nil
+func newCastNode(id CastNodeId, file *CastFile) *CastNode {
+	var hash uint64
+	if file != nil {
+		hash = file.nextHash()
+		for file.hashIndex()[hash] != nil {
+			hash = file.nextHash()
+		}
+	} else {
+		hash = nextHash()
+	}
+
 	return &CastNode{
 		id:         id,
-		hash:       nextHash(),
-		properties: map[CastPropertyName]iCastProperty{},
+		hash:       hash,
+		properties: []iCastProperty{},
 		childNodes: []*CastNode{},
 		parentNode: nil,
 	}
@@ -187,6 +449,31 @@ func (n *CastNode) Hash() uint64 {
 	return n.hash
 }
 
+// SetHash sets the hash of the node. Returns [ErrHashInUse] if another node of the file already has the
+// given hash. References to the previous hash are not updated.
+func (n *CastNode) SetHash(hash uint64) error {
+	if hash == n.hash {
+		return nil
+	}
+
+	if n.file == nil {
+		n.hash = hash
+		return nil
+	}
+
+	index := n.file.hashIndex()
+	if index[hash] != nil {
+		return fmt.Errorf("%w: %#x", ErrHashInUse, hash)
+	}
+
+	if index[n.hash] == n {
+		delete(index, n.hash)
+	}
+	n.hash = hash
+	index[hash] = n
+	return nil
+}
+
 // setParentNode sets the parent node
 func (n *CastNode) setParentNode(node *CastNode) {
 	n.parentNode = node
@@ -197,6 +484,11 @@ func (n *CastNode) GetParentNode() *CastNode {
 	return n.parentNode
 }
 
+// Size returns the encoded size of the node in bytes, including its header, properties and children
+func (n *CastNode) Size() int {
+	return n.len()
+}
+
 // len returns the size of the node
 func (n *CastNode) len() int {
 	l := 0x18
@@ -211,47 +503,16 @@ func (n *CastNode) len() int {
 	return l
 }
 
-// load loads a node from the given [io.Reader]
-func (n *CastNode) load(r io.Reader) error {
-	var header castNodeHeader
-	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return err
-	}
-
-	if n.properties == nil {
-		n.properties = make(map[CastPropertyName]iCastProperty)
-	}
-
-	for range header.PropertyCount {
-		property, err := loadCastProperty(r)
-		if err != nil {
-			return err
-		}
-
-		n.properties[property.Name()] = property
-	}
-
-	n.childNodes = make([]*CastNode, header.ChildCount)
-	for i := range n.childNodes {
-		n.childNodes[i] = &CastNode{}
-		if err := n.childNodes[i].load(r); err != nil {
-			return err
-		}
-		n.childNodes[i].setParentNode(n)
-	}
-
-	return nil
-}
-
 // write writes the node to the given [io.Writer]
 func (n *CastNode) write(w io.Writer) error {
-	if err := binary.Write(w, binary.LittleEndian, castNodeHeader{
+	header := castNodeHeader{
 		Id:            n.id,
 		NodeSize:      uint32(n.len()),
 		NodeHash:      n.hash,
 		PropertyCount: uint32(len(n.properties)),
 		ChildCount:    uint32(len(n.childNodes)),
-	}); err != nil {
+	}
+	if _, err := w.Write(header.append(make([]byte, 0, castNodeHeaderSize))); err != nil {
 		return err
 	}
 
@@ -270,15 +531,87 @@ func (n *CastNode) write(w io.Writer) error {
 	return nil
 }
 
-// GetProperties returns the properties
+// GetProperties returns the properties keyed by their name. The map is built on every call, modifying it
+// does not affect the node. Use [CastNode.Properties] to iterate the properties in their order.
 func (n *CastNode) GetProperties() map[CastPropertyName]iCastProperty {
-	return n.properties
+	properties := make(map[CastPropertyName]iCastProperty, len(n.properties))
+	for _, p := range n.properties {
+		properties[p.Name()] = p
+	}
+	return properties
 }
 
-// GetProperty returns the property with the given name
+// GetProperty returns the property with the given name. Loaded nodes may hold several properties with the
+// same name, the last one is returned then, see [CastNode.GetPropertyAll].
 func (n *CastNode) GetProperty(name CastPropertyName) (iCastProperty, bool) {
-	property, ok := n.properties[name]
-	return property, ok
+	if i := n.propertyIndex(name); i >= 0 {
+		return n.properties[i], true
+	}
+	return nil, false
+}
+
+// GetPropertyAll returns all properties with the given name in their order. Files written by faulty
+// exporters may hold several properties with the same name in a node, they are kept when loading the file
+// so it is written unchanged.
+func (n *CastNode) GetPropertyAll(name CastPropertyName) []iCastProperty {
+	var properties []iCastProperty
+	for _, p := range n.properties {
+		if p.Name() == name {
+			properties = append(properties, p)
+		}
+	}
+	return properties
+}
+
+// HasProperty reports whether the node has a property with the given name
+func (n *CastNode) HasProperty(name CastPropertyName) bool {
+	return n.propertyIndex(name) >= 0
+}
+
+// RenameProperty renames the property with the given name, keeping its position among the properties of
+// the node. Returns an error if the node does not have the property or already has one with the new name.
+func (n *CastNode) RenameProperty(old, new CastPropertyName) error {
+	i := n.propertyIndex(old)
+	if i < 0 {
+		return fmt.Errorf(`cast: property %s not found`, old)
+	}
+	if old == new {
+		return nil
+	}
+	if n.propertyIndex(new) >= 0 {
+		return fmt.Errorf("cast: property %s already exists", new)
+	}
+
+	n.properties[i].setName(new)
+	return nil
+}
+
+// setProperty adds the property to the node, replacing the property with the same name at its position
+func (n *CastNode) setProperty(property iCastProperty) {
+	if i := n.propertyIndex(property.Name()); i >= 0 {
+		n.properties[i] = property
+		return
+	}
+	n.properties = append(n.properties, property)
+}
+
+// removeProperty removes the properties with the given name, reports whether the node had any
+func (n *CastNode) removeProperty(name CastPropertyName) bool {
+	count := len(n.properties)
+	n.properties = slices.DeleteFunc(n.properties, func(p iCastProperty) bool {
+		return p.Name() == name
+	})
+	return len(n.properties) < count
+}
+
+// propertyIndex returns the index of the last property with the given name, -1 if the node does not have it
+func (n *CastNode) propertyIndex(name CastPropertyName) int {
+	for i := len(n.properties) - 1; i >= 0; i-- {
+		if n.properties[i].Name() == name {
+			return i
+		}
+	}
+	return -1
 }
 
 // CreateProperty creates a new property with the given name and type
@@ -288,11 +621,7 @@ func (n *CastNode) CreateProperty(id CastPropertyId, name CastPropertyName) (iCa
 		return nil, err
 	}
 
-	if n.properties == nil {
-		n.properties = make(map[CastPropertyName]iCastProperty)
-	}
-
-	n.properties[name] = property
+	n.setProperty(property)
 	return property, nil
 }
 
@@ -313,6 +642,11 @@ func (n *CastNode) GetChildrenOfType(id CastNodeId) []*CastNode {
 	return nodes
 }
 
+// GetDescendantsOfType returns the descendant nodes with the given type in depth-first order
+func (n *CastNode) GetDescendantsOfType(id CastNodeId) []*CastNode {
+	return n.Find(ByType(id))
+}
+
 // GetChildByHash returns a childnode with the given hash
 func (n *CastNode) GetChildByHash(hash uint64) *CastNode {
 	for _, c := range n.childNodes {
@@ -326,9 +660,12 @@ func (n *CastNode) GetChildByHash(hash uint64) *CastNode {
 
 // CreateChild creates a new childnode
 func (n *CastNode) CreateChild(id CastNodeId) *CastNode {
-	child := newCastNode(id)
+	child := newCastNode(id, n.file)
 	child.setParentNode(n)
 	n.childNodes = append(n.childNodes, child)
+	if n.file != nil {
+		n.file.adopt(child)
+	}
 	return child
 }
 
@@ -352,57 +689,86 @@ const (
 	PropVector4   CastPropertyId = 0x7634
 )
 
+// String returns the short type name of the property id as it is stored in the file
+func (id CastPropertyId) String() string {
+	if id>>8 == 0 {
+		return string(rune(id))
+	}
+	return string([]byte{byte(id >> 8), byte(id)})
+}
+
 // CastPropertyName type alias
 type CastPropertyName string
 
 const (
-	PropNameName                   CastPropertyName = "n"
-	PropNameVertexPositionBuffer   CastPropertyName = "vp"
-	PropNameVertexNormalBuffer     CastPropertyName = "vn"
-	PropNameVertexTangentBuffer    CastPropertyName = "vt"
-	PropNameVertexColorBuffer      CastPropertyName = "vc"
-	PropNameVertexUVBuffer         CastPropertyName = "u%d"
-	PropNameVertexWeightBoneBuffer CastPropertyName = "wv"
-	PropNameFaceBuffer             CastPropertyName = "f"
-	PropNameUVLayerCount           CastPropertyName = "ul"
-	PropNameMaximumWeightInfluence CastPropertyName = "mi"
-	PropNameSkinningMethod         CastPropertyName = "sm"
-	PropNameMaterial               CastPropertyName = "m"
-	PropNameBaseShape              CastPropertyName = "b"
-	PropNameTargetShape            CastPropertyName = "t"
-	PropNameTargetWeightScale      CastPropertyName = "ts"
-	PropNameParentIndex            CastPropertyName = "p"
-	PropNameSegmentScaleCompensate CastPropertyName = "ssc"
-	PropNameLocalPosition          CastPropertyName = "lp"
-	PropNameLocalRotation          CastPropertyName = "lr"
-	PropNameWorldPosition          CastPropertyName = "wp"
-	PropNameWorldRotation          CastPropertyName = "wr"
-	PropNameScale                  CastPropertyName = "s"
-	PropNameStartBone              CastPropertyName = "sb"
-	PropNameEndBone                CastPropertyName = "eb"
-	PropNameTargetBone             CastPropertyName = "tb"
-	PropNamePoleVectorBone         CastPropertyName = "pv"
-	PropNamePoleBone               CastPropertyName = "pb"
-	PropNameTargetRotation         CastPropertyName = "tr"
-	PropNameConstraintType         CastPropertyName = "ct"
-	PropNameConstraintBone         CastPropertyName = "cb"
-	PropNameMaintainOffset         CastPropertyName = "mo"
-	PropNameSkipX                  CastPropertyName = "sx"
-	PropNameSkipY                  CastPropertyName = "sy"
-	PropNameSkipZ                  CastPropertyName = "sz"
-	PropNameType                   CastPropertyName = "t"
-	PropNamePath                   CastPropertyName = "p"
-	PropNameFramerate              CastPropertyName = "fr"
-	PropNameLoop                   CastPropertyName = "lo"
-	PropNameNodeName               CastPropertyName = "nn"
-	PropNameKeyProperty            CastPropertyName = "kp"
-	PropNameKeyFrameBuffer         CastPropertyName = "kb"
-	PropNameKeyValueBuffer         CastPropertyName = "kv"
-	PropNameMode                   CastPropertyName = "m"
-	PropNameAdditiveBlendWeight    CastPropertyName = "ab"
-	PropNameReferenceFile          CastPropertyName = "rf"
-	PropNamePosition               CastPropertyName = "p"
-	PropNameRotation               CastPropertyName = "r"
+	PropNameName                    CastPropertyName = "n"
+	PropNameVertexPositionBuffer    CastPropertyName = "vp"
+	PropNameVertexNormalBuffer      CastPropertyName = "vn"
+	PropNameVertexTangentBuffer     CastPropertyName = "vt"
+	PropNameVertexColorBuffer       CastPropertyName = "vc"
+	PropNameVertexUVBuffer          CastPropertyName = "u%d"
+	PropNameVertexWeightBoneBuffer  CastPropertyName = "wb"
+	PropNameVertexWeightValueBuffer CastPropertyName = "wv"
+	PropNameFaceBuffer              CastPropertyName = "f"
+	PropNameUVLayerCount            CastPropertyName = "ul"
+	PropNameMaximumWeightInfluence  CastPropertyName = "mi"
+	PropNameSkinningMethod          CastPropertyName = "sm"
+	PropNameMaterial                CastPropertyName = "m"
+	PropNameBaseShape               CastPropertyName = "b"
+	PropNameTargetShape             CastPropertyName = "t"
+	PropNameTargetWeightScale       CastPropertyName = "ts"
+	PropNameParentIndex             CastPropertyName = "p"
+	PropNameSegmentScaleCompensate  CastPropertyName = "ssc"
+	PropNameLocalPosition           CastPropertyName = "lp"
+	PropNameLocalRotation           CastPropertyName = "lr"
+	PropNameWorldPosition           CastPropertyName = "wp"
+	PropNameWorldRotation           CastPropertyName = "wr"
+	PropNameScale                   CastPropertyName = "s"
+	PropNameStartBone               CastPropertyName = "sb"
+	PropNameEndBone                 CastPropertyName = "eb"
+	PropNameTargetBone              CastPropertyName = "tb"
+	PropNamePoleVectorBone          CastPropertyName = "pv"
+	PropNamePoleBone                CastPropertyName = "pb"
+	PropNameTargetRotation          CastPropertyName = "tr"
+	PropNameConstraintType          CastPropertyName = "ct"
+	PropNameConstraintBone          CastPropertyName = "cb"
+	PropNameMaintainOffset          CastPropertyName = "mo"
+	PropNameSkipX                   CastPropertyName = "sx"
+	PropNameSkipY                   CastPropertyName = "sy"
+	PropNameSkipZ                   CastPropertyName = "sz"
+	PropNameType                    CastPropertyName = "t"
+	PropNamePath                    CastPropertyName = "p"
+	PropNameFramerate               CastPropertyName = "fr"
+	PropNameLoop                    CastPropertyName = "lo"
+	PropNameNodeName                CastPropertyName = "nn"
+	PropNameKeyProperty             CastPropertyName = "kp"
+	PropNameKeyFrameBuffer          CastPropertyName = "kb"
+	PropNameKeyValueBuffer          CastPropertyName = "kv"
+	PropNameMode                    CastPropertyName = "m"
+	PropNameAdditiveBlendWeight     CastPropertyName = "ab"
+	PropNameReferenceFile           CastPropertyName = "rf"
+	PropNamePosition                CastPropertyName = "p"
+	PropNameRotation                CastPropertyName = "r"
+	PropNameAlbedo                  CastPropertyName = "albedo"
+	PropNameDiffuse                 CastPropertyName = "diffuse"
+	PropNameNormal                  CastPropertyName = "normal"
+	PropNameSpecular                CastPropertyName = "specular"
+	PropNameEmissive                CastPropertyName = "emissive"
+	PropNameGloss                   CastPropertyName = "gloss"
+	PropNameRoughness               CastPropertyName = "roughness"
+	PropNameAmbientOcclusion        CastPropertyName = "ao"
+	PropNameCavity                  CastPropertyName = "cavity"
+	PropNameAnisotropy              CastPropertyName = "aniso"
+	PropNameExtra                   CastPropertyName = "extra%d"
+	PropNameData                    CastPropertyName = "d"
+	PropNameMetalness               CastPropertyName = "metalness"
+	PropNameRoughnessValue          CastPropertyName = "roughnessvalue"
+	PropNameAlbedoTint              CastPropertyName = "albedotint"
+	PropNameEmissiveTint            CastPropertyName = "emissivetint"
+	PropNameUVTiling                CastPropertyName = "uvtiling"
+	PropNameAuthor                  CastPropertyName = "a"
+	PropNameSoftware                CastPropertyName = "s"
+	PropNameUpAxis                  CastPropertyName = "up"
 )
 
 // castPropertyHeader holds header data of the property
@@ -417,9 +783,15 @@ type iCastProperty interface {
 	Id() CastPropertyId     // Id returns the property id
 	Name() CastPropertyName // Name returns the property name
 	Count() int             // Count returns the amount of values held by the property
+	AnyValues() []any       // AnyValues returns a copy of the values held by the property as untyped values
+	Size() int              // Size returns the encoded size of the property in bytes, including its header
 	len() int
-	load(r io.Reader) error
+	setName(name CastPropertyName)
+	load(r io.Reader, count int, buf []byte) error
+	loadLazy(r io.Reader, count int) error
 	write(w io.Writer) error
+	clone() iCastProperty
+	gather(indices []int, stride int)
 }
 
 // CastPropertyValueType is the constraint for possible property types
@@ -432,6 +804,7 @@ type CastProperty[T CastPropertyValueType] struct {
 	id     CastPropertyId
 	name   CastPropertyName
 	values []T
+	lazy   *lazyValues // encoded values not decoded yet, see [WithLazyValues]
 }
 
 // Id returns the property id
@@ -444,24 +817,157 @@ func (p *CastProperty[T]) Name() CastPropertyName {
 	return p.name
 }
 
+// setName sets the name of the property
+func (p *CastProperty[T]) setName(name CastPropertyName) {
+	p.name = name
+}
+
 // Count returns the amount of values held by the property
 func (p *CastProperty[T]) Count() int {
+	if p.lazy != nil {
+		return p.lazy.count
+	}
 	return len(p.values)
 }
 
-// GetValues returns the values held by the property
+// GetValues returns the values held by the property. The returned slice is the one held by the property,
+// modifying its elements modifies the property.
 func (p *CastProperty[T]) GetValues() []T {
-	return p.values
+	return p.decoded()
 }
 
-// SetValues sets the values of the property
+// SetValues sets the values of the property. The property keeps the given slice without copying it, so
+// later modifications of its elements are visible in the property. Use [CastProperty.SetValuesCopy] to
+// decouple them.
 func (p *CastProperty[T]) SetValues(values ...T) {
 	p.values = values
+	p.lazy = nil
+}
+
+// SetValuesCopy sets the values of the property to a copy of the given values
+func (p *CastProperty[T]) SetValuesCopy(values ...T) {
+	p.values = slices.Clone(values)
+	p.lazy = nil
 }
 
-// AddValues adds values to the property
+// AddValues appends values to the property. Like append, it reuses the storage of the property when its
+// capacity allows, see [CastProperty.Grow].
 func (p *CastProperty[T]) AddValues(values ...T) {
-	p.values = append(p.values, values...)
+	p.values = append(p.decoded(), values...)
+	p.lazy = nil
+}
+
+// Grow makes room for at least n more values, so adding them with [CastProperty.AddValues] does not
+// allocate
+func (p *CastProperty[T]) Grow(n int) {
+	p.values = slices.Grow(p.decoded(), n)
+}
+
+// AnyValues returns a copy of the values held by the property as untyped values, for tools handling
+// properties of any type
+func (p *CastProperty[T]) AnyValues() []any {
+	values := make([]any, p.Count())
+	for i, v := range p.decoded() {
+		values[i] = v
+	}
+	return values
+}
+
+// ValueAt returns the value with the given index, it panics if the index is out of range
+func (p *CastProperty[T]) ValueAt(i int) T {
+	return p.decoded()[i]
+}
+
+// SetValueAt sets the value with the given index in place, it panics if the index is out of range
+func (p *CastProperty[T]) SetValueAt(i int, v T) {
+	p.decoded()[i] = v
+}
+
+// Resize sets the amount of values held by the property, dropping values beyond the new count or adding
+// zero values. The existing values are kept in place when the capacity allows.
+func (p *CastProperty[T]) Resize(n int) {
+	values := p.decoded()
+	p.lazy = nil
+	if n <= len(values) {
+		clear(values[n:])
+		p.values = values[:n]
+		return
+	}
+	p.values = append(values, make([]T, n-len(values))...)
+}
+
+// CopyFrom sets the values of the property to n values read from r in their little endian encoding, the way
+// they are stored in cast files, e.g. from a converter decoding another format on the fly. The values are
+// decoded straight into the buffer of the property, reusing its storage when the capacity allows, so no
+// intermediate slice is needed. If reading fails, the property holds the values read before the failure, in
+// multiples of the chunks they are decoded in. Strings are not supported.
+func (p *CastProperty[T]) CopyFrom(r io.Reader, n int) error {
+	size := valueSize[T]()
+	if size == 0 {
+		return fmt.Errorf("cast: cannot copy values of property %s of type %s", p.name, p.id)
+	}
+	if n < 0 {
+		return fmt.Errorf("cast: invalid number of values: %d", n)
+	}
+
+	values := slices.Grow(p.values[:0], n)[:n]
+	p.lazy = nil
+
+	// readValues stages small reads in a buffer of their own
+	var buf []byte
+	if n*size >= codecChunkSize {
+		buf = make([]byte, codecChunkSize)
+	}
+
+	chunk := codecChunkSize / size
+	for start := 0; start < n; start += chunk {
+		end := min(start+chunk, n)
+		if err := readValues(r, values[start:end], buf); err != nil {
+			p.values = values[:start]
+			return err
+		}
+	}
+
+	p.values = values[:n]
+	return nil
+}
+
+// clone returns a copy of the property that does not share its values
+func (p *CastProperty[T]) clone() iCastProperty {
+	return &CastProperty[T]{
+		id:     p.id,
+		name:   p.name,
+		values: slices.Clone(p.decoded()),
+	}
+}
+
+// gather keeps the groups of stride values with the given indices in their order, e.g. the values of the kept
+// vertices of a vertex buffer
+func (p *CastProperty[T]) gather(indices []int, stride int) {
+	values := p.decoded()
+	gathered := make([]T, 0, len(indices)*stride)
+	for _, i := range indices {
+		gathered = append(gathered, values[i*stride:(i+1)*stride]...)
+	}
+	p.SetValues(gathered...)
+}
+
+// decoded decodes the values kept encoded by [WithLazyValues] on first use and returns the values
+func (p *CastProperty[T]) decoded() []T {
+	if l := p.lazy; l != nil {
+		l.once.Do(func() {
+			values := make([]T, l.count)
+			decodeValues(values, l.raw)
+			p.values = values
+			l.raw = nil
+		})
+	}
+	return p.values
+}
+
+// Size returns the encoded size of the property in bytes, including its header
+func (p *CastProperty[T]) Size() int {
+	return p.len()
 }
 
 // Length returns the length of the property
@@ -469,54 +975,81 @@ func (p *CastProperty[T]) len() int {
 	l := 0x8
 
 	l += len(p.name)
-	switch vs := any(p.values).(type) {
-	case []string:
-		l += len(vs[0]) + 1
-	default:
-		l += binary.Size(p.values)
+	// switch on the type parameter, the values of a lazy property are assigned on first access
+	if _, ok := any(*new(T)).(string); ok {
+		l += len(any(p.decoded()).([]string)[0]) + 1
+	} else {
+		l += p.Count() * valueSize[T]()
 	}
 
 	return l
 }
 
-// load loads a property from the given [io.Reader]
-func (p *CastProperty[T]) load(r io.Reader) error {
-	switch any(p.values).(type) {
+// load loads the given number of values of a property from the given [io.Reader] using the given staging
+// buffer, which is allocated if nil. Values beyond the preallocated ones are appended in growing chunks, so a
+// corrupt count cannot allocate much more memory than the data holds.
+func (p *CastProperty[T]) load(r io.Reader, count int, buf []byte) error {
+	switch vs := any(p.values).(type) {
 	case []string:
 		str, err := readString(r)
 		if err != nil {
 			return err
 		}
 
-		p.values = any([]string{str}).([]T)
+		if len(vs) != 1 {
+			vs = make([]string, 1)
+		}
+		vs[0] = str
+		p.values = any(vs).([]T)
 		return nil
 	default:
-		return binary.Read(r, binary.LittleEndian, &p.values)
+		if err := readValues(r, p.values, buf); err != nil {
+			return err
+		}
+
+		for start := len(p.values); start < count; start = len(p.values) {
+			n := min(count-start, max(start, codecChunkSize/valueSize[T]()))
+			p.values = slices.Grow(p.values, n)[:start+n]
+			if err := readValues(r, p.values[start:], buf); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 }
 
+// loadLazy reads the encoded bytes of the given number of values from the given [io.Reader], they are
+// decoded when the values are first accessed
+func (p *CastProperty[T]) loadLazy(r io.Reader, count int) error {
+	raw, err := readBytes(r, count*valueSize[T]())
+	if err != nil {
+		return err
+	}
+
+	p.values = nil
+	p.lazy = &lazyValues{count: count, raw: raw}
+	return nil
+}
+
 // write writes a property to the given [io.Writer]
 func (p *CastProperty[T]) write(w io.Writer) error {
-	if err := binary.Write(w, binary.LittleEndian, castPropertyHeader{
+	header := castPropertyHeader{
 		Id:          p.id,
 		NameSize:    uint16(len(p.name)),
-		ArrayLength: uint32(binary.Size(p.values)),
-	}); err != nil {
-		return err
+		ArrayLength: uint32(p.Count()),
 	}
-
-	if _, err := w.Write([]byte(p.name)); err != nil {
+	b := header.append(make([]byte, 0, castPropertyHeaderSize+len(p.name)))
+	if _, err := w.Write(append(b, p.name...)); err != nil {
 		return err
 	}
 
-	switch vs := any(p.values).(type) {
+	switch vs := any(p.decoded()).(type) {
 	case []string:
-		s := []byte(vs[0] + "\x00")
-		if err := binary.Write(w, binary.LittleEndian, s); err != nil {
+		if _, err := w.Write(append([]byte(vs[0]), 0)); err != nil {
 			return err
 		}
 	default:
-		if err := binary.Write(w, binary.LittleEndian, p.values); err != nil {
+		if err := writeValues(w, p.decoded()); err != nil {
 			return err
 		}
 	}
@@ -593,39 +1126,133 @@ func newCastProperty(id CastPropertyId, name CastPropertyName, size uint32) (iCa
 	}
 }
 
-// loadCastProperty loads a property from the given [io.Reader]
-func loadCastProperty(r io.Reader) (iCastProperty, error) {
-	var header castPropertyHeader
-	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return nil, err
+// CreateProperty creates a new property on the given node with the given values. Returns an error if the
+// property id does not match the type of the values, see [SetProperty] to infer the id.
+func CreateProperty[T CastPropertyValueType](node *CastNode, name CastPropertyName, id CastPropertyId, values ...T) (*CastProperty[T], error) {
+	if want := propertyIdOf[T](); id != want {
+		return nil, fmt.Errorf("cast: property id %#x does not hold values of type %T", id, *new(T))
 	}
 
-	var name = make([]byte, header.NameSize)
-	if err := binary.Read(r, binary.LittleEndian, &name); err != nil {
+	property, err := node.CreateProperty(id, name)
+	if err != nil {
 		return nil, err
 	}
+	p := property.(*CastProperty[T])
+	p.values = append(p.values, values...)
+	return p, nil
+}
 
-	property, err := newCastProperty(header.Id, CastPropertyName(name), header.ArrayLength)
+// GetPropertyValueOr returns the first property value of the given node, or the given default if the node
+// does not have the property or it holds values of another type
+func GetPropertyValueOr[T CastPropertyValueType](node *CastNode, name CastPropertyName, def T) T {
+	v, err := GetPropertyValue[T](node, name)
 	if err != nil {
-		return nil, err
+		return def
 	}
+	return *v
+}
 
-	if err := property.load(r); err != nil {
-		return nil, err
+// MustGetPropertyValues is like [GetPropertyValues] but panics if the property is missing or of another
+// type, for code where that is a programming error such as generated code and test fixtures
+func MustGetPropertyValues[T CastPropertyValueType](node *CastNode, name CastPropertyName) []T {
+	values, err := GetPropertyValues[T](node, name)
+	if err != nil {
+		panic(fmt.Sprintf("cast: MustGetPropertyValues on %s node %#x: %v", node.id, node.hash, err))
 	}
+	return values
+}
 
-	return property, nil
+// MustGetPropertyValue is like [GetPropertyValue] but panics if the property is missing, empty or of another
+// type, see [MustGetPropertyValues]
+func MustGetPropertyValue[T CastPropertyValueType](node *CastNode, name CastPropertyName) T {
+	value, err := GetPropertyValue[T](node, name)
+	if err != nil {
+		panic(fmt.Sprintf("cast: MustGetPropertyValue on %s node %#x: %v", node.id, node.hash, err))
+	}
+	return *value
 }
 
-// CreateProperty creates a new property on the given node with the given values
-func CreateProperty[T CastPropertyValueType](node *CastNode, name CastPropertyName, id CastPropertyId, values ...T) (*CastProperty[T], error) {
-	property, err := node.CreateProperty(id, name)
+// MustCreateProperty is like [CreateProperty] but panics if the property id does not match the type of the
+// values, see [MustGetPropertyValues]
+func MustCreateProperty[T CastPropertyValueType](node *CastNode, name CastPropertyName, id CastPropertyId, values ...T) *CastProperty[T] {
+	p, err := CreateProperty(node, name, id, values...)
+	if err != nil {
+		panic(fmt.Sprintf("cast: MustCreateProperty %s on %s node %#x: %v", name, node.id, node.hash, err))
+	}
+	return p
+}
+
+// GetPropertyValuesAsUint32 returns the values of the Byte, Short or Integer32 property of the given node
+// widened to uint32, for data such as faces and indices stored in the smallest width that fits. The values of
+// an Integer32 property are returned as stored.
+func GetPropertyValuesAsUint32(node *CastNode, name CastPropertyName) ([]uint32, error) {
+	property, ok := node.GetProperty(name)
+	if !ok {
+		return nil, fmt.Errorf(`cast: property %s not found`, name)
+	}
+
+	switch p := property.(type) {
+	case *CastProperty[byte]:
+		return widenIndices(p.decoded()), nil
+	case *CastProperty[uint16]:
+		return widenIndices(p.decoded()), nil
+	case *CastProperty[uint32]:
+		return p.decoded(), nil
+	default:
+		return nil, fmt.Errorf("cast: property %s has a type of %T instead of an index type", name, property)
+	}
+}
+
+// GetPropertyValuesAsFloat64 returns the values of the integer or floating point property of the given node
+// converted to float64
+func GetPropertyValuesAsFloat64(node *CastNode, name CastPropertyName) ([]float64, error) {
+	property, ok := node.GetProperty(name)
+	if !ok {
+		return nil, fmt.Errorf(`cast: property %s not found`, name)
+	}
+
+	values, err := numericValues(property)
 	if err != nil {
 		return nil, err
 	}
-	p := property.(*CastProperty[T])
-	p.values = append(p.values, values...)
-	return p, nil
+
+	converted := make([]float64, values.len)
+	for i := range converted {
+		converted[i] = values.at(i)
+	}
+	return converted, nil
+}
+
+// SetProperty creates a new property on the given node with the given values like [CreateProperty], the id
+// of the property is inferred from the type of the values
+func SetProperty[T CastPropertyValueType](node *CastNode, name CastPropertyName, values ...T) (*CastProperty[T], error) {
+	return CreateProperty(node, name, propertyIdOf[T](), values...)
+}
+
+// propertyIdOf returns the id of the property holding values of the given type
+func propertyIdOf[T CastPropertyValueType]() CastPropertyId {
+	switch any(*new(T)).(type) {
+	case byte:
+		return PropByte
+	case uint16:
+		return PropShort
+	case uint32:
+		return PropInteger32
+	case uint64:
+		return PropInteger64
+	case float32:
+		return PropFloat
+	case float64:
+		return PropDouble
+	case string:
+		return PropString
+	case Vec2:
+		return PropVector2
+	case Vec3:
+		return PropVector3
+	default:
+		return PropVector4
+	}
 }
 
 // GetPropertyValues returns the property values of the given node
@@ -640,7 +1267,7 @@ func GetPropertyValues[T CastPropertyValueType](node *CastNode, name CastPropert
 		return nil, fmt.Errorf("cast: property has a type of %T instead of %T", property, &CastProperty[T]{})
 	}
 
-	return p.values, nil
+	return p.decoded(), nil
 }
 
 // GetPropertyValue returns a pointer to the first property value of the given node
@@ -661,12 +1288,18 @@ func GetPropertyValue[T CastPropertyValueType](node *CastNode, name CastProperty
 
 // readString reads a null terminated string from the given [io.Reader]
 func readString(r io.Reader) (string, error) {
-	str := []byte{}
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = byteReader{r}
+	}
 
+	str := []byte{}
 	for {
-		var b byte
-		err := binary.Read(r, binary.LittleEndian, &b)
-		if err != nil && err != io.EOF {
+		b, err := br.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
 			return "", err
 		}
 
@@ -680,11 +1313,21 @@ func readString(r io.Reader) (string, error) {
 	return string(str), nil
 }
 
-// nextHash returns the next hash
+// byteReader reads single bytes from an [io.Reader]
+type byteReader struct {
+	io.Reader
+}
+
+// ReadByte reads a single byte
+func (r byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.Reader, b[:])
+	return b[0], err
+}
+
+// nextHash returns the next hash of the package wide generator used by nodes not belonging to a file
 func nextHash() uint64 {
-	hash := castHashBase
-	castHashBase++
-	return hash
+	return defaultHashGenerator.NextHash()
 }
 
 // Vec2 is a structure holding data of a Vector2
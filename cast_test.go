@@ -1,9 +1,14 @@
 package cast
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -15,6 +20,30 @@ func assertEqual[T comparable](t testing.TB, got, want T) {
 	}
 }
 
+// loadTestFile loads the given file from the testdata directory
+func loadTestFile(t testing.TB, name string) *CastFile {
+	t.Helper()
+	r, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	castFile, err := Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return castFile
+}
+
+// must returns the value, it panics if err is not nil
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
 func TestLoadCastFile(t *testing.T) {
 	for _, f := range []string{
 		"cube.cast",
@@ -36,6 +65,51 @@ func TestLoadCastFile(t *testing.T) {
 	}
 }
 
+func TestReadHeader(t *testing.T) {
+	data := must(os.ReadFile("testdata/cube.cast"))
+	castFile := must(Load(bytes.NewReader(data)))
+
+	r := bytes.NewReader(data)
+	header, err := ReadHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, header.Valid(), true)
+	assertEqual(t, header.Version, castFile.Version())
+	assertEqual(t, header.Flags, castFile.Flags())
+	assertEqual(t, header.RootNodes, uint32(len(castFile.Roots())))
+	assertEqual(t, header.HasFlag(FlagNone), true)
+	assertEqual(t, r.Len(), len(data)-castHeaderSize)
+
+	header, err = ReadHeader(strings.NewReader("not a cast file"))
+	assertEqual(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+	assertEqual(t, header, Header{})
+
+	header = must(ReadHeader(strings.NewReader("GIF89a not a cast file")))
+	assertEqual(t, header.Valid(), false)
+
+	_, err = ReadHeader(strings.NewReader(""))
+	assertEqual(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+}
+
+func TestSize(t *testing.T) {
+	data := must(os.ReadFile("testdata/cube.cast"))
+	castFile := must(Load(bytes.NewReader(data)))
+
+	size := castHeaderSize
+	for _, root := range castFile.Roots() {
+		size += root.Size()
+	}
+	assertEqual(t, size, len(data))
+
+	node := New().CreateRoot()
+	p := must(CreateProperty(node, PropNameVertexPositionBuffer, PropVector3, Vec3{}, Vec3{}))
+	assertEqual(t, p.Size(), 8+2+2*12)
+	name := must(CreateProperty(node, PropNameName, PropString, "cube"))
+	assertEqual(t, name.Size(), 8+1+5)
+	assertEqual(t, node.Size(), 0x18+p.Size()+name.Size())
+}
+
 func TestWriteCastFile(t *testing.T) {
 	for _, f := range []string{
 		"cube.cast",
@@ -60,6 +134,33 @@ func TestWriteCastFile(t *testing.T) {
 	}
 }
 
+func TestWriteParallel(t *testing.T) {
+	files := make([]*CastFile, 0, 8)
+	for range 8 {
+		files = append(files, loadTestFile(t, "cast_ik.cast"), loadTestFile(t, "cube.cast"))
+	}
+	castFile := must(MergeFiles(files...))
+	assertEqual(t, len(castFile.Roots()), len(files))
+
+	var sequential bytes.Buffer
+	if err := writeNodes(&sequential, castFile.rootNodes); err != nil {
+		t.Fatal(err)
+	}
+
+	var parallel bytes.Buffer
+	if err := writeNodesParallel(&parallel, castFile.rootNodes); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, bytes.Equal(parallel.Bytes(), sequential.Bytes()), true)
+
+	var out bytes.Buffer
+	if err := castFile.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	loaded := must(Load(&out))
+	assertEqual(t, len(loaded.Roots()), len(castFile.Roots()))
+}
+
 func TestCastFile(t *testing.T) {
 	castFile := New()
 
@@ -67,15 +168,26 @@ func TestCastFile(t *testing.T) {
 	assertEqual(t, castFile.Version(), 0x1)
 	assertEqual(t, len(castFile.Roots()), 0)
 
+	assertEqual(t, castFile.HasFlag(FlagNone), true)
+	assertEqual(t, castFile.HasFlag(CastFileFlag(1)), false)
+
 	castFile.SetFlags(1).SetVersion(2)
 	assertEqual(t, castFile.Flags(), 1)
+	assertEqual(t, castFile.HasFlag(CastFileFlag(1)), true)
+
+	castFile.AddFlag(CastFileFlag(4))
+	assertEqual(t, castFile.Flags(), 5)
+	assertEqual(t, castFile.HasFlag(CastFileFlag(5)), true)
+	castFile.ClearFlag(CastFileFlag(5))
+	assertEqual(t, castFile.Flags(), 0)
+	castFile.SetFlags(1)
 	assertEqual(t, castFile.Version(), 0x2)
 	assertEqual(t, len(castFile.Roots()), 0)
 
 	root := castFile.CreateRoot()
 	assertEqual(t, len(castFile.Roots()), 1)
 	assertEqual(t, root.Id(), NodeIdRoot)
-	assertEqual(t, root.Hash(), castHashBase-1)
+	assertEqual(t, root.Hash(), castHashBase)
 	assertEqual(t, root.GetParentNode(), nil)
 	assertEqual(t, len(root.GetProperties()), 0)
 
@@ -85,6 +197,13 @@ func TestCastFile(t *testing.T) {
 	assertEqual(t, root.GetChildrenOfType(NodeIdMesh)[0], mesh)
 	assertEqual(t, root.GetChildByHash(mesh.Hash()), mesh)
 
+	bone := mesh.CreateChild(NodeIdSkeleton).CreateChild(NodeIdBone)
+	assertEqual(t, len(root.GetDescendantsOfType(NodeIdBone)), 1)
+	assertEqual(t, root.GetDescendantsOfType(NodeIdBone)[0], bone)
+	assertEqual(t, len(root.GetDescendantsOfType(NodeIdMesh)), 1)
+	assertEqual(t, len(bone.GetDescendantsOfType(NodeIdBone)), 0)
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdSkeleton)), 1)
+
 	prop, err := mesh.CreateProperty(PropString, PropNameName)
 	if err != nil {
 		t.Fatal(err)
@@ -158,4 +277,298 @@ func TestCastFile(t *testing.T) {
 
 	_, err = mesh.CreateProperty(CastPropertyId(9999), PropNameVertexNormalBuffer)
 	assertEqual(t, err != nil, true)
+
+	_, err = CreateProperty(mesh, PropNameFaceBuffer, PropInteger32, byte(1))
+	assertEqual(t, err != nil, true)
+	_, ok = mesh.GetProperty(PropNameFaceBuffer)
+	assertEqual(t, ok, false)
+
+	prop3, err := SetProperty(mesh, PropNameFaceBuffer, uint16(1), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, prop3.Id(), PropShort)
+	assertEqual(t, must(SetProperty(mesh, PropNameNormal, Vec4{})).Id(), PropVector4)
+	assertEqual(t, must(SetProperty(mesh, PropNameName, "mesh")).Id(), PropString)
+
+}
+
+func TestPropertyInPlace(t *testing.T) {
+	p := must(SetProperty(New().CreateRoot(), PropNameVertexPositionBuffer, Vec3{X: 1}, Vec3{X: 2}, Vec3{X: 3}))
+	values := p.GetValues()
+
+	p.SetValueAt(1, Vec3{Y: 5})
+	assertEqual(t, p.ValueAt(1), Vec3{Y: 5})
+	assertEqual(t, values[1], Vec3{Y: 5})
+
+	p.Resize(2)
+	assertEqual(t, p.Count(), 2)
+	assertEqual(t, values[2], Vec3{})
+
+	p.Resize(4)
+	assertEqual(t, p.Count(), 4)
+	assertEqual(t, p.ValueAt(0), Vec3{X: 1})
+	assertEqual(t, p.ValueAt(3), Vec3{})
+}
+
+func TestRenameProperty(t *testing.T) {
+	castFile := New()
+	node := castFile.CreateRoot()
+	SetProperty(node, PropNameName, "name")
+	SetProperty(node, "legacy", float32(1))
+	SetProperty(node, PropNameScale, Vec3{})
+
+	names := func(n *CastNode) string {
+		var names []CastPropertyName
+		for name := range n.Properties() {
+			names = append(names, name)
+		}
+		return fmt.Sprint(names)
+	}
+
+	if err := node.RenameProperty("legacy", PropNameFramerate); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, names(node), "[n fr s]")
+	assertEqual(t, *must(GetPropertyValue[float32](node, PropNameFramerate)), 1)
+
+	var buf bytes.Buffer
+	if err := castFile.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, names(must(Load(&buf)).Roots()[0]), "[n fr s]")
+
+	assertEqual(t, node.RenameProperty("missing", "other") != nil, true)
+	assertEqual(t, node.RenameProperty(PropNameFramerate, PropNameScale) != nil, true)
+}
+
+func TestDuplicateProperties(t *testing.T) {
+	castFile := New()
+	node := castFile.CreateRoot()
+	SetProperty(node, "dup", uint32(1))
+	SetProperty(node, PropNameName, "name")
+	node.properties = append(node.properties, &CastProperty[uint32]{id: PropInteger32, name: "dup", values: []uint32{2}})
+
+	data := must(castFile.MarshalBinary())
+	loaded := must(Load(bytes.NewReader(data)))
+	root := loaded.Roots()[0]
+	assertEqual(t, len(root.properties), 3)
+	assertEqual(t, bytes.Equal(must(loaded.MarshalBinary()), data), true)
+
+	all := root.GetPropertyAll("dup")
+	assertEqual(t, len(all), 2)
+	assertEqual(t, all[0].(*CastProperty[uint32]).ValueAt(0), 1)
+	assertEqual(t, all[1].(*CastProperty[uint32]).ValueAt(0), 2)
+	assertEqual(t, len(root.GetPropertyAll("missing")), 0)
+
+	// the single value accessors use the last property
+	assertEqual(t, *must(GetPropertyValue[uint32](root, "dup")), 2)
+	SetProperty(root, "dup", uint32(3))
+	assertEqual(t, all[0].(*CastProperty[uint32]).ValueAt(0), 1)
+	assertEqual(t, root.GetPropertyAll("dup")[1].(*CastProperty[uint32]).ValueAt(0), 3)
+
+	// duplicates are compared in order
+	assertEqual(t, Diff(loaded, must(Load(bytes.NewReader(data))), DiffOptions{}).Equal(), false)
+	assertEqual(t, Diff(castFile, must(Load(bytes.NewReader(data))), DiffOptions{}).Equal(), true)
+}
+
+func TestGetPropertyValueOr(t *testing.T) {
+	node := New().CreateRoot()
+	SetProperty(node, PropNameUVLayerCount, byte(2))
+
+	assertEqual(t, GetPropertyValueOr(node, PropNameUVLayerCount, byte(1)), 2)
+	assertEqual(t, GetPropertyValueOr(node, PropNameLoop, byte(0)), 0)
+	assertEqual(t, GetPropertyValueOr(node, PropNameUVLayerCount, "wrong type"), "wrong type")
+	assertEqual(t, node.HasProperty(PropNameUVLayerCount), true)
+	assertEqual(t, node.HasProperty(PropNameLoop), false)
+}
+
+func TestMustAccessors(t *testing.T) {
+	node := New().CreateRoot()
+	MustCreateProperty(node, PropNameScale, PropVector3, Vec3{1, 2, 3})
+	assertEqual(t, MustGetPropertyValues[Vec3](node, PropNameScale)[0], Vec3{1, 2, 3})
+	assertEqual(t, MustGetPropertyValue[Vec3](node, PropNameScale), Vec3{1, 2, 3})
+
+	assertPanics := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	assertPanics("missing", func() { MustGetPropertyValues[Vec3](node, PropNameName) })
+	assertPanics("wrong type", func() { MustGetPropertyValue[float32](node, PropNameScale) })
+	assertPanics("wrong id", func() { MustCreateProperty(node, PropNameName, PropString, byte(1)) })
+}
+
+func TestPropertyAliasing(t *testing.T) {
+	p := must(SetProperty(New().CreateRoot(), PropNameKeyValueBuffer, float32(0)))
+
+	values := []float32{1, 2, 3}
+	p.SetValues(values...)
+	values[0] = 10
+	assertEqual(t, p.ValueAt(0), 10)
+
+	p.SetValuesCopy(values...)
+	values[0] = 20
+	assertEqual(t, p.ValueAt(0), 10)
+
+	p.Grow(1000)
+	capacity := cap(p.GetValues())
+	for i := range 1000 {
+		p.AddValues(float32(i))
+	}
+	assertEqual(t, cap(p.GetValues()), capacity)
+	assertEqual(t, p.Count(), 1003)
+}
+
+func TestCopyFrom(t *testing.T) {
+	values := make([]Vec3, codecChunkSize/12*2+7)
+	for i := range values {
+		values[i] = Vec3{float32(i), -1, 0.5}
+	}
+	var buf bytes.Buffer
+	if err := writeValues(&buf, values); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	p := must(SetProperty(New().CreateRoot(), PropNameVertexPositionBuffer, Vec3{}))
+	if err := p.CopyFrom(bytes.NewReader(data), len(values)); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, slices.Equal(p.GetValues(), values), true)
+
+	// the storage is reused for fewer values
+	storage := &p.GetValues()[0]
+	if err := p.CopyFrom(bytes.NewReader(data), 3); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, p.Count(), 3)
+	assertEqual(t, &p.GetValues()[0], storage)
+
+	// a failed read keeps the complete chunks
+	if err := p.CopyFrom(bytes.NewReader(data[:len(data)-1]), len(values)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	assertEqual(t, p.Count(), codecChunkSize/12*2)
+
+	if err := must(SetProperty(New().CreateRoot(), PropNameName, "name")).CopyFrom(bytes.NewReader(data), 1); err == nil {
+		t.Error("expected error for a string property")
+	}
+}
+
+func TestAnyValues(t *testing.T) {
+	node := New().CreateRoot()
+	SetProperty(node, PropNameName, "name")
+	SetProperty(node, PropNameFaceBuffer, uint16(1), 2)
+
+	for _, p := range node.GetProperties() {
+		values := p.AnyValues()
+		assertEqual(t, len(values), p.Count())
+		switch p.Name() {
+		case PropNameName:
+			assertEqual(t, values[0], any("name"))
+		case PropNameFaceBuffer:
+			assertEqual(t, values[1], any(uint16(2)))
+		}
+	}
+}
+
+func TestGetPropertyValuesAs(t *testing.T) {
+	node := New().CreateRoot()
+	SetProperty(node, PropNameFaceBuffer, byte(1), 2, 255)
+	SetProperty(node, PropNameKeyFrameBuffer, uint16(0), 300)
+	SetProperty(node, PropNameKeyValueBuffer, float32(0.5), 2)
+	SetProperty(node, PropNameName, "name")
+
+	assertEqual(t, fmt.Sprint(must(GetPropertyValuesAsUint32(node, PropNameFaceBuffer))), "[1 2 255]")
+	assertEqual(t, fmt.Sprint(must(GetPropertyValuesAsUint32(node, PropNameKeyFrameBuffer))), "[0 300]")
+	assertEqual(t, fmt.Sprint(must(GetPropertyValuesAsFloat64(node, PropNameKeyFrameBuffer))), "[0 300]")
+	assertEqual(t, fmt.Sprint(must(GetPropertyValuesAsFloat64(node, PropNameKeyValueBuffer))), "[0.5 2]")
+
+	_, err := GetPropertyValuesAsUint32(node, PropNameKeyValueBuffer)
+	assertEqual(t, err != nil, true)
+	_, err = GetPropertyValuesAsFloat64(node, PropNameName)
+	assertEqual(t, err != nil, true)
+	_, err = GetPropertyValuesAsFloat64(node, PropNameParentIndex)
+	assertEqual(t, err != nil, true)
+}
+
+func TestRoundTripCastFile(t *testing.T) {
+	for _, f := range []string{
+		"cube.cast",
+		"cast_constraints.cast",
+		"cast_ik.cast",
+		"pilot_medium_bangalore_LOD0.cast",
+	} {
+		data, err := os.ReadFile(fmt.Sprintf("testdata/%v", f))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		cast, err := Load(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := cast.Write(&buf); err != nil {
+			t.Fatalf("%v", err)
+		}
+		assertEqual(t, buf.Len(), len(data))
+
+		reloaded, err := Load(&buf)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		assertEqual(t, len(reloaded.Roots()), len(cast.Roots()))
+		assertEqual(t, reloaded.Roots()[0].len(), cast.Roots()[0].len())
+		assertEqual(t, reloaded.Roots()[0].Id(), NodeIdRoot)
+		assertEqual(t, reloaded.Roots()[0].Hash(), cast.Roots()[0].Hash())
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	data, err := castFile.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, bytes.Equal(data, must(os.ReadFile("testdata/cast_ik.cast"))), true)
+	assertEqual(t, len(data), cap(data))
+
+	var decoded CastFile
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(decoded.Roots()), len(castFile.Roots()))
+	for n := range decoded.AllNodes() {
+		assertEqual(t, decoded.FindByHash(n.Hash()), n)
+	}
+	decoded.CreateRoot()
+	assertEqual(t, len(decoded.Roots()), len(castFile.Roots())+1)
+
+	if err := decoded.UnmarshalBinary(data[:len(data)/2]); err == nil {
+		t.Error("expected error")
+	}
+	assertEqual(t, len(decoded.Roots()), len(castFile.Roots())+1)
+
+	// gob uses the binary encoding
+	type asset struct {
+		Name string
+		File *CastFile
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(asset{"ik", castFile}); err != nil {
+		t.Fatal(err)
+	}
+	var got asset
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, got.Name, "ik")
+	assertEqual(t, Diff(castFile, got.File, DiffOptions{}).Equal(), true)
 }
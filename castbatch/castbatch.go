@@ -0,0 +1,201 @@
+// Package castbatch converts many cast files concurrently
+package castbatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// ConvertFunc converts the file loaded from the given input path. The returned file is written to the output
+// path given by [Options.Output], a nil file is not written. The function is called concurrently for different
+// inputs and must not retain the loaded file, which is released once the result is written.
+type ConvertFunc func(ctx context.Context, input string, f *cast.CastFile) (*cast.CastFile, error)
+
+// Options configures [Process]
+type Options struct {
+	// Workers is the number of files processed concurrently, runtime.GOMAXPROCS(0) if it is not positive
+	Workers int
+
+	// Output returns the path the result of the given input is written to, creating its directory if needed.
+	// Results are not written if it is nil or returns an empty path.
+	Output func(input string) string
+
+	// LoadOptions are passed to [cast.LoadFile] for every input, e.g. [cast.WithArena] to reuse memory
+	// between files
+	LoadOptions []cast.LoadOption
+
+	// StopOnError stops processing further inputs once an input failed, files already being processed are
+	// finished
+	StopOnError bool
+
+	// Progress is called after every processed input, one call at a time
+	Progress func(p Progress)
+}
+
+// Progress reports the progress of [Process] after an input was processed
+type Progress struct {
+	Input  string // Input is the path of the processed input
+	Err    error  // Err is the failure of the input, nil if it succeeded
+	Done   int    // Done is the number of processed inputs, failed ones included
+	Failed int    // Failed is the number of failed inputs
+	Total  int    // Total is the number of inputs
+}
+
+// FileError is the failure of a single input
+type FileError struct {
+	Input string
+	Err   error
+}
+
+// Error returns the path of the input followed by the failure
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Input, e.Err)
+}
+
+// Unwrap returns the failure of the input
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Error aggregates the failures of the inputs of [Process] in the order of the inputs
+type Error struct {
+	Files []*FileError
+}
+
+// Error returns the number of failed inputs followed by the first failure
+func (e *Error) Error() string {
+	if len(e.Files) == 1 {
+		return "castbatch: " + e.Files[0].Error()
+	}
+	return fmt.Sprintf("castbatch: %d files failed, first: %v", len(e.Files), e.Files[0])
+}
+
+// Unwrap returns the failures of the inputs as [*FileError] values
+func (e *Error) Unwrap() []error {
+	errs := make([]error, len(e.Files))
+	for i, f := range e.Files {
+		errs[i] = f
+	}
+	return errs
+}
+
+// Process loads every input, converts it with the given function and writes the result, see [Options.Output],
+// processing up to [Options.Workers] inputs concurrently. The failures of the inputs, including panics of the
+// function, are returned as an [*Error] once all inputs are processed. When the context is canceled, no further
+// inputs are started and the error of the context is returned along with the failures so far.
+func Process(ctx context.Context, inputs []string, fn ConvertFunc, opts Options) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(inputs))
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		failures = make([]error, len(inputs))
+		progress = Progress{Total: len(inputs)}
+	)
+	report := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		failures[i] = err
+		progress.Input, progress.Err = inputs[i], err
+		progress.Done++
+		if err != nil {
+			progress.Failed++
+			if opts.StopOnError {
+				cancel()
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				report(i, processFile(ctx, inputs[i], fn, opts))
+			}
+		}()
+	}
+
+dispatch:
+	for i := range inputs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var batchErr *Error
+	for i, err := range failures {
+		if err != nil {
+			if batchErr == nil {
+				batchErr = &Error{}
+			}
+			batchErr.Files = append(batchErr.Files, &FileError{Input: inputs[i], Err: err})
+		}
+	}
+
+	// the context of the workers is also canceled by StopOnError, only a cancellation by the caller is reported
+	ctxErr := parent.Err()
+	if batchErr == nil {
+		return ctxErr
+	}
+	if ctxErr == nil {
+		return batchErr
+	}
+	return errors.Join(ctxErr, batchErr)
+}
+
+// processFile loads, converts and writes a single input
+func processFile(ctx context.Context, input string, fn ConvertFunc, opts Options) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	f, err := cast.LoadFile(input, opts.LoadOptions...)
+	if err != nil {
+		return err
+	}
+	defer f.Release()
+
+	result, err := fn(ctx, input, f)
+	if err != nil || result == nil || opts.Output == nil {
+		return err
+	}
+
+	output := opts.Output(input)
+	if output == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return err
+	}
+	return cast.WriteFile(output, result)
+}
@@ -0,0 +1,143 @@
+package castbatch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// writeInputs writes n files with a single named root each into the directory and returns their paths
+func writeInputs(t *testing.T, dir string, n int) []string {
+	t.Helper()
+	inputs := make([]string, n)
+	for i := range inputs {
+		f := cast.New()
+		cast.CreateProperty(f.CreateRoot().CreateChild(cast.NodeIdModel), cast.PropNameName, cast.PropString, string(rune('a'+i)))
+		inputs[i] = filepath.Join(dir, string(rune('a'+i))+".cast")
+		if err := cast.WriteFile(inputs[i], f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return inputs
+}
+
+func TestProcess(t *testing.T) {
+	dir := t.TempDir()
+	inputs := writeInputs(t, dir, 8)
+
+	var progress []Progress
+	err := Process(context.Background(), inputs, func(ctx context.Context, input string, f *cast.CastFile) (*cast.CastFile, error) {
+		cast.SetProperty(f.Roots()[0].GetChildNodes()[0], cast.PropNameName, "converted")
+		return f, nil
+	}, Options{
+		Workers: 3,
+		Output: func(input string) string {
+			return filepath.Join(dir, "out", filepath.Base(input))
+		},
+		LoadOptions: []cast.LoadOption{cast.WithArena()},
+		Progress: func(p Progress) {
+			progress = append(progress, p)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		f, err := cast.LoadFile(filepath.Join(dir, "out", filepath.Base(input)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := cast.GetPropertyValueOr(f.Roots()[0].GetChildNodes()[0], cast.PropNameName, ""); name != "converted" {
+			t.Errorf("%s: name %q, want converted", input, name)
+		}
+	}
+
+	if len(progress) != len(inputs) {
+		t.Fatalf("%d progress reports, want %d", len(progress), len(inputs))
+	}
+	for i, p := range progress {
+		if p.Done != i+1 || p.Total != len(inputs) || p.Failed != 0 || p.Err != nil {
+			t.Errorf("progress %d: %+v", i, p)
+		}
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	dir := t.TempDir()
+	inputs := writeInputs(t, dir, 4)
+	inputs = append(inputs, filepath.Join(dir, "missing.cast"))
+
+	errConvert := errors.New("convert failed")
+	err := Process(context.Background(), inputs, func(ctx context.Context, input string, f *cast.CastFile) (*cast.CastFile, error) {
+		switch filepath.Base(input) {
+		case "b.cast":
+			return nil, errConvert
+		case "c.cast":
+			panic("boom")
+		}
+		return nil, nil
+	}, Options{Workers: 2})
+
+	var batchErr *Error
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("got %v, want *Error", err)
+	}
+	if len(batchErr.Files) != 3 {
+		t.Fatalf("%d failures, want 3: %v", len(batchErr.Files), batchErr.Files)
+	}
+	for i, want := range []string{"b.cast", "c.cast", "missing.cast"} {
+		if got := filepath.Base(batchErr.Files[i].Input); got != want {
+			t.Errorf("failure %d: input %s, want %s", i, got, want)
+		}
+	}
+	if !errors.Is(err, errConvert) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("failures not unwrapped: %v", err)
+	}
+	if !strings.Contains(batchErr.Files[1].Error(), "panic: boom") {
+		t.Errorf("panic not reported: %v", batchErr.Files[1])
+	}
+	if !strings.HasPrefix(err.Error(), "castbatch: 3 files failed") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestProcessStop(t *testing.T) {
+	dir := t.TempDir()
+	inputs := writeInputs(t, dir, 16)
+
+	var calls atomic.Int32
+	err := Process(context.Background(), inputs, func(ctx context.Context, input string, f *cast.CastFile) (*cast.CastFile, error) {
+		calls.Add(1)
+		return nil, errors.New("failed")
+	}, Options{Workers: 1, StopOnError: true})
+
+	var batchErr *Error
+	if !errors.As(err, &batchErr) || errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want only *Error", err)
+	}
+	if n := calls.Load(); n >= int32(len(inputs)) {
+		t.Errorf("processed %d inputs after the first failure", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls.Store(0)
+	err = Process(ctx, inputs, func(ctx context.Context, input string, f *cast.CastFile) (*cast.CastFile, error) {
+		if calls.Add(1) == 2 {
+			cancel()
+		}
+		return nil, nil
+	}, Options{Workers: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if n := calls.Load(); n >= int32(len(inputs)) {
+		t.Errorf("processed %d inputs after the cancellation", n)
+	}
+}
@@ -0,0 +1,162 @@
+// Package castgltf converts the models of cast files to and from glTF 2.0.
+//
+// Models become nodes holding their meshes and bone hierarchy, skinned meshes reference a skin whose joints
+// are the bones. Materials are converted to the metallic roughness model with the albedo and normal maps
+// referenced by path. Files are written as .gltf with the binary data embedded as a base64 data URI, and only
+// such self-contained files can be read. Animations are not converted and no axis conversion is applied,
+// both cast and glTF place the origin of UV coordinates at the top left.
+package castgltf
+
+import (
+	"encoding/json"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// component types of accessors
+const (
+	componentUnsignedByte  = 5121
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+// primitiveTriangles is the mode of primitives made of triangles, the default
+const primitiveTriangles = 4
+
+// dataURIPrefix is the prefix of the data URIs embedding buffers
+const dataURIPrefix = "data:application/octet-stream;base64,"
+
+// document is the JSON structure of a glTF file, limited to the parts that are converted
+type document struct {
+	Asset       asset        `json:"asset"`
+	Scene       *int         `json:"scene,omitempty"`
+	Scenes      []scene      `json:"scenes,omitempty"`
+	Nodes       []node       `json:"nodes,omitempty"`
+	Meshes      []mesh       `json:"meshes,omitempty"`
+	Skins       []skin       `json:"skins,omitempty"`
+	Materials   []material   `json:"materials,omitempty"`
+	Textures    []texture    `json:"textures,omitempty"`
+	Images      []image      `json:"images,omitempty"`
+	Accessors   []accessor   `json:"accessors,omitempty"`
+	BufferViews []bufferView `json:"bufferViews,omitempty"`
+	Buffers     []buffer     `json:"buffers,omitempty"`
+}
+
+// asset holds the metadata of a glTF file
+type asset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+// scene is a set of root nodes
+type scene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes,omitempty"`
+}
+
+// node is an element of the node hierarchy, optionally holding a mesh
+type node struct {
+	Name        string      `json:"name,omitempty"`
+	Children    []int       `json:"children,omitempty"`
+	Mesh        *int        `json:"mesh,omitempty"`
+	Skin        *int        `json:"skin,omitempty"`
+	Matrix      *cast.Mat4  `json:"matrix,omitempty"`
+	Translation *[3]float32 `json:"translation,omitempty"`
+	Rotation    *[4]float32 `json:"rotation,omitempty"`
+	Scale       *[3]float32 `json:"scale,omitempty"`
+}
+
+// mesh is a set of primitives drawn together
+type mesh struct {
+	Name       string      `json:"name,omitempty"`
+	Primitives []primitive `json:"primitives"`
+}
+
+// primitive is geometry with a single material, attributes and indices reference accessors
+type primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       *int           `json:"mode,omitempty"`
+}
+
+// skin binds the vertices of a mesh to joint nodes
+type skin struct {
+	Name                string `json:"name,omitempty"`
+	InverseBindMatrices *int   `json:"inverseBindMatrices,omitempty"`
+	Joints              []int  `json:"joints"`
+}
+
+// material is a material of the metallic roughness model
+type material struct {
+	Name                 string                `json:"name,omitempty"`
+	PBRMetallicRoughness *pbrMetallicRoughness `json:"pbrMetallicRoughness,omitempty"`
+	NormalTexture        *textureInfo          `json:"normalTexture,omitempty"`
+}
+
+// pbrMetallicRoughness holds the parameters of the metallic roughness model
+type pbrMetallicRoughness struct {
+	BaseColorFactor  *[4]float32  `json:"baseColorFactor,omitempty"`
+	BaseColorTexture *textureInfo `json:"baseColorTexture,omitempty"`
+	MetallicFactor   *float32     `json:"metallicFactor,omitempty"`
+	RoughnessFactor  *float32     `json:"roughnessFactor,omitempty"`
+}
+
+// textureInfo references a texture
+type textureInfo struct {
+	Index int `json:"index"`
+}
+
+// texture references the image it samples
+type texture struct {
+	Source *int `json:"source,omitempty"`
+}
+
+// image references an image by URI
+type image struct {
+	Name     string `json:"name,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// accessor describes typed elements stored in a buffer view
+type accessor struct {
+	BufferView    *int            `json:"bufferView,omitempty"`
+	ByteOffset    int             `json:"byteOffset,omitempty"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized,omitempty"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Min           []float32       `json:"min,omitempty"`
+	Max           []float32       `json:"max,omitempty"`
+	Sparse        json.RawMessage `json:"sparse,omitempty"`
+}
+
+// bufferView is a range of a buffer
+type bufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset,omitempty"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride,omitempty"`
+}
+
+// buffer holds binary data, embedded as a data URI
+type buffer struct {
+	URI        string `json:"uri,omitempty"`
+	ByteLength int    `json:"byteLength"`
+}
+
+// componentCounts holds the number of components of the accessor types
+var componentCounts = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+	"MAT4":   16,
+}
+
+// ptr returns a pointer to the given value
+func ptr[T any](v T) *T {
+	return &v
+}
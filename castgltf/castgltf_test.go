@@ -0,0 +1,149 @@
+package castgltf
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+	"github.com/mauserzjeh/go-cast/casttest"
+)
+
+// assertEqual fails if the two values are not equal
+func assertEqual[T comparable](t testing.TB, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got: %v != want: %v", got, want)
+	}
+}
+
+// assertValues fails if the property with the given name holds different values on the two nodes
+func assertValues[T cast.Vec2 | cast.Vec3](t testing.TB, got, want *cast.CastNode, name cast.CastPropertyName) {
+	t.Helper()
+	a, _ := cast.GetPropertyValues[T](got, name)
+	b, _ := cast.GetPropertyValues[T](want, name)
+	if len(a) == 0 || !slices.Equal(a, b) {
+		t.Errorf("property %s: got: %v != want: %v", name, a, b)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := casttest.NewModel("root", "head")
+	wantMesh := cast.AsMesh(want.GetNodesOfType(cast.NodeIdMesh)[0])
+	wantMesh.SetColors([]cast.Vec4{{X: 1, W: 1}, {Y: 1, W: 0.5}, {Z: 1, W: 1}}, cast.ColorFloat)
+	wantMaterial := cast.AsMaterial(want.GetNodesOfType(cast.NodeIdMaterial)[0])
+	wantMaterial.SetMetalness(0.25)
+	albedo := cast.AsFile(wantMaterial.CreateChild(cast.NodeIdFile))
+	cast.CreateProperty(albedo.CastNode, cast.PropNamePath, cast.PropString, "textures/albedo.png")
+	wantMaterial.SetSlot(cast.PropNameAlbedo, albedo)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBones := cast.AsSkeleton(want.GetNodesOfType(cast.NodeIdSkeleton)[0]).Bones()
+	gotBones := cast.AsSkeleton(got.GetNodesOfType(cast.NodeIdSkeleton)[0]).Bones()
+	assertEqual(t, len(gotBones), len(wantBones))
+	for i, b := range gotBones {
+		assertEqual(t, b.Name(), wantBones[i].Name())
+		assertEqual(t, b.ParentIndex(), wantBones[i].ParentIndex())
+		assertEqual(t, b.LocalPosition(), wantBones[i].LocalPosition())
+		assertEqual(t, b.LocalRotation(), wantBones[i].LocalRotation())
+	}
+	assertEqual(t, gotBones[1].WorldPosition(), cast.Vec3{Y: 1})
+
+	gotMesh := cast.AsMesh(got.GetNodesOfType(cast.NodeIdMesh)[0])
+	assertEqual(t, cast.GetPropertyValueOr(gotMesh.CastNode, cast.PropNameName, ""), "mesh")
+	assertValues[cast.Vec3](t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameVertexPositionBuffer)
+	assertValues[cast.Vec3](t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameVertexNormalBuffer)
+	assertValues[cast.Vec2](t, gotMesh.CastNode, wantMesh.CastNode, "u0")
+
+	gotColors, _ := gotMesh.Colors()
+	wantColors, _ := wantMesh.Colors()
+	if !slices.Equal(gotColors, wantColors) {
+		t.Errorf("colors: got: %v != want: %v", gotColors, wantColors)
+	}
+	gotFaces, _ := gotMesh.Faces()
+	wantFaces, _ := wantMesh.Faces()
+	if !slices.Equal(gotFaces, wantFaces) {
+		t.Errorf("faces: got: %v != want: %v", gotFaces, wantFaces)
+	}
+	gotWeights, _ := gotMesh.SkinWeights()
+	wantWeights, _ := wantMesh.SkinWeights()
+	if !slices.EqualFunc(gotWeights, wantWeights, slices.Equal) {
+		t.Errorf("weights: got: %v != want: %v", gotWeights, wantWeights)
+	}
+
+	material := cast.AsMaterial(gotMesh.ResolveReference(cast.PropNameMaterial))
+	assertEqual(t, material.Name(), "material")
+	assertEqual(t, material.Metalness(), float32(0.25))
+	assertEqual(t, material.RoughnessValue(), float32(1))
+	assertEqual(t, material.Slot(cast.PropNameAlbedo).Path(), "textures/albedo.png")
+	assertEqual(t, material.Slot(cast.PropNameNormal), nil)
+}
+
+func TestDecodeTransform(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, casttest.NewModel()); err != nil {
+		t.Fatal(err)
+	}
+
+	// unbind the mesh and move its node one unit along the X axis
+	var doc document
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	delete(doc.Meshes[0].Primitives[0].Attributes, "JOINTS_0")
+	delete(doc.Meshes[0].Primitives[0].Attributes, "WEIGHTS_0")
+	for i := range doc.Nodes {
+		if doc.Nodes[i].Mesh != nil {
+			doc.Nodes[i].Skin = nil
+			doc.Nodes[i].Translation = &[3]float32{1, 0, 0}
+		}
+	}
+	data, err := json.Marshal(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions, _ := cast.GetPropertyValues[cast.Vec3](got.GetNodesOfType(cast.NodeIdMesh)[0], cast.PropNameVertexPositionBuffer)
+	if want := []cast.Vec3{{X: 1}, {X: 2}, {X: 1, Y: 1}}; !slices.Equal(positions, want) {
+		t.Errorf("got: %v != want: %v", positions, want)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, casttest.NewModel()); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.String()
+
+	for name, data := range map[string]string{
+		"json":     valid[:len(valid)/2],
+		"version":  strings.Replace(valid, `"version": "2.0"`, `"version": "1.0"`, 1),
+		"buffer":   strings.Replace(valid, dataURIPrefix, "model.bin#", 1),
+		"accessor": strings.Replace(valid, `"POSITION": 1`, `"POSITION": 99`, 1),
+		"mode":     strings.Replace(valid, `"attributes": {`, `"mode": 1, "attributes": {`, 1),
+		"cycle":    `{"asset": {"version": "2.0"}, "nodes": [{"children": [1]}, {"children": [0]}]}`,
+	} {
+		if data == valid {
+			t.Fatalf("%s: the input was not modified", name)
+		}
+		if _, err := Decode(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
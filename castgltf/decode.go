@@ -0,0 +1,617 @@
+package castgltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// ----------------------- //
+//         DECODE          //
+// ----------------------- //
+
+// Decode reads a glTF file from the given [io.Reader] into a [cast.CastFile] holding a single model. The
+// joints of all skins form its skeleton with the bind pose given by the inverse bind matrices, every mesh
+// primitive becomes a mesh. Meshes that are not skinned have the transform of their node baked in. Only
+// triangle primitives and buffers embedded as data URIs are supported.
+func Decode(r io.Reader) (*cast.CastFile, error) {
+	d := &decoder{}
+	if err := json.NewDecoder(r).Decode(&d.doc); err != nil {
+		return nil, fmt.Errorf("castgltf: %w", err)
+	}
+	if !strings.HasPrefix(d.doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("castgltf: unsupported version %q", d.doc.Asset.Version)
+	}
+
+	for i, b := range d.doc.Buffers {
+		data, err := decodeDataURI(b.URI)
+		if err != nil {
+			return nil, fmt.Errorf("castgltf: buffer %d: %w", i, err)
+		}
+		if len(data) < b.ByteLength {
+			return nil, fmt.Errorf("castgltf: buffer %d holds %d bytes, expected %d", i, len(data), b.ByteLength)
+		}
+		d.buffers = append(d.buffers, data)
+	}
+
+	if err := d.computeWorldMatrices(); err != nil {
+		return nil, err
+	}
+
+	f := cast.New()
+	model := cast.AsModel(f.CreateRoot().CreateChild(cast.NodeIdModel))
+
+	if err := d.decodeSkeleton(model); err != nil {
+		return nil, err
+	}
+
+	for i := range d.doc.Materials {
+		node, err := d.decodeMaterial(model, i)
+		if err != nil {
+			return nil, fmt.Errorf("castgltf: material %d: %w", i, err)
+		}
+		d.materials = append(d.materials, node.Hash())
+	}
+
+	for i, n := range d.doc.Nodes {
+		if n.Mesh == nil {
+			continue
+		}
+		if err := d.decodeMeshNode(model, i); err != nil {
+			return nil, fmt.Errorf("castgltf: node %d: %w", i, err)
+		}
+	}
+	return f, nil
+}
+
+// decoder converts a parsed glTF document
+type decoder struct {
+	doc     document
+	buffers [][]byte
+
+	// parent indices, -1 for root nodes, and world transforms of the nodes
+	parents []int
+	world   []cast.Mat4
+
+	// bone indices by node index and hashes of the converted materials
+	bones     map[int]int
+	materials []uint64
+}
+
+// decodeDataURI returns the data embedded in a base64 data URI, external files are not read
+func decodeDataURI(uri string) ([]byte, error) {
+	header, data, ok := strings.Cut(uri, ",")
+	if !strings.HasPrefix(uri, "data:") || !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, fmt.Errorf("only base64 data URIs are supported")
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
+
+// localMatrix returns the transform of the node relative to its parent
+func (n *node) localMatrix() cast.Mat4 {
+	if n.Matrix != nil {
+		return *n.Matrix
+	}
+
+	t, r, s := cast.Vec3{}, cast.QuatIdent(), cast.Vec3{X: 1, Y: 1, Z: 1}
+	if n.Translation != nil {
+		t = cast.Vec3{X: n.Translation[0], Y: n.Translation[1], Z: n.Translation[2]}
+	}
+	if n.Rotation != nil {
+		r = cast.Quat{X: n.Rotation[0], Y: n.Rotation[1], Z: n.Rotation[2], W: n.Rotation[3]}
+	}
+	if n.Scale != nil {
+		s = cast.Vec3{X: n.Scale[0], Y: n.Scale[1], Z: n.Scale[2]}
+	}
+	return cast.Mat4FromTRS(t, r, s)
+}
+
+// computeWorldMatrices computes the parents and world transforms of the nodes, the node hierarchy must be a
+// forest
+func (d *decoder) computeWorldMatrices() error {
+	nodes := d.doc.Nodes
+	parents := make([]int, len(nodes))
+	for i := range parents {
+		parents[i] = -1
+	}
+	for i, n := range nodes {
+		for _, c := range n.Children {
+			if c < 0 || c >= len(nodes) {
+				return fmt.Errorf("castgltf: node %d: child %d out of range", i, c)
+			}
+			if parents[c] != -1 || c == i {
+				return fmt.Errorf("castgltf: node %d has more than one parent", c)
+			}
+			parents[c] = i
+		}
+	}
+
+	d.parents = parents
+	d.world = make([]cast.Mat4, len(nodes))
+	done := make([]bool, len(nodes))
+	var compute func(i, depth int) (cast.Mat4, error)
+	compute = func(i, depth int) (cast.Mat4, error) {
+		if done[i] {
+			return d.world[i], nil
+		}
+		if depth > len(nodes) {
+			return cast.Mat4{}, fmt.Errorf("castgltf: node %d is part of a cycle", i)
+		}
+
+		m := nodes[i].localMatrix()
+		if p := parents[i]; p >= 0 {
+			parent, err := compute(p, depth+1)
+			if err != nil {
+				return cast.Mat4{}, err
+			}
+			m = parent.Mul(m)
+		}
+		d.world[i], done[i] = m, true
+		return m, nil
+	}
+
+	for i := range nodes {
+		if _, err := compute(i, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeSkeleton creates a skeleton of the joints of all skins. A joint is parented to its nearest ancestor
+// that is a joint as well.
+func (d *decoder) decodeSkeleton(model *cast.Model) error {
+	d.bones = make(map[int]int)
+
+	var joints []int
+	bindPose := make(map[int]cast.Mat4)
+	for i, s := range d.doc.Skins {
+		var inverseBindMatrices []float64
+		if s.InverseBindMatrices != nil {
+			values, _, err := d.read(*s.InverseBindMatrices, "MAT4")
+			if err != nil {
+				return fmt.Errorf("castgltf: skin %d: %w", i, err)
+			}
+			if len(values) != len(s.Joints)*16 {
+				return fmt.Errorf("castgltf: skin %d: %d inverse bind matrices for %d joints", i, len(values)/16, len(s.Joints))
+			}
+			inverseBindMatrices = values
+		}
+
+		for j, joint := range s.Joints {
+			if joint < 0 || joint >= len(d.doc.Nodes) {
+				return fmt.Errorf("castgltf: skin %d: joint %d out of range", i, joint)
+			}
+			if _, ok := d.bones[joint]; ok {
+				continue
+			}
+			d.bones[joint] = len(joints)
+			joints = append(joints, joint)
+
+			bindPose[joint] = d.world[joint]
+			if inverseBindMatrices != nil {
+				var m cast.Mat4
+				for c := range m {
+					m[c] = float32(inverseBindMatrices[j*16+c])
+				}
+				bindPose[joint] = m.Inverse()
+			}
+		}
+	}
+	if len(joints) == 0 {
+		return nil
+	}
+
+	skeleton := cast.AsSkeleton(model.CreateChild(cast.NodeIdSkeleton))
+	for i, joint := range joints {
+		bone := cast.AsBone(skeleton.CreateChild(cast.NodeIdBone))
+		name := d.doc.Nodes[joint].Name
+		if name == "" {
+			name = fmt.Sprintf("bone_%d", i)
+		}
+		cast.CreateProperty(bone.CastNode, cast.PropNameName, cast.PropString, name)
+
+		parent, local := -1, bindPose[joint]
+		for p := d.parents[joint]; p >= 0; p = d.parents[p] {
+			if index, ok := d.bones[p]; ok {
+				parent, local = index, bindPose[p].Inverse().Mul(local)
+				break
+			}
+		}
+		cast.CreateProperty(bone.CastNode, cast.PropNameParentIndex, cast.PropInteger32, uint32(int32(parent)))
+		if err := bone.SetLocalMatrix(local); err != nil {
+			return err
+		}
+	}
+	return skeleton.ComputeWorldTransforms()
+}
+
+// decodeMaterial creates the material with the given index
+func (d *decoder) decodeMaterial(model *cast.Model, index int) (*cast.CastNode, error) {
+	m := d.doc.Materials[index]
+	node := model.CreateChild(cast.NodeIdMaterial)
+	name := m.Name
+	if name == "" {
+		name = fmt.Sprintf("material_%d", index)
+	}
+	cast.CreateProperty(node, cast.PropNameName, cast.PropString, name)
+	cast.CreateProperty(node, cast.PropNameType, cast.PropString, "pbr")
+
+	material := cast.AsMaterial(node)
+	pbr := m.PBRMetallicRoughness
+	if pbr == nil {
+		pbr = &pbrMetallicRoughness{}
+	}
+
+	tint := cast.Vec4{X: 1, Y: 1, Z: 1, W: 1}
+	if pbr.BaseColorFactor != nil {
+		tint = cast.Vec4{X: pbr.BaseColorFactor[0], Y: pbr.BaseColorFactor[1], Z: pbr.BaseColorFactor[2], W: pbr.BaseColorFactor[3]}
+	}
+	if err := material.SetAlbedoTint(tint); err != nil {
+		return nil, err
+	}
+	if err := material.SetMetalness(valueOr(pbr.MetallicFactor, 1)); err != nil {
+		return nil, err
+	}
+	if err := material.SetRoughnessValue(valueOr(pbr.RoughnessFactor, 1)); err != nil {
+		return nil, err
+	}
+
+	slots := []struct {
+		name cast.CastPropertyName
+		info *textureInfo
+	}{
+		{cast.PropNameAlbedo, pbr.BaseColorTexture},
+		{cast.PropNameNormal, m.NormalTexture},
+	}
+	for _, slot := range slots {
+		if slot.info == nil {
+			continue
+		}
+		file, err := d.decodeTexture(node, slot.info.Index)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", slot.name, err)
+		}
+		if err := material.SetSlot(slot.name, file); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// valueOr returns the value the pointer points to or the given default if it is nil
+func valueOr[T any](v *T, def T) T {
+	if v == nil {
+		return def
+	}
+	return *v
+}
+
+// decodeTexture creates a file of the image of the texture with the given index under the material. Images
+// embedded as data URIs are embedded into the file.
+func (d *decoder) decodeTexture(material *cast.CastNode, index int) (*cast.File, error) {
+	if index < 0 || index >= len(d.doc.Textures) || d.doc.Textures[index].Source == nil {
+		return nil, fmt.Errorf("texture %d out of range", index)
+	}
+	source := *d.doc.Textures[index].Source
+	if source < 0 || source >= len(d.doc.Images) {
+		return nil, fmt.Errorf("image %d out of range", source)
+	}
+	img := d.doc.Images[source]
+	if img.URI == "" {
+		return nil, fmt.Errorf("image %d has no URI", source)
+	}
+
+	file := cast.AsFile(material.CreateChild(cast.NodeIdFile))
+	if !strings.HasPrefix(img.URI, "data:") {
+		_, err := cast.CreateProperty(file.CastNode, cast.PropNamePath, cast.PropString, img.URI)
+		return file, err
+	}
+
+	data, err := decodeDataURI(img.URI)
+	if err != nil {
+		return nil, fmt.Errorf("image %d: %w", source, err)
+	}
+	path := img.Name
+	if path == "" {
+		path = fmt.Sprintf("image_%d", source)
+	}
+	if _, err := cast.CreateProperty(file.CastNode, cast.PropNamePath, cast.PropString, path); err != nil {
+		return nil, err
+	}
+	return file, file.SetData(data)
+}
+
+// decodeMeshNode creates a mesh for every primitive of the mesh of the node with the given index
+func (d *decoder) decodeMeshNode(model *cast.Model, index int) error {
+	n := d.doc.Nodes[index]
+	if *n.Mesh < 0 || *n.Mesh >= len(d.doc.Meshes) {
+		return fmt.Errorf("mesh %d out of range", *n.Mesh)
+	}
+	m := d.doc.Meshes[*n.Mesh]
+
+	var joints []int
+	if n.Skin != nil {
+		if *n.Skin < 0 || *n.Skin >= len(d.doc.Skins) {
+			return fmt.Errorf("skin %d out of range", *n.Skin)
+		}
+		joints = d.doc.Skins[*n.Skin].Joints
+	}
+
+	for i, p := range m.Primitives {
+		mesh := cast.AsMesh(model.CreateChild(cast.NodeIdMesh))
+		name := m.Name
+		if len(m.Primitives) > 1 {
+			name = fmt.Sprintf("%s_%d", m.Name, i)
+		}
+		if name != "" {
+			cast.CreateProperty(mesh.CastNode, cast.PropNameName, cast.PropString, name)
+		}
+
+		if err := d.decodePrimitive(mesh, p, joints); err != nil {
+			return fmt.Errorf("mesh %d primitive %d: %w", *n.Mesh, i, err)
+		}
+		if n.Skin == nil && d.world[index] != cast.Ident4() {
+			if err := mesh.BakeTransform(d.world[index]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodePrimitive reads the attributes, indices and material of the primitive into the mesh, the joints of
+// the skin map the joint indices of the primitive to nodes
+func (d *decoder) decodePrimitive(mesh *cast.Mesh, p primitive, joints []int) error {
+	if p.Mode != nil && *p.Mode != primitiveTriangles {
+		return fmt.Errorf("unsupported mode %d", *p.Mode)
+	}
+
+	position, ok := p.Attributes["POSITION"]
+	if !ok {
+		return fmt.Errorf("missing POSITION attribute")
+	}
+	positions, err := d.readVec3(position)
+	if err != nil {
+		return fmt.Errorf("POSITION: %w", err)
+	}
+	vertexCount := len(positions)
+	if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameVertexPositionBuffer, cast.PropVector3, positions...); err != nil {
+		return err
+	}
+
+	if normal, ok := p.Attributes["NORMAL"]; ok {
+		normals, err := d.readVec3(normal)
+		if err != nil {
+			return fmt.Errorf("NORMAL: %w", err)
+		}
+		if len(normals) != vertexCount {
+			return fmt.Errorf("%d normals for %d vertices", len(normals), vertexCount)
+		}
+		if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameVertexNormalBuffer, cast.PropVector3, normals...); err != nil {
+			return err
+		}
+	}
+
+	for layer := 0; ; layer++ {
+		texcoord, ok := p.Attributes[fmt.Sprintf("TEXCOORD_%d", layer)]
+		if !ok {
+			break
+		}
+		values, _, err := d.read(texcoord, "VEC2")
+		if err != nil {
+			return fmt.Errorf("TEXCOORD_%d: %w", layer, err)
+		}
+		uvs := make([]cast.Vec2, 0, len(values)/2)
+		for i := 0; i < len(values); i += 2 {
+			uvs = append(uvs, cast.Vec2{X: float32(values[i]), Y: float32(values[i+1])})
+		}
+		if err := mesh.SetUVLayer(layer, uvs); err != nil {
+			return err
+		}
+	}
+
+	if color, ok := p.Attributes["COLOR_0"]; ok {
+		values, components, err := d.read(color, "VEC3", "VEC4")
+		if err != nil {
+			return fmt.Errorf("COLOR_0: %w", err)
+		}
+		colors := make([]cast.Vec4, 0, len(values)/components)
+		for i := 0; i < len(values); i += components {
+			c := cast.Vec4{X: float32(values[i]), Y: float32(values[i+1]), Z: float32(values[i+2]), W: 1}
+			if components == 4 {
+				c.W = float32(values[i+3])
+			}
+			colors = append(colors, c)
+		}
+		if err := mesh.SetColors(colors, cast.ColorFloat); err != nil {
+			return err
+		}
+	}
+
+	if err := d.decodeWeights(mesh, p, joints, vertexCount); err != nil {
+		return err
+	}
+
+	var faces []uint32
+	if p.Indices != nil {
+		values, _, err := d.read(*p.Indices, "SCALAR")
+		if err != nil {
+			return fmt.Errorf("indices: %w", err)
+		}
+		faces = make([]uint32, len(values))
+		for i, v := range values {
+			faces[i] = uint32(v)
+		}
+	} else {
+		faces = make([]uint32, vertexCount)
+		for i := range faces {
+			faces[i] = uint32(i)
+		}
+	}
+	if len(faces) > 0 {
+		if err := mesh.SetFaces(faces...); err != nil {
+			return err
+		}
+	}
+
+	if p.Material != nil {
+		if *p.Material < 0 || *p.Material >= len(d.materials) {
+			return fmt.Errorf("material %d out of range", *p.Material)
+		}
+		if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameMaterial, cast.PropInteger64, d.materials[*p.Material]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeWeights reads the sets of joints and weights of the primitive into the skin weights of the mesh,
+// influences without weight are dropped
+func (d *decoder) decodeWeights(mesh *cast.Mesh, p primitive, joints []int, vertexCount int) error {
+	var weights [][]cast.SkinWeight
+	for set := 0; ; set++ {
+		jointsIndex, ok := p.Attributes[fmt.Sprintf("JOINTS_%d", set)]
+		if !ok {
+			break
+		}
+		weightsIndex, ok := p.Attributes[fmt.Sprintf("WEIGHTS_%d", set)]
+		if !ok {
+			return fmt.Errorf("missing WEIGHTS_%d attribute", set)
+		}
+		if joints == nil {
+			return fmt.Errorf("JOINTS_%d without a skin", set)
+		}
+
+		jointValues, _, err := d.read(jointsIndex, "VEC4")
+		if err != nil {
+			return fmt.Errorf("JOINTS_%d: %w", set, err)
+		}
+		weightValues, _, err := d.read(weightsIndex, "VEC4")
+		if err != nil {
+			return fmt.Errorf("WEIGHTS_%d: %w", set, err)
+		}
+		if len(jointValues) != vertexCount*4 || len(weightValues) != vertexCount*4 {
+			return fmt.Errorf("JOINTS_%d and WEIGHTS_%d do not match %d vertices", set, set, vertexCount)
+		}
+
+		if weights == nil {
+			weights = make([][]cast.SkinWeight, vertexCount)
+		}
+		for i, w := range weightValues {
+			if w == 0 {
+				continue
+			}
+			joint := int(jointValues[i])
+			if joint >= len(joints) {
+				return fmt.Errorf("joint %d out of range", joint)
+			}
+			weights[i/4] = append(weights[i/4], cast.SkinWeight{Bone: uint32(d.bones[joints[joint]]), Weight: float32(w)})
+		}
+	}
+	if weights == nil {
+		return nil
+	}
+	return mesh.SetSkinWeights(weights)
+}
+
+// readVec3 reads the VEC3 accessor with the given index
+func (d *decoder) readVec3(index int) ([]cast.Vec3, error) {
+	values, _, err := d.read(index, "VEC3")
+	if err != nil {
+		return nil, err
+	}
+	vectors := make([]cast.Vec3, 0, len(values)/3)
+	for i := 0; i < len(values); i += 3 {
+		vectors = append(vectors, cast.Vec3{X: float32(values[i]), Y: float32(values[i+1]), Z: float32(values[i+2])})
+	}
+	return vectors, nil
+}
+
+// read returns the components of the elements of the accessor with the given index and the number of
+// components per element. The type of the accessor must be one of the given types, normalized integers are
+// mapped to the range [0, 1].
+func (d *decoder) read(index int, types ...string) ([]float64, int, error) {
+	if index < 0 || index >= len(d.doc.Accessors) {
+		return nil, 0, fmt.Errorf("accessor %d out of range", index)
+	}
+	a := d.doc.Accessors[index]
+	if !slices.Contains(types, a.Type) {
+		return nil, 0, fmt.Errorf("accessor %d: unexpected type %s", index, a.Type)
+	}
+	if len(a.Sparse) > 0 {
+		return nil, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	if a.BufferView == nil {
+		return nil, 0, fmt.Errorf("accessor %d has no buffer view", index)
+	}
+
+	var size int
+	var maximum float64
+	switch a.ComponentType {
+	case componentUnsignedByte:
+		size, maximum = 1, math.MaxUint8
+	case componentUnsignedShort:
+		size, maximum = 2, math.MaxUint16
+	case componentUnsignedInt:
+		size, maximum = 4, math.MaxUint32
+	case componentFloat:
+		size = 4
+	default:
+		return nil, 0, fmt.Errorf("accessor %d: unsupported component type %d", index, a.ComponentType)
+	}
+
+	if *a.BufferView < 0 || *a.BufferView >= len(d.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("accessor %d: buffer view %d out of range", index, *a.BufferView)
+	}
+	view := d.doc.BufferViews[*a.BufferView]
+	if view.Buffer < 0 || view.Buffer >= len(d.buffers) {
+		return nil, 0, fmt.Errorf("buffer view %d: buffer %d out of range", *a.BufferView, view.Buffer)
+	}
+	buf := d.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteOffset > len(buf) || view.ByteLength > len(buf)-view.ByteOffset {
+		return nil, 0, fmt.Errorf("buffer view %d out of range", *a.BufferView)
+	}
+	data := buf[view.ByteOffset : view.ByteOffset+view.ByteLength]
+
+	components := componentCounts[a.Type]
+	elementSize := components * size
+	stride := max(view.ByteStride, elementSize)
+	if a.Count < 0 || a.ByteOffset < 0 || a.ByteOffset > len(data) {
+		return nil, 0, fmt.Errorf("accessor %d out of range", index)
+	}
+	data = data[a.ByteOffset:]
+	if a.Count > 0 && (len(data) < elementSize || a.Count-1 > (len(data)-elementSize)/stride) {
+		return nil, 0, fmt.Errorf("accessor %d: %d elements exceed buffer view %d", index, a.Count, *a.BufferView)
+	}
+
+	values := make([]float64, 0, a.Count*components)
+	for i := range a.Count {
+		element := data[i*stride:]
+		for c := range components {
+			var v float64
+			switch a.ComponentType {
+			case componentUnsignedByte:
+				v = float64(element[c])
+			case componentUnsignedShort:
+				v = float64(binary.LittleEndian.Uint16(element[c*2:]))
+			case componentUnsignedInt:
+				v = float64(binary.LittleEndian.Uint32(element[c*4:]))
+			case componentFloat:
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(element[c*4:])))
+			}
+			if a.Normalized && maximum > 0 {
+				v /= maximum
+			}
+			values = append(values, v)
+		}
+	}
+	return values, components, nil
+}
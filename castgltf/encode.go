@@ -0,0 +1,384 @@
+package castgltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"path/filepath"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// ----------------------- //
+//         ENCODE          //
+// ----------------------- //
+
+// Encode writes the models of the given [cast.CastFile] to the given [io.Writer] as a glTF file. Every model
+// becomes a node of the scene holding a node per mesh and the root bones of its first skeleton. Skinned
+// meshes reference a skin with up to 65536 bones, the influences of a vertex are written in sets of four.
+// Vertex tangents are not converted.
+func Encode(w io.Writer, f *cast.CastFile) error {
+	e := &encoder{
+		doc: document{
+			Asset:  asset{Version: "2.0", Generator: "go-cast"},
+			Scene:  ptr(0),
+			Scenes: []scene{{}},
+		},
+		materials: make(map[uint64]int),
+		images:    make(map[uint64]int),
+	}
+
+	for _, node := range f.Find(cast.ByType(cast.NodeIdModel)) {
+		index, err := e.encodeModel(cast.AsModel(node))
+		if err != nil {
+			return err
+		}
+		e.doc.Scenes[0].Nodes = append(e.doc.Scenes[0].Nodes, index)
+	}
+
+	if e.data.Len() > 0 {
+		e.doc.Buffers = []buffer{{
+			URI:        dataURIPrefix + base64.StdEncoding.EncodeToString(e.data.Bytes()),
+			ByteLength: e.data.Len(),
+		}}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&e.doc)
+}
+
+// encoder builds a glTF document and its binary data
+type encoder struct {
+	doc  document
+	data bytes.Buffer
+
+	// indices of the converted materials and images by the hash of their node
+	materials map[uint64]int
+	images    map[uint64]int
+}
+
+// encodeModel adds the model with its skeleton, materials and meshes and returns the index of its node
+func (e *encoder) encodeModel(model *cast.Model) (int, error) {
+	modelNode := node{Name: cast.GetPropertyValueOr(model.CastNode, cast.PropNameName, "")}
+
+	var skinIndex *int
+	if skeletons := model.Skeletons(); len(skeletons) > 0 {
+		roots, index, err := e.encodeSkeleton(skeletons[0])
+		if err != nil {
+			return 0, err
+		}
+		modelNode.Children = append(modelNode.Children, roots...)
+		skinIndex = index
+	}
+
+	for _, n := range model.GetChildrenOfType(cast.NodeIdMaterial) {
+		e.encodeMaterial(cast.AsMaterial(n))
+	}
+
+	for _, m := range model.Meshes() {
+		index, skinned, err := e.encodeMesh(m)
+		if err != nil {
+			return 0, fmt.Errorf("castgltf: mesh %q: %w", cast.GetPropertyValueOr(m.CastNode, cast.PropNameName, ""), err)
+		}
+
+		meshNode := node{Name: e.doc.Meshes[index].Name, Mesh: ptr(index)}
+		if skinned {
+			if skinIndex == nil {
+				return 0, fmt.Errorf("castgltf: mesh %q is skinned but the model has no skeleton", meshNode.Name)
+			}
+			meshNode.Skin = skinIndex
+		}
+		e.doc.Nodes = append(e.doc.Nodes, meshNode)
+		modelNode.Children = append(modelNode.Children, len(e.doc.Nodes)-1)
+	}
+
+	e.doc.Nodes = append(e.doc.Nodes, modelNode)
+	return len(e.doc.Nodes) - 1, nil
+}
+
+// encodeSkeleton adds a node per bone and a skin joining them, returns the indices of the nodes of the root
+// bones and the index of the skin. Skeletons without bones have no skin.
+func (e *encoder) encodeSkeleton(skeleton *cast.Skeleton) ([]int, *int, error) {
+	bones := skeleton.Bones()
+	if len(bones) == 0 {
+		return nil, nil, nil
+	}
+	if len(bones) > math.MaxUint16+1 {
+		return nil, nil, fmt.Errorf("castgltf: %d bones exceed the maximum of %d", len(bones), math.MaxUint16+1)
+	}
+
+	base := len(e.doc.Nodes)
+	joints := make([]int, len(bones))
+	for i, bone := range bones {
+		t, r, s := bone.LocalPosition(), bone.LocalRotation(), bone.Scale()
+		e.doc.Nodes = append(e.doc.Nodes, node{
+			Name:        bone.Name(),
+			Translation: &[3]float32{t.X, t.Y, t.Z},
+			Rotation:    &[4]float32{r.X, r.Y, r.Z, r.W},
+			Scale:       &[3]float32{s.X, s.Y, s.Z},
+		})
+		joints[i] = base + i
+	}
+
+	var roots []int
+	for i, bone := range bones {
+		if p := bone.ParentIndex(); p >= 0 && p < len(bones) && p != i {
+			e.doc.Nodes[base+p].Children = append(e.doc.Nodes[base+p].Children, base+i)
+		} else {
+			roots = append(roots, base+i)
+		}
+	}
+
+	matrices := skeleton.InverseBindMatrices()
+	values := make([]float32, 0, len(matrices)*16)
+	for _, m := range matrices {
+		values = append(values, m[:]...)
+	}
+	inverseBindMatrices := e.addAccessor(floatBytes(values), componentFloat, "MAT4", len(matrices), false)
+
+	e.doc.Skins = append(e.doc.Skins, skin{
+		Name:                cast.GetPropertyValueOr(skeleton.CastNode, cast.PropNameName, ""),
+		InverseBindMatrices: ptr(inverseBindMatrices),
+		Joints:              joints,
+	})
+	return roots, ptr(len(e.doc.Skins) - 1), nil
+}
+
+// encodeMaterial adds the material with its albedo and normal maps
+func (e *encoder) encodeMaterial(m *cast.Material) {
+	tint := m.AlbedoTint()
+	pbr := &pbrMetallicRoughness{
+		BaseColorFactor: &[4]float32{tint.X, tint.Y, tint.Z, tint.W},
+		MetallicFactor:  ptr(m.Metalness()),
+		RoughnessFactor: ptr(m.RoughnessValue()),
+	}
+
+	albedo := m.Slot(cast.PropNameAlbedo)
+	if albedo == nil {
+		albedo = m.Slot(cast.PropNameDiffuse)
+	}
+	if albedo != nil {
+		pbr.BaseColorTexture = e.textureOf(albedo)
+	}
+
+	var normal *textureInfo
+	if file := m.Slot(cast.PropNameNormal); file != nil {
+		normal = e.textureOf(file)
+	}
+
+	e.doc.Materials = append(e.doc.Materials, material{
+		Name:                 m.Name(),
+		PBRMetallicRoughness: pbr,
+		NormalTexture:        normal,
+	})
+	e.materials[m.Hash()] = len(e.doc.Materials) - 1
+}
+
+// textureOf returns a texture of the image of the given file, files shared by materials share the image. The
+// image references the file by path, embedded contents are embedded as a data URI.
+func (e *encoder) textureOf(file *cast.File) *textureInfo {
+	index, ok := e.images[file.Hash()]
+	if !ok {
+		img := image{URI: file.Path()}
+		if data := file.Data(); data != nil {
+			img.Name = file.Path()
+			img.MimeType = mime.TypeByExtension(filepath.Ext(file.Path()))
+			if img.MimeType == "" {
+				img.MimeType = "image/png"
+			}
+			img.URI = "data:" + img.MimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		}
+
+		e.doc.Images = append(e.doc.Images, img)
+		e.doc.Textures = append(e.doc.Textures, texture{Source: ptr(len(e.doc.Images) - 1)})
+		index = len(e.doc.Textures) - 1
+		e.images[file.Hash()] = index
+	}
+	return &textureInfo{Index: index}
+}
+
+// encodeMesh adds the mesh as a mesh of a single primitive, returns its index and whether it is skinned
+func (e *encoder) encodeMesh(m *cast.Mesh) (int, bool, error) {
+	if err := m.CheckIndices(); err != nil {
+		return 0, false, err
+	}
+
+	positions, err := cast.GetPropertyValues[cast.Vec3](m.CastNode, cast.PropNameVertexPositionBuffer)
+	if err != nil {
+		return 0, false, err
+	}
+	vertexCount := len(positions)
+
+	p := primitive{Attributes: make(map[string]int)}
+	minimum, maximum := bounds(positions)
+	position := e.addAccessor(vec3Bytes(positions), componentFloat, "VEC3", vertexCount, false)
+	e.doc.Accessors[position].Min, e.doc.Accessors[position].Max = minimum, maximum
+	p.Attributes["POSITION"] = position
+
+	if normals, err := cast.GetPropertyValues[cast.Vec3](m.CastNode, cast.PropNameVertexNormalBuffer); err == nil {
+		if len(normals) != vertexCount {
+			return 0, false, fmt.Errorf("%d normals for %d vertices", len(normals), vertexCount)
+		}
+		p.Attributes["NORMAL"] = e.addAccessor(vec3Bytes(normals), componentFloat, "VEC3", vertexCount, false)
+	}
+
+	for i := range m.UVLayerCount() {
+		uvs, err := m.UVLayer(i)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(uvs) != vertexCount {
+			return 0, false, fmt.Errorf("UV layer %d holds %d coordinates for %d vertices", i, len(uvs), vertexCount)
+		}
+		values := make([]float32, 0, len(uvs)*2)
+		for _, uv := range uvs {
+			values = append(values, uv.X, uv.Y)
+		}
+		p.Attributes[fmt.Sprintf("TEXCOORD_%d", i)] = e.addAccessor(floatBytes(values), componentFloat, "VEC2", vertexCount, false)
+	}
+
+	if m.HasProperty(cast.PropNameVertexColorBuffer) {
+		colors, err := m.Colors()
+		if err != nil {
+			return 0, false, err
+		}
+		if len(colors) != vertexCount {
+			return 0, false, fmt.Errorf("%d colors for %d vertices", len(colors), vertexCount)
+		}
+		p.Attributes["COLOR_0"] = e.addAccessor(vec4Bytes(colors), componentFloat, "VEC4", vertexCount, false)
+	}
+
+	skinned := m.HasProperty(cast.PropNameVertexWeightValueBuffer)
+	if skinned {
+		if err := e.encodeWeights(m, &p); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if faces, err := m.Faces(); err == nil {
+		indices := make([]byte, 0, len(faces)*4)
+		for _, index := range faces {
+			indices = binary.LittleEndian.AppendUint32(indices, index)
+		}
+		p.Indices = ptr(e.addAccessor(indices, componentUnsignedInt, "SCALAR", len(faces), false))
+	}
+
+	if material := m.ResolveReference(cast.PropNameMaterial); material != nil {
+		if index, ok := e.materials[material.Hash()]; ok {
+			p.Material = ptr(index)
+		}
+	}
+
+	e.doc.Meshes = append(e.doc.Meshes, mesh{
+		Name:       cast.GetPropertyValueOr(m.CastNode, cast.PropNameName, ""),
+		Primitives: []primitive{p},
+	})
+	return len(e.doc.Meshes) - 1, skinned, nil
+}
+
+// encodeWeights adds the bone influences of the mesh to the primitive in sets of four, padded with zero weights
+func (e *encoder) encodeWeights(m *cast.Mesh, p *primitive) error {
+	weights, err := m.SkinWeights()
+	if err != nil {
+		return err
+	}
+
+	influences := 0
+	if len(weights) > 0 {
+		influences = len(weights[0])
+	}
+
+	for set := 0; set*4 < influences; set++ {
+		joints := make([]byte, 0, len(weights)*8)
+		values := make([]float32, 0, len(weights)*4)
+		for _, vertex := range weights {
+			for i := set * 4; i < set*4+4; i++ {
+				var w cast.SkinWeight
+				if i < len(vertex) {
+					w = vertex[i]
+				}
+				if w.Bone > math.MaxUint16 {
+					return fmt.Errorf("bone index %d out of range", w.Bone)
+				}
+				joints = binary.LittleEndian.AppendUint16(joints, uint16(w.Bone))
+				values = append(values, w.Weight)
+			}
+		}
+		p.Attributes[fmt.Sprintf("JOINTS_%d", set)] = e.addAccessor(joints, componentUnsignedShort, "VEC4", len(weights), false)
+		p.Attributes[fmt.Sprintf("WEIGHTS_%d", set)] = e.addAccessor(floatBytes(values), componentFloat, "VEC4", len(weights), false)
+	}
+	return nil
+}
+
+// addAccessor appends the data to the buffer, aligned to 4 bytes, and adds an accessor of it. Returns the
+// index of the accessor.
+func (e *encoder) addAccessor(data []byte, componentType int, typ string, count int, normalized bool) int {
+	for e.data.Len()%4 != 0 {
+		e.data.WriteByte(0)
+	}
+
+	e.doc.BufferViews = append(e.doc.BufferViews, bufferView{
+		Buffer:     0,
+		ByteOffset: e.data.Len(),
+		ByteLength: len(data),
+	})
+	e.data.Write(data)
+
+	e.doc.Accessors = append(e.doc.Accessors, accessor{
+		BufferView:    ptr(len(e.doc.BufferViews) - 1),
+		ComponentType: componentType,
+		Normalized:    normalized,
+		Count:         count,
+		Type:          typ,
+	})
+	return len(e.doc.Accessors) - 1
+}
+
+// bounds returns the component-wise minimum and maximum of the positions, required for position accessors
+func bounds(positions []cast.Vec3) ([]float32, []float32) {
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	minimum := []float32{positions[0].X, positions[0].Y, positions[0].Z}
+	maximum := []float32{positions[0].X, positions[0].Y, positions[0].Z}
+	for _, p := range positions[1:] {
+		for c, v := range [3]float32{p.X, p.Y, p.Z} {
+			minimum[c], maximum[c] = min(minimum[c], v), max(maximum[c], v)
+		}
+	}
+	return minimum, maximum
+}
+
+// floatBytes returns the little endian encoding of the values
+func floatBytes(values []float32) []byte {
+	data := make([]byte, 0, len(values)*4)
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+	}
+	return data
+}
+
+// vec3Bytes returns the little endian encoding of the components of the vectors
+func vec3Bytes(values []cast.Vec3) []byte {
+	floats := make([]float32, 0, len(values)*3)
+	for _, v := range values {
+		floats = append(floats, v.X, v.Y, v.Z)
+	}
+	return floatBytes(floats)
+}
+
+// vec4Bytes returns the little endian encoding of the components of the vectors
+func vec4Bytes(values []cast.Vec4) []byte {
+	floats := make([]float32, 0, len(values)*4)
+	for _, v := range values {
+		floats = append(floats, v.X, v.Y, v.Z, v.W)
+	}
+	return floatBytes(floats)
+}
@@ -0,0 +1,251 @@
+// Package castjson converts cast files to and from a JSON representation
+package castjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// jsonFile is the JSON representation of a [cast.CastFile]
+type jsonFile struct {
+	Version uint32     `json:"version"`
+	Flags   uint32     `json:"flags"`
+	Roots   []jsonNode `json:"roots"`
+}
+
+// jsonNode is the JSON representation of a [cast.CastNode]
+type jsonNode struct {
+	Id         string         `json:"id"`
+	Hash       string         `json:"hash"`
+	Properties []jsonProperty `json:"properties"`
+	Children   []jsonNode     `json:"children"`
+}
+
+// jsonProperty is the JSON representation of a property
+type jsonProperty struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Values any    `json:"values"`
+}
+
+// propertyIds holds the property ids by the type names they are encoded with
+var propertyIds = map[string]cast.CastPropertyId{}
+
+func init() {
+	for _, id := range []cast.CastPropertyId{
+		cast.PropByte, cast.PropShort, cast.PropInteger32, cast.PropInteger64, cast.PropFloat,
+		cast.PropDouble, cast.PropString, cast.PropVector2, cast.PropVector3, cast.PropVector4,
+	} {
+		propertyIds[id.String()] = id
+	}
+}
+
+// Marshal returns the JSON encoding of the given [cast.CastFile]
+func Marshal(f *cast.CastFile) ([]byte, error) {
+	jf, err := fromFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(jf, "", "  ")
+}
+
+// Encode writes the JSON encoding of the given [cast.CastFile] to the given [io.Writer]
+func Encode(w io.Writer, f *cast.CastFile) error {
+	jf, err := fromFile(f)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jf)
+}
+
+// fromFile converts a [cast.CastFile] into its JSON representation
+func fromFile(f *cast.CastFile) (*jsonFile, error) {
+	jf := &jsonFile{
+		Version: f.Version(),
+		Flags:   f.Flags(),
+		Roots:   make([]jsonNode, 0, len(f.Roots())),
+	}
+
+	for _, root := range f.Roots() {
+		jn, err := fromNode(root)
+		if err != nil {
+			return nil, err
+		}
+		jf.Roots = append(jf.Roots, jn)
+	}
+
+	return jf, nil
+}
+
+// fromNode converts a [cast.CastNode] into its JSON representation
+func fromNode(n *cast.CastNode) (jsonNode, error) {
+	jn := jsonNode{
+		Id:         n.Id().String(),
+		Hash:       fmt.Sprintf("%#016x", n.Hash()),
+		Properties: make([]jsonProperty, 0),
+		Children:   make([]jsonNode, 0, len(n.GetChildNodes())),
+	}
+
+	for name, p := range n.Properties() {
+		jn.Properties = append(jn.Properties, jsonProperty{
+			Name:   string(name),
+			Type:   p.Id().String(),
+			Values: p.AnyValues(),
+		})
+	}
+
+	for _, c := range n.GetChildNodes() {
+		jc, err := fromNode(c)
+		if err != nil {
+			return jn, err
+		}
+		jn.Children = append(jn.Children, jc)
+	}
+
+	return jn, nil
+}
+
+// Unmarshal parses the JSON encoding of a [cast.CastFile] as produced by [Marshal]
+func Unmarshal(data []byte) (*cast.CastFile, error) {
+	return Decode(bytes.NewReader(data))
+}
+
+// Decode reads the JSON encoding of a [cast.CastFile] as produced by [Encode] from the given [io.Reader]
+func Decode(r io.Reader) (*cast.CastFile, error) {
+	// numbers are kept as they are written, 64 bit integers do not fit into a float64
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var jf jsonFile
+	if err := dec.Decode(&jf); err != nil {
+		return nil, err
+	}
+	return toFile(&jf)
+}
+
+// toFile converts the JSON representation into a [cast.CastFile]
+func toFile(jf *jsonFile) (*cast.CastFile, error) {
+	f := cast.New().SetVersion(jf.Version).SetFlags(jf.Flags)
+	for _, jn := range jf.Roots {
+		if jn.Id != cast.NodeIdRoot.String() {
+			return nil, fmt.Errorf("castjson: root node has id %q", jn.Id)
+		}
+
+		// the tree is built detached from the file and attached once complete, so nodes sharing a hash are
+		// kept as they are, as when loading a cast file
+		placeholder := f.CreateRoot()
+		root := placeholder.Clone(true)
+		if err := toNode(root, &jn); err != nil {
+			return nil, err
+		}
+		if err := f.ReplaceRoot(placeholder, root); err != nil {
+			return nil, fmt.Errorf("castjson: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// toNode fills the given node with the hash, properties and children of the JSON representation
+func toNode(n *cast.CastNode, jn *jsonNode) error {
+	hash, err := strconv.ParseUint(jn.Hash, 0, 64)
+	if err != nil {
+		return fmt.Errorf("castjson: invalid hash %q of %s node", jn.Hash, jn.Id)
+	}
+	if err := n.SetHash(hash); err != nil {
+		return err
+	}
+
+	for _, jp := range jn.Properties {
+		if err := toProperty(n, &jp); err != nil {
+			return fmt.Errorf("castjson: property %s of %s node %#x: %w", jp.Name, jn.Id, hash, err)
+		}
+	}
+
+	for _, jc := range jn.Children {
+		id, err := cast.ParseCastNodeId(jc.Id)
+		if err != nil {
+			return err
+		}
+		if err := toNode(n.CreateChild(id), &jc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toProperty creates the property of the JSON representation on the given node
+func toProperty(n *cast.CastNode, jp *jsonProperty) error {
+	id, ok := propertyIds[jp.Type]
+	if !ok {
+		return fmt.Errorf("invalid type %q", jp.Type)
+	}
+
+	raw, err := json.Marshal(jp.Values)
+	if err != nil {
+		return err
+	}
+
+	name := cast.CastPropertyName(jp.Name)
+	switch id {
+	case cast.PropByte:
+		// a []byte would be decoded from a base64 string instead of a list of numbers
+		values, err := decodeValues[uint16](raw)
+		if err != nil {
+			return err
+		}
+		bytes := make([]byte, len(values))
+		for i, v := range values {
+			if v > 0xFF {
+				return fmt.Errorf("value %d out of range of a byte", v)
+			}
+			bytes[i] = byte(v)
+		}
+		_, err = cast.CreateProperty(n, name, id, bytes...)
+		return err
+	case cast.PropShort:
+		return createProperty[uint16](n, name, id, raw)
+	case cast.PropInteger32:
+		return createProperty[uint32](n, name, id, raw)
+	case cast.PropInteger64:
+		return createProperty[uint64](n, name, id, raw)
+	case cast.PropFloat:
+		return createProperty[float32](n, name, id, raw)
+	case cast.PropDouble:
+		return createProperty[float64](n, name, id, raw)
+	case cast.PropString:
+		return createProperty[string](n, name, id, raw)
+	case cast.PropVector2:
+		return createProperty[cast.Vec2](n, name, id, raw)
+	case cast.PropVector3:
+		return createProperty[cast.Vec3](n, name, id, raw)
+	default:
+		return createProperty[cast.Vec4](n, name, id, raw)
+	}
+}
+
+// createProperty creates a property holding the values decoded from the given JSON array
+func createProperty[T cast.CastPropertyValueType](n *cast.CastNode, name cast.CastPropertyName, id cast.CastPropertyId, raw []byte) error {
+	values, err := decodeValues[T](raw)
+	if err != nil {
+		return err
+	}
+	_, err = cast.CreateProperty(n, name, id, values...)
+	return err
+}
+
+// decodeValues decodes the given JSON array
+func decodeValues[T any](raw []byte) ([]T, error) {
+	var values []T
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,103 @@
+package castjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func TestMarshal(t *testing.T) {
+	r, err := os.Open("../testdata/cube.cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	castFile, err := cast.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Marshal(castFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var jf jsonFile
+	if err := json.Unmarshal(data, &jf); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jf.Roots) != 1 || jf.Roots[0].Id != "root" {
+		t.Fatalf("unexpected roots: %+v", jf.Roots)
+	}
+
+	model := jf.Roots[0].Children[0]
+	if model.Id != "modl" {
+		t.Fatalf("got: %v != want: modl", model.Id)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	r, err := os.Open("../testdata/cast_ik.cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	castFile, err := cast.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Marshal(castFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the binary encodings match only if the hashes, property types and values all survived
+	want, err := castFile.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("decoded file does not match the original")
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"roots": [{"id": "modl", "hash": "0x1"}]}`,
+		`{"roots": [{"id": "root", "hash": "x"}]}`,
+		`{"roots": [{"id": "root", "hash": "0x1", "properties": [{"name": "n", "type": "q", "values": []}]}]}`,
+		`{"roots": [{"id": "root", "hash": "0x1", "properties": [{"name": "n", "type": "b", "values": [256]}]}]}`,
+	} {
+		if _, err := Unmarshal([]byte(data)); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+}
+
+func TestUnmarshalDuplicateHashes(t *testing.T) {
+	// nodes sharing a hash are kept as they are, as when loading a cast file
+	castFile, err := Unmarshal([]byte(`{"roots": [{"id": "root", "hash": "0x0", "children": [{"id": "modl", "hash": "0x0"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := castFile.GetNodesOfType(cast.NodeIdModel)
+	if len(models) != 1 || models[0].Hash() != 0 {
+		t.Errorf("unexpected models: %v", models)
+	}
+}
@@ -0,0 +1,121 @@
+// Package castmath converts the math types of cast to and from the types of go-gl/mathgl (mgl32) and gonum,
+// for renderers and tools built on those libraries. It is a separate module so the cast package itself stays
+// free of dependencies.
+//
+// Matrices of cast and mgl32 are both column-major and convert without rearranging, gonum matrices are
+// indexed by row and column.
+package castmath
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/mauserzjeh/go-cast"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/num/quat"
+	"gonum.org/v1/gonum/spatial/r3"
+)
+
+// ----------------------- //
+//         MGL32           //
+// ----------------------- //
+
+// MglVec2 converts the vector to an mgl32 vector
+func MglVec2(v cast.Vec2) mgl32.Vec2 {
+	return mgl32.Vec2{v.X, v.Y}
+}
+
+// Vec2FromMgl converts the mgl32 vector to a cast vector
+func Vec2FromMgl(v mgl32.Vec2) cast.Vec2 {
+	return cast.Vec2{X: v[0], Y: v[1]}
+}
+
+// MglVec3 converts the vector to an mgl32 vector
+func MglVec3(v cast.Vec3) mgl32.Vec3 {
+	return mgl32.Vec3{v.X, v.Y, v.Z}
+}
+
+// Vec3FromMgl converts the mgl32 vector to a cast vector
+func Vec3FromMgl(v mgl32.Vec3) cast.Vec3 {
+	return cast.Vec3{X: v[0], Y: v[1], Z: v[2]}
+}
+
+// MglVec4 converts the vector to an mgl32 vector
+func MglVec4(v cast.Vec4) mgl32.Vec4 {
+	return mgl32.Vec4{v.X, v.Y, v.Z, v.W}
+}
+
+// Vec4FromMgl converts the mgl32 vector to a cast vector
+func Vec4FromMgl(v mgl32.Vec4) cast.Vec4 {
+	return cast.Vec4{X: v[0], Y: v[1], Z: v[2], W: v[3]}
+}
+
+// MglQuat converts the quaternion to an mgl32 quaternion
+func MglQuat(q cast.Quat) mgl32.Quat {
+	return mgl32.Quat{W: q.W, V: mgl32.Vec3{q.X, q.Y, q.Z}}
+}
+
+// QuatFromMgl converts the mgl32 quaternion to a cast quaternion
+func QuatFromMgl(q mgl32.Quat) cast.Quat {
+	return cast.Quat{X: q.V[0], Y: q.V[1], Z: q.V[2], W: q.W}
+}
+
+// MglMat4 converts the matrix to an mgl32 matrix
+func MglMat4(m cast.Mat4) mgl32.Mat4 {
+	return mgl32.Mat4(m)
+}
+
+// Mat4FromMgl converts the mgl32 matrix to a cast matrix
+func Mat4FromMgl(m mgl32.Mat4) cast.Mat4 {
+	return cast.Mat4(m)
+}
+
+// ----------------------- //
+//          GONUM          //
+// ----------------------- //
+
+// R3Vec converts the vector to a gonum r3 vector
+func R3Vec(v cast.Vec3) r3.Vec {
+	return r3.Vec{X: float64(v.X), Y: float64(v.Y), Z: float64(v.Z)}
+}
+
+// Vec3FromR3 converts the gonum r3 vector to a cast vector
+func Vec3FromR3(v r3.Vec) cast.Vec3 {
+	return cast.Vec3{X: float32(v.X), Y: float32(v.Y), Z: float32(v.Z)}
+}
+
+// GonumQuat converts the quaternion to a gonum quaternion
+func GonumQuat(q cast.Quat) quat.Number {
+	return quat.Number{Real: float64(q.W), Imag: float64(q.X), Jmag: float64(q.Y), Kmag: float64(q.Z)}
+}
+
+// QuatFromGonum converts the gonum quaternion to a cast quaternion
+func QuatFromGonum(q quat.Number) cast.Quat {
+	return cast.Quat{X: float32(q.Imag), Y: float32(q.Jmag), Z: float32(q.Kmag), W: float32(q.Real)}
+}
+
+// Dense converts the matrix to a gonum 4x4 dense matrix
+func Dense(m cast.Mat4) *mat.Dense {
+	d := mat.NewDense(4, 4, nil)
+	for row := range 4 {
+		for col := range 4 {
+			d.Set(row, col, float64(m.At(row, col)))
+		}
+	}
+	return d
+}
+
+// Mat4FromDense converts the gonum matrix to a cast matrix, returns an error if it is not 4x4
+func Mat4FromDense(m mat.Matrix) (cast.Mat4, error) {
+	if r, c := m.Dims(); r != 4 || c != 4 {
+		return cast.Mat4{}, fmt.Errorf("castmath: matrix of %dx%d instead of 4x4", r, c)
+	}
+
+	var res cast.Mat4
+	for row := range 4 {
+		for col := range 4 {
+			res[col*4+row] = float32(m.At(row, col))
+		}
+	}
+	return res, nil
+}
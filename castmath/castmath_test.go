@@ -0,0 +1,68 @@
+package castmath
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/num/quat"
+)
+
+// assertEqual fails if the two values are not equal
+func assertEqual[T comparable](t testing.TB, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got: %v != want: %v", got, want)
+	}
+}
+
+// assertNearVec3 fails if a component of the two vectors differs by more than 1e-5
+func assertNearVec3(t testing.TB, got, want cast.Vec3) {
+	t.Helper()
+	if math.Abs(float64(got.X-want.X)) > 1e-5 || math.Abs(float64(got.Y-want.Y)) > 1e-5 || math.Abs(float64(got.Z-want.Z)) > 1e-5 {
+		t.Errorf("got: %v != want: %v", got, want)
+	}
+}
+
+func TestMgl(t *testing.T) {
+	assertEqual(t, Vec2FromMgl(MglVec2(cast.Vec2{X: 1, Y: 2})), cast.Vec2{X: 1, Y: 2})
+	assertEqual(t, Vec3FromMgl(MglVec3(cast.Vec3{X: 1, Y: 2, Z: 3})), cast.Vec3{X: 1, Y: 2, Z: 3})
+	assertEqual(t, Vec4FromMgl(MglVec4(cast.Vec4{X: 1, Y: 2, Z: 3, W: 4})), cast.Vec4{X: 1, Y: 2, Z: 3, W: 4})
+
+	q := cast.QuatFromEuler(0.3, -0.2, 1.1)
+	assertEqual(t, QuatFromMgl(MglQuat(q)), q)
+
+	v := cast.Vec3{X: 1, Y: 2, Z: 3}
+	assertNearVec3(t, Vec3FromMgl(MglQuat(q).Rotate(MglVec3(v))), q.Rotate(v))
+
+	m := cast.Mat4FromTRS(cast.Vec3{X: 1, Y: 2, Z: 3}, q, cast.Vec3{X: 2, Y: 2, Z: 2})
+	assertEqual(t, Mat4FromMgl(MglMat4(m)), m)
+	assertNearVec3(t, Vec3FromMgl(MglMat4(m).Mul4x1(MglVec3(v).Vec4(1)).Vec3()), m.TransformPoint(v))
+}
+
+func TestGonum(t *testing.T) {
+	v := cast.Vec3{X: 1, Y: 2, Z: 3}
+	assertEqual(t, Vec3FromR3(R3Vec(v)), v)
+
+	q := cast.QuatFromEuler(0.3, -0.2, 1.1)
+	assertEqual(t, QuatFromGonum(GonumQuat(q)), q)
+
+	// rotating a pure quaternion by q v q* matches the rotation of the vector
+	g := GonumQuat(q)
+	r := quat.Mul(quat.Mul(g, quat.Number{Imag: 1, Jmag: 2, Kmag: 3}), quat.Conj(g))
+	assertNearVec3(t, cast.Vec3{X: float32(r.Imag), Y: float32(r.Jmag), Z: float32(r.Kmag)}, q.Rotate(v))
+
+	m := cast.Mat4FromTRS(cast.Vec3{X: 1, Y: 2, Z: 3}, q, cast.Vec3{X: 1, Y: 1, Z: 1})
+	d := Dense(m)
+	assertEqual(t, float32(d.At(0, 3)), 1)
+	back, err := Mat4FromDense(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, back, m)
+
+	if _, err := Mat4FromDense(mat.NewDense(3, 3, nil)); err == nil {
+		t.Error("expected error for a 3x3 matrix")
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/mauserzjeh/go-cast/castmath
+
+go 1.23.0
+
+require (
+	github.com/go-gl/mathgl v1.2.0
+	github.com/mauserzjeh/go-cast v0.0.0-20261017024342-c2d710cc72ca
+	gonum.org/v1/gonum v0.16.0
+)
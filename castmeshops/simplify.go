@@ -0,0 +1,447 @@
+// Package castmeshops provides geometry operations on cast meshes
+package castmeshops
+
+import (
+	"cmp"
+	"container/heap"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// boundaryWeight scales the quadrics keeping open borders and UV seams in place
+const boundaryWeight = 1000
+
+// Simplify returns a copy of the mesh reduced to at most the given amount of triangles using quadric error
+// metrics. Edges are collapsed into one of their vertices, so the UVs, colors and skin weights of the remaining
+// vertices are kept as they are. Open borders and UV seams are preserved as far as possible. The copy is
+// assigned fresh hashes and does not belong to a file until it is attached with [cast.CastNode.MoveTo].
+// Simplification stops early if no further edge can be collapsed without flipping a face.
+func Simplify(mesh *cast.Mesh, targetTriangles int) (*cast.Mesh, error) {
+	positions, err := cast.GetPropertyValues[cast.Vec3](mesh.CastNode, cast.PropNameVertexPositionBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mesh.CheckIndices(); err != nil {
+		return nil, err
+	}
+	faces, err := mesh.Faces()
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSimplifier(positions, faces)
+	s.simplify(targetTriangles)
+	keep, faces := s.compact()
+
+	simplified := cast.AsMesh(mesh.Clone(false))
+	if err := remapVertices(simplified, len(positions), keep); err != nil {
+		return nil, err
+	}
+	if err := simplified.SetFaces(faces...); err != nil {
+		return nil, err
+	}
+	return simplified, nil
+}
+
+// remapVertices keeps the vertices with the given indices in all per-vertex buffers of the mesh
+func remapVertices(mesh *cast.Mesh, vertexCount int, keep []int) error {
+	names := []cast.CastPropertyName{
+		cast.PropNameVertexPositionBuffer,
+		cast.PropNameVertexNormalBuffer,
+		cast.PropNameVertexTangentBuffer,
+		cast.PropNameVertexColorBuffer,
+		cast.PropNameVertexWeightBoneBuffer,
+		cast.PropNameVertexWeightValueBuffer,
+	}
+	for _, i := range mesh.UVLayers() {
+		names = append(names, cast.CastPropertyName(fmt.Sprintf(string(cast.PropNameVertexUVBuffer), i)))
+	}
+
+	for _, name := range names {
+		p, ok := mesh.GetProperty(name)
+		if !ok {
+			continue
+		}
+		if p.Count()%vertexCount != 0 {
+			return fmt.Errorf("castmeshops: property %s holds %d values for %d vertices", name, p.Count(), vertexCount)
+		}
+		stride := p.Count() / vertexCount
+
+		switch p := p.(type) {
+		case *cast.CastProperty[byte]:
+			remap(p, keep, stride)
+		case *cast.CastProperty[uint16]:
+			remap(p, keep, stride)
+		case *cast.CastProperty[uint32]:
+			remap(p, keep, stride)
+		case *cast.CastProperty[float32]:
+			remap(p, keep, stride)
+		case *cast.CastProperty[cast.Vec2]:
+			remap(p, keep, stride)
+		case *cast.CastProperty[cast.Vec3]:
+			remap(p, keep, stride)
+		case *cast.CastProperty[cast.Vec4]:
+			remap(p, keep, stride)
+		default:
+			return fmt.Errorf("castmeshops: property %s has an unsupported type of %T", name, p)
+		}
+	}
+	return nil
+}
+
+// remap keeps the values of the vertices with the given indices, stride values per vertex
+func remap[T cast.CastPropertyValueType](p *cast.CastProperty[T], keep []int, stride int) {
+	values := p.GetValues()
+	remapped := make([]T, 0, len(keep)*stride)
+	for _, i := range keep {
+		remapped = append(remapped, values[i*stride:(i+1)*stride]...)
+	}
+	p.SetValues(remapped...)
+}
+
+// quadric is a symmetric 4x4 matrix measuring the squared distance to a set of planes
+type quadric [10]float64
+
+// planeQuadric returns the quadric of the plane with the given unit normal through the given point
+func planeQuadric(n, p vec3, weight float64) quadric {
+	a, b, c := n[0], n[1], n[2]
+	d := -n.dot(p)
+	return quadric{
+		a * a, a * b, a * c, a * d,
+		b * b, b * c, b * d,
+		c * c, c * d,
+		d * d,
+	}.scale(weight)
+}
+
+// add returns the sum of the quadrics
+func (q quadric) add(o quadric) quadric {
+	for i := range q {
+		q[i] += o[i]
+	}
+	return q
+}
+
+// scale returns the quadric scaled by the given factor
+func (q quadric) scale(f float64) quadric {
+	for i := range q {
+		q[i] *= f
+	}
+	return q
+}
+
+// error returns the error of the given point
+func (q quadric) error(p vec3) float64 {
+	x, y, z := p[0], p[1], p[2]
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+}
+
+// vec3 is a vector of float64 used for the error computations
+type vec3 [3]float64
+
+// sub returns the difference of the vectors
+func (a vec3) sub(b vec3) vec3 {
+	return vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+// dot returns the dot product of the vectors
+func (a vec3) dot(b vec3) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+// cross returns the cross product of the vectors
+func (a vec3) cross(b vec3) vec3 {
+	return vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+// length returns the length of the vector
+func (a vec3) length() float64 {
+	return math.Sqrt(a.dot(a))
+}
+
+// normalize returns the vector scaled to unit length, a zero vector is returned as is
+func (a vec3) normalize() vec3 {
+	l := a.length()
+	if l == 0 {
+		return a
+	}
+	return vec3{a[0] / l, a[1] / l, a[2] / l}
+}
+
+// collapse is a candidate edge collapse merging the vertex remove into the vertex keep
+type collapse struct {
+	cost          float64
+	keep, remove  int
+	keepVersion   int
+	removeVersion int
+}
+
+// collapseQueue is a min-heap of collapses ordered by cost
+type collapseQueue []collapse
+
+// Len implements [heap.Interface]
+func (q collapseQueue) Len() int {
+	return len(q)
+}
+
+// Less implements [heap.Interface]
+func (q collapseQueue) Less(i, j int) bool {
+	return q[i].cost < q[j].cost
+}
+
+// Swap implements [heap.Interface]
+func (q collapseQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+// Push implements [heap.Interface]
+func (q *collapseQueue) Push(x any) {
+	*q = append(*q, x.(collapse))
+}
+
+// Pop implements [heap.Interface]
+func (q *collapseQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// simplifier holds the state of a simplification
+type simplifier struct {
+	positions   []vec3
+	faces       [][3]int
+	faceAlive   []bool
+	vertexFaces [][]int
+	quadrics    []quadric
+	versions    []int
+	alive       []bool
+	triangles   int
+	queue       collapseQueue
+}
+
+// newSimplifier creates a simplifier for the given positions and face indices
+func newSimplifier(positions []cast.Vec3, indices []uint32) *simplifier {
+	s := &simplifier{
+		positions:   make([]vec3, len(positions)),
+		faces:       make([][3]int, len(indices)/3),
+		faceAlive:   make([]bool, len(indices)/3),
+		vertexFaces: make([][]int, len(positions)),
+		quadrics:    make([]quadric, len(positions)),
+		versions:    make([]int, len(positions)),
+		alive:       make([]bool, len(positions)),
+	}
+
+	for i, p := range positions {
+		s.positions[i] = vec3{float64(p.X), float64(p.Y), float64(p.Z)}
+	}
+
+	edges := make(map[[2]int]int)
+	for f := range s.faces {
+		face := [3]int{int(indices[f*3]), int(indices[f*3+1]), int(indices[f*3+2])}
+		s.faces[f] = face
+		if face[0] == face[1] || face[1] == face[2] || face[0] == face[2] {
+			continue
+		}
+
+		s.faceAlive[f] = true
+		s.triangles++
+		for _, v := range face {
+			s.vertexFaces[v] = append(s.vertexFaces[v], f)
+			s.alive[v] = true
+		}
+
+		normal, area := s.faceNormal(face)
+		if area > 0 {
+			q := planeQuadric(normal, s.positions[face[0]], area)
+			for _, v := range face {
+				s.quadrics[v] = s.quadrics[v].add(q)
+			}
+		}
+
+		for i := range 3 {
+			edges[edgeKey(face[i], face[(i+1)%3])]++
+		}
+	}
+
+	// open borders and UV seams are edges used by a single face, they are kept in place by planes
+	// perpendicular to the face along the edge
+	for f, face := range s.faces {
+		if !s.faceAlive[f] {
+			continue
+		}
+
+		normal, _ := s.faceNormal(face)
+		for i := range 3 {
+			a, b := face[i], face[(i+1)%3]
+			if edges[edgeKey(a, b)] != 1 {
+				continue
+			}
+
+			edge := s.positions[b].sub(s.positions[a])
+			q := planeQuadric(edge.cross(normal).normalize(), s.positions[a], boundaryWeight*edge.dot(edge))
+			s.quadrics[a] = s.quadrics[a].add(q)
+			s.quadrics[b] = s.quadrics[b].add(q)
+		}
+	}
+
+	// queue the edges in a fixed order so the result does not depend on the map iteration order
+	keys := slices.SortedFunc(maps.Keys(edges), func(a, b [2]int) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	for _, edge := range keys {
+		s.push(edge[0], edge[1])
+	}
+	return s
+}
+
+// edgeKey returns the key of the undirected edge between the given vertices
+func edgeKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// faceNormal returns the unit normal and the area of the given face
+func (s *simplifier) faceNormal(face [3]int) (vec3, float64) {
+	p0, p1, p2 := s.positions[face[0]], s.positions[face[1]], s.positions[face[2]]
+	n := p1.sub(p0).cross(p2.sub(p0))
+	l := n.length()
+	return n.normalize(), l / 2
+}
+
+// push queues the cheaper collapse of the edge between the given vertices
+func (s *simplifier) push(a, b int) {
+	q := s.quadrics[a].add(s.quadrics[b])
+	keep, remove := a, b
+	cost := q.error(s.positions[a])
+	if c := q.error(s.positions[b]); c < cost {
+		keep, remove, cost = b, a, c
+	}
+
+	heap.Push(&s.queue, collapse{
+		cost:          cost,
+		keep:          keep,
+		remove:        remove,
+		keepVersion:   s.versions[keep],
+		removeVersion: s.versions[remove],
+	})
+}
+
+// simplify collapses edges until the amount of triangles reaches the target
+func (s *simplifier) simplify(targetTriangles int) {
+	for s.triangles > targetTriangles && s.queue.Len() > 0 {
+		c := heap.Pop(&s.queue).(collapse)
+		if !s.alive[c.keep] || !s.alive[c.remove] ||
+			s.versions[c.keep] != c.keepVersion || s.versions[c.remove] != c.removeVersion {
+			continue
+		}
+
+		if s.flips(c.remove, c.keep) {
+			continue
+		}
+		s.collapse(c.remove, c.keep)
+	}
+}
+
+// flips reports whether moving the vertex remove onto the vertex keep flips one of its faces
+func (s *simplifier) flips(remove, keep int) bool {
+	for _, f := range s.vertexFaces[remove] {
+		face := s.faces[f]
+		if !s.faceAlive[f] || slices.Contains(face[:], keep) {
+			continue
+		}
+
+		before, _ := s.faceNormal(face)
+		for i := range face {
+			if face[i] == remove {
+				face[i] = keep
+			}
+		}
+		after, area := s.faceNormal(face)
+		if area == 0 || before.dot(after) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// collapse merges the vertex remove into the vertex keep
+func (s *simplifier) collapse(remove, keep int) {
+	s.alive[remove] = false
+	s.versions[keep]++
+	s.quadrics[keep] = s.quadrics[keep].add(s.quadrics[remove])
+
+	for _, f := range s.vertexFaces[remove] {
+		if !s.faceAlive[f] {
+			continue
+		}
+
+		face := &s.faces[f]
+		if slices.Contains(face[:], keep) {
+			s.faceAlive[f] = false
+			s.triangles--
+			continue
+		}
+
+		for i := range face {
+			if face[i] == remove {
+				face[i] = keep
+			}
+		}
+		s.vertexFaces[keep] = append(s.vertexFaces[keep], f)
+	}
+	s.vertexFaces[remove] = nil
+
+	// drop the dead faces of the kept vertex and requeue its edges
+	s.vertexFaces[keep] = slices.DeleteFunc(s.vertexFaces[keep], func(f int) bool {
+		return !s.faceAlive[f]
+	})
+
+	var neighbors []int
+	for _, f := range s.vertexFaces[keep] {
+		for _, v := range s.faces[f] {
+			if v != keep {
+				neighbors = append(neighbors, v)
+			}
+		}
+	}
+	slices.Sort(neighbors)
+	for _, v := range slices.Compact(neighbors) {
+		s.push(keep, v)
+	}
+}
+
+// compact returns the indices of the vertices used by the remaining faces and the faces indexing into them
+func (s *simplifier) compact() ([]int, []uint32) {
+	remap := make([]int, len(s.positions))
+	for i := range remap {
+		remap[i] = -1
+	}
+
+	var keep []int
+	var faces []uint32
+	for f, face := range s.faces {
+		if !s.faceAlive[f] {
+			continue
+		}
+
+		for _, v := range face {
+			if remap[v] < 0 {
+				remap[v] = len(keep)
+				keep = append(keep, v)
+			}
+			faces = append(faces, uint32(remap[v]))
+		}
+	}
+	return keep, faces
+}
@@ -0,0 +1,84 @@
+package castmeshops
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func TestSimplify(t *testing.T) {
+	r, err := os.Open("../testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	castFile, err := cast.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mesh := cast.AsMesh(castFile.Find(cast.ByType(cast.NodeIdMesh))[0])
+	faces, _ := mesh.Faces()
+	target := len(faces) / 3 / 4
+
+	lod, err := Simplify(mesh, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lodFaces, err := lod.Faces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lodFaces)/3 > target || len(lodFaces)/3 < target/2 {
+		t.Fatalf("got %d triangles for a target of %d", len(lodFaces)/3, target)
+	}
+
+	vertexCount := lod.VertexCount()
+	if vertexCount >= mesh.VertexCount() {
+		t.Fatalf("got %d vertices, original has %d", vertexCount, mesh.VertexCount())
+	}
+	for _, i := range lodFaces {
+		if int(i) >= vertexCount {
+			t.Fatalf("face index %d out of range of %d vertices", i, vertexCount)
+		}
+	}
+
+	mi, _ := cast.GetPropertyValue[byte](lod.CastNode, cast.PropNameMaximumWeightInfluence)
+	for name, stride := range map[cast.CastPropertyName]int{
+		cast.PropNameVertexNormalBuffer:      1,
+		cast.PropNameVertexColorBuffer:       1,
+		"u0":                                 1,
+		"u1":                                 1,
+		cast.PropNameVertexWeightBoneBuffer:  int(*mi),
+		cast.PropNameVertexWeightValueBuffer: int(*mi),
+	} {
+		p, ok := lod.GetProperty(name)
+		if !ok {
+			t.Fatalf("missing property %s", name)
+		}
+		if p.Count() != vertexCount*stride {
+			t.Errorf("property %s holds %d values for %d vertices", name, p.Count(), vertexCount)
+		}
+	}
+
+	if lod.Hash() == mesh.Hash() || lod.GetParentNode() != nil {
+		t.Error("expected a detached copy with a new hash")
+	}
+	original, _ := mesh.Faces()
+	if len(original) != len(faces) {
+		t.Error("original mesh was modified")
+	}
+}
+
+func TestSimplifyInvalidFaces(t *testing.T) {
+	mesh := cast.AsMesh(cast.New().CreateRoot().CreateChild(cast.NodeIdModel).CreateChild(cast.NodeIdMesh))
+	cast.CreateProperty(mesh.CastNode, cast.PropNameVertexPositionBuffer, cast.PropVector3, cast.Vec3{}, cast.Vec3{X: 1}, cast.Vec3{Y: 1})
+	cast.CreateProperty(mesh.CastNode, cast.PropNameFaceBuffer, cast.PropByte, []byte{0, 1, 7}...)
+
+	if _, err := Simplify(mesh, 1); err == nil {
+		t.Fatal("expected error for a face index out of range")
+	}
+}
@@ -0,0 +1,376 @@
+// Package castobj converts the meshes of cast files to and from the Wavefront OBJ format.
+//
+// OBJ only describes static geometry: positions, normals, the first UV layer and triangulated faces.
+// Skeletons, weights, animations and material properties are not converted, materials are referenced by
+// name only. UV coordinates of cast have their origin at the top left, so V is flipped in both directions.
+package castobj
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// ----------------------- //
+//         ENCODE          //
+// ----------------------- //
+
+// Encode writes the meshes of all models of the given [cast.CastFile] to the given [io.Writer] as OBJ,
+// one object per mesh named after the mesh
+func Encode(w io.Writer, f *cast.CastFile) error {
+	bw := bufio.NewWriter(w)
+	e := &encoder{w: bw}
+
+	for i, node := range f.Find(cast.ByType(cast.NodeIdMesh)) {
+		if err := e.encodeMesh(cast.AsMesh(node), i); err != nil {
+			return err
+		}
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+// encoder writes OBJ statements, keeping the first write error
+type encoder struct {
+	w *bufio.Writer
+
+	// vertex counts of the meshes written so far, OBJ indices are global to the file
+	positions, uvs, normals int
+	err                     error
+}
+
+// printf writes a formatted statement
+func (e *encoder) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// encodeMesh writes the given mesh as an object, i is used to name meshes without a name
+func (e *encoder) encodeMesh(mesh *cast.Mesh, i int) error {
+	if err := mesh.CheckIndices(); err != nil {
+		return err
+	}
+
+	positions, err := cast.GetPropertyValues[cast.Vec3](mesh.CastNode, cast.PropNameVertexPositionBuffer)
+	if err != nil {
+		return err
+	}
+	normals, _ := cast.GetPropertyValues[cast.Vec3](mesh.CastNode, cast.PropNameVertexNormalBuffer)
+	uvs, _ := mesh.UVLayer(0)
+	faces, _ := mesh.Faces()
+
+	// buffers not matching the positions are left out rather than producing indices out of range
+	if len(normals) != len(positions) {
+		normals = nil
+	}
+	if len(uvs) != len(positions) {
+		uvs = nil
+	}
+
+	name := cast.GetPropertyValueOr(mesh.CastNode, cast.PropNameName, "")
+	if name == "" {
+		name = fmt.Sprintf("mesh%d", i)
+	}
+	e.printf("o %s\n", name)
+
+	for _, p := range positions {
+		e.printf("v %v %v %v\n", p.X, p.Y, p.Z)
+	}
+	for _, uv := range uvs {
+		e.printf("vt %v %v\n", uv.X, 1-uv.Y)
+	}
+	for _, n := range normals {
+		e.printf("vn %v %v %v\n", n.X, n.Y, n.Z)
+	}
+
+	if material := cast.AsMaterial(mesh.ResolveReference(cast.PropNameMaterial)); material != nil && material.Name() != "" {
+		e.printf("usemtl %s\n", material.Name())
+	}
+
+	for t := 0; t+2 < len(faces); t += 3 {
+		e.printf("f %s %s %s\n", e.corner(faces[t], uvs != nil, normals != nil), e.corner(faces[t+1], uvs != nil, normals != nil), e.corner(faces[t+2], uvs != nil, normals != nil))
+	}
+
+	e.positions += len(positions)
+	if uvs != nil {
+		e.uvs += len(uvs)
+	}
+	if normals != nil {
+		e.normals += len(normals)
+	}
+	return e.err
+}
+
+// corner returns the face corner referencing the given vertex of the current mesh
+func (e *encoder) corner(vertex uint32, uv, normal bool) string {
+	p := strconv.Itoa(e.positions + int(vertex) + 1)
+	switch {
+	case uv && normal:
+		return fmt.Sprintf("%s/%d/%d", p, e.uvs+int(vertex)+1, e.normals+int(vertex)+1)
+	case uv:
+		return fmt.Sprintf("%s/%d", p, e.uvs+int(vertex)+1)
+	case normal:
+		return fmt.Sprintf("%s//%d", p, e.normals+int(vertex)+1)
+	default:
+		return p
+	}
+}
+
+// ----------------------- //
+//         DECODE          //
+// ----------------------- //
+
+// Decode reads OBJ data from the given [io.Reader] into a [cast.CastFile] holding a single model. Every
+// object, group and material change starts a new mesh, as a cast mesh has a single material. Polygons are
+// triangulated as fans and face corners sharing the same position, UV and normal are merged into one vertex.
+// Materials are created by name, material libraries are not read.
+func Decode(r io.Reader) (*cast.CastFile, error) {
+	d := &decoder{
+		materials: make(map[string]*cast.CastNode),
+	}
+
+	f := cast.New()
+	d.model = f.CreateRoot().CreateChild(cast.NodeIdModel)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if err := d.decodeLine(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("castobj: line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := d.flush(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// corner is a face corner, indices into the position, UV and normal lists, -1 if absent
+type corner [3]int
+
+// decoder holds the state of the OBJ data read so far
+type decoder struct {
+	model *cast.CastNode
+
+	positions []cast.Vec3
+	uvs       []cast.Vec2
+	normals   []cast.Vec3
+	materials map[string]*cast.CastNode
+
+	// the mesh being read
+	name     string
+	material string
+	corners  []corner
+}
+
+// decodeLine decodes a single OBJ statement, unsupported statements are ignored
+func (d *decoder) decodeLine(line string) error {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	args := fields[1:]
+	switch fields[0] {
+	case "v":
+		v, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		d.positions = append(d.positions, cast.Vec3{X: v[0], Y: v[1], Z: v[2]})
+	case "vt":
+		v, err := parseFloats(args, 2)
+		if err != nil {
+			return err
+		}
+		d.uvs = append(d.uvs, cast.Vec2{X: v[0], Y: 1 - v[1]})
+	case "vn":
+		v, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		d.normals = append(d.normals, cast.Vec3{X: v[0], Y: v[1], Z: v[2]})
+	case "f":
+		return d.decodeFace(args)
+	case "o", "g":
+		if err := d.flush(); err != nil {
+			return err
+		}
+		d.name = strings.Join(args, " ")
+	case "usemtl":
+		if err := d.flush(); err != nil {
+			return err
+		}
+		d.material = strings.Join(args, " ")
+	}
+	return nil
+}
+
+// decodeFace triangulates the polygon with the given corners as a fan
+func (d *decoder) decodeFace(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("face with %d vertices", len(args))
+	}
+
+	corners := make([]corner, len(args))
+	for i, arg := range args {
+		c, err := d.parseCorner(arg)
+		if err != nil {
+			return err
+		}
+		corners[i] = c
+	}
+
+	for i := 1; i+1 < len(corners); i++ {
+		d.corners = append(d.corners, corners[0], corners[i], corners[i+1])
+	}
+	return nil
+}
+
+// parseCorner parses a face corner of the form v, v/vt, v//vn or v/vt/vn
+func (d *decoder) parseCorner(s string) (corner, error) {
+	c := corner{-1, -1, -1}
+	counts := [3]int{len(d.positions), len(d.uvs), len(d.normals)}
+
+	parts := strings.Split(s, "/")
+	if len(parts) > 3 || parts[0] == "" {
+		return c, fmt.Errorf("invalid face vertex %q", s)
+	}
+
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return c, fmt.Errorf("invalid face vertex %q", s)
+		}
+
+		// negative indices are relative to the end of the list read so far
+		if index < 0 {
+			index += counts[i]
+		} else {
+			index--
+		}
+		if index < 0 || index >= counts[i] {
+			return c, fmt.Errorf("face vertex %q out of range", s)
+		}
+		c[i] = index
+	}
+	return c, nil
+}
+
+// flush creates a mesh from the faces read since the last flush, if there are any
+func (d *decoder) flush() error {
+	if len(d.corners) == 0 {
+		return nil
+	}
+	defer func() {
+		d.corners = d.corners[:0]
+	}()
+
+	var (
+		vertices  = make(map[corner]uint32)
+		positions []cast.Vec3
+		uvs       []cast.Vec2
+		normals   []cast.Vec3
+		faces     = make([]uint32, len(d.corners))
+		hasUV     bool
+		hasNormal bool
+	)
+
+	for i, c := range d.corners {
+		index, ok := vertices[c]
+		if !ok {
+			index = uint32(len(positions))
+			vertices[c] = index
+
+			positions = append(positions, d.positions[c[0]])
+			var uv cast.Vec2
+			if c[1] >= 0 {
+				uv, hasUV = d.uvs[c[1]], true
+			}
+			uvs = append(uvs, uv)
+			var normal cast.Vec3
+			if c[2] >= 0 {
+				normal, hasNormal = d.normals[c[2]], true
+			}
+			normals = append(normals, normal)
+		}
+		faces[i] = index
+	}
+
+	mesh := cast.AsMesh(d.model.CreateChild(cast.NodeIdMesh))
+	if d.name != "" {
+		if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameName, cast.PropString, d.name); err != nil {
+			return err
+		}
+	}
+	if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameVertexPositionBuffer, cast.PropVector3, positions...); err != nil {
+		return err
+	}
+	if hasNormal {
+		if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameVertexNormalBuffer, cast.PropVector3, normals...); err != nil {
+			return err
+		}
+	}
+	if hasUV {
+		if err := mesh.SetUVLayer(0, uvs); err != nil {
+			return err
+		}
+	}
+	if err := mesh.SetFaces(faces...); err != nil {
+		return err
+	}
+
+	if d.material != "" {
+		_, err := cast.CreateProperty(mesh.CastNode, cast.PropNameMaterial, cast.PropInteger64, d.materialNode(d.material).Hash())
+		return err
+	}
+	return nil
+}
+
+// materialNode returns the material with the given name, creating it on first use
+func (d *decoder) materialNode(name string) *cast.CastNode {
+	if m, ok := d.materials[name]; ok {
+		return m
+	}
+
+	m := d.model.CreateChild(cast.NodeIdMaterial)
+	cast.CreateProperty(m, cast.PropNameName, cast.PropString, name)
+	cast.CreateProperty(m, cast.PropNameType, cast.PropString, "pbr")
+	d.materials[name] = m
+	return m
+}
+
+// parseFloats parses the first n of the given fields, further fields such as the optional W component
+// are ignored
+func parseFloats(fields []string, n int) ([]float32, error) {
+	if len(fields) < n {
+		return nil, fmt.Errorf("expected %d values, got %d", n, len(fields))
+	}
+
+	values := make([]float32, n)
+	for i := range values {
+		v, err := strconv.ParseFloat(fields[i], 32)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = float32(v)
+	}
+	return values, nil
+}
@@ -0,0 +1,131 @@
+package castobj
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+	"github.com/mauserzjeh/go-cast/casttest"
+)
+
+// assertEqual fails if the two values are not equal
+func assertEqual[T comparable](t testing.TB, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got: %v != want: %v", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, casttest.NewModel()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `o mesh
+v 0 0 0
+v 1 0 0
+v 0 1 0
+vt 0 1
+vt 1 1
+vt 0 0
+vn 0 0 1
+vn 0 0 1
+vn 0 0 1
+usemtl material
+f 1/1/1 2/2/2 3/3/3
+`
+	assertEqual(t, buf.String(), want)
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := casttest.NewModel()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the skeleton and the weights are not part of OBJ, the geometry and the material name are
+	wantMesh := cast.AsMesh(want.GetNodesOfType(cast.NodeIdMesh)[0])
+	meshes := got.GetNodesOfType(cast.NodeIdMesh)
+	assertEqual(t, len(meshes), 1)
+	gotMesh := cast.AsMesh(meshes[0])
+
+	for _, name := range []cast.CastPropertyName{cast.PropNameVertexPositionBuffer, cast.PropNameVertexNormalBuffer} {
+		if !slices.Equal(cast.MustGetPropertyValues[cast.Vec3](gotMesh.CastNode, name), cast.MustGetPropertyValues[cast.Vec3](wantMesh.CastNode, name)) {
+			t.Errorf("property %s differs", name)
+		}
+	}
+	gotUVs, _ := gotMesh.UVLayer(0)
+	wantUVs, _ := wantMesh.UVLayer(0)
+	if !slices.Equal(gotUVs, wantUVs) {
+		t.Errorf("got: %v != want: %v", gotUVs, wantUVs)
+	}
+	gotFaces, _ := gotMesh.Faces()
+	wantFaces, _ := wantMesh.Faces()
+	if !slices.Equal(gotFaces, wantFaces) {
+		t.Errorf("got: %v != want: %v", gotFaces, wantFaces)
+	}
+	assertEqual(t, cast.GetPropertyValueOr(gotMesh.CastNode, cast.PropNameName, ""), "mesh")
+	assertEqual(t, cast.AsMaterial(gotMesh.ResolveReference(cast.PropNameMaterial)).Name(), "material")
+}
+
+func TestDecode(t *testing.T) {
+	data := `# a quad and a triangle sharing an edge
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vn 0 0 1
+o quad
+usemtl a
+f 1/1/1 2/1/1 3/1/1 4/1/1
+usemtl b
+f -4//-1 -2//-1 -1//-1
+`
+	castFile, err := Decode(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meshes := castFile.GetNodesOfType(cast.NodeIdMesh)
+	assertEqual(t, len(meshes), 2)
+
+	quad := cast.AsMesh(meshes[0])
+	faces, _ := quad.Faces()
+	if !slices.Equal(faces, []uint32{0, 1, 2, 0, 2, 3}) {
+		t.Errorf("unexpected faces: %v", faces)
+	}
+	uvs, _ := quad.UVLayer(0)
+	assertEqual(t, uvs[0], cast.Vec2{Y: 1})
+	assertEqual(t, cast.GetPropertyValueOr(quad.CastNode, cast.PropNameName, ""), "quad")
+	assertEqual(t, cast.AsMaterial(quad.ResolveReference(cast.PropNameMaterial)).Name(), "a")
+
+	triangle := cast.AsMesh(meshes[1])
+	assertEqual(t, triangle.VertexCount(), 3)
+	assertEqual(t, triangle.UVLayerCount(), 0)
+	positions, _ := cast.GetPropertyValues[cast.Vec3](triangle.CastNode, cast.PropNameVertexPositionBuffer)
+	assertEqual(t, positions[2], cast.Vec3{Y: 1})
+	assertEqual(t, cast.AsMaterial(triangle.ResolveReference(cast.PropNameMaterial)).Name(), "b")
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, data := range []string{
+		"v 0 0\n",
+		"v 0 0 0\nf 1 1\n",
+		"v 0 0 0\nf 1 1 2\n",
+		"v 0 0 0\nf 1/x 1 1\n",
+	} {
+		if _, err := Decode(strings.NewReader(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
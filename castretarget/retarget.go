@@ -0,0 +1,160 @@
+// Package castretarget transfers animations between skeletons
+package castretarget
+
+import (
+	"fmt"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// Options configures [Retarget]
+type Options struct {
+	// Rename maps the names of source bones to the names of target bones, bones missing from the map keep
+	// their name
+	Rename map[string]string
+}
+
+// Retarget returns a copy of the animation with its curves mapped from the bones of the source skeleton to
+// the bones of the target skeleton with the same name, or the name given by the rename table. Curves of bones
+// missing from either skeleton are dropped.
+//
+// Absolute curves are adjusted for the differing bind poses: rotations are applied relative to the bind rotation
+// of the target bone, translations are offset and scales are multiplied by the difference between the bind poses.
+// Additive and relative curves are copied as they are. The copy is assigned fresh hashes and does not belong to
+// a file until it is attached with [cast.CastNode.MoveTo].
+func Retarget(animation *cast.Animation, source, target *cast.CastNode, opts Options) (*cast.Animation, error) {
+	retargeted := cast.AsAnimation(animation.Clone(false))
+
+	for _, curve := range retargeted.Curves() {
+		name := curve.NodeName()
+		targetName := name
+		if renamed, ok := opts.Rename[name]; ok {
+			targetName = renamed
+		}
+
+		sourceBone, targetBone := findBone(source, name), findBone(target, targetName)
+		if sourceBone == nil || targetBone == nil {
+			curve.Remove()
+			continue
+		}
+
+		if _, err := cast.CreateProperty(curve.CastNode, cast.PropNameNodeName, cast.PropString, targetName); err != nil {
+			return nil, err
+		}
+
+		mode, err := curve.Mode()
+		if err != nil {
+			return nil, err
+		}
+		if mode != cast.CurveModeAbsolute {
+			continue
+		}
+
+		if err := adjustCurve(curve, newBindPose(sourceBone), newBindPose(targetBone)); err != nil {
+			return nil, fmt.Errorf("castretarget: curve %s.%s: %w", name, curve.KeyProperty(), err)
+		}
+	}
+	return retargeted, nil
+}
+
+// findBone returns the bone of the skeleton with the given name, nil if there is none
+func findBone(skeleton *cast.CastNode, name string) *cast.CastNode {
+	bones := skeleton.Find(cast.AllOf(cast.ByType(cast.NodeIdBone), cast.ByName(name)))
+	if len(bones) == 0 {
+		return nil
+	}
+	return bones[0]
+}
+
+// bindPose holds the local bind transform of a bone
+type bindPose struct {
+	position cast.Vec3
+	rotation cast.Vec4
+	scale    cast.Vec3
+}
+
+// newBindPose returns the local bind transform of the bone, missing properties default to the identity
+func newBindPose(bone *cast.CastNode) bindPose {
+	pose := bindPose{
+		rotation: cast.Vec4{W: 1},
+		scale:    cast.Vec3{X: 1, Y: 1, Z: 1},
+	}
+	if lp, err := cast.GetPropertyValue[cast.Vec3](bone, cast.PropNameLocalPosition); err == nil {
+		pose.position = *lp
+	}
+	if lr, err := cast.GetPropertyValue[cast.Vec4](bone, cast.PropNameLocalRotation); err == nil {
+		pose.rotation = *lr
+	}
+	if s, err := cast.GetPropertyValue[cast.Vec3](bone, cast.PropNameScale); err == nil {
+		pose.scale = *s
+	}
+	return pose
+}
+
+// adjustCurve maps the absolute values of the curve from the source bind pose to the target bind pose
+func adjustCurve(curve *cast.Curve, source, target bindPose) error {
+	kp := curve.KeyProperty()
+	switch kp {
+	case cast.KeyPropertyRotation:
+		values, err := cast.GetPropertyValues[cast.Vec4](curve.CastNode, cast.PropNameKeyValueBuffer)
+		if err != nil {
+			return err
+		}
+		inverse := cast.Quat(source.rotation).Conjugate()
+		for i, q := range values {
+			values[i] = cast.Vec4(cast.Quat(target.rotation).Mul(inverse.Mul(cast.Quat(q))))
+		}
+		return nil
+	case cast.KeyPropertyTranslationX, cast.KeyPropertyTranslationY, cast.KeyPropertyTranslationZ:
+		offset := component(target.position, kp[1]) - component(source.position, kp[1])
+		return mapValues(curve, func(v float64) float64 {
+			return v + float64(offset)
+		})
+	case cast.KeyPropertyScaleX, cast.KeyPropertyScaleY, cast.KeyPropertyScaleZ:
+		from, to := component(source.scale, kp[1]), component(target.scale, kp[1])
+		if from == 0 {
+			return nil
+		}
+		return mapValues(curve, func(v float64) float64 {
+			return v * float64(to) / float64(from)
+		})
+	default:
+		return nil
+	}
+}
+
+// mapValues replaces the scalar keyframe values of the curve with the result of fn
+func mapValues(curve *cast.Curve, fn func(v float64) float64) error {
+	p, ok := curve.GetProperty(cast.PropNameKeyValueBuffer)
+	if !ok {
+		return fmt.Errorf(`property %s not found`, cast.PropNameKeyValueBuffer)
+	}
+
+	switch p := p.(type) {
+	case *cast.CastProperty[float32]:
+		values := p.GetValues()
+		for i, v := range values {
+			values[i] = float32(fn(float64(v)))
+		}
+	case *cast.CastProperty[float64]:
+		values := p.GetValues()
+		for i, v := range values {
+			values[i] = fn(v)
+		}
+	default:
+		return fmt.Errorf("key value buffer has a type of %T", p)
+	}
+	return nil
+}
+
+// component returns the component of the vector with the given axis name ('x', 'y' or 'z')
+func component(v cast.Vec3, axis byte) float32 {
+	switch axis {
+	case 'x':
+		return v.X
+	case 'y':
+		return v.Y
+	default:
+		return v.Z
+	}
+}
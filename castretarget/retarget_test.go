@@ -0,0 +1,71 @@
+package castretarget
+
+import (
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// createSkeleton creates a skeleton with a bone of each given name at the given local position and rotation
+func createSkeleton(names []string, position cast.Vec3, rotation cast.Vec4) *cast.CastNode {
+	skeleton := cast.New().CreateRoot().CreateChild(cast.NodeIdModel).CreateChild(cast.NodeIdSkeleton)
+	for _, name := range names {
+		bone := skeleton.CreateChild(cast.NodeIdBone)
+		cast.CreateProperty(bone, cast.PropNameName, cast.PropString, name)
+		cast.CreateProperty(bone, cast.PropNameLocalPosition, cast.PropVector3, position)
+		cast.CreateProperty(bone, cast.PropNameLocalRotation, cast.PropVector4, rotation)
+	}
+	return skeleton
+}
+
+// createCurve creates a curve animating the given property of the node
+func createCurve[T cast.CastPropertyValueType](animation *cast.CastNode, nodeName, keyProperty string, id cast.CastPropertyId, values ...T) *cast.CastNode {
+	curve := animation.CreateChild(cast.NodeIdCurve)
+	cast.CreateProperty(curve, cast.PropNameNodeName, cast.PropString, nodeName)
+	cast.CreateProperty(curve, cast.PropNameKeyProperty, cast.PropString, keyProperty)
+	cast.CreateProperty(curve, cast.PropNameKeyFrameBuffer, cast.PropByte, make([]byte, len(values))...)
+	cast.CreateProperty(curve, cast.PropNameKeyValueBuffer, id, values...)
+	return curve
+}
+
+func TestRetarget(t *testing.T) {
+	source := createSkeleton([]string{"spine", "head"}, cast.Vec3{Y: 10}, cast.Vec4{W: 1})
+	target := createSkeleton([]string{"Spine1", "head"}, cast.Vec3{Y: 12}, cast.Vec4{Z: 1})
+
+	animation := cast.AsAnimation(cast.New().CreateRoot().CreateChild(cast.NodeIdAnimation))
+	createCurve(animation.CastNode, "spine", cast.KeyPropertyTranslationY, cast.PropFloat, float32(11))
+	createCurve(animation.CastNode, "head", cast.KeyPropertyRotation, cast.PropVector4, cast.Vec4{X: 1})
+	additive := createCurve(animation.CastNode, "head", cast.KeyPropertyTranslationY, cast.PropFloat, float32(1))
+	cast.AsCurve(additive).SetMode(cast.CurveModeAdditive)
+	createCurve(animation.CastNode, "tail", cast.KeyPropertyTranslationY, cast.PropFloat, float32(1))
+
+	retargeted, err := Retarget(animation, source, target, Options{Rename: map[string]string{"spine": "Spine1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	curves := retargeted.Curves()
+	if len(curves) != 3 {
+		t.Fatalf("got %d curves, want 3", len(curves))
+	}
+
+	if curves[0].NodeName() != "Spine1" {
+		t.Errorf("got: %v != want: Spine1", curves[0].NodeName())
+	}
+	if v, _ := curves[0].Evaluate(0); v != 13 {
+		t.Errorf("got: %v != want: 13", v)
+	}
+
+	// the rotation of 180 degrees around X relative to the bind pose is applied on the target bind rotation
+	if q, _ := curves[1].EvaluateRotation(0); q != (cast.Vec4{Y: 1}) {
+		t.Errorf("got: %v != want: %v", q, cast.Vec4{Y: 1})
+	}
+
+	if v, _ := curves[2].Evaluate(0); v != 1 {
+		t.Errorf("additive curve was adjusted to %v", v)
+	}
+
+	if len(animation.Curves()) != 4 {
+		t.Error("source animation was modified")
+	}
+}
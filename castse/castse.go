@@ -0,0 +1,250 @@
+// Package castse converts cast files to and from the SEModel and SEAnim formats of SETools.
+//
+// SEModel holds a single model with its skeleton, meshes and simple materials referencing a diffuse, normal
+// and specular map. SEAnim holds a single skeletal animation with per bone translation, rotation and scale
+// keys and named notes.
+package castse
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// maxPrealloc bounds the capacity allocated up front for counts read from the data, so corrupt counts fail
+// at the end of the data instead of exhausting memory
+const maxPrealloc = 1 << 16
+
+// writer writes little endian values, keeping the first write error
+type writer struct {
+	w   *bufio.Writer
+	buf [8]byte
+	err error
+}
+
+// newWriter returns a writer buffering the writes to the given [io.Writer]
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w)}
+}
+
+// write writes the first n bytes of the buffer
+func (w *writer) write(n int) {
+	if w.err == nil {
+		_, w.err = w.w.Write(w.buf[:n])
+	}
+}
+
+// u8 writes a byte
+func (w *writer) u8(v byte) {
+	w.buf[0] = v
+	w.write(1)
+}
+
+// u16 writes an unsigned short
+func (w *writer) u16(v uint16) {
+	binary.LittleEndian.PutUint16(w.buf[:], v)
+	w.write(2)
+}
+
+// u32 writes an unsigned integer
+func (w *writer) u32(v uint32) {
+	binary.LittleEndian.PutUint32(w.buf[:], v)
+	w.write(4)
+}
+
+// f32 writes a float
+func (w *writer) f32(v float32) {
+	w.u32(math.Float32bits(v))
+}
+
+// vec2 writes the components of a Vector2
+func (w *writer) vec2(v cast.Vec2) {
+	w.f32(v.X)
+	w.f32(v.Y)
+}
+
+// vec3 writes the components of a Vector3
+func (w *writer) vec3(v cast.Vec3) {
+	w.f32(v.X)
+	w.f32(v.Y)
+	w.f32(v.Z)
+}
+
+// vec4 writes the components of a Vector4, quaternions in the order X, Y, Z, W
+func (w *writer) vec4(v cast.Vec4) {
+	w.f32(v.X)
+	w.f32(v.Y)
+	w.f32(v.Z)
+	w.f32(v.W)
+}
+
+// bytes writes the given bytes as they are
+func (w *writer) bytes(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+// str writes a null terminated string
+func (w *writer) str(s string) {
+	w.bytes([]byte(s))
+	w.u8(0)
+}
+
+// index writes an index with the width used for the given number of elements: a byte for up to 0xFF
+// elements, a short for up to 0xFFFF elements and an integer otherwise
+func (w *writer) index(v uint32, count int) {
+	switch {
+	case count <= math.MaxUint8:
+		w.u8(byte(v))
+	case count <= math.MaxUint16:
+		w.u16(uint16(v))
+	default:
+		w.u32(v)
+	}
+}
+
+// flush flushes the buffered writes and returns the first error
+func (w *writer) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// reader reads little endian values, keeping the first read error. Values read after an error are zero.
+type reader struct {
+	r   *bufio.Reader
+	buf [8]byte
+	err error
+}
+
+// newReader returns a reader buffering the reads from the given [io.Reader]
+func newReader(r io.Reader) *reader {
+	return &reader{r: bufio.NewReader(r)}
+}
+
+// read reads n bytes into the buffer
+func (r *reader) read(n int) []byte {
+	if r.err != nil {
+		clear(r.buf[:])
+		return r.buf[:n]
+	}
+	if _, err := io.ReadFull(r.r, r.buf[:n]); err != nil {
+		r.err = fmt.Errorf("unexpected end of data: %w", err)
+		clear(r.buf[:])
+	}
+	return r.buf[:n]
+}
+
+// u8 reads a byte
+func (r *reader) u8() byte {
+	return r.read(1)[0]
+}
+
+// u16 reads an unsigned short
+func (r *reader) u16() uint16 {
+	return binary.LittleEndian.Uint16(r.read(2))
+}
+
+// u32 reads an unsigned integer
+func (r *reader) u32() uint32 {
+	return binary.LittleEndian.Uint32(r.read(4))
+}
+
+// f32 reads a float
+func (r *reader) f32() float32 {
+	return math.Float32frombits(r.u32())
+}
+
+// f64 reads a double
+func (r *reader) f64() float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(r.read(8)))
+}
+
+// vec2 reads a Vector2
+func (r *reader) vec2() cast.Vec2 {
+	return cast.Vec2{X: r.f32(), Y: r.f32()}
+}
+
+// vec3 reads a Vector3
+func (r *reader) vec3() cast.Vec3 {
+	return cast.Vec3{X: r.f32(), Y: r.f32(), Z: r.f32()}
+}
+
+// vec4 reads a Vector4, quaternions in the order X, Y, Z, W
+func (r *reader) vec4() cast.Vec4 {
+	return cast.Vec4{X: r.f32(), Y: r.f32(), Z: r.f32(), W: r.f32()}
+}
+
+// count reads a count stored as an unsigned integer, counts that do not fit into an int32 are an error
+func (r *reader) count() int {
+	v := r.u32()
+	if v > math.MaxInt32 {
+		r.err = fmt.Errorf("count %d out of range", v)
+		return 0
+	}
+	return int(v)
+}
+
+// skip discards n bytes
+func (r *reader) skip(n int) {
+	for range n {
+		r.u8()
+	}
+}
+
+// str reads a null terminated string
+func (r *reader) str() string {
+	if r.err != nil {
+		return ""
+	}
+	s, err := r.r.ReadString(0)
+	if err != nil {
+		r.err = fmt.Errorf("unexpected end of data: %w", err)
+		return ""
+	}
+	return s[:len(s)-1]
+}
+
+// index reads an index with the width used for the given number of elements, see [writer.index]
+func (r *reader) index(count int) uint32 {
+	switch {
+	case count <= math.MaxUint8:
+		return uint32(r.u8())
+	case count <= math.MaxUint16:
+		return uint32(r.u16())
+	default:
+		return r.u32()
+	}
+}
+
+// magic reads the magic and the version of a file and checks them
+func (r *reader) magic(magic string) error {
+	b := make([]byte, len(magic))
+	if _, err := io.ReadFull(r.r, b); err != nil || string(b) != magic {
+		return fmt.Errorf("castse: not a %s file", magic)
+	}
+	if version := r.u16(); r.err == nil && version != 1 {
+		return fmt.Errorf("castse: unsupported %s version %d", magic, version)
+	}
+	return r.err
+}
+
+// firstOfType returns the first node of the given type in the file
+func firstOfType(f *cast.CastFile, id cast.CastNodeId) (*cast.CastNode, error) {
+	nodes := f.Find(cast.ByType(id))
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("castse: file has no %s node", id)
+	}
+	return nodes[0], nil
+}
+
+// name returns the name of the node
+func name(n *cast.CastNode) string {
+	return cast.GetPropertyValueOr(n, cast.PropNameName, "")
+}
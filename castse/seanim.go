@@ -0,0 +1,474 @@
+package castse
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// ----------------------- //
+//         SEANIM          //
+// ----------------------- //
+
+// animation types of a SEAnim file
+const (
+	animTypeAbsolute = 0
+	animTypeAdditive = 1
+	animTypeRelative = 2
+	animTypeDelta    = 3
+)
+
+// flags of a SEAnim file
+const (
+	animLooped = 1 << 0
+
+	animPresenceTranslation = 1 << 0
+	animPresenceRotation    = 1 << 1
+	animPresenceScale       = 1 << 2
+	animPresenceNotes       = 1 << 6
+
+	animHighPrecision = 1 << 0
+)
+
+// animHeaderSize is the size of the SEAnim header following the magic and the version
+const animHeaderSize = 0x1C
+
+// animTypes maps the curve modes to the animation types
+var animTypes = map[cast.CurveMode]byte{
+	cast.CurveModeAbsolute: animTypeAbsolute,
+	cast.CurveModeAdditive: animTypeAdditive,
+	cast.CurveModeRelative: animTypeRelative,
+}
+
+// curveModes maps the animation types to the curve modes, delta animations move the root relative to its
+// rest position
+var curveModes = map[byte]cast.CurveMode{
+	animTypeAbsolute: cast.CurveModeAbsolute,
+	animTypeAdditive: cast.CurveModeAdditive,
+	animTypeRelative: cast.CurveModeRelative,
+	animTypeDelta:    cast.CurveModeRelative,
+}
+
+// translationProperties and scaleProperties hold the key properties of the components of a Vector3 key
+var (
+	translationProperties = [3]string{cast.KeyPropertyTranslationX, cast.KeyPropertyTranslationY, cast.KeyPropertyTranslationZ}
+	scaleProperties       = [3]string{cast.KeyPropertyScaleX, cast.KeyPropertyScaleY, cast.KeyPropertyScaleZ}
+)
+
+// animBone holds the curves animating a bone
+type animBone struct {
+	name        string
+	mode        cast.CurveMode
+	translation [3]*cast.Curve
+	rotation    *cast.Curve
+	scale       [3]*cast.Curve
+}
+
+// key is a translation, rotation or scale key
+type key[T cast.Vec3 | cast.Vec4] struct {
+	frame uint32
+	value T
+}
+
+// EncodeAnim writes the first animation of the given [cast.CastFile] to the given [io.Writer] as SEAnim.
+// The animation type is the mode of the first curve, bones animated in another mode are written with a
+// modifier. SEAnim keys hold all three components of a translation or scale, so the component curves of a
+// bone are evaluated at the union of their keyframes, missing components are 0 for translations and 1 for
+// scales. Visibility curves are not converted. The keyframes of the notification tracks become notes named
+// after the track.
+func EncodeAnim(w io.Writer, f *cast.CastFile) error {
+	node, err := firstOfType(f, cast.NodeIdAnimation)
+	if err != nil {
+		return err
+	}
+	animation := cast.AsAnimation(node)
+
+	var bones []*animBone
+	indices := make(map[string]int)
+	for _, curve := range animation.Curves() {
+		i, ok := indices[curve.NodeName()]
+		if !ok {
+			mode, err := curve.Mode()
+			if err != nil {
+				return err
+			}
+			i = len(bones)
+			indices[curve.NodeName()] = i
+			bones = append(bones, &animBone{name: curve.NodeName(), mode: mode})
+		}
+
+		bone := bones[i]
+		switch kp := curve.KeyProperty(); kp {
+		case cast.KeyPropertyRotation:
+			bone.rotation = curve
+		default:
+			if c := slices.Index(translationProperties[:], kp); c >= 0 {
+				bone.translation[c] = curve
+			} else if c := slices.Index(scaleProperties[:], kp); c >= 0 {
+				bone.scale[c] = curve
+			}
+		}
+	}
+
+	animType := byte(animTypeAbsolute)
+	if len(bones) > 0 {
+		animType = animTypes[bones[0].mode]
+	}
+
+	type note struct {
+		frame uint32
+		name  string
+	}
+	var notes []note
+	for _, track := range animation.GetChildrenOfType(cast.NodeIdNotificationTrack) {
+		frames, err := cast.GetPropertyValuesAsUint32(track, cast.PropNameKeyFrameBuffer)
+		if err != nil {
+			continue
+		}
+		for _, frame := range frames {
+			notes = append(notes, note{frame, name(track)})
+		}
+	}
+
+	translations := make([][]key[cast.Vec3], len(bones))
+	rotations := make([][]key[cast.Vec4], len(bones))
+	scales := make([][]key[cast.Vec3], len(bones))
+	var presence, modifiers byte
+	for i, bone := range bones {
+		if translations[i], err = vectorKeys(bone.translation, 0); err != nil {
+			return fmt.Errorf("castse: translation of %q: %w", bone.name, err)
+		}
+		if rotations[i], err = rotationKeys(bone.rotation); err != nil {
+			return fmt.Errorf("castse: rotation of %q: %w", bone.name, err)
+		}
+		if scales[i], err = vectorKeys(bone.scale, 1); err != nil {
+			return fmt.Errorf("castse: scale of %q: %w", bone.name, err)
+		}
+
+		if len(translations[i]) > 0 {
+			presence |= animPresenceTranslation
+		}
+		if len(rotations[i]) > 0 {
+			presence |= animPresenceRotation
+		}
+		if len(scales[i]) > 0 {
+			presence |= animPresenceScale
+		}
+		if animTypes[bone.mode] != animType {
+			if modifiers == math.MaxUint8 {
+				return fmt.Errorf("castse: more than %d bones with a modifier", math.MaxUint8)
+			}
+			modifiers++
+		}
+	}
+	if len(notes) > 0 {
+		presence |= animPresenceNotes
+	}
+
+	_, end := animation.FrameRange()
+	frameCount := int(min(end, math.MaxInt32-1)) + 1
+
+	var flags byte
+	if cast.GetPropertyValueOr(animation.CastNode, cast.PropNameLoop, byte(0)) != 0 {
+		flags |= animLooped
+	}
+
+	bw := newWriter(w)
+	bw.bytes([]byte("SEAnim"))
+	bw.u16(1)
+	bw.u16(animHeaderSize)
+	bw.u8(animType)
+	bw.u8(flags)
+	bw.u8(presence)
+	bw.u8(0)
+	bw.bytes([]byte{0, 0})
+	bw.f32(animation.Framerate())
+	bw.u32(uint32(frameCount))
+	bw.u32(uint32(len(bones)))
+	bw.u8(modifiers)
+	bw.bytes([]byte{0, 0, 0})
+	bw.u32(uint32(len(notes)))
+
+	for _, bone := range bones {
+		bw.str(bone.name)
+	}
+	for i, bone := range bones {
+		if t := animTypes[bone.mode]; t != animType {
+			bw.index(uint32(i), min(len(bones), math.MaxUint16))
+			bw.u8(t)
+		}
+	}
+
+	for i := range bones {
+		bw.u8(0)
+		if presence&animPresenceTranslation != 0 {
+			bw.index(uint32(len(translations[i])), frameCount)
+			for _, k := range translations[i] {
+				bw.index(k.frame, frameCount)
+				bw.vec3(k.value)
+			}
+		}
+		if presence&animPresenceRotation != 0 {
+			bw.index(uint32(len(rotations[i])), frameCount)
+			for _, k := range rotations[i] {
+				bw.index(k.frame, frameCount)
+				bw.vec4(k.value)
+			}
+		}
+		if presence&animPresenceScale != 0 {
+			bw.index(uint32(len(scales[i])), frameCount)
+			for _, k := range scales[i] {
+				bw.index(k.frame, frameCount)
+				bw.vec3(k.value)
+			}
+		}
+	}
+
+	for _, n := range notes {
+		bw.index(n.frame, frameCount)
+		bw.str(n.name)
+	}
+	return bw.flush()
+}
+
+// vectorKeys evaluates the component curves at the union of their keyframes, missing components hold the
+// given default
+func vectorKeys(curves [3]*cast.Curve, def float32) ([]key[cast.Vec3], error) {
+	var frames []uint32
+	for _, curve := range curves {
+		if curve == nil {
+			continue
+		}
+		f, err := curve.KeyFrames()
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, f...)
+	}
+	slices.Sort(frames)
+	frames = slices.Compact(frames)
+
+	keys := make([]key[cast.Vec3], len(frames))
+	for i, frame := range frames {
+		keys[i].frame = frame
+		for c, curve := range curves {
+			v := float64(def)
+			if curve != nil {
+				var err error
+				if v, err = curve.Evaluate(float64(frame)); err != nil {
+					return nil, err
+				}
+			}
+			switch c {
+			case 0:
+				keys[i].value.X = float32(v)
+			case 1:
+				keys[i].value.Y = float32(v)
+			default:
+				keys[i].value.Z = float32(v)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// rotationKeys returns the keys of the rotation curve, none if it is nil
+func rotationKeys(curve *cast.Curve) ([]key[cast.Vec4], error) {
+	if curve == nil {
+		return nil, nil
+	}
+
+	frames, values, err := curve.RotationKeys()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]key[cast.Vec4], len(frames))
+	for i := range keys {
+		keys[i] = key[cast.Vec4]{frames[i], values[i]}
+	}
+	return keys, nil
+}
+
+// DecodeAnim reads a SEAnim file from the given [io.Reader] into a [cast.CastFile] holding a single
+// animation. Every bone gets a curve per animated component, in the mode given by its modifier or the
+// animation type; delta animations become relative curves. Notes with the same name are collected into a
+// notification track.
+func DecodeAnim(r io.Reader) (*cast.CastFile, error) {
+	br := newReader(r)
+	if err := br.magic("SEAnim"); err != nil {
+		return nil, err
+	}
+
+	headerSize := int(br.u16())
+	animType := br.u8()
+	flags := br.u8()
+	presence := br.u8()
+	precision := br.u8()
+	br.skip(2)
+	framerate := br.f32()
+	frameCount := br.count()
+	boneCount := br.count()
+	modifierCount := int(br.u8())
+	br.skip(3)
+	noteCount := br.count()
+	br.skip(headerSize - animHeaderSize)
+	if br.err != nil {
+		return nil, fmt.Errorf("castse: header: %w", br.err)
+	}
+
+	mode, ok := curveModes[animType]
+	if !ok {
+		return nil, fmt.Errorf("castse: invalid animation type %d", animType)
+	}
+
+	vec3 := br.vec3
+	vec4 := br.vec4
+	if precision&animHighPrecision != 0 {
+		vec3 = func() cast.Vec3 {
+			return cast.Vec3{X: float32(br.f64()), Y: float32(br.f64()), Z: float32(br.f64())}
+		}
+		vec4 = func() cast.Vec4 {
+			return cast.Vec4{X: float32(br.f64()), Y: float32(br.f64()), Z: float32(br.f64()), W: float32(br.f64())}
+		}
+	}
+
+	names := make([]string, 0, min(boneCount, maxPrealloc))
+	for range boneCount {
+		names = append(names, br.str())
+		if br.err != nil {
+			return nil, fmt.Errorf("castse: bone names: %w", br.err)
+		}
+	}
+
+	modes := make([]cast.CurveMode, len(names))
+	for i := range modes {
+		modes[i] = mode
+	}
+	for range modifierCount {
+		bone := int(br.index(min(boneCount, math.MaxUint16)))
+		m, ok := curveModes[br.u8()]
+		if br.err != nil {
+			return nil, fmt.Errorf("castse: modifiers: %w", br.err)
+		}
+		if !ok || bone >= len(modes) {
+			return nil, fmt.Errorf("castse: invalid modifier of bone %d", bone)
+		}
+		modes[bone] = m
+	}
+
+	f := cast.New()
+	animation := f.CreateRoot().CreateChild(cast.NodeIdAnimation)
+	cast.CreateProperty(animation, cast.PropNameFramerate, cast.PropFloat, framerate)
+	cast.CreateProperty(animation, cast.PropNameLoop, cast.PropByte, flags&animLooped)
+
+	for i, boneName := range names {
+		br.u8()
+		if presence&animPresenceTranslation != 0 {
+			keys := readKeys(br, frameCount, vec3)
+			if err := createVectorCurves(animation, boneName, translationProperties, modes[i], keys); err != nil {
+				return nil, fmt.Errorf("castse: translation of %q: %w", boneName, err)
+			}
+		}
+		if presence&animPresenceRotation != 0 {
+			keys := readKeys(br, frameCount, vec4)
+			if len(keys) > 0 {
+				frames := make([]uint32, len(keys))
+				values := make([]cast.Vec4, len(keys))
+				for j, k := range keys {
+					frames[j], values[j] = k.frame, k.value
+				}
+				curve := createCurve(animation, boneName, cast.KeyPropertyRotation)
+				if err := curve.SetRotationKeys(frames, values); err != nil {
+					return nil, fmt.Errorf("castse: rotation of %q: %w", boneName, err)
+				}
+				if err := curve.SetMode(modes[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if presence&animPresenceScale != 0 {
+			keys := readKeys(br, frameCount, vec3)
+			if err := createVectorCurves(animation, boneName, scaleProperties, modes[i], keys); err != nil {
+				return nil, fmt.Errorf("castse: scale of %q: %w", boneName, err)
+			}
+		}
+		if br.err != nil {
+			return nil, fmt.Errorf("castse: bone %q: %w", boneName, br.err)
+		}
+	}
+
+	if presence&animPresenceNotes != 0 {
+		var order []string
+		frames := make(map[string][]uint32)
+		for range noteCount {
+			frame := br.index(frameCount)
+			noteName := br.str()
+			if br.err != nil {
+				return nil, fmt.Errorf("castse: notes: %w", br.err)
+			}
+			if _, ok := frames[noteName]; !ok {
+				order = append(order, noteName)
+			}
+			frames[noteName] = append(frames[noteName], frame)
+		}
+
+		for _, noteName := range order {
+			track := animation.CreateChild(cast.NodeIdNotificationTrack)
+			cast.CreateProperty(track, cast.PropNameName, cast.PropString, noteName)
+			cast.CreateProperty(track, cast.PropNameKeyFrameBuffer, cast.PropInteger32, frames[noteName]...)
+		}
+	}
+	return f, nil
+}
+
+// readKeys reads the key count followed by the keys, values are read with the given function
+func readKeys[T cast.Vec3 | cast.Vec4](br *reader, frameCount int, value func() T) []key[T] {
+	count := int(br.index(frameCount))
+	keys := make([]key[T], 0, min(count, maxPrealloc))
+	for range count {
+		frame := br.index(frameCount)
+		keys = append(keys, key[T]{frame, value()})
+		if br.err != nil {
+			return nil
+		}
+	}
+	return keys
+}
+
+// createVectorCurves creates a curve per component of the vector keys with the given key properties
+func createVectorCurves(animation *cast.CastNode, nodeName string, properties [3]string, mode cast.CurveMode, keys []key[cast.Vec3]) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	frames := make([]uint32, len(keys))
+	values := [3][]float64{}
+	for i, k := range keys {
+		frames[i] = k.frame
+		values[0] = append(values[0], float64(k.value.X))
+		values[1] = append(values[1], float64(k.value.Y))
+		values[2] = append(values[2], float64(k.value.Z))
+	}
+
+	for c, kp := range properties {
+		curve := createCurve(animation, nodeName, kp)
+		if err := curve.SetKeys(frames, values[c]); err != nil {
+			return err
+		}
+		if err := curve.SetMode(mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createCurve creates a curve animating the given property of the node
+func createCurve(animation *cast.CastNode, nodeName, keyProperty string) *cast.Curve {
+	curve := cast.AsCurve(animation.CreateChild(cast.NodeIdCurve))
+	cast.CreateProperty(curve.CastNode, cast.PropNameNodeName, cast.PropString, nodeName)
+	cast.CreateProperty(curve.CastNode, cast.PropNameKeyProperty, cast.PropString, keyProperty)
+	return curve
+}
@@ -0,0 +1,109 @@
+package castse
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+	"github.com/mauserzjeh/go-cast/casttest"
+)
+
+// curveOf returns the curve of the animation animating the given property of the node
+func curveOf(t testing.TB, f *cast.CastFile, nodeName, keyProperty string) *cast.Curve {
+	t.Helper()
+	for _, n := range f.GetNodesOfType(cast.NodeIdCurve) {
+		if c := cast.AsCurve(n); c.NodeName() == nodeName && c.KeyProperty() == keyProperty {
+			return c
+		}
+	}
+	t.Fatalf("curve %s.%s not found", nodeName, keyProperty)
+	return nil
+}
+
+func TestAnimRoundTrip(t *testing.T) {
+	want := casttest.NewAnimation(30, 5, "root", "head")
+	animation := want.GetNodesOfType(cast.NodeIdAnimation)[0]
+	cast.CreateProperty(animation, cast.PropNameLoop, cast.PropByte, byte(1))
+	for _, kp := range []string{cast.KeyPropertyRotation, cast.KeyPropertyTranslationY} {
+		curveOf(t, want, "head", kp).SetMode(cast.CurveModeAdditive)
+	}
+	track := animation.CreateChild(cast.NodeIdNotificationTrack)
+	cast.CreateProperty(track, cast.PropNameName, cast.PropString, "footstep")
+	cast.CreateProperty(track, cast.PropNameKeyFrameBuffer, cast.PropByte, byte(1), byte(3))
+
+	var buf bytes.Buffer
+	if err := EncodeAnim(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("SEAnim\x01\x00\x1C\x00")) {
+		t.Errorf("unexpected header: %q", buf.Bytes()[:10])
+	}
+
+	got, err := DecodeAnim(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotAnimation := cast.AsAnimation(got.GetNodesOfType(cast.NodeIdAnimation)[0])
+	assertEqual(t, gotAnimation.Framerate(), float32(30))
+	assertEqual(t, cast.GetPropertyValueOr(gotAnimation.CastNode, cast.PropNameLoop, byte(0)), byte(1))
+	// the translation is written with all three components
+	assertEqual(t, len(gotAnimation.Curves()), 2*4)
+
+	for _, bone := range []string{"root", "head"} {
+		wantFrames, wantValues, _ := curveOf(t, want, bone, cast.KeyPropertyRotation).RotationKeys()
+		gotFrames, gotValues, err := curveOf(t, got, bone, cast.KeyPropertyRotation).RotationKeys()
+		if err != nil || !slices.Equal(gotFrames, wantFrames) || !slices.Equal(gotValues, wantValues) {
+			t.Errorf("rotation of %s: got: %v %v != want: %v %v", bone, gotFrames, gotValues, wantFrames, wantValues)
+		}
+
+		wantFrames, wantKeys, _ := curveOf(t, want, bone, cast.KeyPropertyTranslationY).Keys()
+		gotFrames, gotKeys, err := curveOf(t, got, bone, cast.KeyPropertyTranslationY).Keys()
+		if err != nil || !slices.Equal(gotFrames, wantFrames) || !slices.Equal(gotKeys, wantKeys) {
+			t.Errorf("translation of %s: got: %v %v != want: %v %v", bone, gotFrames, gotKeys, wantFrames, wantKeys)
+		}
+
+		_, x, _ := curveOf(t, got, bone, cast.KeyPropertyTranslationX).Keys()
+		assertEqual(t, x[len(x)-1], 0)
+	}
+
+	rootMode, _ := curveOf(t, got, "root", cast.KeyPropertyTranslationZ).Mode()
+	assertEqual(t, rootMode, cast.CurveModeAbsolute)
+	headMode, _ := curveOf(t, got, "head", cast.KeyPropertyTranslationZ).Mode()
+	assertEqual(t, headMode, cast.CurveModeAdditive)
+
+	tracks := got.GetNodesOfType(cast.NodeIdNotificationTrack)
+	assertEqual(t, len(tracks), 1)
+	frames, _ := cast.GetPropertyValuesAsUint32(tracks[0], cast.PropNameKeyFrameBuffer)
+	assertEqual(t, cast.GetPropertyValueOr(tracks[0], cast.PropNameName, ""), "footstep")
+	if !slices.Equal(frames, []uint32{1, 3}) {
+		t.Errorf("got: %v != want: [1 3]", frames)
+	}
+}
+
+func TestDecodeAnimInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeAnim(&buf, casttest.NewAnimation(30, 5, "root")); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	invalidType := slices.Clone(data)
+	invalidType[10] = 9
+
+	for name, data := range map[string][]byte{
+		"magic":     append([]byte("SEModel"), data[6:]...),
+		"type":      invalidType,
+		"truncated": data[:len(data)-4],
+		"header":    data[:20],
+	} {
+		if _, err := DecodeAnim(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if err := EncodeAnim(&buf, cast.New()); err == nil {
+		t.Error("expected an error for a file without an animation")
+	}
+}
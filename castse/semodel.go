@@ -0,0 +1,495 @@
+package castse
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// ----------------------- //
+//         SEMODEL         //
+// ----------------------- //
+
+// flags of the data present in a SEModel file
+const (
+	modelPresenceBones     = 1 << 0
+	modelPresenceMeshes    = 1 << 1
+	modelPresenceMaterials = 1 << 2
+
+	boneWorldTransforms = 1 << 0
+	boneLocalTransforms = 1 << 1
+	boneScales          = 1 << 2
+
+	meshUVs     = 1 << 0
+	meshNormals = 1 << 1
+	meshColors  = 1 << 2
+	meshWeights = 1 << 3
+)
+
+// modelHeaderSize is the size of the SEModel header following the magic and the version
+const modelHeaderSize = 0x14
+
+// EncodeModel writes the first model of the given [cast.CastFile] to the given [io.Writer] as SEModel. The
+// bones of its first skeleton are written with their local and world transforms, bones without world
+// transforms get them from the bind pose. Every UV layer of a mesh refers to the material of the mesh. The
+// diffuse, normal and specular maps of the materials are written by path, the albedo map stands in for a
+// missing diffuse map.
+func EncodeModel(w io.Writer, f *cast.CastFile) error {
+	node, err := firstOfType(f, cast.NodeIdModel)
+	if err != nil {
+		return err
+	}
+	model := cast.AsModel(node)
+
+	var bones []*cast.Bone
+	var bindPose []cast.Mat4
+	if skeletons := model.Skeletons(); len(skeletons) > 0 {
+		bones = skeletons[0].Bones()
+		bindPose = skeletons[0].BindPoseMatrices()
+	}
+
+	meshes := model.Meshes()
+	materials := model.GetChildrenOfType(cast.NodeIdMaterial)
+	materialIndices := make(map[uint64]int, len(materials))
+	for i, m := range materials {
+		materialIndices[m.Hash()] = i
+	}
+
+	var presence, meshFlags byte
+	if len(bones) > 0 {
+		presence |= modelPresenceBones
+	}
+	if len(meshes) > 0 {
+		presence |= modelPresenceMeshes
+	}
+	if len(materials) > 0 {
+		presence |= modelPresenceMaterials
+	}
+	for _, mesh := range meshes {
+		if mesh.UVLayerCount() > 0 {
+			meshFlags |= meshUVs
+		}
+		if mesh.HasProperty(cast.PropNameVertexNormalBuffer) {
+			meshFlags |= meshNormals
+		}
+		if mesh.HasProperty(cast.PropNameVertexColorBuffer) {
+			meshFlags |= meshColors
+		}
+		if mesh.HasProperty(cast.PropNameVertexWeightValueBuffer) {
+			meshFlags |= meshWeights
+		}
+	}
+
+	bw := newWriter(w)
+	bw.bytes([]byte("SEModel"))
+	bw.u16(1)
+	bw.u16(modelHeaderSize)
+	bw.u8(presence)
+	bw.u8(boneWorldTransforms | boneLocalTransforms | boneScales)
+	bw.u8(meshFlags)
+	bw.u32(uint32(len(bones)))
+	bw.u32(uint32(len(meshes)))
+	bw.u32(uint32(len(materials)))
+	bw.bytes([]byte{0, 0, 0})
+
+	for _, bone := range bones {
+		bw.str(bone.Name())
+	}
+	for i, bone := range bones {
+		position, rotation := bone.WorldPosition(), bone.WorldRotation()
+		if !bone.HasProperty(cast.PropNameWorldPosition) && !bone.HasProperty(cast.PropNameWorldRotation) {
+			position, rotation, _ = bindPose[i].Decompose()
+		}
+
+		bw.u8(0)
+		bw.u32(uint32(int32(bone.ParentIndex())))
+		bw.vec3(position)
+		bw.vec4(cast.Vec4(rotation))
+		bw.vec3(bone.LocalPosition())
+		bw.vec4(cast.Vec4(bone.LocalRotation()))
+		bw.vec3(bone.Scale())
+	}
+
+	for _, mesh := range meshes {
+		material := -1
+		if m := mesh.ResolveReference(cast.PropNameMaterial); m != nil {
+			if i, ok := materialIndices[m.Hash()]; ok {
+				material = i
+			}
+		}
+		if err := encodeMesh(bw, mesh, meshFlags, len(bones), material); err != nil {
+			return fmt.Errorf("castse: mesh %q: %w", name(mesh.CastNode), err)
+		}
+	}
+
+	for _, node := range materials {
+		material := cast.AsMaterial(node)
+		diffuse := material.Slot(cast.PropNameDiffuse)
+		if diffuse == nil {
+			diffuse = material.Slot(cast.PropNameAlbedo)
+		}
+
+		bw.str(material.Name())
+		bw.u8(1)
+		for _, file := range []*cast.File{diffuse, material.Slot(cast.PropNameNormal), material.Slot(cast.PropNameSpecular)} {
+			var path string
+			if file != nil {
+				path = file.Path()
+			}
+			bw.str(path)
+		}
+	}
+
+	return bw.flush()
+}
+
+// encodeMesh writes the given mesh with the buffers selected by the flags, buffers the mesh does not have are
+// written with default values
+func encodeMesh(bw *writer, mesh *cast.Mesh, flags byte, boneCount, material int) error {
+	if err := mesh.CheckIndices(); err != nil {
+		return err
+	}
+
+	positions, err := cast.GetPropertyValues[cast.Vec3](mesh.CastNode, cast.PropNameVertexPositionBuffer)
+	if err != nil {
+		return err
+	}
+	vertexCount := len(positions)
+
+	layers := make([][]cast.Vec2, mesh.UVLayerCount())
+	for i := range layers {
+		if layers[i], err = mesh.UVLayer(i); err != nil {
+			return err
+		}
+		if len(layers[i]) != vertexCount {
+			return fmt.Errorf("UV layer %d holds %d coordinates for %d vertices", i, len(layers[i]), vertexCount)
+		}
+	}
+
+	var normals []cast.Vec3
+	if mesh.HasProperty(cast.PropNameVertexNormalBuffer) {
+		if normals, err = cast.GetPropertyValues[cast.Vec3](mesh.CastNode, cast.PropNameVertexNormalBuffer); err != nil {
+			return err
+		}
+	}
+
+	var colors []cast.Vec4
+	if mesh.HasProperty(cast.PropNameVertexColorBuffer) {
+		if colors, err = mesh.Colors(); err != nil {
+			return err
+		}
+	}
+
+	var weights [][]cast.SkinWeight
+	if mesh.HasProperty(cast.PropNameVertexWeightValueBuffer) {
+		if weights, err = mesh.SkinWeights(); err != nil {
+			return err
+		}
+	}
+	influences := 0
+	if len(weights) > 0 {
+		influences = len(weights[0])
+	}
+
+	faces, err := mesh.Faces()
+	if err != nil && mesh.HasProperty(cast.PropNameFaceBuffer) {
+		return err
+	}
+
+	for _, buffer := range []int{len(normals), len(colors), len(weights)} {
+		if buffer != 0 && buffer != vertexCount {
+			return fmt.Errorf("vertex buffer holds %d values for %d vertices", buffer, vertexCount)
+		}
+	}
+
+	bw.u8(0)
+	bw.u8(byte(len(layers)))
+	bw.u8(byte(influences))
+	bw.u32(uint32(vertexCount))
+	bw.u32(uint32(len(faces) / 3))
+
+	for _, p := range positions {
+		bw.vec3(p)
+	}
+	if flags&meshUVs != 0 {
+		for v := range vertexCount {
+			for _, layer := range layers {
+				bw.vec2(layer[v])
+			}
+		}
+	}
+	if flags&meshNormals != 0 {
+		for v := range vertexCount {
+			var n cast.Vec3
+			if normals != nil {
+				n = normals[v]
+			}
+			bw.vec3(n)
+		}
+	}
+	if flags&meshColors != 0 {
+		for v := range vertexCount {
+			c := cast.Vec4{X: 1, Y: 1, Z: 1, W: 1}
+			if colors != nil {
+				c = colors[v]
+			}
+			bw.u32(cast.PackColor(c))
+		}
+	}
+	if flags&meshWeights != 0 {
+		for _, vertex := range weights {
+			for _, w := range vertex {
+				bw.index(w.Bone, boneCount)
+				bw.f32(w.Weight)
+			}
+		}
+	}
+	for _, index := range faces {
+		bw.index(index, vertexCount)
+	}
+	for range layers {
+		bw.u32(uint32(int32(material)))
+	}
+	return bw.err
+}
+
+// DecodeModel reads a SEModel file from the given [io.Reader] into a [cast.CastFile] holding a single model.
+// The maps of simple materials become file nodes in the diffuse, normal and specular slots. A mesh refers
+// to the material of its first UV layer.
+func DecodeModel(r io.Reader) (*cast.CastFile, error) {
+	br := newReader(r)
+	if err := br.magic("SEModel"); err != nil {
+		return nil, err
+	}
+
+	headerSize := int(br.u16())
+	presence := br.u8()
+	boneFlags := br.u8()
+	meshFlags := br.u8()
+	boneCount := br.count()
+	meshCount := br.count()
+	materialCount := br.count()
+	br.skip(headerSize - 17)
+	if br.err != nil {
+		return nil, fmt.Errorf("castse: header: %w", br.err)
+	}
+	if presence&modelPresenceBones == 0 {
+		boneCount = 0
+	}
+	if presence&modelPresenceMeshes == 0 {
+		meshCount = 0
+	}
+	if presence&modelPresenceMaterials == 0 {
+		materialCount = 0
+	}
+
+	f := cast.New()
+	model := cast.AsModel(f.CreateRoot().CreateChild(cast.NodeIdModel))
+
+	if boneCount > 0 {
+		if err := decodeBones(br, model.CreateChild(cast.NodeIdSkeleton), boneCount, boneFlags); err != nil {
+			return nil, err
+		}
+	}
+
+	type meshData struct {
+		mesh      *cast.Mesh
+		materials []int32
+	}
+	meshes := make([]meshData, 0, min(meshCount, maxPrealloc))
+	for i := range meshCount {
+		mesh := cast.AsMesh(model.CreateChild(cast.NodeIdMesh))
+		materials, err := decodeMesh(br, mesh, meshFlags, boneCount)
+		if err != nil {
+			return nil, fmt.Errorf("castse: mesh %d: %w", i, err)
+		}
+		meshes = append(meshes, meshData{mesh, materials})
+	}
+
+	materials := make([]*cast.CastNode, 0, min(materialCount, maxPrealloc))
+	for i := range materialCount {
+		material, err := decodeMaterial(br, model.CreateChild(cast.NodeIdMaterial))
+		if err != nil {
+			return nil, fmt.Errorf("castse: material %d: %w", i, err)
+		}
+		materials = append(materials, material)
+	}
+
+	for _, m := range meshes {
+		if len(m.materials) == 0 || m.materials[0] < 0 {
+			continue
+		}
+		if int(m.materials[0]) >= len(materials) {
+			return nil, fmt.Errorf("castse: material index %d out of range of %d materials", m.materials[0], len(materials))
+		}
+		if _, err := cast.CreateProperty(m.mesh.CastNode, cast.PropNameMaterial, cast.PropInteger64, materials[m.materials[0]].Hash()); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// decodeBones reads the names and transforms of the bones into the skeleton, the flags select the transforms
+// present in the data
+func decodeBones(br *reader, skeleton *cast.CastNode, count int, flags byte) error {
+	names := make([]string, 0, min(count, maxPrealloc))
+	for range count {
+		names = append(names, br.str())
+		if br.err != nil {
+			return fmt.Errorf("castse: bone names: %w", br.err)
+		}
+	}
+
+	for _, boneName := range names {
+		bone := skeleton.CreateChild(cast.NodeIdBone)
+		cast.CreateProperty(bone, cast.PropNameName, cast.PropString, boneName)
+
+		br.u8()
+		cast.CreateProperty(bone, cast.PropNameParentIndex, cast.PropInteger32, br.u32())
+		if flags&boneWorldTransforms != 0 {
+			cast.CreateProperty(bone, cast.PropNameWorldPosition, cast.PropVector3, br.vec3())
+			cast.CreateProperty(bone, cast.PropNameWorldRotation, cast.PropVector4, br.vec4())
+		}
+		if flags&boneLocalTransforms != 0 {
+			cast.CreateProperty(bone, cast.PropNameLocalPosition, cast.PropVector3, br.vec3())
+			cast.CreateProperty(bone, cast.PropNameLocalRotation, cast.PropVector4, br.vec4())
+		}
+		if flags&boneScales != 0 {
+			cast.CreateProperty(bone, cast.PropNameScale, cast.PropVector3, br.vec3())
+		}
+		if br.err != nil {
+			return fmt.Errorf("castse: bone %q: %w", boneName, br.err)
+		}
+	}
+	return nil
+}
+
+// decodeMesh reads the buffers selected by the flags into the mesh and returns the material indices of its
+// UV layers
+func decodeMesh(br *reader, mesh *cast.Mesh, flags byte, boneCount int) ([]int32, error) {
+	br.u8()
+	layerCount := int(br.u8())
+	influences := int(br.u8())
+	vertexCount := br.count()
+	faceCount := br.count()
+	if br.err != nil {
+		return nil, br.err
+	}
+	capacity := min(vertexCount, maxPrealloc)
+
+	positions := make([]cast.Vec3, 0, capacity)
+	for range vertexCount {
+		positions = append(positions, br.vec3())
+		if br.err != nil {
+			return nil, br.err
+		}
+	}
+	if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameVertexPositionBuffer, cast.PropVector3, positions...); err != nil {
+		return nil, err
+	}
+
+	if flags&meshUVs != 0 && layerCount > 0 {
+		layers := make([][]cast.Vec2, layerCount)
+		for range vertexCount {
+			for l := range layers {
+				layers[l] = append(layers[l], br.vec2())
+			}
+			if br.err != nil {
+				return nil, br.err
+			}
+		}
+		for l, uvs := range layers {
+			if err := mesh.SetUVLayer(l, uvs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if flags&meshNormals != 0 {
+		normals := make([]cast.Vec3, 0, capacity)
+		for range vertexCount {
+			normals = append(normals, br.vec3())
+			if br.err != nil {
+				return nil, br.err
+			}
+		}
+		if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameVertexNormalBuffer, cast.PropVector3, normals...); err != nil {
+			return nil, err
+		}
+	}
+
+	if flags&meshColors != 0 {
+		colors := make([]uint32, 0, capacity)
+		for range vertexCount {
+			colors = append(colors, br.u32())
+			if br.err != nil {
+				return nil, br.err
+			}
+		}
+		if _, err := cast.CreateProperty(mesh.CastNode, cast.PropNameVertexColorBuffer, cast.PropInteger32, colors...); err != nil {
+			return nil, err
+		}
+	}
+
+	if flags&meshWeights != 0 && influences > 0 {
+		weights := make([][]cast.SkinWeight, 0, capacity)
+		for range vertexCount {
+			vertex := make([]cast.SkinWeight, influences)
+			for i := range vertex {
+				vertex[i] = cast.SkinWeight{Bone: br.index(boneCount), Weight: br.f32()}
+			}
+			if br.err != nil {
+				return nil, br.err
+			}
+			weights = append(weights, vertex)
+		}
+		if len(weights) > 0 {
+			if err := mesh.SetSkinWeights(weights); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	faces := make([]uint32, 0, min(faceCount, maxPrealloc)*3)
+	for range faceCount {
+		faces = append(faces, br.index(vertexCount), br.index(vertexCount), br.index(vertexCount))
+		if br.err != nil {
+			return nil, br.err
+		}
+	}
+	if err := mesh.SetFaces(faces...); err != nil {
+		return nil, err
+	}
+
+	materials := make([]int32, layerCount)
+	for i := range materials {
+		materials[i] = int32(br.u32())
+	}
+	return materials, br.err
+}
+
+// decodeMaterial reads a material into the given material node and returns it. The maps of simple materials
+// become file nodes referenced by the diffuse, normal and specular slots.
+func decodeMaterial(br *reader, node *cast.CastNode) (*cast.CastNode, error) {
+	cast.CreateProperty(node, cast.PropNameName, cast.PropString, br.str())
+	cast.CreateProperty(node, cast.PropNameType, cast.PropString, "pbr")
+	if br.u8() == 0 {
+		return node, br.err
+	}
+
+	material := cast.AsMaterial(node)
+	for _, slot := range []cast.CastPropertyName{cast.PropNameDiffuse, cast.PropNameNormal, cast.PropNameSpecular} {
+		path := br.str()
+		if path == "" {
+			continue
+		}
+
+		file := cast.AsFile(node.CreateChild(cast.NodeIdFile))
+		cast.CreateProperty(file.CastNode, cast.PropNamePath, cast.PropString, path)
+		if err := material.SetSlot(slot, file); err != nil {
+			return nil, err
+		}
+	}
+	return node, br.err
+}
@@ -0,0 +1,112 @@
+package castse
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+	"github.com/mauserzjeh/go-cast/casttest"
+)
+
+// assertEqual fails if the two values are not equal
+func assertEqual[T comparable](t testing.TB, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got: %v != want: %v", got, want)
+	}
+}
+
+// assertValues fails if the property with the given name holds different values on the two nodes
+func assertValues[T cast.Vec2 | cast.Vec3](t testing.TB, got, want *cast.CastNode, name cast.CastPropertyName) {
+	t.Helper()
+	a, _ := cast.GetPropertyValues[T](got, name)
+	b, _ := cast.GetPropertyValues[T](want, name)
+	if len(a) == 0 || !slices.Equal(a, b) {
+		t.Errorf("property %s: got: %v != want: %v", name, a, b)
+	}
+}
+
+// assertNumbers fails if the numeric property with the given name holds different values on the two nodes,
+// regardless of their widths
+func assertNumbers(t testing.TB, got, want *cast.CastNode, name cast.CastPropertyName) {
+	t.Helper()
+	a, _ := cast.GetPropertyValuesAsFloat64(got, name)
+	b, _ := cast.GetPropertyValuesAsFloat64(want, name)
+	if len(a) == 0 || !slices.Equal(a, b) {
+		t.Errorf("property %s: got: %v != want: %v", name, a, b)
+	}
+}
+
+func TestModelRoundTrip(t *testing.T) {
+	want := casttest.NewModel("root", "head")
+	wantMesh := cast.AsMesh(want.GetNodesOfType(cast.NodeIdMesh)[0])
+	wantMesh.SetColors([]cast.Vec4{{X: 1, W: 1}, {Y: 1, W: 1}, {Z: 1, W: 1}}, cast.ColorPacked)
+	diffuse := cast.AsFile(want.GetNodesOfType(cast.NodeIdMaterial)[0].CreateChild(cast.NodeIdFile))
+	cast.CreateProperty(diffuse.CastNode, cast.PropNamePath, cast.PropString, "textures/diffuse.png")
+	cast.AsMaterial(diffuse.GetParentNode()).SetSlot(cast.PropNameAlbedo, diffuse)
+
+	var buf bytes.Buffer
+	if err := EncodeModel(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("SEModel\x01\x00\x14\x00")) {
+		t.Errorf("unexpected header: %q", buf.Bytes()[:11])
+	}
+
+	got, err := DecodeModel(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBones := cast.AsSkeleton(want.GetNodesOfType(cast.NodeIdSkeleton)[0]).Bones()
+	gotBones := cast.AsSkeleton(got.GetNodesOfType(cast.NodeIdSkeleton)[0]).Bones()
+	assertEqual(t, len(gotBones), len(wantBones))
+	for i, b := range gotBones {
+		assertEqual(t, b.Name(), wantBones[i].Name())
+		assertEqual(t, b.ParentIndex(), wantBones[i].ParentIndex())
+		assertEqual(t, b.LocalPosition(), wantBones[i].LocalPosition())
+		assertEqual(t, b.LocalRotation(), wantBones[i].LocalRotation())
+		assertEqual(t, b.Scale(), cast.Vec3{X: 1, Y: 1, Z: 1})
+	}
+	// the world transforms come from the bind pose
+	assertEqual(t, gotBones[1].WorldPosition(), cast.Vec3{Y: 1})
+
+	gotMesh := cast.AsMesh(got.GetNodesOfType(cast.NodeIdMesh)[0])
+	assertValues[cast.Vec3](t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameVertexPositionBuffer)
+	assertValues[cast.Vec3](t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameVertexNormalBuffer)
+	assertValues[cast.Vec2](t, gotMesh.CastNode, wantMesh.CastNode, "u0")
+	assertNumbers(t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameVertexColorBuffer)
+	assertNumbers(t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameFaceBuffer)
+	assertNumbers(t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameVertexWeightBoneBuffer)
+	assertNumbers(t, gotMesh.CastNode, wantMesh.CastNode, cast.PropNameVertexWeightValueBuffer)
+	assertEqual(t, gotMesh.UVLayerCount(), 1)
+
+	material := cast.AsMaterial(gotMesh.ResolveReference(cast.PropNameMaterial))
+	assertEqual(t, material.Name(), "material")
+	assertEqual(t, material.Slot(cast.PropNameDiffuse).Path(), "textures/diffuse.png")
+	assertEqual(t, material.Slot(cast.PropNameNormal), nil)
+}
+
+func TestDecodeModelInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeModel(&buf, casttest.NewModel()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	for name, data := range map[string][]byte{
+		"magic":     append([]byte("SEAnim\x00"), data[7:]...),
+		"version":   append([]byte("SEModel\x02"), data[8:]...),
+		"truncated": data[:len(data)-4],
+		"header":    data[:12],
+	} {
+		if _, err := DecodeModel(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if err := EncodeModel(&buf, cast.New()); err == nil {
+		t.Error("expected an error for a file without a model")
+	}
+}
@@ -0,0 +1,98 @@
+package casttest
+
+import "github.com/mauserzjeh/go-cast"
+
+// NewModel returns a file holding a minimal valid model named "model": a skeleton with a chain of bones with
+// the given names, each parented to the previous one and placed one unit above it, a material named
+// "material" and a mesh named "mesh" made of a single triangle with normals, a UV layer and its vertices
+// fully weighted to the first bone. Without names the skeleton holds a single bone named "root".
+func NewModel(bones ...string) *cast.CastFile {
+	if len(bones) == 0 {
+		bones = []string{"root"}
+	}
+
+	castFile := cast.New()
+	model := castFile.CreateRoot().CreateChild(cast.NodeIdModel)
+	cast.CreateProperty(model, cast.PropNameName, cast.PropString, "model")
+
+	skeleton := model.CreateChild(cast.NodeIdSkeleton)
+	for i, name := range bones {
+		bone := skeleton.CreateChild(cast.NodeIdBone)
+		cast.CreateProperty(bone, cast.PropNameName, cast.PropString, name)
+		cast.CreateProperty(bone, cast.PropNameParentIndex, cast.PropInteger32, uint32(i-1))
+		cast.CreateProperty(bone, cast.PropNameLocalPosition, cast.PropVector3, cast.Vec3{Y: min(float32(i), 1)})
+		cast.CreateProperty(bone, cast.PropNameLocalRotation, cast.PropVector4, cast.Vec4{W: 1})
+	}
+
+	material := model.CreateChild(cast.NodeIdMaterial)
+	cast.CreateProperty(material, cast.PropNameName, cast.PropString, "material")
+	cast.CreateProperty(material, cast.PropNameType, cast.PropString, "pbr")
+
+	mesh := cast.AsMesh(model.CreateChild(cast.NodeIdMesh))
+	cast.CreateProperty(mesh.CastNode, cast.PropNameName, cast.PropString, "mesh")
+	cast.CreateProperty(mesh.CastNode, cast.PropNameVertexPositionBuffer, cast.PropVector3, cast.Vec3{}, cast.Vec3{X: 1}, cast.Vec3{Y: 1})
+	cast.CreateProperty(mesh.CastNode, cast.PropNameVertexNormalBuffer, cast.PropVector3, cast.Vec3{Z: 1}, cast.Vec3{Z: 1}, cast.Vec3{Z: 1})
+	mesh.SetUVLayer(0, []cast.Vec2{{}, {X: 1}, {Y: 1}})
+	mesh.SetFaces(0, 1, 2)
+	cast.CreateProperty(mesh.CastNode, cast.PropNameMaximumWeightInfluence, cast.PropByte, byte(1))
+	cast.CreateProperty(mesh.CastNode, cast.PropNameVertexWeightBoneBuffer, cast.PropByte, []byte{0, 0, 0}...)
+	cast.CreateProperty(mesh.CastNode, cast.PropNameVertexWeightValueBuffer, cast.PropFloat, []float32{1, 1, 1}...)
+	cast.CreateProperty(mesh.CastNode, cast.PropNameMaterial, cast.PropInteger64, material.Hash())
+
+	return castFile
+}
+
+// NewAnimation returns a file holding a minimal valid animation named "animation" with the given framerate
+// and number of frames. Each of the given bones is animated by a rotation curve holding the identity and a
+// translation curve along the Y axis moving it one unit per second.
+func NewAnimation(framerate float32, frames int, bones ...string) *cast.CastFile {
+	castFile := cast.New()
+	animation := castFile.CreateRoot().CreateChild(cast.NodeIdAnimation)
+	cast.CreateProperty(animation, cast.PropNameName, cast.PropString, "animation")
+	cast.CreateProperty(animation, cast.PropNameFramerate, cast.PropFloat, framerate)
+	cast.CreateProperty(animation, cast.PropNameLoop, cast.PropByte, byte(0))
+
+	keyFrames := make([]uint32, frames)
+	rotations := make([]cast.Vec4, frames)
+	translations := make([]float32, frames)
+	for i := range frames {
+		keyFrames[i] = uint32(i)
+		rotations[i] = cast.Vec4{W: 1}
+		translations[i] = float32(i) / framerate
+	}
+
+	for _, bone := range bones {
+		createCurve(animation, bone, cast.KeyPropertyRotation, keyFrames, cast.PropVector4, rotations)
+		createCurve(animation, bone, cast.KeyPropertyTranslationY, keyFrames, cast.PropFloat, translations)
+	}
+	return castFile
+}
+
+// createCurve creates a curve animating the given property of the node with the given keyframes and values.
+// The keyframes are stored in the smallest width that holds them.
+func createCurve[T cast.CastPropertyValueType](animation *cast.CastNode, nodeName, keyProperty string, keyFrames []uint32, id cast.CastPropertyId, values []T) {
+	curve := animation.CreateChild(cast.NodeIdCurve)
+	cast.CreateProperty(curve, cast.PropNameNodeName, cast.PropString, nodeName)
+	cast.CreateProperty(curve, cast.PropNameKeyProperty, cast.PropString, keyProperty)
+
+	switch last := len(keyFrames) - 1; {
+	case last <= 0xFF:
+		cast.CreateProperty(curve, cast.PropNameKeyFrameBuffer, cast.PropByte, convert[byte](keyFrames)...)
+	case last <= 0xFFFF:
+		cast.CreateProperty(curve, cast.PropNameKeyFrameBuffer, cast.PropShort, convert[uint16](keyFrames)...)
+	default:
+		cast.CreateProperty(curve, cast.PropNameKeyFrameBuffer, cast.PropInteger32, keyFrames...)
+	}
+
+	cast.CreateProperty(curve, cast.PropNameKeyValueBuffer, id, values...)
+	cast.AsCurve(curve).SetMode(cast.CurveModeAbsolute)
+}
+
+// convert converts the keyframes to a narrower type
+func convert[T byte | uint16](keyFrames []uint32) []T {
+	converted := make([]T, len(keyFrames))
+	for i, k := range keyFrames {
+		converted[i] = T(k)
+	}
+	return converted
+}
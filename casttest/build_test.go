@@ -0,0 +1,48 @@
+package casttest
+
+import (
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func TestNewModel(t *testing.T) {
+	castFile := NewModel("pelvis", "spine", "head")
+	if err := castFile.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	skeleton := cast.AsSkeleton(castFile.GetNodesOfType(cast.NodeIdSkeleton)[0])
+	if len(skeleton.Bones()) != 3 {
+		t.Errorf("got %d bones, want 3", len(skeleton.Bones()))
+	}
+
+	mesh := cast.AsMesh(castFile.GetNodesOfType(cast.NodeIdMesh)[0])
+	if mesh.ResolveReference(cast.PropNameMaterial) != castFile.GetNodesOfType(cast.NodeIdMaterial)[0] {
+		t.Error("mesh does not reference the material")
+	}
+	if faces, err := mesh.Faces(); err != nil || len(faces) != 3 {
+		t.Errorf("got faces %v, %v", faces, err)
+	}
+
+	AssertRoundTrip(t, castFile)
+	AssertRoundTrip(t, NewModel())
+}
+
+func TestNewAnimation(t *testing.T) {
+	castFile := NewAnimation(30, 300, "spine", "head")
+	if err := castFile.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	animation := cast.AsAnimation(castFile.GetNodesOfType(cast.NodeIdAnimation)[0])
+	curves := animation.Curves()
+	if len(curves) != 4 {
+		t.Fatalf("got %d curves, want 4", len(curves))
+	}
+	if v, err := curves[1].Evaluate(299); err != nil || v != float64(float32(299)/30) {
+		t.Errorf("got %v, %v", v, err)
+	}
+
+	AssertRoundTrip(t, castFile)
+}
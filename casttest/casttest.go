@@ -0,0 +1,100 @@
+// Package casttest provides helpers for testing code that produces or consumes cast files: semantic comparison
+// against golden files, builders for minimal valid models and animations and round trip checks.
+package casttest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// update makes [AssertGolden] write the golden files instead of comparing against them
+var update = flag.Bool("casttest.update", false, "update the golden cast files")
+
+// Compare returns the semantic differences between two files. Unlike [cast.Diff], the node hashes of both
+// files are replaced with content based hashes first, see [cast.CastFile.AssignContentHashes], so files built
+// with different hashes compare equal as long as their references point to the same nodes. Nodes are matched
+// by type and name and properties by name, neither depends on their order. The given files are left
+// untouched.
+func Compare(got, want *cast.CastFile, opts cast.DiffOptions) *cast.FileDiff {
+	got, want = got.Clone(), want.Clone()
+	got.AssignContentHashes()
+	want.AssignContentHashes()
+	return cast.Diff(want, got, opts)
+}
+
+// AssertEqual reports an error listing the differences if the files differ semantically, see [Compare]
+func AssertEqual(t testing.TB, got, want *cast.CastFile, opts cast.DiffOptions) {
+	t.Helper()
+	if diff := Compare(got, want, opts); !diff.Equal() {
+		t.Errorf("cast files differ (- want, + got):\n%s", diff)
+	}
+}
+
+// AssertGolden compares the file semantically against the golden file at the given path, see [Compare]. Run
+// the tests with the -casttest.update flag to write the golden file, creating its directory if needed.
+func AssertGolden(t testing.TB, got *cast.CastFile, path string, opts cast.DiffOptions) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := cast.WriteFile(path, got); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := cast.LoadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist, run the tests with -casttest.update to create it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := Compare(got, want, opts); !diff.Equal() {
+		t.Errorf("cast file differs from golden file %s (- want, + got):\n%s", path, diff)
+	}
+}
+
+// AssertRoundTrip writes the file, loads it back and returns the loaded file. It reports an error if the
+// loaded file differs from the given one in any value, hash or header field, or if writing the loaded file
+// does not reproduce the same bytes.
+func AssertRoundTrip(t testing.TB, f *cast.CastFile) *cast.CastFile {
+	t.Helper()
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := cast.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Version() != f.Version() || loaded.Flags() != f.Flags() {
+		t.Errorf("header changed by round trip: version %d, flags %#x, want version %d, flags %#x",
+			loaded.Version(), loaded.Flags(), f.Version(), f.Flags())
+	}
+	if diff := cast.Diff(f, loaded, cast.DiffOptions{}); !diff.Equal() {
+		t.Errorf("cast file changed by round trip (- written, + loaded):\n%s", diff)
+	}
+
+	again, err := loaded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Error("writing the loaded file does not reproduce the written bytes")
+	}
+	return loaded
+}
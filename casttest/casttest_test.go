@@ -0,0 +1,46 @@
+package casttest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func TestCompare(t *testing.T) {
+	want := NewModel("root", "head")
+
+	// the same model with fresh hashes and the material after the mesh
+	got := NewModel("root", "head")
+	got.SetHashGenerator(cast.NewRandomHashGenerator())
+	got.RemapHashes()
+	model := got.GetNodesOfType(cast.NodeIdModel)[0]
+	if err := got.GetNodesOfType(cast.NodeIdMaterial)[0].MoveTo(model); err != nil {
+		t.Fatal(err)
+	}
+	AssertEqual(t, got, want, cast.DiffOptions{})
+
+	mesh := got.GetNodesOfType(cast.NodeIdMesh)[0]
+	positions, _ := cast.GetPropertyValues[cast.Vec3](mesh, cast.PropNameVertexPositionBuffer)
+	positions[1].X += 0.001
+	if !Compare(got, want, cast.DiffOptions{FloatTolerance: 0.01}).Equal() {
+		t.Error("expected files to be equal within the tolerance")
+	}
+
+	diff := Compare(got, want, cast.DiffOptions{})
+	if !strings.Contains(diff.String(), "~ vp: 1 of 3 values differ from index 1") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "model.cast")
+
+	*update = true
+	AssertGolden(t, NewModel("root"), path, cast.DiffOptions{})
+	*update = false
+
+	AssertGolden(t, NewModel("root"), path, cast.DiffOptions{})
+	AssertGolden(t, AssertRoundTrip(t, NewModel("root")), path, cast.DiffOptions{})
+}
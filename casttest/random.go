@@ -0,0 +1,142 @@
+package casttest
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// randomNodeIds holds the node types used by [GenerateRandomFile]
+var randomNodeIds = []cast.CastNodeId{
+	cast.NodeIdModel,
+	cast.NodeIdMesh,
+	cast.NodeIdBlendShape,
+	cast.NodeIdSkeleton,
+	cast.NodeIdBone,
+	cast.NodeIdIKHandle,
+	cast.NodeIdConstraint,
+	cast.NodeIdAnimation,
+	cast.NodeIdCurve,
+	cast.NodeIdNotificationTrack,
+	cast.NodeIdMaterial,
+	cast.NodeIdFile,
+	cast.NodeIdInstance,
+	cast.NodeIdMetadata,
+}
+
+const (
+	randomMaxRoots      = 3  // maximum number of root nodes of a random file
+	randomMaxDepth      = 4  // maximum nesting depth below the roots
+	randomMaxChildren   = 4  // maximum number of children of a node
+	randomMaxProperties = 6  // maximum number of properties of a node
+	randomMaxValues     = 16 // maximum number of values of a property
+)
+
+// GenerateRandomFile returns a structurally valid file with random nodes and properties derived from the seed,
+// the same seed always yields the same file. Nodes have random types, all property types occur with random
+// names and values, Integer64 properties may reference other nodes of the file and files are kept small
+// enough to be used as fuzzing seeds. The contents follow no semantics, e.g. meshes lack position buffers, so
+// the files are meant to exercise code processing arbitrary files, not specific node types.
+func GenerateRandomFile(seed uint64) *cast.CastFile {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9E3779B97F4A7C15))
+	castFile := cast.New()
+
+	var nodes []*cast.CastNode
+	var populate func(n *cast.CastNode, depth int)
+	populate = func(n *cast.CastNode, depth int) {
+		nodes = append(nodes, n)
+		for range rng.IntN(randomMaxProperties + 1) {
+			randomProperty(rng, n, nodes)
+		}
+
+		if depth < randomMaxDepth {
+			for range rng.IntN(randomMaxChildren + 1) {
+				populate(n.CreateChild(randomNodeIds[rng.IntN(len(randomNodeIds))]), depth+1)
+			}
+		}
+	}
+
+	for range 1 + rng.IntN(randomMaxRoots) {
+		populate(castFile.CreateRoot(), 0)
+	}
+	return castFile
+}
+
+// randomProperty creates a property of a random type with a random name and values on the node. Integer64
+// properties reference one of the given nodes half of the time.
+func randomProperty(rng *rand.Rand, n *cast.CastNode, nodes []*cast.CastNode) {
+	name := cast.CastPropertyName(randomString(rng, 1+rng.IntN(3)))
+	count := rng.IntN(randomMaxValues + 1)
+
+	switch rng.IntN(10) {
+	case 0:
+		cast.CreateProperty(n, name, cast.PropByte, randomValues(count, func() byte { return byte(rng.Uint32()) })...)
+	case 1:
+		cast.CreateProperty(n, name, cast.PropShort, randomValues(count, func() uint16 { return uint16(rng.Uint32()) })...)
+	case 2:
+		cast.CreateProperty(n, name, cast.PropInteger32, randomValues(count, rng.Uint32)...)
+	case 3:
+		cast.CreateProperty(n, name, cast.PropInteger64, randomValues(count, func() uint64 {
+			if rng.IntN(2) == 0 {
+				return nodes[rng.IntN(len(nodes))].Hash()
+			}
+			return rng.Uint64()
+		})...)
+	case 4:
+		cast.CreateProperty(n, name, cast.PropFloat, randomValues(count, func() float32 { return randomFloat(rng) })...)
+	case 5:
+		cast.CreateProperty(n, name, cast.PropDouble, randomValues(count, func() float64 { return float64(randomFloat(rng)) })...)
+	case 6:
+		cast.CreateProperty(n, name, cast.PropString, randomString(rng, rng.IntN(24)))
+	case 7:
+		cast.CreateProperty(n, name, cast.PropVector2, randomValues(count, func() cast.Vec2 {
+			return cast.Vec2{X: randomFloat(rng), Y: randomFloat(rng)}
+		})...)
+	case 8:
+		cast.CreateProperty(n, name, cast.PropVector3, randomValues(count, func() cast.Vec3 {
+			return cast.Vec3{X: randomFloat(rng), Y: randomFloat(rng), Z: randomFloat(rng)}
+		})...)
+	default:
+		cast.CreateProperty(n, name, cast.PropVector4, randomValues(count, func() cast.Vec4 {
+			return cast.Vec4{X: randomFloat(rng), Y: randomFloat(rng), Z: randomFloat(rng), W: randomFloat(rng)}
+		})...)
+	}
+}
+
+// randomValues returns count values produced by the given function
+func randomValues[T cast.CastPropertyValueType](count int, value func() T) []T {
+	values := make([]T, count)
+	for i := range values {
+		values[i] = value()
+	}
+	return values
+}
+
+// randomFloat returns a random float in the range [-1000, 1000)
+func randomFloat(rng *rand.Rand) float32 {
+	return (rng.Float32() - 0.5) * 2000
+}
+
+// randomString returns a random string of lowercase letters and digits with the given length
+func randomString(rng *rand.Rand, length int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = alphabet[rng.IntN(len(alphabet))]
+	}
+	return string(b)
+}
+
+// AddRandomSeeds adds the encodings of the random files generated from the seeds 0 to n-1 to the seed corpus
+// of the fuzz target, see [GenerateRandomFile]
+func AddRandomSeeds(f *testing.F, n int) {
+	f.Helper()
+	for seed := range uint64(n) {
+		data, err := GenerateRandomFile(seed).MarshalBinary()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
@@ -0,0 +1,49 @@
+package casttest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func TestGenerateRandomFile(t *testing.T) {
+	a := encode(t, GenerateRandomFile(1))
+	b := encode(t, GenerateRandomFile(1))
+	if !bytes.Equal(a, b) {
+		t.Error("the same seed generated different files")
+	}
+	if bytes.Equal(a, encode(t, GenerateRandomFile(2))) {
+		t.Error("different seeds generated the same file")
+	}
+
+	for seed := range uint64(50) {
+		castFile := GenerateRandomFile(seed)
+		if len(castFile.Roots()) == 0 {
+			t.Fatalf("seed %d: file has no roots", seed)
+		}
+		AssertRoundTrip(t, castFile)
+	}
+}
+
+func FuzzLoad(f *testing.F) {
+	AddRandomSeeds(f, 8)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		castFile, err := cast.Load(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		AssertRoundTrip(t, castFile)
+	})
+}
+
+// encode returns the encoding of the file
+func encode(t testing.TB, f *cast.CastFile) []byte {
+	t.Helper()
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,125 @@
+// Command castconvert converts cast files between the supported formats.
+//
+// Usage:
+//
+//	castconvert [-from format] [-to format] <input> <output>
+//
+// When a format flag is omitted it is inferred from the file extension.
+// Supported formats for both input and output: cast, gltf, json, obj, seanim, semodel.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mauserzjeh/go-cast"
+	"github.com/mauserzjeh/go-cast/castgltf"
+	"github.com/mauserzjeh/go-cast/castjson"
+	"github.com/mauserzjeh/go-cast/castobj"
+	"github.com/mauserzjeh/go-cast/castse"
+)
+
+// readers holds the supported input formats
+var readers = map[string]func(r io.Reader) (*cast.CastFile, error){
+	"cast":    func(r io.Reader) (*cast.CastFile, error) { return cast.Load(r) },
+	"gltf":    castgltf.Decode,
+	"json":    castjson.Decode,
+	"obj":     castobj.Decode,
+	"seanim":  castse.DecodeAnim,
+	"semodel": castse.DecodeModel,
+}
+
+// writers holds the supported output formats
+var writers = map[string]func(w io.Writer, f *cast.CastFile) error{
+	"cast":    func(w io.Writer, f *cast.CastFile) error { return f.Write(w) },
+	"gltf":    castgltf.Encode,
+	"json":    castjson.Encode,
+	"obj":     castobj.Encode,
+	"seanim":  castse.EncodeAnim,
+	"semodel": castse.EncodeModel,
+}
+
+func main() {
+	from := flag.String("from", "", "input format (default: inferred from the input extension)")
+	to := flag.String("to", "", "output format (default: inferred from the output extension)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: castconvert [-from format] [-to format] <input> <output>\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "input formats: %s\n", strings.Join(formats(readers), ", "))
+		fmt.Fprintf(flag.CommandLine.Output(), "output formats: %s\n\n", strings.Join(formats(writers), ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := convert(flag.Arg(0), flag.Arg(1), *from, *to); err != nil {
+		fmt.Fprintf(os.Stderr, "castconvert: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// convert converts the input file into the output file
+func convert(input, output, from, to string) error {
+	if from == "" {
+		from = formatOf(input)
+	}
+	if to == "" {
+		to = formatOf(output)
+	}
+
+	read, ok := readers[from]
+	if !ok {
+		return fmt.Errorf("unsupported input format %q", from)
+	}
+
+	write, ok := writers[to]
+	if !ok {
+		return fmt.Errorf("unsupported output format %q", to)
+	}
+
+	r, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	castFile, err := read(r)
+	if err != nil {
+		return err
+	}
+
+	w, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	if err := write(w, castFile); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// formatOf returns the format name inferred from the extension of the given path
+func formatOf(path string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// formats returns the sorted format names of the given registry
+func formats[T any](registry map[string]T) []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
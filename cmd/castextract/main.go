@@ -0,0 +1,113 @@
+// Command castextract extracts a single node, such as a model, skeleton or animation, from a
+// cast file into a standalone cast file.
+//
+// Usage:
+//
+//	castextract -o <output> [-type tag] [-name name] [-hash hash] [-index n] <input>
+//
+// The node is selected by its four character type tag (e.g. modl, skel, anim), its name and/or
+// its hash. When multiple nodes match, -index selects which one is extracted. Nodes referenced
+// by the extracted node, such as materials and files, are extracted along with it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func main() {
+	output := flag.String("o", "", "output file")
+	typ := flag.String("type", "", "four character type tag of the node (e.g. modl, skel, anim)")
+	name := flag.String("name", "", "name of the node")
+	hash := flag.String("hash", "", "hash of the node")
+	index := flag.Int("index", 0, "index of the node among the matching nodes")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: castextract -o <output> [-type tag] [-name name] [-hash hash] [-index n] <input>\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := extract(flag.Arg(0), *output, *typ, *name, *hash, *index); err != nil {
+		fmt.Fprintf(os.Stderr, "castextract: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// extract extracts the matching node of the input file into the output file
+func extract(input, output, typ, name, hash string, index int) error {
+	match, err := matcher(typ, name, hash)
+	if err != nil {
+		return err
+	}
+
+	castFile, err := cast.LoadFile(input)
+	if err != nil {
+		return err
+	}
+
+	var matches []*cast.CastNode
+	for _, root := range castFile.Roots() {
+		matches = appendMatches(matches, root, match)
+	}
+
+	if index < 0 || index >= len(matches) {
+		return fmt.Errorf("no matching node at index %d (%d matches)", index, len(matches))
+	}
+
+	return cast.WriteFile(output, matches[index].ExtractToFile())
+}
+
+// matcher returns a function reporting whether a node matches the given criteria
+func matcher(typ, name, hash string) (func(n *cast.CastNode) bool, error) {
+	if typ == "" && name == "" && hash == "" {
+		return nil, fmt.Errorf("at least one of -type, -name or -hash is required")
+	}
+
+	if typ != "" && len(typ) != 4 {
+		return nil, fmt.Errorf("invalid type tag %q", typ)
+	}
+
+	var h uint64
+	if hash != "" {
+		var err error
+		if h, err = strconv.ParseUint(hash, 0, 64); err != nil {
+			return nil, fmt.Errorf("invalid hash %q", hash)
+		}
+	}
+
+	return func(n *cast.CastNode) bool {
+		if typ != "" && n.Id().String() != typ {
+			return false
+		}
+		if hash != "" && n.Hash() != h {
+			return false
+		}
+		if name != "" {
+			value, err := cast.GetPropertyValue[string](n, cast.PropNameName)
+			if err != nil || *value != name {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// appendMatches appends the given node and its descendants that match to the given slice
+func appendMatches(matches []*cast.CastNode, n *cast.CastNode, match func(n *cast.CastNode) bool) []*cast.CastNode {
+	if match(n) {
+		matches = append(matches, n)
+	}
+	for _, c := range n.GetChildNodes() {
+		matches = appendMatches(matches, c, match)
+	}
+	return matches
+}
@@ -0,0 +1,56 @@
+// Command castmerge combines the root nodes of multiple cast files into a single file.
+//
+// Usage:
+//
+//	castmerge -o <output> <input> [input...]
+//
+// Hash collisions between the inputs are resolved by assigning fresh hashes to the
+// colliding nodes and updating the references to them. Materials and files that are
+// identical across the inputs are only kept once.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func main() {
+	output := flag.String("o", "", "output file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: castmerge -o <output> <input> [input...]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := merge(*output, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "castmerge: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// merge merges the input files into the output file
+func merge(output string, inputs []string) error {
+	files := make([]*cast.CastFile, len(inputs))
+	for i, input := range inputs {
+		f, err := cast.LoadFile(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		files[i] = f
+	}
+
+	merged, err := cast.MergeFiles(files...)
+	if err != nil {
+		return err
+	}
+
+	return cast.WriteFile(output, merged)
+}
@@ -0,0 +1,150 @@
+// Command castoptimize runs optimization passes over a cast file to reduce its size.
+//
+// Usage:
+//
+//	castoptimize -o <output> [-weld] [-narrow] [-compress] [-epsilon e] [-dedupe] <input>
+//
+// All passes run by default, a pass is disabled by setting its flag to false, e.g. -weld=false.
+//
+//   - weld merges identical vertices of meshes, meshes of blend shapes are skipped
+//   - narrow stores face, weight bone and keyframe buffers with the narrowest type
+//   - compress removes the keyframes of curves that interpolation reproduces within -epsilon
+//   - dedupe removes duplicate materials and files
+//
+// The effect of every pass and the statistics of the file before and after are printed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+// options selects the passes run by [optimize]
+type options struct {
+	weld, narrow, compress, dedupe bool
+	epsilon                        float64
+}
+
+func main() {
+	output := flag.String("o", "", "output file")
+	var opts options
+	flag.BoolVar(&opts.weld, "weld", true, "merge identical vertices of meshes")
+	flag.BoolVar(&opts.narrow, "narrow", true, "store index buffers with the narrowest type")
+	flag.BoolVar(&opts.compress, "compress", true, "remove redundant keyframes of curves")
+	flag.Float64Var(&opts.epsilon, "epsilon", 1e-4, "largest error of the compressed curves, in radians for rotations")
+	flag.BoolVar(&opts.dedupe, "dedupe", true, "remove duplicate materials and files")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: castoptimize -o <output> [-weld] [-narrow] [-compress] [-epsilon e] [-dedupe] <input>\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output == "" || flag.NArg() != 1 || opts.epsilon < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := optimize(os.Stdout, flag.Arg(0), *output, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "castoptimize: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// optimize runs the selected passes over the input file, writes the result into the output file and prints
+// the effect of the passes to the given writer
+func optimize(w io.Writer, input, output string, opts options) error {
+	castFile, err := cast.LoadFile(input)
+	if err != nil {
+		return err
+	}
+	before := castFile.Stats()
+
+	if opts.weld {
+		removed, err := weld(castFile)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "weld: removed %d vertices\n", removed)
+	}
+
+	if opts.narrow {
+		fmt.Fprintf(w, "narrow: narrowed %d buffers\n", castFile.NarrowIndices())
+	}
+
+	if opts.compress {
+		keyframes := castFile.Stats().Keyframes
+		for _, node := range castFile.GetNodesOfType(cast.NodeIdCurve) {
+			curve := cast.AsCurve(node)
+			if err := curve.Compress(opts.epsilon); err != nil {
+				return fmt.Errorf("curve %s.%s: %w", curve.NodeName(), curve.KeyProperty(), err)
+			}
+		}
+		fmt.Fprintf(w, "compress: removed %d keyframes\n", keyframes-castFile.Stats().Keyframes)
+	}
+
+	if opts.dedupe {
+		fmt.Fprintf(w, "dedupe: removed %d nodes\n", castFile.Deduplicate())
+	}
+
+	if err := cast.WriteFile(output, castFile); err != nil {
+		return err
+	}
+	return printStats(w, input, output, before, castFile.Stats())
+}
+
+// weld merges the identical vertices of the meshes that are not part of a blend shape and returns the number of
+// removed vertices
+func weld(castFile *cast.CastFile) (int, error) {
+	shapes := make(map[uint64]bool)
+	for _, node := range castFile.GetNodesOfType(cast.NodeIdBlendShape) {
+		blendShape := cast.AsBlendShape(node)
+		if base := blendShape.BaseShape(); base != nil {
+			shapes[base.Hash()] = true
+		}
+		for _, target := range blendShape.Targets() {
+			shapes[target.Hash] = true
+		}
+	}
+
+	total := 0
+	for _, node := range castFile.GetNodesOfType(cast.NodeIdMesh) {
+		if shapes[node.Hash()] {
+			continue
+		}
+
+		removed, err := cast.AsMesh(node).WeldVertices()
+		if err != nil {
+			return total, fmt.Errorf("mesh %#x: %w", node.Hash(), err)
+		}
+		total += removed
+	}
+	return total, nil
+}
+
+// printStats prints the sizes of the input and output files and the statistics of the file before and after
+// the passes
+func printStats(w io.Writer, input, output string, before, after cast.Stats) error {
+	inputInfo, err := os.Stat(input)
+	if err != nil {
+		return err
+	}
+	outputInfo, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "\t before\t after\t\n")
+	fmt.Fprintf(tw, "bytes\t %d\t %d\t\n", inputInfo.Size(), outputInfo.Size())
+	fmt.Fprintf(tw, "vertices\t %d\t %d\t\n", before.Vertices, after.Vertices)
+	fmt.Fprintf(tw, "triangles\t %d\t %d\t\n", before.Triangles, after.Triangles)
+	fmt.Fprintf(tw, "keyframes\t %d\t %d\t\n", before.Keyframes, after.Keyframes)
+	fmt.Fprintf(tw, "materials\t %d\t %d\t\n", before.Nodes[cast.NodeIdMaterial], after.Nodes[cast.NodeIdMaterial])
+	fmt.Fprintf(tw, "files\t %d\t %d\t\n", before.Nodes[cast.NodeIdFile], after.Nodes[cast.NodeIdFile])
+	return tw.Flush()
+}
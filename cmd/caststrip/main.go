@@ -0,0 +1,64 @@
+// Command caststrip removes selected data from a cast file, e.g. to produce a slimmed runtime
+// variant of an authoring file.
+//
+// Usage:
+//
+//	caststrip -o <output> [-tangents] [-colors] [-uv n] [-notifications] <input>
+//
+// -tangents and -colors remove the vertex tangent and color buffers of meshes, -uv keeps only
+// the first n UV layers of meshes and -notifications removes the notification tracks of
+// animations. The number of removed properties and nodes is printed when done.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mauserzjeh/go-cast"
+)
+
+func main() {
+	output := flag.String("o", "", "output file")
+	tangents := flag.Bool("tangents", false, "remove the vertex tangents of meshes")
+	colors := flag.Bool("colors", false, "remove the vertex colors of meshes")
+	uv := flag.Int("uv", 0, "number of UV layers kept on meshes, 0 keeps all of them")
+	notifications := flag.Bool("notifications", false, "remove the notification tracks of animations")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: caststrip -o <output> [-tangents] [-colors] [-uv n] [-notifications] <input>\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output == "" || flag.NArg() != 1 || *uv < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := cast.StripOptions{
+		Tangents:           *tangents,
+		Colors:             *colors,
+		MaxUVLayers:        *uv,
+		NotificationTracks: *notifications,
+	}
+	if err := strip(flag.Arg(0), *output, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "caststrip: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// strip removes the selected data of the input file and writes the result into the output file
+func strip(input, output string, opts cast.StripOptions) error {
+	castFile, err := cast.LoadFile(input)
+	if err != nil {
+		return err
+	}
+
+	properties, nodes := castFile.Strip(opts)
+	if err := cast.WriteFile(output, castFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("removed %d properties and %d nodes\n", properties, nodes)
+	return nil
+}
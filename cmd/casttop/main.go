@@ -0,0 +1,190 @@
+// Command casttop lists the largest nodes and property buffers of cast files.
+//
+// Usage:
+//
+//	casttop [-n count] [-type types] <input> [input...]
+//
+// Inputs are cast files or directories, which are searched recursively for files with the
+// .cast extension. Nodes are ranked by their encoded size including their descendants, so
+// by default only meshes, skeletons and animations are ranked; -type selects other comma
+// separated node types, given as tags (e.g. modl) or names (e.g. model), and an empty -type
+// ranks all nodes. Properties of all nodes are ranked by their encoded size.
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mauserzjeh/go-cast"
+	"github.com/mauserzjeh/go-cast/internal/cliutil"
+)
+
+func main() {
+	count := flag.Int("n", 10, "number of nodes and properties to list")
+	types := flag.String("type", "mesh,skeleton,animation", "comma separated node types to rank, empty for all")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: casttop [-n count] [-type types] <input> [input...]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || *count <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := top(os.Stdout, flag.Args(), *count, *types); err != nil {
+		fmt.Fprintf(os.Stderr, "casttop: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// item is a ranked node or property
+type item struct {
+	size        int
+	file        string
+	path        string // path is the path of the node, e.g. root[0]/modl[0]/mesh[2]
+	name        string // name is the name property of the node, empty if it has none
+	description string // description describes the property, empty for nodes
+}
+
+// ranking holds the largest items seen so far
+type ranking struct {
+	items []item
+	count int
+}
+
+// add adds the item to the ranking, dropping the smallest items beyond the count
+func (r *ranking) add(i item) {
+	r.items = append(r.items, i)
+	if len(r.items) >= 2*r.count {
+		r.trim()
+	}
+}
+
+// trim sorts the items by decreasing size and drops the ones beyond the count
+func (r *ranking) trim() {
+	slices.SortStableFunc(r.items, func(a, b item) int {
+		return cmp.Compare(b.size, a.size)
+	})
+	r.items = r.items[:min(len(r.items), r.count)]
+}
+
+// top prints the largest nodes and properties of the inputs to the given writer
+func top(w io.Writer, inputs []string, count int, types string) error {
+	var match map[cast.CastNodeId]bool
+	if types != "" {
+		match = make(map[cast.CastNodeId]bool)
+		for _, s := range strings.Split(types, ",") {
+			id, err := cast.ParseCastNodeId(strings.TrimSpace(s))
+			if err != nil {
+				return err
+			}
+			match[id] = true
+		}
+	}
+
+	files, err := castFiles(inputs)
+	if err != nil {
+		return err
+	}
+
+	nodes, properties := &ranking{count: count}, &ranking{count: count}
+	for _, file := range files {
+		castFile, err := cast.LoadFile(file, cast.WithLazyValues())
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		rankNodes(castFile.Roots(), "", func(n *cast.CastNode, path string) {
+			name := cast.GetPropertyValueOr(n, cast.PropNameName, "")
+			if match == nil || match[n.Id()] {
+				nodes.add(item{size: n.Size(), file: file, path: path, name: name})
+			}
+			for _, p := range n.Properties() {
+				properties.add(item{
+					size:        p.Size(),
+					file:        file,
+					path:        path,
+					name:        name,
+					description: fmt.Sprintf("%s (%s x%d)", p.Name(), p.Id(), p.Count()),
+				})
+			}
+		})
+	}
+	nodes.trim()
+	properties.trim()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "largest nodes:\n")
+	printItems(tw, nodes.items, len(files) > 1)
+	fmt.Fprintf(tw, "\nlargest properties:\n")
+	printItems(tw, properties.items, len(files) > 1)
+	return tw.Flush()
+}
+
+// printItems prints the items, one per line, prefixed by their file if there are multiple files
+func printItems(w io.Writer, items []item, withFile bool) {
+	for _, i := range items {
+		line := i.path
+		if withFile {
+			line = i.file + ": " + line
+		}
+		if i.name != "" {
+			line += fmt.Sprintf(" %q", i.name)
+		}
+		if i.description != "" {
+			line += " " + i.description
+		}
+		fmt.Fprintf(w, "%s\t  %s\n", cliutil.FormatSize(int64(i.size)), line)
+	}
+}
+
+// rankNodes calls fn for the given siblings and their descendants along with their paths
+func rankNodes(nodes []*cast.CastNode, prefix string, fn func(n *cast.CastNode, path string)) {
+	counts := make(map[cast.CastNodeId]int)
+	for _, n := range nodes {
+		path := fmt.Sprintf("%s%s[%d]", prefix, n.Id(), counts[n.Id()])
+		counts[n.Id()]++
+
+		fn(n, path)
+		rankNodes(n.GetChildNodes(), path+"/", fn)
+	}
+}
+
+// castFiles returns the given files and the cast files found in the given directories
+func castFiles(inputs []string) ([]string, error) {
+	var files []string
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+
+		err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".cast") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
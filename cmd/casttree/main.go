@@ -0,0 +1,179 @@
+// Command casttree prints the node hierarchy of a cast file as a tree.
+//
+// Usage:
+//
+//	casttree [-depth n] [-type types] [-props] [-ascii] <input>
+//
+// Every node is printed with its type tag, name, hash and encoded size. -depth limits the
+// nesting level of the printed nodes, root nodes are at level 0. -type limits the output to
+// the nodes of the given comma separated types, given as tags (e.g. modl) or names (e.g.
+// model), along with their ancestors. -props lists the properties of every node with their
+// type, number of values and encoded size, the ancestors only printed for -type are listed
+// without their properties.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mauserzjeh/go-cast"
+	"github.com/mauserzjeh/go-cast/internal/cliutil"
+)
+
+func main() {
+	depth := flag.Int("depth", -1, "maximum nesting level of the printed nodes, -1 for no limit")
+	types := flag.String("type", "", "comma separated node types to print (e.g. modl,mesh or model,mesh)")
+	props := flag.Bool("props", false, "list the properties of every node with their sizes")
+	ascii := flag.Bool("ascii", false, "draw the tree with ASCII characters only")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: casttree [-depth n] [-type types] [-props] [-ascii] <input>\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := printTree(os.Stdout, flag.Arg(0), *depth, *types, *props, *ascii); err != nil {
+		fmt.Fprintf(os.Stderr, "casttree: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printTree prints the node hierarchy of the input file to the given writer
+func printTree(w io.Writer, input string, depth int, types string, props, ascii bool) error {
+	p := &printer{maxDepth: depth, props: props}
+	if types != "" {
+		p.types = make(map[cast.CastNodeId]bool)
+		for _, s := range strings.Split(types, ",") {
+			id, err := cast.ParseCastNodeId(strings.TrimSpace(s))
+			if err != nil {
+				return err
+			}
+			p.types[id] = true
+		}
+	}
+
+	p.branch, p.last, p.pipe = "├── ", "└── ", "│   "
+	if ascii {
+		p.branch, p.last, p.pipe = "|-- ", "`-- ", "|   "
+	}
+
+	castFile, err := cast.LoadFile(input, cast.WithLazyValues())
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	p.w = bw
+	for _, root := range castFile.Roots() {
+		if p.visible(root, 0) {
+			p.printNode(root, "", "", 0)
+		}
+	}
+	return bw.Flush()
+}
+
+// printer prints nodes as a tree
+type printer struct {
+	w                  *bufio.Writer
+	maxDepth           int                      // maxDepth is the deepest printed nesting level, -1 for no limit
+	types              map[cast.CastNodeId]bool // types holds the printed node types, nil to print all of them
+	props              bool
+	branch, last, pipe string // the connectors of the tree
+}
+
+// entry is a line below a node, either a property or a child node
+type entry struct {
+	text string
+	node *cast.CastNode
+}
+
+// printNode prints the node at the given nesting level and its entries. The prefix is printed before the lines
+// of the node and its entries, the connector links the node to its parent.
+func (p *printer) printNode(n *cast.CastNode, prefix, connector string, depth int) {
+	fmt.Fprintf(p.w, "%s%s%s\n", prefix, connector, label(n))
+
+	var entries []entry
+	if p.props && p.matches(n) {
+		for _, property := range n.Properties() {
+			entries = append(entries, entry{text: fmt.Sprintf("%s: %s x%d, %s",
+				property.Name(), property.Id(), property.Count(), cliutil.FormatSize(int64(property.Size())))})
+		}
+	}
+
+	var hidden int
+	for _, c := range n.GetChildNodes() {
+		switch {
+		case p.maxDepth >= 0 && depth >= p.maxDepth:
+			if p.matches(c) {
+				hidden++
+			}
+		case p.visible(c, depth+1):
+			entries = append(entries, entry{node: c})
+		}
+	}
+	if hidden > 0 {
+		entries = append(entries, entry{text: fmt.Sprintf("... %d child nodes", hidden)})
+	}
+
+	switch connector {
+	case "":
+	case p.last:
+		prefix += strings.Repeat(" ", len([]rune(p.last)))
+	default:
+		prefix += p.pipe
+	}
+
+	for i, e := range entries {
+		connector := p.branch
+		if i == len(entries)-1 {
+			connector = p.last
+		}
+
+		if e.node != nil {
+			p.printNode(e.node, prefix, connector, depth+1)
+		} else {
+			fmt.Fprintf(p.w, "%s%s%s\n", prefix, connector, e.text)
+		}
+	}
+}
+
+// matches reports whether the node has one of the printed types
+func (p *printer) matches(n *cast.CastNode) bool {
+	return p.types == nil || p.types[n.Id()]
+}
+
+// visible reports whether the node at the given nesting level is printed, because it has one of the printed
+// types or one of its descendants within the depth limit does
+func (p *printer) visible(n *cast.CastNode, depth int) bool {
+	if p.matches(n) {
+		return true
+	}
+	if p.maxDepth >= 0 && depth >= p.maxDepth {
+		return false
+	}
+	for _, c := range n.GetChildNodes() {
+		if p.visible(c, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// label returns the description of the node printed in the tree
+func label(n *cast.CastNode) string {
+	var b strings.Builder
+	b.WriteString(n.Id().String())
+	if name := cast.GetPropertyValueOr(n, cast.PropNameName, ""); name != "" {
+		fmt.Fprintf(&b, " %q", name)
+	}
+	fmt.Fprintf(&b, " %#x, %s", n.Hash(), cliutil.FormatSize(int64(n.Size())))
+	return b.String()
+}
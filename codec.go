@@ -0,0 +1,351 @@
+package cast
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// ----------------------- //
+//          CODEC          //
+// ----------------------- //
+
+const (
+	castHeaderSize         = 16 // size of the encoded castHeader
+	castNodeHeaderSize     = 24 // size of the encoded castNodeHeader
+	castPropertyHeaderSize = 8  // size of the encoded castPropertyHeader
+
+	// codecChunkSize is the size of the buffer used to encode and decode property values in chunks
+	codecChunkSize = 32 << 10
+)
+
+// OverflowError reports a value of a file that does not fit the header field storing it, e.g. a node whose
+// encoding exceeds 4 GiB. It wraps [ErrSizeOverflow].
+type OverflowError struct {
+	Path  string // Path of the node, e.g. "root[0]/modl[0]/mesh[2]"
+	Field string // Field describes the header field that overflows, e.g. "node size"
+	Value int64  // Value is the value that does not fit the field
+	Max   int64  // Max is the largest value the field can hold
+}
+
+// Error returns the error message
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("cast: %s: %s %d exceeds the maximum of %d", e.Path, e.Field, e.Value, e.Max)
+}
+
+// Unwrap returns [ErrSizeOverflow]
+func (e *OverflowError) Unwrap() error {
+	return ErrSizeOverflow
+}
+
+// checkSizes returns an [*OverflowError] for the first value of the file that does not fit its header field
+func (n *CastFile) checkSizes() error {
+	for _, root := range n.rootNodes {
+		if _, err := checkNodeSizes(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNodeSizes returns the encoded size of the node, or an [*OverflowError] for the first value of the node
+// or its descendants that does not fit its header field
+func checkNodeSizes(n *CastNode) (int64, error) {
+	if err := checkPropertySizes(n); err != nil {
+		return 0, err
+	}
+
+	size := int64(castNodeHeaderSize)
+	for _, p := range n.properties {
+		size += int64(p.len())
+	}
+	for _, c := range n.childNodes {
+		s, err := checkNodeSizes(c)
+		if err != nil {
+			return 0, err
+		}
+		size += s
+	}
+
+	if size > math.MaxUint32 {
+		return 0, &OverflowError{Path: nodePath(n), Field: "node size", Value: size, Max: math.MaxUint32}
+	}
+	return size, nil
+}
+
+// checkPropertySizes returns an [*OverflowError] for the first property of the node whose name or number of
+// values does not fit its header field
+func checkPropertySizes(n *CastNode) error {
+	for _, p := range n.properties {
+		if len(p.Name()) > math.MaxUint16 {
+			return &OverflowError{Path: nodePath(n), Field: "property name length", Value: int64(len(p.Name())), Max: math.MaxUint16}
+		}
+		if int64(p.Count()) > math.MaxUint32 {
+			return &OverflowError{Path: nodePath(n), Field: fmt.Sprintf("array length of property %s", p.Name()), Value: int64(p.Count()), Max: math.MaxUint32}
+		}
+	}
+	return nil
+}
+
+// nodePath returns the path of the node within its tree, e.g. "root[0]/modl[0]/mesh[2]". The index of a root
+// node is its index among the roots of its file, 0 if it does not belong to one.
+func nodePath(n *CastNode) string {
+	siblings := func(n *CastNode) []*CastNode {
+		if n.parentNode != nil {
+			return n.parentNode.childNodes
+		}
+		if n.file != nil {
+			return n.file.rootNodes
+		}
+		return nil
+	}
+
+	var path string
+	for ; n != nil; n = n.parentNode {
+		index := 0
+		for _, s := range siblings(n) {
+			if s == n {
+				break
+			}
+			if s.id == n.id {
+				index++
+			}
+		}
+
+		element := fmt.Sprintf("%s[%d]", n.id, index)
+		if path == "" {
+			path = element
+		} else {
+			path = element + "/" + path
+		}
+	}
+	return path
+}
+
+// append appends the encoded header to b
+func (h castHeader) append(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, h.Magic)
+	b = binary.LittleEndian.AppendUint32(b, h.Version)
+	b = binary.LittleEndian.AppendUint32(b, h.RootNodes)
+	return binary.LittleEndian.AppendUint32(b, h.Flags)
+}
+
+// decode decodes the header from b holding at least [castHeaderSize] bytes
+func (h *castHeader) decode(b []byte) {
+	h.Magic = binary.LittleEndian.Uint32(b)
+	h.Version = binary.LittleEndian.Uint32(b[4:])
+	h.RootNodes = binary.LittleEndian.Uint32(b[8:])
+	h.Flags = binary.LittleEndian.Uint32(b[12:])
+}
+
+// append appends the encoded header to b
+func (h castNodeHeader) append(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(h.Id))
+	b = binary.LittleEndian.AppendUint32(b, h.NodeSize)
+	b = binary.LittleEndian.AppendUint64(b, h.NodeHash)
+	b = binary.LittleEndian.AppendUint32(b, h.PropertyCount)
+	return binary.LittleEndian.AppendUint32(b, h.ChildCount)
+}
+
+// decode decodes the header from b holding at least [castNodeHeaderSize] bytes
+func (h *castNodeHeader) decode(b []byte) {
+	h.Id = CastNodeId(binary.LittleEndian.Uint32(b))
+	h.NodeSize = binary.LittleEndian.Uint32(b[4:])
+	h.NodeHash = binary.LittleEndian.Uint64(b[8:])
+	h.PropertyCount = binary.LittleEndian.Uint32(b[16:])
+	h.ChildCount = binary.LittleEndian.Uint32(b[20:])
+}
+
+// append appends the encoded header to b
+func (h castPropertyHeader) append(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, uint16(h.Id))
+	b = binary.LittleEndian.AppendUint16(b, h.NameSize)
+	return binary.LittleEndian.AppendUint32(b, h.ArrayLength)
+}
+
+// decode decodes the header from b holding at least [castPropertyHeaderSize] bytes
+func (h *castPropertyHeader) decode(b []byte) {
+	h.Id = CastPropertyId(binary.LittleEndian.Uint16(b))
+	h.NameSize = binary.LittleEndian.Uint16(b[2:])
+	h.ArrayLength = binary.LittleEndian.Uint32(b[4:])
+}
+
+// valueSize returns the encoded size of a single value of type T, 0 for strings
+func valueSize[T CastPropertyValueType]() int {
+	switch any(*new(T)).(type) {
+	case byte:
+		return 1
+	case uint16:
+		return 2
+	case uint32, float32:
+		return 4
+	case uint64, float64, Vec2:
+		return 8
+	case Vec3:
+		return 12
+	case Vec4:
+		return 16
+	default:
+		return 0
+	}
+}
+
+// appendValues appends the little endian encoding of the values to b. Strings are not supported.
+func appendValues[T CastPropertyValueType](b []byte, values []T) []byte {
+	le := binary.LittleEndian
+	switch vs := any(values).(type) {
+	case []byte:
+		b = append(b, vs...)
+	case []uint16:
+		for _, v := range vs {
+			b = le.AppendUint16(b, v)
+		}
+	case []uint32:
+		for _, v := range vs {
+			b = le.AppendUint32(b, v)
+		}
+	case []uint64:
+		for _, v := range vs {
+			b = le.AppendUint64(b, v)
+		}
+	case []float32:
+		for _, v := range vs {
+			b = le.AppendUint32(b, math.Float32bits(v))
+		}
+	case []float64:
+		for _, v := range vs {
+			b = le.AppendUint64(b, math.Float64bits(v))
+		}
+	case []Vec2:
+		for _, v := range vs {
+			b = le.AppendUint32(b, math.Float32bits(v.X))
+			b = le.AppendUint32(b, math.Float32bits(v.Y))
+		}
+	case []Vec3:
+		for _, v := range vs {
+			b = le.AppendUint32(b, math.Float32bits(v.X))
+			b = le.AppendUint32(b, math.Float32bits(v.Y))
+			b = le.AppendUint32(b, math.Float32bits(v.Z))
+		}
+	case []Vec4:
+		for _, v := range vs {
+			b = le.AppendUint32(b, math.Float32bits(v.X))
+			b = le.AppendUint32(b, math.Float32bits(v.Y))
+			b = le.AppendUint32(b, math.Float32bits(v.Z))
+			b = le.AppendUint32(b, math.Float32bits(v.W))
+		}
+	}
+	return b
+}
+
+// decodeValues decodes the values from their little endian encoding in b, which holds exactly as many
+// values. Strings are not supported.
+func decodeValues[T CastPropertyValueType](values []T, b []byte) {
+	le := binary.LittleEndian
+	switch vs := any(values).(type) {
+	case []byte:
+		copy(vs, b)
+	case []uint16:
+		for i := range vs {
+			vs[i] = le.Uint16(b[i*2:])
+		}
+	case []uint32:
+		for i := range vs {
+			vs[i] = le.Uint32(b[i*4:])
+		}
+	case []uint64:
+		for i := range vs {
+			vs[i] = le.Uint64(b[i*8:])
+		}
+	case []float32:
+		for i := range vs {
+			vs[i] = math.Float32frombits(le.Uint32(b[i*4:]))
+		}
+	case []float64:
+		for i := range vs {
+			vs[i] = math.Float64frombits(le.Uint64(b[i*8:]))
+		}
+	case []Vec2:
+		for i := range vs {
+			b := b[i*8:]
+			vs[i] = Vec2{
+				X: math.Float32frombits(le.Uint32(b)),
+				Y: math.Float32frombits(le.Uint32(b[4:])),
+			}
+		}
+	case []Vec3:
+		for i := range vs {
+			b := b[i*12:]
+			vs[i] = Vec3{
+				X: math.Float32frombits(le.Uint32(b)),
+				Y: math.Float32frombits(le.Uint32(b[4:])),
+				Z: math.Float32frombits(le.Uint32(b[8:])),
+			}
+		}
+	case []Vec4:
+		for i := range vs {
+			b := b[i*16:]
+			vs[i] = Vec4{
+				X: math.Float32frombits(le.Uint32(b)),
+				Y: math.Float32frombits(le.Uint32(b[4:])),
+				Z: math.Float32frombits(le.Uint32(b[8:])),
+				W: math.Float32frombits(le.Uint32(b[12:])),
+			}
+		}
+	}
+}
+
+// readValues fills the values from their little endian encoding read from r, in chunks so that large
+// buffers are not staged in memory twice. The chunks are staged in the given buffer, which is allocated if
+// it is smaller than [codecChunkSize]. Strings are not supported.
+func readValues[T CastPropertyValueType](r io.Reader, values []T, buf []byte) error {
+	size := valueSize[T]()
+	chunk := codecChunkSize / size
+	if len(buf) < codecChunkSize {
+		buf = make([]byte, min(len(values), chunk)*size)
+	}
+
+	for len(values) > 0 {
+		n := min(len(values), chunk)
+		b := buf[:n*size]
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+
+		decodeValues(values[:n], b)
+		values = values[n:]
+	}
+	return nil
+}
+
+// readBytes reads n bytes from r. Large reads are appended in growing chunks, so a corrupt size cannot allocate
+// much more memory than the data holds.
+func readBytes(r io.Reader, n int) ([]byte, error) {
+	b := make([]byte, 0, min(n, codecChunkSize))
+	for start := 0; start < n; start = len(b) {
+		chunk := min(n-start, max(start, codecChunkSize))
+		b = slices.Grow(b, chunk)[:start+chunk]
+		if _, err := io.ReadFull(r, b[start:]); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// writeValues writes the little endian encoding of the values to w in chunks. Strings are not supported.
+func writeValues[T CastPropertyValueType](w io.Writer, values []T) error {
+	size := valueSize[T]()
+	chunk := codecChunkSize / size
+	buf := make([]byte, 0, min(len(values), chunk)*size)
+
+	for len(values) > 0 {
+		n := min(len(values), chunk)
+		if _, err := w.Write(appendValues(buf[:0], values[:n])); err != nil {
+			return err
+		}
+		values = values[n:]
+	}
+	return nil
+}
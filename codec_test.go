@@ -0,0 +1,178 @@
+package cast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testCodec checks the encoding of the values against [binary.Write] and decodes them back
+func testCodec[T CastPropertyValueType](t *testing.T, values []T) {
+	t.Helper()
+
+	var want bytes.Buffer
+	if err := binary.Write(&want, binary.LittleEndian, values); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, valueSize[T](), binary.Size(values[:1]))
+
+	var got bytes.Buffer
+	if err := writeValues(&got, values); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("%T: encoding differs from encoding/binary", values)
+	}
+
+	decoded := make([]T, len(values))
+	if err := readValues(&got, decoded, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded, values) {
+		t.Errorf("%T: got %v, want %v", values, decoded, values)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	testCodec(t, []byte{0, 1, 0xFF})
+	testCodec(t, []uint16{0, 1, 0xFFFF, 0x1234})
+	testCodec(t, []uint32{0, 1, 0xFFFFFFFF, 0x12345678})
+	testCodec(t, []uint64{0, 1, castHashBase, 0xFFFFFFFFFFFFFFFF})
+	testCodec(t, []float32{0, -1.5, 3.25e10})
+	testCodec(t, []float64{0, -1.5, 3.25e100})
+	testCodec(t, []Vec2{{1, 2}, {-3, 4}})
+	testCodec(t, []Vec3{{1, 2, 3}, {-4, 5, -6}})
+	testCodec(t, []Vec4{{1, 2, 3, 4}, {-5, 6, -7, 8}})
+
+	// values spanning several chunks
+	large := make([]Vec3, codecChunkSize/4)
+	for i := range large {
+		large[i] = Vec3{float32(i), float32(-i), 0.5}
+	}
+	testCodec(t, large)
+}
+
+func TestCodecHeaders(t *testing.T) {
+	fileHeader := castHeader{Magic: castMagic, Version: 1, RootNodes: 2, Flags: 3}
+	nodeHeader := castNodeHeader{Id: NodeIdMesh, NodeSize: 100, NodeHash: castHashBase, PropertyCount: 4, ChildCount: 5}
+	propertyHeader := castPropertyHeader{Id: PropVector3, NameSize: 2, ArrayLength: 6}
+
+	for _, h := range []struct {
+		header  any
+		encoded []byte
+		size    int
+	}{
+		{fileHeader, fileHeader.append(nil), castHeaderSize},
+		{nodeHeader, nodeHeader.append(nil), castNodeHeaderSize},
+		{propertyHeader, propertyHeader.append(nil), castPropertyHeaderSize},
+	} {
+		var want bytes.Buffer
+		if err := binary.Write(&want, binary.LittleEndian, h.header); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, len(h.encoded), h.size)
+		if !bytes.Equal(h.encoded, want.Bytes()) {
+			t.Errorf("%T: encoding differs from encoding/binary", h.header)
+		}
+	}
+
+	var decodedFile castHeader
+	decodedFile.decode(fileHeader.append(nil))
+	assertEqual(t, decodedFile, fileHeader)
+
+	var decodedNode castNodeHeader
+	decodedNode.decode(nodeHeader.append(nil))
+	assertEqual(t, decodedNode, nodeHeader)
+
+	var decodedProperty castPropertyHeader
+	decodedProperty.decode(propertyHeader.append(nil))
+	assertEqual(t, decodedProperty, propertyHeader)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/cast_ik.cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	castFile := must(Load(bytes.NewReader(data)))
+	var buf bytes.Buffer
+	if err := castFile.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := must(Load(bytes.NewReader(buf.Bytes())))
+	var again bytes.Buffer
+	if err := reloaded.Write(&again); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, bytes.Equal(buf.Bytes(), again.Bytes()), true)
+	assertEqual(t, buf.Len(), len(data))
+}
+
+// hugeProperty returns a property claiming the given number of values without holding them
+func hugeProperty(name CastPropertyName, count int) *CastProperty[Vec4] {
+	return &CastProperty[Vec4]{id: PropVector4, name: name, lazy: &lazyValues{count: count}}
+}
+
+func TestOverflow(t *testing.T) {
+	castFile := New()
+	mesh := castFile.CreateRoot().CreateChild(NodeIdModel).CreateChild(NodeIdMesh)
+	mesh.properties = append(mesh.properties, hugeProperty("vp", 1<<28))
+
+	var buf bytes.Buffer
+	err := castFile.Write(&buf)
+	var overflow *OverflowError
+	if !errors.As(err, &overflow) || !errors.Is(err, ErrSizeOverflow) {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+	assertEqual(t, overflow.Path, "root[0]/modl[0]/mesh[0]")
+	assertEqual(t, overflow.Field, "node size")
+	assertEqual(t, overflow.Value, int64(1<<32+castPropertyHeaderSize+2+castNodeHeaderSize))
+	assertEqual(t, buf.Len(), 0)
+
+	var count uint64 = math.MaxUint32 + 1
+	mesh.properties[0] = hugeProperty("vp", int(count))
+	if err := castFile.Write(&buf); !errors.As(err, &overflow) || overflow.Field != "array length of property vp" {
+		t.Errorf("expected array length overflow, got %v", err)
+	}
+
+	mesh.properties[0] = &CastProperty[byte]{id: PropByte, name: CastPropertyName(strings.Repeat("n", math.MaxUint16+1))}
+	if err := castFile.Write(&buf); !errors.As(err, &overflow) || overflow.Field != "property name length" {
+		t.Errorf("expected name length overflow, got %v", err)
+	}
+	assertEqual(t, buf.Len(), 0)
+}
+
+func BenchmarkLoad(b *testing.B) {
+	data, err := os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		if _, err := Load(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	castFile := loadTestFile(b, "pilot_medium_bangalore_LOD0.cast")
+
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for range b.N {
+		buf.Reset()
+		if err := castFile.Write(&buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.SetBytes(int64(buf.Len()))
+}
@@ -0,0 +1,515 @@
+package cast
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ----------------------- //
+//          CURVE          //
+// ----------------------- //
+
+// Key properties of a curve, the values of its key property
+const (
+	KeyPropertyRotation     = "rq" // Rotation quaternions, Vector4
+	KeyPropertyTranslationX = "tx" // Translation along the X axis
+	KeyPropertyTranslationY = "ty" // Translation along the Y axis
+	KeyPropertyTranslationZ = "tz" // Translation along the Z axis
+	KeyPropertyScaleX       = "sx" // Scale along the X axis
+	KeyPropertyScaleY       = "sy" // Scale along the Y axis
+	KeyPropertyScaleZ       = "sz" // Scale along the Z axis
+	KeyPropertyVisibility   = "vb" // Visibility
+)
+
+// CurveMode is the way the values of a curve are applied to the animated node, stored in the mode property
+type CurveMode int
+
+const (
+	CurveModeAbsolute CurveMode = iota // Values replace the transform of the node
+	CurveModeAdditive                  // Values are added on top of the current animation of the node
+	CurveModeRelative                  // Values are relative to the rest pose of the node
+)
+
+// curveModeNames holds the names of the curve modes as stored in the mode property
+var curveModeNames = map[CurveMode]string{
+	CurveModeAbsolute: "absolute",
+	CurveModeAdditive: "additive",
+	CurveModeRelative: "relative",
+}
+
+// String returns the name of the curve mode as stored in the mode property
+func (m CurveMode) String() string {
+	if name, ok := curveModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("CurveMode(%d)", int(m))
+}
+
+// ParseCurveMode parses a curve mode from its name as stored in the mode property
+func ParseCurveMode(s string) (CurveMode, error) {
+	for mode, name := range curveModeNames {
+		if name == s {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("cast: invalid curve mode: %q", s)
+}
+
+// Curve wraps an animation curve node
+type Curve struct {
+	*CastNode
+}
+
+// AsCurve returns the node as a [Curve], nil if it is not a curve node
+func AsCurve(n *CastNode) *Curve {
+	if n == nil || n.id != NodeIdCurve {
+		return nil
+	}
+	return &Curve{n}
+}
+
+// NodeName returns the name of the node animated by the curve
+func (c *Curve) NodeName() string {
+	return GetPropertyValueOr(c.CastNode, PropNameNodeName, "")
+}
+
+// KeyProperty returns the animated property of the node, e.g. [KeyPropertyRotation]
+func (c *Curve) KeyProperty() string {
+	return GetPropertyValueOr(c.CastNode, PropNameKeyProperty, "")
+}
+
+// Mode returns the mode of the curve, [CurveModeAbsolute] if it is not set. Returns an error if the mode
+// property holds an unknown mode.
+func (c *Curve) Mode() (CurveMode, error) {
+	mode, err := GetPropertyValue[string](c.CastNode, PropNameMode)
+	if err != nil {
+		return CurveModeAbsolute, nil
+	}
+	return ParseCurveMode(*mode)
+}
+
+// SetMode sets the mode of the curve
+func (c *Curve) SetMode(mode CurveMode) error {
+	if _, ok := curveModeNames[mode]; !ok {
+		return fmt.Errorf("cast: invalid curve mode: %d", mode)
+	}
+	_, err := CreateProperty(c.CastNode, PropNameMode, PropString, mode.String())
+	return err
+}
+
+// Interpolation is the way a curve is interpolated between its keyframes
+type Interpolation int
+
+const (
+	InterpolationLinear Interpolation = iota // Values are interpolated linearly, rotations spherically
+	InterpolationStep                        // The value of the previous keyframe is held until the next one
+)
+
+// Interpolation returns the interpolation of the curve: step for visibility curves, linear otherwise
+func (c *Curve) Interpolation() Interpolation {
+	if c.KeyProperty() == KeyPropertyVisibility {
+		return InterpolationStep
+	}
+	return InterpolationLinear
+}
+
+// KeyFrames returns the keyframes of the curve widened to uint32
+func (c *Curve) KeyFrames() ([]uint32, error) {
+	return GetPropertyValuesAsUint32(c.CastNode, PropNameKeyFrameBuffer)
+}
+
+// Keys returns the keyframes and values of a scalar curve regardless of the widths they are stored with,
+// the keyframes widened to uint32 and the values converted to float64. Returns an error if the numbers of
+// keyframes and values differ.
+func (c *Curve) Keys() ([]uint32, []float64, error) {
+	frames, err := c.KeyFrames()
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := GetPropertyValuesAsFloat64(c.CastNode, PropNameKeyValueBuffer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(frames) != len(values) {
+		return nil, nil, fmt.Errorf("cast: curve has %d keyframes but %d values", len(frames), len(values))
+	}
+	return frames, values, nil
+}
+
+// RotationKeys returns the keyframes widened to uint32 and the quaternions of a rotation curve. Returns an
+// error if the numbers of keyframes and values differ.
+func (c *Curve) RotationKeys() ([]uint32, []Vec4, error) {
+	frames, err := c.KeyFrames()
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := GetPropertyValues[Vec4](c.CastNode, PropNameKeyValueBuffer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(frames) != len(values) {
+		return nil, nil, fmt.Errorf("cast: curve has %d keyframes but %d values", len(frames), len(values))
+	}
+	return frames, values, nil
+}
+
+// SetKeys replaces the keyframes and values of a scalar curve. The keyframes are stored with the narrowest
+// index type holding the last one. Values of visibility curves are rounded and stored with the narrowest
+// integer type holding them, all other values are stored as floats. Returns an error if the numbers of
+// keyframes and values differ, the keyframes are not increasing or a visibility value is negative.
+func (c *Curve) SetKeys(frames []uint32, values []float64) error {
+	if err := checkKeys(frames, len(values)); err != nil {
+		return err
+	}
+
+	id := PropFloat
+	if c.KeyProperty() == KeyPropertyVisibility {
+		var largest float64
+		for _, v := range values {
+			if v < 0 {
+				return fmt.Errorf("cast: negative visibility value %v", v)
+			}
+			largest = max(largest, math.Round(v))
+		}
+		id = indexPropertyId(int(min(largest, math.MaxInt32)))
+	}
+
+	if err := setNumericValues(c.CastNode, PropNameKeyValueBuffer, id, values); err != nil {
+		return err
+	}
+	return c.setKeyFrames(frames)
+}
+
+// SetRotationKeys replaces the keyframes and quaternions of a rotation curve, the keyframes are stored with the
+// narrowest index type holding the last one. Returns an error if the numbers of keyframes and values differ or
+// the keyframes are not increasing.
+func (c *Curve) SetRotationKeys(frames []uint32, values []Vec4) error {
+	if err := checkKeys(frames, len(values)); err != nil {
+		return err
+	}
+
+	if _, err := CreateProperty(c.CastNode, PropNameKeyValueBuffer, PropVector4, values...); err != nil {
+		return err
+	}
+	return c.setKeyFrames(frames)
+}
+
+// checkKeys checks that the keyframes are increasing and match the number of values
+func checkKeys(frames []uint32, valueCount int) error {
+	if len(frames) != valueCount {
+		return fmt.Errorf("cast: %d keyframes but %d values", len(frames), valueCount)
+	}
+	for i := 1; i < len(frames); i++ {
+		if frames[i] <= frames[i-1] {
+			return fmt.Errorf("cast: keyframe %d at frame %d does not follow frame %d", i, frames[i], frames[i-1])
+		}
+	}
+	return nil
+}
+
+// setKeyFrames stores the increasing keyframes with the narrowest index type holding the last one
+func (c *Curve) setKeyFrames(frames []uint32) error {
+	var last uint32
+	if len(frames) > 0 {
+		last = frames[len(frames)-1]
+	}
+	return setIndexValues(c.CastNode, PropNameKeyFrameBuffer, indexPropertyId(int(min(last, math.MaxInt32))), frames)
+}
+
+// Evaluate returns the value of a scalar curve at the given frame. Frames before the first and after the
+// last keyframe hold the value of the nearest keyframe. The value is returned as stored, for additive and
+// relative curves see [Curve.EvaluateOn].
+func (c *Curve) Evaluate(frame float64) (float64, error) {
+	values, err := c.scalarValues()
+	if err != nil {
+		return 0, err
+	}
+
+	a, b, alpha, err := c.keyframeSpan(frame, values.len)
+	if err != nil {
+		return 0, err
+	}
+	return values.at(a) + (values.at(b)-values.at(a))*alpha, nil
+}
+
+// EvaluateRotation returns the rotation quaternion of a rotation curve at the given frame, see [Curve.Evaluate]
+func (c *Curve) EvaluateRotation(frame float64) (Vec4, error) {
+	values, err := GetPropertyValues[Vec4](c.CastNode, PropNameKeyValueBuffer)
+	if err != nil {
+		return Vec4{}, err
+	}
+
+	a, b, alpha, err := c.keyframeSpan(frame, len(values))
+	if err != nil {
+		return Vec4{}, err
+	}
+	return Vec4(Quat(values[a]).Slerp(Quat(values[b]), float32(alpha))), nil
+}
+
+// EvaluateOn returns the value of a scalar curve at the given frame applied on the given base value according
+// to the mode of the curve. Absolute curves ignore the base. Additive and relative translations are added to
+// the base, scales multiply it.
+func (c *Curve) EvaluateOn(frame, base float64) (float64, error) {
+	mode, err := c.Mode()
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := c.Evaluate(frame)
+	if err != nil || mode == CurveModeAbsolute {
+		return v, err
+	}
+
+	switch c.KeyProperty() {
+	case KeyPropertyScaleX, KeyPropertyScaleY, KeyPropertyScaleZ:
+		return base * v, nil
+	case KeyPropertyVisibility:
+		return v, nil
+	default:
+		return base + v, nil
+	}
+}
+
+// EvaluateRotationOn returns the rotation of a rotation curve at the given frame applied on the given base
+// rotation according to the mode of the curve. Absolute curves ignore the base, additive and relative
+// rotations are applied after the base.
+func (c *Curve) EvaluateRotationOn(frame float64, base Vec4) (Vec4, error) {
+	mode, err := c.Mode()
+	if err != nil {
+		return Vec4{}, err
+	}
+
+	q, err := c.EvaluateRotation(frame)
+	if err != nil || mode == CurveModeAbsolute {
+		return q, err
+	}
+	return Vec4(Quat(base).Mul(Quat(q))), nil
+}
+
+// Compress removes the keyframes that interpolating between the remaining keyframes reproduces within the given
+// tolerance. For rotation curves the tolerance is the angle between the rotations in radians. The first and the
+// last keyframe are always kept.
+func (c *Curve) Compress(epsilon float64) error {
+	p, ok := c.GetProperty(PropNameKeyFrameBuffer)
+	if !ok {
+		return fmt.Errorf(`cast: property %s not found`, PropNameKeyFrameBuffer)
+	}
+	frames, err := c.KeyFrames()
+	if err != nil {
+		return err
+	}
+
+	var keep []int
+	if c.KeyProperty() == KeyPropertyRotation {
+		values, err := GetPropertyValues[Vec4](c.CastNode, PropNameKeyValueBuffer)
+		if err != nil {
+			return err
+		}
+		if len(values) != len(frames) {
+			return fmt.Errorf("cast: curve has %d keyframes but %d values", len(frames), len(values))
+		}
+
+		keep = reduceKeyframes(frames, func(a, b, i int, alpha float32) bool {
+			q := Quat(values[a]).Slerp(Quat(values[b]), alpha)
+			dot := math.Abs(float64(q.Dot(Quat(values[i]))))
+			return 2*math.Acos(min(dot, 1)) <= epsilon
+		})
+	} else {
+		values, err := c.scalarValues()
+		if err != nil {
+			return err
+		}
+		if values.len != len(frames) {
+			return fmt.Errorf("cast: curve has %d keyframes but %d values", len(frames), values.len)
+		}
+
+		step := c.Interpolation() == InterpolationStep
+		keep = reduceKeyframes(frames, func(a, b, i int, alpha float32) bool {
+			v := values.at(a)
+			if !step {
+				v += (values.at(b) - values.at(a)) * float64(alpha)
+			}
+			return math.Abs(v-values.at(i)) <= epsilon
+		})
+	}
+
+	keptFrames := make([]uint32, len(keep))
+	for i, k := range keep {
+		keptFrames[i] = frames[k]
+	}
+	return c.keepKeyframes(keep, keptFrames, p.Id())
+}
+
+// keepKeyframes keeps the values of the keyframes with the given indices and stores their new frames in the
+// keyframe buffer with the given property id
+func (c *Curve) keepKeyframes(keep []int, frames []uint32, id CastPropertyId) error {
+	if c.KeyProperty() == KeyPropertyRotation {
+		values, err := GetPropertyValues[Vec4](c.CastNode, PropNameKeyValueBuffer)
+		if err != nil {
+			return err
+		}
+
+		kept := make([]Vec4, len(keep))
+		for i, k := range keep {
+			kept[i] = values[k]
+		}
+		if _, err := CreateProperty(c.CastNode, PropNameKeyValueBuffer, PropVector4, kept...); err != nil {
+			return err
+		}
+	} else {
+		values, err := c.scalarValues()
+		if err != nil {
+			return err
+		}
+
+		kept := make([]float64, len(keep))
+		for i, k := range keep {
+			kept[i] = values.at(k)
+		}
+		kv, _ := c.GetProperty(PropNameKeyValueBuffer)
+		if err := setNumericValues(c.CastNode, PropNameKeyValueBuffer, kv.Id(), kept); err != nil {
+			return err
+		}
+	}
+
+	return setIndexValues(c.CastNode, PropNameKeyFrameBuffer, id, frames)
+}
+
+// reduceKeyframes returns the indices of the keyframes to keep. Segments between kept keyframes are extended as
+// long as reproduces reports that interpolating from keyframe a to keyframe b by alpha reproduces every keyframe
+// i in between.
+func reduceKeyframes(frames []uint32, reproduces func(a, b, i int, alpha float32) bool) []int {
+	if len(frames) <= 2 {
+		return []int{0, len(frames) - 1}[:len(frames)]
+	}
+
+	keep := []int{0}
+	start := 0
+	for end := 2; end < len(frames); end++ {
+		for i := start + 1; i < end; i++ {
+			alpha := float32(frames[i]-frames[start]) / float32(frames[end]-frames[start])
+			if !reproduces(start, end, i, alpha) {
+				start = end - 1
+				keep = append(keep, start)
+				break
+			}
+		}
+	}
+	return append(keep, len(frames)-1)
+}
+
+// keyframeSpan returns the indices of the keyframes around the given frame and the interpolation factor
+// between them, honoring the interpolation of the curve
+func (c *Curve) keyframeSpan(frame float64, valueCount int) (int, int, float64, error) {
+	p, ok := c.GetProperty(PropNameKeyFrameBuffer)
+	if !ok {
+		return 0, 0, 0, fmt.Errorf(`cast: property %s not found`, PropNameKeyFrameBuffer)
+	}
+
+	frames, err := numericValues(p)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if frames.len == 0 {
+		return 0, 0, 0, ErrEmptyValues
+	}
+	if frames.len != valueCount {
+		return 0, 0, 0, fmt.Errorf("cast: curve has %d keyframes but %d values", frames.len, valueCount)
+	}
+
+	next := sort.Search(frames.len, func(i int) bool {
+		return frames.at(i) > frame
+	})
+	switch {
+	case next == 0:
+		return 0, 0, 0, nil
+	case next == frames.len:
+		return next - 1, next - 1, 0, nil
+	case c.Interpolation() == InterpolationStep:
+		return next - 1, next - 1, 0, nil
+	}
+
+	start, end := frames.at(next-1), frames.at(next)
+	return next - 1, next, (frame - start) / (end - start), nil
+}
+
+// scalarValues returns the values of a scalar curve
+func (c *Curve) scalarValues() (numeric, error) {
+	p, ok := c.GetProperty(PropNameKeyValueBuffer)
+	if !ok {
+		return numeric{}, fmt.Errorf(`cast: property %s not found`, PropNameKeyValueBuffer)
+	}
+	return numericValues(p)
+}
+
+// numeric provides access to the values of a numeric property of any type as float64
+type numeric struct {
+	at  func(i int) float64
+	len int
+}
+
+// numericValues returns an accessor for the values of the given numeric property
+func numericValues(p iCastProperty) (numeric, error) {
+	switch p := p.(type) {
+	case *CastProperty[byte]:
+		return numericOf(p.GetValues()), nil
+	case *CastProperty[uint16]:
+		return numericOf(p.GetValues()), nil
+	case *CastProperty[uint32]:
+		return numericOf(p.GetValues()), nil
+	case *CastProperty[uint64]:
+		return numericOf(p.GetValues()), nil
+	case *CastProperty[float32]:
+		return numericOf(p.GetValues()), nil
+	case *CastProperty[float64]:
+		return numericOf(p.GetValues()), nil
+	default:
+		return numeric{}, fmt.Errorf("cast: property %s has a type of %T instead of a numeric type", p.Name(), p)
+	}
+}
+
+// numericOf returns an accessor for the given values
+func numericOf[T byte | uint16 | uint32 | uint64 | float32 | float64](values []T) numeric {
+	return numeric{
+		at:  func(i int) float64 { return float64(values[i]) },
+		len: len(values),
+	}
+}
+
+// setNumericValues stores the values in the numeric property with the given name using the given property id,
+// values stored in an integer type are rounded
+func setNumericValues(n *CastNode, name CastPropertyName, id CastPropertyId, values []float64) error {
+	var err error
+	switch id {
+	case PropByte:
+		_, err = CreateProperty(n, name, id, convertNumeric[byte](values)...)
+	case PropShort:
+		_, err = CreateProperty(n, name, id, convertNumeric[uint16](values)...)
+	case PropInteger32:
+		_, err = CreateProperty(n, name, id, convertNumeric[uint32](values)...)
+	case PropInteger64:
+		_, err = CreateProperty(n, name, id, convertNumeric[uint64](values)...)
+	case PropFloat:
+		_, err = CreateProperty(n, name, id, convertNumeric[float32](values)...)
+	case PropDouble:
+		_, err = CreateProperty(n, name, id, values...)
+	default:
+		err = fmt.Errorf("cast: invalid numeric property id: %v", id)
+	}
+	return err
+}
+
+// convertNumeric converts the values to the given type, rounding them for integer types
+func convertNumeric[T byte | uint16 | uint32 | uint64 | float32](values []float64) []T {
+	converted := make([]T, len(values))
+	for i, v := range values {
+		switch any(converted).(type) {
+		case []float32:
+			converted[i] = T(v)
+		default:
+			converted[i] = T(math.Round(v))
+		}
+	}
+	return converted
+}
@@ -0,0 +1,163 @@
+package cast
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// createCurve creates a curve animating the given property of the node with the given keyframes and values
+func createCurve[T CastPropertyValueType](parent *CastNode, nodeName, keyProperty string, id CastPropertyId, frames []uint16, values ...T) *Curve {
+	curve := AsCurve(parent.CreateChild(NodeIdCurve))
+	CreateProperty(curve.CastNode, PropNameNodeName, PropString, nodeName)
+	CreateProperty(curve.CastNode, PropNameKeyProperty, PropString, keyProperty)
+	CreateProperty(curve.CastNode, PropNameKeyFrameBuffer, PropShort, frames...)
+	CreateProperty(curve.CastNode, PropNameKeyValueBuffer, id, values...)
+	return curve
+}
+
+func TestCurveEvaluate(t *testing.T) {
+	animation := New().CreateRoot().CreateChild(NodeIdAnimation)
+	curve := createCurve(animation, "j_root", KeyPropertyTranslationX, PropFloat, []uint16{0, 10, 20}, float32(0), float32(10), float32(30))
+
+	for frame, want := range map[float64]float64{-5: 0, 0: 0, 5: 5, 10: 10, 15: 20, 20: 30, 25: 30} {
+		got, err := curve.Evaluate(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, got, want)
+	}
+
+	assertEqual(t, curve.NodeName(), "j_root")
+	assertEqual(t, must(curve.EvaluateOn(5, 100)), 5)
+	curve.SetMode(CurveModeAdditive)
+	assertEqual(t, must(curve.EvaluateOn(5, 100)), 105)
+
+	visibility := createCurve(animation, "j_root", KeyPropertyVisibility, PropByte, []uint16{0, 10}, byte(1), byte(0))
+	assertEqual(t, visibility.Interpolation(), InterpolationStep)
+	assertEqual(t, must(visibility.Evaluate(9.9)), 1)
+	assertEqual(t, must(visibility.Evaluate(10)), 0)
+
+	half := float32(math.Sqrt2 / 2)
+	rotation := createCurve(animation, "j_root", KeyPropertyRotation, PropVector4, []uint16{0, 10}, Vec4{W: 1}, Vec4{Z: 1, W: 0})
+	q := must(rotation.EvaluateRotation(5))
+	if math.Abs(float64(q.Z-half)) > 1e-6 || math.Abs(float64(q.W-half)) > 1e-6 {
+		t.Errorf("got: %v != want: 90 degrees around Z", q)
+	}
+
+	rotation.SetMode(CurveModeRelative)
+	q = must(rotation.EvaluateRotationOn(10, Vec4{Z: 1, W: 0}))
+	assertEqual(t, q, Vec4{W: -1})
+
+	empty := AsCurve(animation.CreateChild(NodeIdCurve))
+	if _, err := empty.Evaluate(0); err == nil {
+		t.Error("expected error for a curve without keyframes")
+	}
+}
+
+func TestCurveCompress(t *testing.T) {
+	animation := New().CreateRoot().CreateChild(NodeIdAnimation)
+	curve := createCurve(animation, "j_root", KeyPropertyTranslationX, PropFloat,
+		[]uint16{0, 1, 2, 3, 4, 5, 6},
+		float32(0), float32(1), float32(2), float32(3), float32(3), float32(3.001), float32(3))
+
+	if err := curve.Compress(0.01); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, slices.Equal(must(curve.KeyFrames()), []uint32{0, 3, 6}), true)
+	assertEqual(t, must(curve.Evaluate(1.5)), 1.5)
+
+	visibility := createCurve(animation, "j_root", KeyPropertyVisibility, PropByte, []uint16{0, 1, 2, 3}, byte(1), byte(1), byte(0), byte(0))
+	if err := visibility.Compress(0); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, slices.Equal(must(visibility.KeyFrames()), []uint32{0, 2, 3}), true)
+	assertEqual(t, must(visibility.Evaluate(1.5)), 1)
+
+	half := float32(math.Sqrt2 / 2)
+	rotation := createCurve(animation, "j_root", KeyPropertyRotation, PropVector4, []uint16{0, 5, 10}, Vec4{W: 1}, Vec4{Z: half, W: half}, Vec4{Z: 1})
+	if err := rotation.Compress(1e-4); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(must(rotation.KeyFrames())), 2)
+}
+
+func TestCurveMode(t *testing.T) {
+	curve := AsCurve(New().CreateRoot().CreateChild(NodeIdAnimation).CreateChild(NodeIdCurve))
+	assertEqual(t, must(curve.Mode()), CurveModeAbsolute)
+
+	for _, mode := range []CurveMode{CurveModeAbsolute, CurveModeAdditive, CurveModeRelative} {
+		if err := curve.SetMode(mode); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, must(curve.Mode()), mode)
+		assertEqual(t, must(ParseCurveMode(mode.String())), mode)
+	}
+	assertEqual(t, GetPropertyValueOr(curve.CastNode, PropNameMode, ""), "relative")
+
+	if err := curve.SetMode(CurveMode(7)); err == nil {
+		t.Error("expected error for an invalid mode")
+	}
+
+	CreateProperty(curve.CastNode, PropNameMode, PropString, "Relative")
+	if _, err := curve.Mode(); err == nil {
+		t.Error("expected error for an invalid mode property")
+	}
+	if _, err := curve.EvaluateOn(0, 1); err == nil {
+		t.Error("expected error evaluating a curve with an invalid mode")
+	}
+}
+
+func TestCurveKeys(t *testing.T) {
+	animation := New().CreateRoot().CreateChild(NodeIdAnimation)
+	curve := createCurve(animation, "j_root", KeyPropertyTranslationX, PropShort, []uint16{0, 300}, uint16(1), uint16(2))
+
+	frames, values, err := curve.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, [2]uint32(frames), [2]uint32{0, 300})
+	assertEqual(t, [2]float64(values), [2]float64{1, 2})
+
+	// keyframes are stored with the narrowest type, values as floats
+	if err := curve.SetKeys([]uint32{0, 10}, []float64{0.5, 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	kb, _ := curve.GetProperty(PropNameKeyFrameBuffer)
+	assertEqual(t, kb.Id(), PropByte)
+	assertEqual(t, [2]float32(must(GetPropertyValues[float32](curve.CastNode, PropNameKeyValueBuffer))), [2]float32{0.5, 1.5})
+	if err := curve.SetKeys([]uint32{0, 70000}, []float64{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	kb, _ = curve.GetProperty(PropNameKeyFrameBuffer)
+	assertEqual(t, kb.Id(), PropInteger32)
+
+	assertEqual(t, curve.SetKeys([]uint32{0}, []float64{0, 1}) != nil, true)
+	assertEqual(t, curve.SetKeys([]uint32{5, 5}, []float64{0, 1}) != nil, true)
+
+	visibility := createCurve(animation, "j_root", KeyPropertyVisibility, PropInteger32, []uint16{0}, uint32(1))
+	if err := visibility.SetKeys([]uint32{0, 1000}, []float64{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	kb, _ = visibility.GetProperty(PropNameKeyFrameBuffer)
+	assertEqual(t, kb.Id(), PropShort)
+	assertEqual(t, [2]byte(must(GetPropertyValues[byte](visibility.CastNode, PropNameKeyValueBuffer))), [2]byte{1, 0})
+	assertEqual(t, visibility.SetKeys([]uint32{0}, []float64{-1}) != nil, true)
+
+	rotation := createCurve(animation, "j_root", KeyPropertyRotation, PropVector4, []uint16{0, 1}, Vec4{W: 1}, Vec4{X: 1})
+	rotationFrames, quats, err := rotation.RotationKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, [2]uint32(rotationFrames), [2]uint32{0, 1})
+	assertEqual(t, quats[1], Vec4{X: 1})
+	if err := rotation.SetRotationKeys([]uint32{256}, []Vec4{{Y: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	kb, _ = rotation.GetProperty(PropNameKeyFrameBuffer)
+	assertEqual(t, kb.Id(), PropShort)
+
+	CreateProperty(rotation.CastNode, PropNameKeyFrameBuffer, PropByte, []byte{0, 1}...)
+	_, _, err = rotation.RotationKeys()
+	assertEqual(t, err != nil, true)
+}
@@ -0,0 +1,901 @@
+package cast
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------- //
+//         DECODE          //
+// ----------------------- //
+
+// LoadError describes a failure while loading a cast file
+type LoadError struct {
+	Path     string           // Path of the node being loaded, e.g. "root[0]/modl[0]/mesh[2]", empty for the file header
+	Property CastPropertyName // Property being loaded, empty if the failure is not within a property
+	Offset   int64            // Offset in the stream of the header of the element that failed to load
+	Err      error            // Err is the underlying error
+}
+
+// Error returns the error message
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	b.WriteString("cast: load")
+	if e.Path != "" {
+		b.WriteString(" " + e.Path)
+	}
+	if e.Property != "" {
+		fmt.Fprintf(&b, " property %q", e.Property)
+	}
+	fmt.Fprintf(&b, " at offset %d: %v", e.Offset, e.Err)
+	return b.String()
+}
+
+// Unwrap returns the underlying error
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// WarningKind describes how data was degraded during a lenient load or which inconsistency was found
+type WarningKind int
+
+const (
+	WarningPropertySkipped  WarningKind = iota // A property could not be decoded and was left out
+	WarningNodeResynced                        // The remaining data of a node was skipped to continue at the next node
+	WarningBufferClamped                       // The values of a property exceeding the size of its node were dropped
+	WarningNodeSizeMismatch                    // A node was decoded from more bytes than its header states
+	WarningUVLayerCount                        // The UV layer count of a mesh does not match its UV layers, see [Mesh.SyncUVLayerCount]
+)
+
+// warningKindNames holds the names of the warning kinds
+var warningKindNames = map[WarningKind]string{
+	WarningPropertySkipped:  "property skipped",
+	WarningNodeResynced:     "node resynced",
+	WarningBufferClamped:    "buffer clamped",
+	WarningNodeSizeMismatch: "node size mismatch",
+	WarningUVLayerCount:     "UV layer count mismatch",
+}
+
+// String returns the name of the warning kind
+func (k WarningKind) String() string {
+	if name, ok := warningKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("WarningKind(%d)", int(k))
+}
+
+// Warning describes data that was degraded during a lenient load or is inconsistent, see [Load]
+type Warning struct {
+	Kind     WarningKind      // Kind of the warning
+	Path     string           // Path of the affected node, e.g. "root[0]/modl[0]/mesh[2]"
+	Property CastPropertyName // Affected property, empty if the warning concerns the node
+	Offset   int64            // Offset in the stream of the header of the affected element
+	Message  string           // Message describing the warning
+}
+
+// String returns a description of the warning
+func (w Warning) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", w.Kind, w.Path)
+	if w.Property != "" {
+		fmt.Fprintf(&b, " property %q", w.Property)
+	}
+	fmt.Fprintf(&b, " at offset %d: %s", w.Offset, w.Message)
+	return b.String()
+}
+
+// Warnings returns the warnings collected while loading the file, see [Load]
+func (n *CastFile) Warnings() []Warning {
+	return n.warnings
+}
+
+// LoadOption configures how [Load] and [LoadPartial] decode a file
+type LoadOption func(d *decoder)
+
+// WithResyncNodes makes loading continue at the next node when the data of a node cannot be decoded,
+// using the size stored in the node header. The property that failed is skipped along with the remaining
+// properties and children of the node. Nodes spanning fewer bytes than their header states are skipped
+// to their end as well.
+func WithResyncNodes() LoadOption {
+	return func(d *decoder) {
+		d.resync = true
+	}
+}
+
+// WithClampBuffers makes loading drop the values of a property that exceed the size stored in the
+// header of its node, instead of reading them from the following data
+func WithClampBuffers() LoadOption {
+	return func(d *decoder) {
+		d.clamp = true
+	}
+}
+
+// WithNodeFilter makes loading decode only the subtrees of the nodes with the given types. Other nodes are
+// skipped using the size stored in their header without decoding their properties, unless they may contain
+// nodes of the given types, e.g. a model containing a skeleton. Such ancestors are kept without their
+// properties, so the matching nodes keep their place in the tree, and dropped if they hold no matching node.
+// Root nodes are always kept.
+//
+//	cast.Load(r, cast.WithNodeFilter(cast.NodeIdSkeleton, cast.NodeIdAnimation))
+func WithNodeFilter(ids ...CastNodeId) LoadOption {
+	filter := make(map[CastNodeId]bool, len(ids))
+	for _, id := range ids {
+		filter[id] = true
+	}
+
+	return func(d *decoder) {
+		d.filter = filter
+	}
+}
+
+// nodeDescendants lists the types of the nodes that may occur below nodes of each type. Nodes of other types
+// may contain any node.
+var nodeDescendants = map[CastNodeId][]CastNodeId{
+	NodeIdModel:             {NodeIdMesh, NodeIdBlendShape, NodeIdSkeleton, NodeIdBone, NodeIdIKHandle, NodeIdConstraint, NodeIdMaterial, NodeIdFile},
+	NodeIdMesh:              {},
+	NodeIdBlendShape:        {},
+	NodeIdSkeleton:          {NodeIdBone, NodeIdIKHandle, NodeIdConstraint},
+	NodeIdBone:              {},
+	NodeIdIKHandle:          {},
+	NodeIdConstraint:        {},
+	NodeIdAnimation:         {NodeIdCurve, NodeIdNotificationTrack},
+	NodeIdCurve:             {},
+	NodeIdNotificationTrack: {},
+	NodeIdMaterial:          {NodeIdFile},
+	NodeIdFile:              {},
+	NodeIdInstance:          {},
+	NodeIdMetadata:          {},
+}
+
+// mayContainMatch reports whether a node of the given type may contain nodes passing the filter
+func (d *decoder) mayContainMatch(id CastNodeId) bool {
+	descendants, ok := nodeDescendants[id]
+	if !ok {
+		return true
+	}
+	for _, descendant := range descendants {
+		if d.filter[descendant] {
+			return true
+		}
+	}
+	return false
+}
+
+// maxPreallocatedSize is the maximum size of the values of a property allocated before they are read
+const maxPreallocatedSize = 1 << 20
+
+// lazyMinSize is the minimum encoded size of the values of a property kept encoded by [WithLazyValues]
+const lazyMinSize = 4 << 10
+
+// WithLazyValues makes loading keep the encoded values of large properties and decode them only when they are
+// first accessed, e.g. by [CastProperty.GetValues]. Tools only touching a few buffers of a file, such as reading
+// its metadata or renaming bones, skip decoding all others. Accessing the values is safe for concurrent use.
+func WithLazyValues() LoadOption {
+	return func(d *decoder) {
+		d.lazy = true
+	}
+}
+
+// lazyValues holds the encoded values of a property until they are decoded, see [WithLazyValues]
+type lazyValues struct {
+	once  sync.Once
+	count int
+	raw   []byte
+}
+
+// LoadLimits bounds the resources used to load a file, see [WithLimits]. Zero fields are unlimited.
+type LoadLimits struct {
+	MaxBytes  int64 // Maximum number of bytes read from the stream
+	MaxDepth  int   // Maximum nesting depth of the nodes, root nodes are at depth 1
+	MaxNodes  int   // Maximum number of nodes
+	MaxValues int   // Maximum number of values of a single property
+}
+
+// WithLimits makes loading fail with an error wrapping [ErrLimitExceeded] as soon as the file exceeds one of
+// the given limits. Property buffers are checked against the limits before they are allocated.
+func WithLimits(limits LoadLimits) LoadOption {
+	return func(d *decoder) {
+		d.limits = limits
+	}
+}
+
+// WithStrictSizes makes loading fail when the sizes stored in the node headers do not match the data: a node
+// must lie within its parent, the values of a property within its node and the data of a node must span
+// exactly its size. Property buffers are checked against the size of their node before they are allocated.
+func WithStrictSizes() LoadOption {
+	return func(d *decoder) {
+		d.strict = true
+	}
+}
+
+// WithContext makes loading fail with the error of the context once it is done. The context is checked
+// before each node, a read blocked in the underlying [io.Reader] is not interrupted.
+func WithContext(ctx context.Context) LoadOption {
+	return func(d *decoder) {
+		d.ctx = ctx
+	}
+}
+
+// WithTimeout makes loading fail with [context.DeadlineExceeded] when it takes longer than the given duration,
+// measured from the call to [Load]. Like [WithContext], a read blocked in the underlying [io.Reader] is not
+// interrupted, set a deadline on network connections as well.
+func WithTimeout(timeout time.Duration) LoadOption {
+	return func(d *decoder) {
+		d.deadline = time.Now().Add(timeout)
+	}
+}
+
+// SafeLoadProfile bundles the options for loading untrusted files, such as user uploads on a server. Files
+// are limited to 128 MiB, 64 levels of nesting, a million nodes and 32 million values per property, node
+// sizes are checked strictly and loading is aborted after 30 seconds. The memory used is bounded by a small
+// multiple of the byte limit, no input can cause unbounded recursion. Options given after the profile
+// override its settings, e.g. to raise the limits:
+//
+//	cast.Load(r, cast.SafeLoadProfile, cast.WithLimits(limits))
+var SafeLoadProfile LoadOption = func(d *decoder) {
+	WithLimits(LoadLimits{
+		MaxBytes:  128 << 20,
+		MaxDepth:  64,
+		MaxNodes:  1 << 20,
+		MaxValues: 32 << 20,
+	})(d)
+	WithStrictSizes()(d)
+	WithTimeout(30 * time.Second)(d)
+}
+
+// Decoder loads cast files like [Load], reusing its scratch buffers across calls to [Decoder.Decode], which
+// saves allocations when many files are loaded in a row. A Decoder must not be used concurrently.
+type Decoder struct {
+	d    decoder
+	opts []LoadOption
+}
+
+// NewDecoder creates a new [Decoder] loading files with the given options
+func NewDecoder(opts ...LoadOption) *Decoder {
+	return &Decoder{opts: opts}
+}
+
+// Decode loads a [CastFile] from the given [io.Reader], see [Load]
+func (dec *Decoder) Decode(r io.Reader) (*CastFile, error) {
+	return dec.d.load(r, dec.opts)
+}
+
+// decoderPool holds the decoders used by [Load] and [LoadPartial]
+var decoderPool = sync.Pool{
+	New: func() any {
+		return new(decoder)
+	},
+}
+
+// maxInternedNames is the maximum number of property names interned by a decoder
+const maxInternedNames = 4096
+
+// pathElement is a node on the path of the decoder
+type pathElement struct {
+	id    CastNodeId
+	index int // index among the siblings of the same type
+}
+
+// decoder decodes a cast file while keeping track of the position within the stream
+type decoder struct {
+	r        io.Reader
+	br       io.ByteReader // br is the reader if it reads single bytes
+	offset   int64
+	seekEnd  int64 // seekEnd is the offset of the end of a seekable reader, 0 if not determined yet, -1 if unknown
+	path     []pathElement
+	resync   bool
+	clamp    bool
+	strict   bool
+	lazy     bool
+	filter   map[CastNodeId]bool
+	matched  bool // whether the current node is within a subtree passing the filter
+	limits   LoadLimits
+	nodes    int
+	ctx      context.Context
+	deadline time.Time
+	warnings []Warning
+	arena    *arena
+
+	// scratch data kept across loads
+	counts  []map[CastNodeId]int        // sibling counts per depth
+	names   map[string]CastPropertyName // interned property names
+	buf     []byte                      // staging buffer for names and property values
+	scratch [castNodeHeaderSize]byte
+}
+
+// reset prepares the decoder for reading from the given [io.Reader] with the given options, keeping the
+// scratch data
+func (d *decoder) reset(r io.Reader, opts []LoadOption) {
+	br, _ := r.(io.ByteReader)
+	*d = decoder{
+		r:      r,
+		br:     br,
+		path:   d.path[:0],
+		counts: d.counts,
+		names:  d.names,
+		buf:    d.buf,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+}
+
+// load decodes a file from the given [io.Reader] with the given options, see [Load]
+func (d *decoder) load(r io.Reader, opts []LoadOption) (*CastFile, error) {
+	castFile, err := d.loadPartial(r, opts)
+	if err != nil {
+		if castFile != nil {
+			castFile.Release()
+		}
+		return nil, err
+	}
+	return castFile, nil
+}
+
+// loadPartial decodes a file from the given [io.Reader] with the given options, see [LoadPartial]
+func (d *decoder) loadPartial(r io.Reader, opts []LoadOption) (*CastFile, error) {
+	d.reset(r, opts)
+	defer d.reset(nil, nil)
+	return d.decodeFile()
+}
+
+// pathString returns the path of the current node, e.g. "root[0]/modl[0]/mesh[2]"
+func (d *decoder) pathString() string {
+	var b strings.Builder
+	for i, e := range d.path {
+		if i > 0 {
+			b.WriteByte('/')
+		}
+		fmt.Fprintf(&b, "%s[%d]", e.id, e.index)
+	}
+	return b.String()
+}
+
+// siblingCounts returns the cleared map counting the decoded children per type of a node at the given depth
+func (d *decoder) siblingCounts(depth int) map[CastNodeId]int {
+	for len(d.counts) <= depth {
+		d.counts = append(d.counts, make(map[CastNodeId]int))
+	}
+	clear(d.counts[depth])
+	return d.counts[depth]
+}
+
+// buffer returns the staging buffer with at least the given size
+func (d *decoder) buffer(size int) []byte {
+	if cap(d.buf) < size {
+		d.buf = make([]byte, max(size, codecChunkSize))
+	}
+	return d.buf[:size]
+}
+
+// internName returns the property name held by b, interned so repeated names share their memory
+func (d *decoder) internName(b []byte) CastPropertyName {
+	if name, ok := d.names[string(b)]; ok {
+		return name
+	}
+
+	name := CastPropertyName(b)
+	if d.names == nil {
+		d.names = make(map[string]CastPropertyName)
+	}
+	if len(d.names) < maxInternedNames {
+		d.names[string(name)] = name
+	}
+	return name
+}
+
+// Read reads from the underlying reader and advances the offset
+func (d *decoder) Read(p []byte) (int, error) {
+	if d.limits.MaxBytes > 0 {
+		remaining := d.limits.MaxBytes - d.offset
+		if remaining <= 0 && len(p) > 0 {
+			return 0, fmt.Errorf("%w: more than %d bytes", ErrLimitExceeded, d.limits.MaxBytes)
+		}
+		p = p[:min(int64(len(p)), remaining)]
+	}
+
+	n, err := d.r.Read(p)
+	d.offset += int64(n)
+	return n, err
+}
+
+// error wraps the given error with the current path, the given property name and offset
+func (d *decoder) error(offset int64, property CastPropertyName, err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return &LoadError{
+		Path:     d.pathString(),
+		Property: property,
+		Offset:   offset,
+		Err:      err,
+	}
+}
+
+// warn records a warning for the current path
+func (d *decoder) warn(kind WarningKind, offset int64, property CastPropertyName, format string, args ...any) {
+	d.warnings = append(d.warnings, Warning{
+		Kind:     kind,
+		Path:     d.pathString(),
+		Property: property,
+		Offset:   offset,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// newNode creates a node from the given header, from the arena if there is one
+func (d *decoder) newNode(header castNodeHeader) *CastNode {
+	if d.arena != nil {
+		return d.arena.newNode(header)
+	}
+
+	return &CastNode{
+		id:         header.Id,
+		hash:       header.NodeHash,
+		properties: make([]iCastProperty, 0, capacity(header.PropertyCount)),
+		childNodes: make([]*CastNode, 0, capacity(header.ChildCount)),
+	}
+}
+
+// newProperty creates a property with the given type, name and size, from the arena if there is one
+func (d *decoder) newProperty(id CastPropertyId, name CastPropertyName, size uint32) (iCastProperty, error) {
+	if d.arena != nil {
+		return d.arena.newProperty(id, name, size)
+	}
+	return newCastProperty(id, name, size)
+}
+
+// releaseArena releases the arena if loading fails before the file is created
+func (d *decoder) releaseArena() {
+	if d.arena != nil {
+		d.arena.release()
+		d.arena = nil
+	}
+}
+
+// readHeader reads a header of the given size into the scratch buffer and decodes it
+func (d *decoder) readHeader(size int, decode func(b []byte)) error {
+	b := d.scratch[:size]
+	if _, err := io.ReadFull(d, b); err != nil {
+		return err
+	}
+	decode(b)
+	return nil
+}
+
+// ReadByte reads a single byte from the underlying reader and advances the offset
+func (d *decoder) ReadByte() (byte, error) {
+	if d.br == nil || d.limits.MaxBytes > 0 {
+		var b [1]byte
+		_, err := io.ReadFull(d, b[:])
+		return b[0], err
+	}
+
+	b, err := d.br.ReadByte()
+	if err == nil {
+		d.offset++
+	}
+	return b, err
+}
+
+// skip discards the given number of bytes, seeking past them if the underlying reader is an [io.Seeker]
+func (d *decoder) skip(n int64) error {
+	if d.seekEnd == 0 {
+		end, err := d.streamEnd()
+		if err != nil {
+			return err
+		}
+		d.seekEnd = end
+	}
+
+	if d.seekEnd > 0 && d.offset+n <= d.seekEnd {
+		if _, err := d.r.(io.Seeker).Seek(n, io.SeekCurrent); err != nil {
+			return err
+		}
+		d.offset += n
+		return nil
+	}
+
+	_, err := io.CopyN(io.Discard, d, n)
+	return err
+}
+
+// skipString discards a null terminated string
+func (d *decoder) skipString() error {
+	for {
+		b, err := d.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if b == 0 {
+			return nil
+		}
+	}
+}
+
+// streamEnd returns the offset of the end of the underlying reader if it is an [io.Seeker], -1 otherwise.
+// Readers limited by [LoadLimits.MaxBytes] are never seeked, so the limit applies to skipped data as well.
+func (d *decoder) streamEnd() (int64, error) {
+	s, ok := d.r.(io.Seeker)
+	if !ok || d.limits.MaxBytes > 0 {
+		return -1, nil
+	}
+
+	current, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1, nil
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1, nil
+	}
+	if _, err := s.Seek(current, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return d.offset + end - current, nil
+}
+
+// decodeFile decodes a cast file
+func (d *decoder) decodeFile() (*CastFile, error) {
+	var header castHeader
+	if err := d.readHeader(castHeaderSize, header.decode); err != nil {
+		d.releaseArena()
+		return nil, d.error(0, "", err)
+	}
+
+	if header.Magic != castMagic {
+		d.releaseArena()
+		return nil, d.error(0, "", fmt.Errorf("invalid cast file magic: %#x", header.Magic))
+	}
+
+	castFile := &CastFile{
+		flags:         header.Flags,
+		version:       header.Version,
+		rootNodes:     make([]*CastNode, 0, capacity(header.RootNodes)),
+		hashGenerator: NewSequentialHashGenerator(castHashBase),
+		arena:         d.arena,
+	}
+
+	siblings := d.siblingCounts(0)
+	for range header.RootNodes {
+		root, err := d.decodeNode(siblings, -1)
+		if root != nil {
+			castFile.rootNodes = append(castFile.rootNodes, root)
+			castFile.adopt(root)
+		}
+		if err != nil {
+			castFile.warnings = d.warnings
+			return castFile, err
+		}
+	}
+
+	castFile.warnings = d.warnings
+	return castFile, nil
+}
+
+// decodeNode decodes a node and its descendants. The siblings map counts the already decoded
+// siblings of the node per type, it is used to build the path of the node. The parent end is the
+// offset at which the parent node ends, -1 for root nodes. On failure the node is returned with the
+// properties and children decoded so far, unless its header could not be read.
+func (d *decoder) decodeNode(siblings map[CastNodeId]int, parentEnd int64) (*CastNode, error) {
+	start := d.offset
+	if err := d.checkNode(); err != nil {
+		return nil, d.error(start, "", err)
+	}
+
+	var header castNodeHeader
+	if err := d.readHeader(castNodeHeaderSize, header.decode); err != nil {
+		return nil, d.error(start, "", err)
+	}
+	end := start + int64(header.NodeSize)
+
+	d.path = append(d.path, pathElement{header.Id, siblings[header.Id]})
+	siblings[header.Id]++
+	defer func() {
+		d.path = d.path[:len(d.path)-1]
+	}()
+
+	if d.strict {
+		if header.NodeSize < castNodeHeaderSize {
+			return nil, d.error(start, "", fmt.Errorf("node size %d is smaller than its header", header.NodeSize))
+		}
+		if parentEnd >= 0 && end > parentEnd {
+			return nil, d.error(start, "", fmt.Errorf("node exceeds its parent by %d bytes", end-parentEnd))
+		}
+	}
+
+	if d.filter != nil && !d.matched {
+		if d.filter[header.Id] {
+			d.matched = true
+			defer func() {
+				d.matched = false
+			}()
+		} else {
+			return d.decodeAncestor(header, start, end, parentEnd < 0)
+		}
+	}
+
+	n := d.newNode(header)
+
+	for range header.PropertyCount {
+		property, err := d.decodeProperty(end)
+		if err != nil {
+			if d.resync {
+				return n, d.resyncNode(end, err)
+			}
+			return n, err
+		}
+
+		// properties with duplicate names are kept, so the file is written unchanged
+		n.properties = append(n.properties, property)
+	}
+
+	// mismatching UV layer counts crash importers, they are reported but kept so the file round trips
+	if mesh := AsMesh(n); mesh != nil {
+		if err := mesh.checkUVLayerCount(); err != nil {
+			d.warn(WarningUVLayerCount, start, PropNameUVLayerCount, "%v", err)
+		}
+	}
+
+	children := d.siblingCounts(len(d.path))
+	for range header.ChildCount {
+		child, err := d.decodeNode(children, end)
+		if child != nil {
+			child.setParentNode(n)
+			n.childNodes = append(n.childNodes, child)
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if d.resync && d.offset != end {
+		return n, d.resyncNode(end, nil)
+	}
+
+	if d.strict && d.offset != end {
+		return n, d.error(start, "", fmt.Errorf("node data spans %d bytes instead of %d", d.offset-start, header.NodeSize))
+	}
+
+	return n, nil
+}
+
+// decodeAncestor decodes a node not passing the filter of [WithNodeFilter] whose header starting at the given
+// offset was read. The node is skipped, unless it may contain nodes passing the filter. Then only its children
+// are decoded and the node is returned if it is a root node or holds any of them.
+func (d *decoder) decodeAncestor(header castNodeHeader, start, end int64, root bool) (*CastNode, error) {
+	if !root && !d.mayContainMatch(header.Id) {
+		if header.NodeSize < castNodeHeaderSize {
+			return nil, d.error(start, "", fmt.Errorf("node size %d is smaller than its header", header.NodeSize))
+		}
+		if err := d.skip(end - d.offset); err != nil {
+			return nil, d.error(start, "", err)
+		}
+		return nil, nil
+	}
+
+	n := d.newNode(header)
+	for range header.PropertyCount {
+		if _, _, err := d.skipProperty(end); err != nil {
+			return nil, err
+		}
+	}
+
+	children := d.siblingCounts(len(d.path))
+	for range header.ChildCount {
+		child, err := d.decodeNode(children, end)
+		if child != nil {
+			child.setParentNode(n)
+			n.childNodes = append(n.childNodes, child)
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if !root && len(n.childNodes) == 0 {
+		return nil, nil
+	}
+	return n, nil
+}
+
+// skipProperty skips a property of the node ending at the given offset without decoding its values and
+// returns its name, along with the value if it is a string property holding the name of the node
+func (d *decoder) skipProperty(end int64) (CastPropertyName, string, error) {
+	start := d.offset
+
+	var header castPropertyHeader
+	if err := d.readHeader(castPropertyHeaderSize, header.decode); err != nil {
+		return "", "", d.error(start, "", err)
+	}
+
+	b := d.buffer(int(header.NameSize))
+	if _, err := io.ReadFull(d, b); err != nil {
+		return "", "", d.error(start, "", err)
+	}
+	name := d.internName(b)
+
+	var value string
+	var err error
+	switch size := propertyValueSize(header.Id); {
+	case header.Id == PropString && name == PropNameName:
+		value, err = readString(d)
+	case header.Id == PropString:
+		err = d.skipString()
+	case size == 0:
+		err = fmt.Errorf("cast: invalid property id: %#x", header.Id)
+	default:
+		if err = d.checkValues(header.Id, header.ArrayLength, end); err == nil {
+			err = d.skip(size * int64(header.ArrayLength))
+		}
+	}
+	if err != nil {
+		return "", "", d.error(start, name, err)
+	}
+	return name, value, nil
+}
+
+// checkNode returns an error if decoding another node would exceed the limits or the context is done
+func (d *decoder) checkNode() error {
+	if d.ctx != nil {
+		if err := d.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if !d.deadline.IsZero() && time.Now().After(d.deadline) {
+		return context.DeadlineExceeded
+	}
+
+	d.nodes++
+	if d.limits.MaxNodes > 0 && d.nodes > d.limits.MaxNodes {
+		return fmt.Errorf("%w: more than %d nodes", ErrLimitExceeded, d.limits.MaxNodes)
+	}
+	if d.limits.MaxDepth > 0 && len(d.path) >= d.limits.MaxDepth {
+		return fmt.Errorf("%w: more than %d levels of nodes", ErrLimitExceeded, d.limits.MaxDepth)
+	}
+	return nil
+}
+
+// checkValues returns an error if the given number of values of a property with the given id would exceed
+// the limits or, with strict sizes, the node ending at the given offset
+func (d *decoder) checkValues(id CastPropertyId, count uint32, end int64) error {
+	if d.limits.MaxValues > 0 && int64(count) > int64(d.limits.MaxValues) {
+		return fmt.Errorf("%w: %d values exceed the maximum of %d", ErrLimitExceeded, count, d.limits.MaxValues)
+	}
+
+	size := propertyValueSize(id) * int64(count)
+	if d.limits.MaxBytes > 0 && d.offset+size > d.limits.MaxBytes {
+		return fmt.Errorf("%w: more than %d bytes", ErrLimitExceeded, d.limits.MaxBytes)
+	}
+	if d.strict && d.offset+size > end {
+		return fmt.Errorf("property data exceeds its node by %d bytes", d.offset+size-end)
+	}
+	return nil
+}
+
+// capacity returns the capacity to preallocate for the given number of elements read from a header, which
+// is bounded so that corrupt counts do not cause huge allocations
+func capacity(count uint32) int {
+	return int(min(count, 1024))
+}
+
+// resyncNode skips the remaining data of the current node ending at the given offset. The error that
+// caused the resync, if any, is recorded as a skipped property.
+func (d *decoder) resyncNode(end int64, cause error) error {
+	if le, ok := cause.(*LoadError); ok {
+		if le.Err == io.ErrUnexpectedEOF || le.Property == "" {
+			return cause
+		}
+		d.warn(WarningPropertySkipped, le.Offset, le.Property, "%v", le.Err)
+	}
+
+	if d.offset > end {
+		d.warn(WarningNodeSizeMismatch, d.offset, "", "node data exceeds its size by %d bytes", d.offset-end)
+		return nil
+	}
+
+	d.warn(WarningNodeResynced, d.offset, "", "skipped %d bytes", end-d.offset)
+	if err := d.skip(end - d.offset); err != nil {
+		return d.error(d.offset, "", err)
+	}
+	return nil
+}
+
+// decodeProperty decodes a property of the node ending at the given offset
+func (d *decoder) decodeProperty(end int64) (iCastProperty, error) {
+	start := d.offset
+
+	var header castPropertyHeader
+	if err := d.readHeader(castPropertyHeaderSize, header.decode); err != nil {
+		return nil, d.error(start, "", err)
+	}
+
+	b := d.buffer(int(header.NameSize))
+	if _, err := io.ReadFull(d, b); err != nil {
+		return nil, d.error(start, "", err)
+	}
+	name := d.internName(b)
+
+	var clamped int64
+	if size := propertyValueSize(header.Id); d.clamp && size > 0 {
+		available := max(end-d.offset, 0) / size
+		if int64(header.ArrayLength) > available {
+			d.warn(WarningBufferClamped, start, name, "clamped %d values to %d", header.ArrayLength, available)
+			clamped = end - d.offset - available*size
+			header.ArrayLength = uint32(available)
+		}
+	}
+
+	if header.Id == PropString {
+		// a string property holds a single null terminated string regardless of its array length
+		header.ArrayLength = 1
+	}
+
+	if err := d.checkValues(header.Id, header.ArrayLength, end); err != nil {
+		return nil, d.error(start, name, err)
+	}
+
+	// large buffers are kept encoded until they are accessed with lazy values enabled
+	size := propertyValueSize(header.Id)
+	lazy := d.lazy && size > 0 && size*int64(header.ArrayLength) >= lazyMinSize
+
+	// large buffers are allocated as their data is read, so corrupt counts do not exhaust the memory
+	count := header.ArrayLength
+	if lazy {
+		count = 0
+	} else if size > 0 && size*int64(count) > maxPreallocatedSize {
+		count = uint32(maxPreallocatedSize / size)
+	}
+
+	property, err := d.newProperty(header.Id, name, count)
+	if err != nil {
+		return nil, d.error(start, name, err)
+	}
+
+	if lazy {
+		err = property.loadLazy(d, int(header.ArrayLength))
+	} else {
+		err = property.load(d, int(header.ArrayLength), d.buffer(codecChunkSize))
+	}
+	if err != nil {
+		return nil, d.error(start, name, err)
+	}
+
+	if clamped > 0 {
+		if err := d.skip(clamped); err != nil {
+			return nil, d.error(start, name, err)
+		}
+	}
+
+	return property, nil
+}
+
+// propertyValueSize returns the size of a single value of a property with the given id, or 0 if the
+// values have no fixed size
+func propertyValueSize(id CastPropertyId) int64 {
+	switch id {
+	case PropByte:
+		return 1
+	case PropShort:
+		return 2
+	case PropInteger32, PropFloat:
+		return 4
+	case PropInteger64, PropDouble, PropVector2:
+		return 8
+	case PropVector3:
+		return 12
+	case PropVector4:
+		return 16
+	default:
+		return 0
+	}
+}
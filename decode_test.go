@@ -0,0 +1,579 @@
+package cast
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadError(t *testing.T) {
+	data, err := os.ReadFile("testdata/cube.cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := Load(bytes.NewReader(data[:len(data)-4]))
+
+		var loadErr *LoadError
+		if !errors.As(err, &loadErr) {
+			t.Fatalf("expected *LoadError, got %v", err)
+		}
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected io.ErrUnexpectedEOF, got %v", loadErr.Err)
+		}
+		if !strings.HasPrefix(loadErr.Path, "root[0]/") {
+			t.Errorf("unexpected path %q", loadErr.Path)
+		}
+		if loadErr.Property == "" {
+			t.Errorf("expected property name in %v", err)
+		}
+		if loadErr.Offset <= 0 || loadErr.Offset >= int64(len(data)) {
+			t.Errorf("unexpected offset %d", loadErr.Offset)
+		}
+	})
+
+	t.Run("magic", func(t *testing.T) {
+		corrupt := bytes.Clone(data)
+		corrupt[0] = 0
+		_, err := Load(bytes.NewReader(corrupt))
+
+		var loadErr *LoadError
+		if !errors.As(err, &loadErr) {
+			t.Fatalf("expected *LoadError, got %v", err)
+		}
+		assertEqual(t, loadErr.Path, "")
+		assertEqual(t, loadErr.Offset, 0)
+	})
+
+	t.Run("message", func(t *testing.T) {
+		err := &LoadError{Path: "root[0]/modl[0]/mesh[2]", Property: PropNameVertexPositionBuffer, Offset: 1234, Err: io.ErrUnexpectedEOF}
+		assertEqual(t, err.Error(), `cast: load root[0]/modl[0]/mesh[2] property "vp" at offset 1234: unexpected EOF`)
+	})
+}
+
+func TestLoadPartial(t *testing.T) {
+	data, err := os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	total := len(full.Find(Any()))
+
+	castFile, err := LoadPartial(bytes.NewReader(data[:len(data)/2]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if castFile == nil {
+		t.Fatal("expected partial file")
+	}
+	assertEqual(t, len(castFile.Roots()), 1)
+
+	partial := len(castFile.Find(Any()))
+	if partial == 0 || partial >= total {
+		t.Errorf("expected a partial tree, got %d of %d nodes", partial, total)
+	}
+	for _, n := range castFile.Find(Any()) {
+		if n.hash != castFile.Roots()[0].hash && n.parentNode == nil {
+			t.Errorf("node %v is not attached", n.id)
+		}
+	}
+
+	castFile, err = Load(bytes.NewReader(data[:len(data)/2]))
+	if err == nil || castFile != nil {
+		t.Errorf("expected Load to fail without a file, got %v", err)
+	}
+}
+
+// corruptTestFile writes a file holding a mesh with a position buffer followed by a bone and passes the
+// data and the offset of the position buffer header to corrupt
+func corruptTestFile(t testing.TB, corrupt func(data []byte, offset int)) []byte {
+	t.Helper()
+	castFile := New()
+	root := castFile.CreateRoot()
+	mesh := root.CreateChild(NodeIdMesh)
+	if _, err := CreateProperty(mesh, PropNameVertexPositionBuffer, PropVector3, Vec3{1, 2, 3}, Vec3{4, 5, 6}); err != nil {
+		t.Fatal(err)
+	}
+	root.CreateChild(NodeIdBone)
+
+	var buf bytes.Buffer
+	if err := castFile.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	corrupt(data, bytes.Index(data, []byte(PropNameVertexPositionBuffer))-8)
+	return data
+}
+
+func TestLoadLenient(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		for _, f := range []string{"cube.cast", "cast_ik.cast", "pilot_medium_bangalore_LOD0.cast"} {
+			r, err := os.Open("testdata/" + f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			castFile, err := Load(r, WithResyncNodes(), WithClampBuffers())
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, len(castFile.Warnings()), 0)
+		}
+	})
+
+	t.Run("resync", func(t *testing.T) {
+		data := corruptTestFile(t, func(data []byte, offset int) {
+			binary.LittleEndian.PutUint16(data[offset:], 0x7a7a)
+		})
+
+		if _, err := Load(bytes.NewReader(data)); err == nil {
+			t.Fatal("expected error without lenient options")
+		}
+
+		castFile, err := Load(bytes.NewReader(data), WithResyncNodes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		warnings := castFile.Warnings()
+		assertEqual(t, len(warnings), 2)
+		assertEqual(t, warnings[0].Kind, WarningPropertySkipped)
+		assertEqual(t, warnings[0].Path, "root[0]/mesh[0]")
+		assertEqual(t, warnings[0].Property, PropNameVertexPositionBuffer)
+		assertEqual(t, warnings[1].Kind, WarningNodeResynced)
+
+		assertEqual(t, len(castFile.Find(ByType(NodeIdMesh))), 1)
+		assertEqual(t, len(castFile.Find(ByType(NodeIdBone))), 1)
+		_, ok := castFile.Find(ByType(NodeIdMesh))[0].GetProperty(PropNameVertexPositionBuffer)
+		assertEqual(t, ok, false)
+	})
+
+	t.Run("clamp", func(t *testing.T) {
+		data := corruptTestFile(t, func(data []byte, offset int) {
+			binary.LittleEndian.PutUint32(data[offset+4:], 100)
+		})
+
+		castFile, err := Load(bytes.NewReader(data), WithClampBuffers())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		warnings := castFile.Warnings()
+		assertEqual(t, len(warnings), 1)
+		assertEqual(t, warnings[0].Kind, WarningBufferClamped)
+		assertEqual(t, warnings[0].String(), `buffer clamped: root[0]/mesh[0] property "vp" at offset 64: clamped 100 values to 2`)
+
+		values, err := GetPropertyValues[Vec3](castFile.Find(ByType(NodeIdMesh))[0], PropNameVertexPositionBuffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, len(values), 2)
+		assertEqual(t, len(castFile.Find(ByType(NodeIdBone))), 1)
+	})
+}
+
+func TestLoadUVLayerCount(t *testing.T) {
+	castFile := New()
+	mesh := AsMesh(castFile.CreateRoot().CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, uvLayerName(0), PropVector2, Vec2{})
+	CreateProperty(mesh.CastNode, PropNameUVLayerCount, PropByte, byte(2))
+
+	data := must(castFile.MarshalBinary())
+	loaded := must(Load(bytes.NewReader(data)))
+	assertEqual(t, len(loaded.Warnings()), 1)
+	assertEqual(t, loaded.Warnings()[0].Kind, WarningUVLayerCount)
+	assertEqual(t, loaded.Warnings()[0].Path, "root[0]/mesh[0]")
+	assertEqual(t, bytes.Equal(must(loaded.MarshalBinary()), data), true)
+
+	must(mesh.SyncUVLayerCount())
+	assertEqual(t, len(must(Load(bytes.NewReader(must(castFile.MarshalBinary())))).Warnings()), 0)
+}
+
+func TestLoadLimits(t *testing.T) {
+	data, err := os.ReadFile("testdata/cube.cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("safe", func(t *testing.T) {
+		for _, f := range []string{"cube.cast", "cast_constraints.cast", "cast_ik.cast", "pilot_medium_bangalore_LOD0.cast"} {
+			r, err := os.Open("testdata/" + f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if _, err := Load(r, SafeLoadProfile); err != nil {
+				t.Errorf("%s: %v", f, err)
+			}
+		}
+	})
+
+	for name, limits := range map[string]LoadLimits{
+		"bytes":  {MaxBytes: int64(len(data) / 2)},
+		"depth":  {MaxDepth: 1},
+		"nodes":  {MaxNodes: 2},
+		"values": {MaxValues: 4},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(bytes.NewReader(data), WithLimits(limits))
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("expected ErrLimitExceeded, got %v", err)
+			}
+		})
+	}
+
+	t.Run("length", func(t *testing.T) {
+		data := corruptTestFile(t, func(data []byte, offset int) {
+			binary.LittleEndian.PutUint32(data[offset+4:], 0xFFFFFFFF)
+		})
+
+		_, err := Load(bytes.NewReader(data), SafeLoadProfile)
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("expected ErrLimitExceeded, got %v", err)
+		}
+
+		_, err = Load(bytes.NewReader(data), WithStrictSizes())
+		var loadErr *LoadError
+		if !errors.As(err, &loadErr) {
+			t.Fatalf("expected *LoadError, got %v", err)
+		}
+		assertEqual(t, loadErr.Property, PropNameVertexPositionBuffer)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		data := corruptTestFile(t, func(data []byte, offset int) {
+			// shrink the size of the mesh node, its property now exceeds it
+			size := binary.LittleEndian.Uint32(data[offset-20:])
+			binary.LittleEndian.PutUint32(data[offset-20:], size-4)
+		})
+
+		if _, err := Load(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(bytes.NewReader(data), WithStrictSizes()); err == nil {
+			t.Error("expected error with strict sizes")
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := Load(bytes.NewReader(data), WithContext(ctx)); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if _, err := Load(bytes.NewReader(data), WithTimeout(-time.Second)); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestLoadLargeCounts(t *testing.T) {
+	// a corrupt count far beyond the data fails without allocating its size
+	for _, opts := range [][]LoadOption{nil, {WithLazyValues()}} {
+		data := corruptTestFile(t, func(data []byte, offset int) {
+			binary.LittleEndian.PutUint32(data[offset+4:], 0xFFFFFFF0)
+		})
+		if _, err := Load(bytes.NewReader(data), opts...); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+		}
+	}
+
+	// buffers exceeding the preallocated size are read completely
+	castFile := New()
+	values := make([]Vec3, 3*maxPreallocatedSize/12+5)
+	for i := range values {
+		values[i] = Vec3{float32(i), 1, 2}
+	}
+	CreateProperty(castFile.CreateRoot(), PropNameVertexPositionBuffer, PropVector3, values...)
+	data := must(castFile.MarshalBinary())
+
+	loaded := must(Load(bytes.NewReader(data)))
+	got := must(GetPropertyValues[Vec3](loaded.Roots()[0], PropNameVertexPositionBuffer))
+	assertEqual(t, len(got), len(values))
+	assertEqual(t, got[len(got)-1], values[len(values)-1])
+	assertEqual(t, got[maxPreallocatedSize/12], values[maxPreallocatedSize/12])
+}
+
+func FuzzLoad(f *testing.F) {
+	data, err := os.ReadFile("testdata/cube.cast")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		castFile, err := Load(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		// whatever loads must survive a round trip unchanged
+		var buf bytes.Buffer
+		if err := castFile.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		reloaded, err := Load(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again bytes.Buffer
+		if err := reloaded.Write(&again); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again.Bytes(), buf.Bytes()) {
+			t.Error("output differs after a round trip")
+		}
+	})
+}
+
+func FuzzSafeLoad(f *testing.F) {
+	data, err := os.ReadFile("testdata/cube.cast")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		castFile, err := Load(bytes.NewReader(data), SafeLoadProfile)
+		if err != nil {
+			return
+		}
+		if err := castFile.Write(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestDecoder(t *testing.T) {
+	dec := NewDecoder(WithLimits(LoadLimits{MaxNodes: 10000}))
+	for _, f := range []string{"cube.cast", "cast_ik.cast", "cube.cast"} {
+		data, err := os.ReadFile("testdata/" + f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want bytes.Buffer
+		if err := must(Load(bytes.NewReader(data))).Write(&want); err != nil {
+			t.Fatal(err)
+		}
+
+		castFile, err := dec.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got bytes.Buffer
+		if err := castFile.Write(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("%s: output differs when decoded with a reused decoder", f)
+		}
+
+		// a failed decode does not affect the next one
+		if _, err := dec.Decode(bytes.NewReader(data[:len(data)/2])); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+		}
+	}
+
+	data := corruptTestFile(t, func(data []byte, offset int) {
+		binary.LittleEndian.PutUint32(data[offset+4:], 100)
+	})
+	dec = NewDecoder(WithClampBuffers())
+	for range 2 {
+		castFile, err := dec.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, len(castFile.Warnings()), 1)
+		assertEqual(t, castFile.Warnings()[0].Path, "root[0]/mesh[0]")
+	}
+}
+
+func TestLazyValues(t *testing.T) {
+	for _, f := range []string{"cube.cast", "cast_ik.cast", "pilot_medium_bangalore_LOD0.cast"} {
+		data, err := os.ReadFile("testdata/" + f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want bytes.Buffer
+		if err := must(Load(bytes.NewReader(data))).Write(&want); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, opts := range [][]LoadOption{{WithLazyValues()}, {WithLazyValues(), WithArena()}} {
+			var got bytes.Buffer
+			if err := must(Load(bytes.NewReader(data), opts...)).Write(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("%s: output differs when loaded with lazy values", f)
+			}
+		}
+	}
+
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	lazyFile := must(Load(bytes.NewReader(must(os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast"))), WithLazyValues()))
+	mesh := castFile.GetNodesOfType(NodeIdMesh)[0]
+	lazyMesh := lazyFile.GetNodesOfType(NodeIdMesh)[0]
+
+	want := must(GetPropertyValues[Vec3](mesh, PropNameVertexPositionBuffer))
+	p, _ := lazyMesh.GetProperty(PropNameVertexPositionBuffer)
+	vp := p.(*CastProperty[Vec3])
+	assertEqual(t, vp.lazy != nil, true)
+	assertEqual(t, vp.Count(), len(want))
+	assertEqual(t, vp.values == nil, true)
+
+	// concurrent readers decode the values once
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assertEqual(t, vp.ValueAt(len(want)-1), want[len(want)-1])
+		}()
+	}
+	wg.Wait()
+	assertEqual(t, len(vp.GetValues()), len(want))
+	assertEqual(t, vp.lazy.raw == nil, true)
+
+	vp.SetValues(Vec3{1, 2, 3})
+	assertEqual(t, vp.lazy == nil, true)
+	assertEqual(t, vp.Count(), 1)
+
+	// small buffers are decoded right away
+	if p, ok := lazyMesh.GetProperty(PropNameName); ok {
+		assertEqual(t, p.(*CastProperty[string]).lazy == nil, true)
+	}
+}
+
+// TestLazyValuesConcurrentReaders is meant to be run with -race, sizing and writing a lazy property must not
+// race with decoding its values
+func TestLazyValuesConcurrentReaders(t *testing.T) {
+	lazyFile := must(Load(bytes.NewReader(must(os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast"))), WithLazyValues()))
+	mesh := lazyFile.GetNodesOfType(NodeIdMesh)[0]
+	p, _ := mesh.GetProperty(PropNameVertexPositionBuffer)
+	vp := p.(*CastProperty[Vec3])
+	assertEqual(t, vp.lazy != nil, true)
+	size := vp.Size()
+
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch i % 3 {
+			case 0:
+				assertEqual(t, vp.Size(), size)
+			case 1:
+				if err := vp.write(io.Discard); err != nil {
+					t.Error(err)
+				}
+			default:
+				assertEqual(t, len(must(GetPropertyValues[Vec3](mesh, PropNameVertexPositionBuffer))), vp.Count())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNodeFilter(t *testing.T) {
+	data := must(os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast"))
+	castFile := must(Load(bytes.NewReader(data)))
+
+	filtered := must(Load(bytes.NewReader(data), WithNodeFilter(NodeIdSkeleton)))
+	assertEqual(t, len(filtered.GetNodesOfType(NodeIdMesh)), 0)
+	assertEqual(t, len(filtered.GetNodesOfType(NodeIdMaterial)), 0)
+	assertEqual(t, len(filtered.GetNodesOfType(NodeIdBone)), len(castFile.GetNodesOfType(NodeIdBone)))
+
+	// ancestors are kept without their properties
+	model := filtered.GetNodesOfType(NodeIdModel)[0]
+	assertEqual(t, len(model.properties), 0)
+	assertEqual(t, len(model.childNodes), 1)
+	assertEqual(t, model.childNodes[0].id, NodeIdSkeleton)
+	assertEqual(t, model.childNodes[0].parentNode, model)
+
+	skeleton := filtered.GetNodesOfType(NodeIdSkeleton)[0]
+	want := castFile.GetNodesOfType(NodeIdSkeleton)[0]
+	assertEqual(t, skeleton.hash, want.hash)
+	assertEqual(t, diffProperties(skeleton, want, DiffOptions{}) == nil, true)
+
+	// nodes following skipped siblings are decoded
+	filtered = must(Load(bytes.NewReader(data), WithNodeFilter(NodeIdMesh, NodeIdMaterial)))
+	assertEqual(t, len(filtered.GetNodesOfType(NodeIdSkeleton)), 0)
+	meshes, wantMeshes := filtered.GetNodesOfType(NodeIdMesh), castFile.GetNodesOfType(NodeIdMesh)
+	assertEqual(t, len(meshes), len(wantMeshes))
+	for i := range meshes {
+		assertEqual(t, diffProperties(meshes[i], wantMeshes[i], DiffOptions{}) == nil, true)
+	}
+	assertEqual(t, len(filtered.GetNodesOfType(NodeIdFile)), len(castFile.GetNodesOfType(NodeIdFile)))
+
+	// roots are kept without any matching node
+	filtered = must(Load(bytes.NewReader(data), WithNodeFilter(NodeIdAnimation)))
+	assertEqual(t, len(filtered.Roots()), len(castFile.Roots()))
+	for _, root := range filtered.Roots() {
+		assertEqual(t, len(root.childNodes), 0)
+	}
+
+	// skipping past the end of the data fails
+	_, err := Load(bytes.NewReader(data[:len(data)-1]), WithNodeFilter(NodeIdAnimation))
+	assertEqual(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+}
+
+func BenchmarkLoadNodeFilter(b *testing.B) {
+	data, err := os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		if _, err := Load(bytes.NewReader(data), WithNodeFilter(NodeIdSkeleton)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadLazy(b *testing.B) {
+	data, err := os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		if _, err := Load(bytes.NewReader(data), WithLazyValues()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	data, err := os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	dec := NewDecoder(WithArena())
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		castFile, err := dec.Decode(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		castFile.Release()
+	}
+}
@@ -0,0 +1,156 @@
+package cast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ----------------------- //
+//         DEDUPE          //
+// ----------------------- //
+
+// Deduplicate removes the Material and File nodes that are identical by content to an earlier node of the same
+// type, comparing their properties and descendants while ignoring the node hashes. The File children of
+// materials are only removed along with a duplicate material, so every surviving material keeps owning its
+// files. Every Integer64 property referencing a removed node or one of its descendants is rewritten to the
+// corresponding surviving node, so meshes keep their materials. Returns the number of removed nodes.
+func (n *CastFile) Deduplicate() int {
+	return n.deduplicate(NodeIdMaterial, NodeIdFile)
+}
+
+// deduplicate removes the nodes of the given types that are identical by content to an earlier node of the same
+// type in depth-first order and rewrites the references to the removed nodes and their descendants to the
+// corresponding surviving nodes. Returns the number of removed nodes.
+func (n *CastFile) deduplicate(ids ...CastNodeId) int {
+	remap := make(map[uint64]uint64)
+	removed := 0
+
+	for _, id := range ids {
+		survivors := make(map[string]*CastNode)
+		for _, node := range n.GetNodesOfType(id) {
+			// files of surviving materials stay owned by them
+			if id == NodeIdFile && node.parentNode != nil && node.parentNode.id == NodeIdMaterial {
+				continue
+			}
+
+			key := string(canonicalContent(node))
+			survivor, ok := survivors[key]
+			if !ok {
+				survivors[key] = node
+				continue
+			}
+
+			// nodes are matched in depth-first order, so their descendants line up
+			nodes, survivorNodes := slices.Collect(node.all()), slices.Collect(survivor.all())
+			for i, c := range nodes {
+				remap[c.hash] = survivorNodes[i].hash
+			}
+
+			node.detach()
+			for c := range node.all() {
+				c.file = nil
+			}
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		resolveRemap(remap)
+		for node := range n.AllNodes() {
+			remapHashReferences(node, remap)
+		}
+		n.invalidateIndex()
+	}
+	return removed
+}
+
+// resolveRemap replaces every target of the remap that is remapped itself, e.g. by a later pass, with the final
+// target of the chain
+func resolveRemap(remap map[uint64]uint64) {
+	for from, to := range remap {
+		for steps := 0; steps < len(remap); steps++ {
+			next, ok := remap[to]
+			if !ok || next == to {
+				break
+			}
+			to = next
+		}
+		remap[from] = to
+	}
+}
+
+// canonicalContent returns an encoding of the content of the node and its descendants that is independent of
+// the node hashes and the order of the properties. Integer64 values referencing nodes within the subtree are
+// encoded by the position of the referenced node, so identical subtrees with different hashes encode equally.
+func canonicalContent(n *CastNode) []byte {
+	local := make(map[uint64]uint32)
+	i := uint32(0)
+	for c := range n.all() {
+		if _, ok := local[c.hash]; !ok {
+			local[c.hash] = i
+		}
+		i++
+	}
+
+	var buf bytes.Buffer
+	writeCanonicalContent(&buf, n, local)
+	return buf.Bytes()
+}
+
+// canonicalWriter is the destination of the canonical content
+type canonicalWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// writeCanonicalContent writes the canonical content of the node and its descendants to the writer. Integer64
+// values found in local are encoded by the position it maps them to. If local is nil, the node hashes are
+// encoded as well and all Integer64 values as they are.
+func writeCanonicalContent(buf canonicalWriter, n *CastNode, local map[uint64]uint32) {
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(n.id)))
+	if local == nil {
+		buf.Write(binary.LittleEndian.AppendUint64(nil, n.hash))
+	}
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(n.properties))))
+
+	properties := slices.SortedFunc(slices.Values(n.properties), func(a, b iCastProperty) int {
+		return strings.Compare(string(a.Name()), string(b.Name()))
+	})
+
+	for _, p := range properties {
+		name := p.Name()
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(p.Id())))
+		buf.WriteString(string(name))
+		buf.WriteByte(0)
+		buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(p.Count())))
+
+		switch p := p.(type) {
+		case *CastProperty[uint64]:
+			for _, v := range p.GetValues() {
+				if i, ok := local[v]; ok {
+					buf.WriteByte('L')
+					buf.Write(binary.LittleEndian.AppendUint32(nil, i))
+				} else {
+					buf.WriteByte('H')
+					buf.Write(binary.LittleEndian.AppendUint64(nil, v))
+				}
+			}
+		case *CastProperty[string]:
+			for _, v := range p.GetValues() {
+				buf.WriteString(v)
+				buf.WriteByte(0)
+			}
+		default:
+			p.write(buf)
+		}
+	}
+
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(n.childNodes))))
+	for _, c := range n.childNodes {
+		writeCanonicalContent(buf, c, local)
+	}
+}
@@ -0,0 +1,85 @@
+package cast
+
+import "testing"
+
+func TestDeduplicate(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	for range 2 {
+		if err := Merge(castFile, loadTestFile(t, "cube.cast")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every material references its own copy of the same texture and every root holds the same file
+	materials := castFile.GetNodesOfType(NodeIdMaterial)
+	assertEqual(t, len(materials), 3)
+	for i, material := range materials {
+		texture := material.CreateChild(NodeIdFile)
+		CreateProperty(texture, PropNamePath, PropString, "albedo.png")
+		AsMaterial(material).SetSlot(PropNameAlbedo, AsFile(texture))
+
+		file := castFile.Roots()[i].CreateChild(NodeIdFile)
+		CreateProperty(file, PropNamePath, PropString, "shared.cast")
+	}
+
+	// the material of the third cube differs and survives
+	SetProperty(materials[2], PropNameName, "changed")
+
+	// the second material and the root files of the later cubes are removed, the third material keeps its
+	// texture although it equals the one of the first material
+	texture := AsMaterial(materials[2]).Slot(PropNameAlbedo)
+	assertEqual(t, castFile.Deduplicate(), 3)
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdMaterial)), 2)
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdFile)), 3)
+
+	meshes := castFile.GetNodesOfType(NodeIdMesh)
+	assertEqual(t, meshes[0].ResolveReference(PropNameMaterial), materials[0])
+	assertEqual(t, meshes[1].ResolveReference(PropNameMaterial), materials[0])
+	assertEqual(t, meshes[2].ResolveReference(PropNameMaterial), materials[2])
+	assertEqual(t, castFile.FindByHash(materials[1].Hash()), nil)
+	assertEqual(t, AsMaterial(materials[2]).Slot(PropNameAlbedo).CastNode, texture.CastNode)
+
+	assertEqual(t, castFile.Deduplicate(), 0)
+}
+
+func TestDeduplicateMaterialFiles(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	var textures []*CastNode
+	for range 2 {
+		material := root.CreateChild(NodeIdMaterial)
+		texture := material.CreateChild(NodeIdFile)
+		CreateProperty(texture, PropNamePath, PropString, "albedo.png")
+		AsMaterial(material).SetSlot(PropNameAlbedo, AsFile(texture))
+		textures = append(textures, texture)
+	}
+	shared := root.CreateChild(NodeIdFile)
+	CreateProperty(shared, PropNamePath, PropString, "albedo.png")
+
+	// an instance references the texture of the duplicate material and the root file
+	instance := root.CreateChild(NodeIdInstance)
+	CreateProperty(instance, PropNameReferenceFile, PropInteger64, textures[1].Hash(), shared.Hash())
+
+	// the duplicate material goes with its texture, the root file survives as it only equals material files
+	assertEqual(t, castFile.Deduplicate(), 1)
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdFile)), 2)
+	assertEqual(t, textures[0].GetParentNode().Id(), NodeIdMaterial)
+	assertEqual(t, shared.GetParentNode(), root)
+
+	references := instance.ResolveReferences(PropNameReferenceFile)
+	assertEqual(t, references[0], textures[0])
+	assertEqual(t, references[1], shared)
+	for _, ref := range references {
+		assertEqual(t, castFile.FindByHash(ref.Hash()), ref)
+	}
+}
+
+func TestDeduplicateRemapChains(t *testing.T) {
+	remap := map[uint64]uint64{1: 2, 2: 3, 3: 4}
+	castFile := New()
+	node := castFile.CreateRoot()
+	CreateProperty(node, "r", PropInteger64, uint64(1), uint64(2), uint64(5))
+	resolveRemap(remap)
+	remapHashReferences(node, remap)
+	assertEqual(t, [3]uint64(must(GetPropertyValues[uint64](node, "r"))), [3]uint64{4, 4, 5})
+}
@@ -0,0 +1,361 @@
+package cast
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ----------------------- //
+//          DIFF           //
+// ----------------------- //
+
+// DiffOptions configures [Diff]
+type DiffOptions struct {
+	// FloatTolerance is the largest absolute difference between two float values, or between the components
+	// of two vectors, that is still considered equal
+	FloatTolerance float64
+}
+
+// DiffKind is the kind of a difference between two files
+type DiffKind int
+
+const (
+	DiffAdded   DiffKind = iota // The node or property only exists in the second file
+	DiffRemoved                 // The node or property only exists in the first file
+	DiffChanged                 // The node or property exists in both files with differing properties or values
+)
+
+// String returns the name of the kind
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("DiffKind(%d)", int(k))
+	}
+}
+
+// symbol returns the prefix of the kind in the textual report
+func (k DiffKind) symbol() byte {
+	switch k {
+	case DiffAdded:
+		return '+'
+	case DiffRemoved:
+		return '-'
+	default:
+		return '~'
+	}
+}
+
+// PropertyDiff describes the difference of a property between two matched nodes
+type PropertyDiff struct {
+	Kind     DiffKind
+	Name     CastPropertyName
+	A, B     iCastProperty // The property in the first and second file, nil if it does not exist there
+	Changed  int           // Changed is the number of differing values, values beyond the shorter buffer included
+	First    int           // First is the index of the first differing value
+	MaxDelta float64       // MaxDelta is the largest difference of the float values exceeding the tolerance
+}
+
+// String returns a description of the difference
+func (d PropertyDiff) String() string {
+	switch {
+	case d.Kind == DiffAdded:
+		return fmt.Sprintf("+ %s (%s, %d values)", d.Name, d.B.Id(), d.B.Count())
+	case d.Kind == DiffRemoved:
+		return fmt.Sprintf("- %s (%s, %d values)", d.Name, d.A.Id(), d.A.Count())
+	case d.A.Id() != d.B.Id():
+		return fmt.Sprintf("~ %s: type %s -> %s", d.Name, d.A.Id(), d.B.Id())
+	}
+
+	s := fmt.Sprintf("~ %s: %d of %d values differ from index %d", d.Name, d.Changed, max(d.A.Count(), d.B.Count()), d.First)
+	if d.A.Count() != d.B.Count() {
+		s += fmt.Sprintf(", count %d -> %d", d.A.Count(), d.B.Count())
+	}
+	if d.MaxDelta > 0 {
+		s += fmt.Sprintf(", max delta %g", d.MaxDelta)
+	}
+	return s
+}
+
+// NodeDiff describes a node that was added, removed or changed between two files
+type NodeDiff struct {
+	Kind       DiffKind
+	Path       string         // Path is the path of the node, e.g. "root[0]/modl[0]/mesh[2]", in the second file for added nodes
+	Id         CastNodeId     // Id is the type of the node
+	Name       string         // Name is the name property of the node, empty if it has none
+	A, B       *CastNode      // The node in the first and second file, nil if it does not exist there
+	Properties []PropertyDiff // Properties holds the property differences of a changed node
+}
+
+// String returns a description of the difference
+func (d NodeDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%c %s", d.Kind.symbol(), d.Path)
+	if d.Name != "" {
+		fmt.Fprintf(&b, " %q", d.Name)
+	}
+	for _, p := range d.Properties {
+		fmt.Fprintf(&b, "\n    %s", p)
+	}
+	return b.String()
+}
+
+// FileDiff holds the differences between two files in depth-first order, see [Diff]
+type FileDiff struct {
+	Nodes []NodeDiff
+}
+
+// Equal reports whether the files have no differences
+func (d *FileDiff) Equal() bool {
+	return len(d.Nodes) == 0
+}
+
+// String returns a readable report of the differences, one node per line followed by its changed properties
+func (d *FileDiff) String() string {
+	lines := make([]string, len(d.Nodes))
+	for i, n := range d.Nodes {
+		lines[i] = n.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares the nodes of two files. Child nodes are matched to the children of the matched parent by type,
+// name and hash, then the remaining ones by type and name in order, so nodes whose hash was reassigned still
+// match. Unmatched nodes are reported as removed or added along with their descendants, matched nodes whose
+// properties differ as changed. Float values, including vector components, are compared with the tolerance of
+// the options; all other values must be equal.
+func Diff(a, b *CastFile, opts DiffOptions) *FileDiff {
+	d := &FileDiff{}
+	d.diffNodes(a.rootNodes, b.rootNodes, "", "", opts)
+	return d
+}
+
+// diffNodeKey identifies a node for matching
+type diffNodeKey struct {
+	id   CastNodeId
+	name string
+	hash uint64
+}
+
+// diffNodes matches the given siblings of both files and records their differences
+func (d *FileDiff) diffNodes(as, bs []*CastNode, pathA, pathB string, opts DiffOptions) {
+	keys := func(nodes []*CastNode, prefix string) ([]diffNodeKey, []string) {
+		keys := make([]diffNodeKey, len(nodes))
+		paths := make([]string, len(nodes))
+		counts := make(map[CastNodeId]int)
+		for i, n := range nodes {
+			keys[i] = diffNodeKey{n.id, contentName(n), n.hash}
+			paths[i] = fmt.Sprintf("%s%s[%d]", prefix, n.id, counts[n.id])
+			counts[n.id]++
+		}
+		return keys, paths
+	}
+	keysA, pathsA := keys(as, pathA)
+	keysB, pathsB := keys(bs, pathB)
+
+	// match by type, name and hash first, then by type and name
+	matches := make([]int, len(as))
+	matchedB := make([]bool, len(bs))
+	for i := range matches {
+		matches[i] = -1
+	}
+	for _, ignoreHash := range []bool{false, true} {
+		candidates := make(map[diffNodeKey][]int)
+		for j, key := range keysB {
+			if !matchedB[j] {
+				if ignoreHash {
+					key.hash = 0
+				}
+				candidates[key] = append(candidates[key], j)
+			}
+		}
+
+		for i, key := range keysA {
+			if matches[i] >= 0 {
+				continue
+			}
+			if ignoreHash {
+				key.hash = 0
+			}
+			if c := candidates[key]; len(c) > 0 {
+				matches[i] = c[0]
+				matchedB[c[0]] = true
+				candidates[key] = c[1:]
+			}
+		}
+	}
+
+	for i, n := range as {
+		j := matches[i]
+		if j < 0 {
+			d.addSubtree(DiffRemoved, n, pathsA[i])
+			continue
+		}
+
+		if properties := diffProperties(n, bs[j], opts); len(properties) > 0 {
+			d.Nodes = append(d.Nodes, NodeDiff{
+				Kind:       DiffChanged,
+				Path:       pathsA[i],
+				Id:         n.id,
+				Name:       keysA[i].name,
+				A:          n,
+				B:          bs[j],
+				Properties: properties,
+			})
+		}
+		d.diffNodes(n.childNodes, bs[j].childNodes, pathsA[i]+"/", pathsB[j]+"/", opts)
+	}
+
+	for j, n := range bs {
+		if !matchedB[j] {
+			d.addSubtree(DiffAdded, n, pathsB[j])
+		}
+	}
+}
+
+// addSubtree records the node with the given path and its descendants as added or removed
+func (d *FileDiff) addSubtree(kind DiffKind, n *CastNode, path string) {
+	diff := NodeDiff{Kind: kind, Path: path, Id: n.id, Name: contentName(n)}
+	if kind == DiffAdded {
+		diff.B = n
+	} else {
+		diff.A = n
+	}
+	d.Nodes = append(d.Nodes, diff)
+
+	counts := make(map[CastNodeId]int)
+	for _, c := range n.childNodes {
+		d.addSubtree(kind, c, fmt.Sprintf("%s/%s[%d]", path, c.id, counts[c.id]))
+		counts[c.id]++
+	}
+}
+
+// diffProperties returns the differences between the properties of two matched nodes. Properties sharing
+// their name are matched in order.
+func diffProperties(a, b *CastNode, opts DiffOptions) []PropertyDiff {
+	var diffs []PropertyDiff
+	occurrences := make(map[CastPropertyName]int)
+	for _, pa := range a.properties {
+		k := occurrences[pa.Name()]
+		occurrences[pa.Name()]++
+
+		pb := nthProperty(b, pa.Name(), k)
+		if pb == nil {
+			diffs = append(diffs, PropertyDiff{Kind: DiffRemoved, Name: pa.Name(), A: pa})
+			continue
+		}
+
+		if diff := diffProperty(pa, pb, opts.FloatTolerance); diff.Changed > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	for _, pb := range b.properties {
+		if occurrences[pb.Name()] > 0 {
+			occurrences[pb.Name()]--
+			continue
+		}
+		diffs = append(diffs, PropertyDiff{Kind: DiffAdded, Name: pb.Name(), B: pb})
+	}
+	return diffs
+}
+
+// nthProperty returns the property with the given name and index among the properties sharing the name,
+// nil if the node has fewer of them
+func nthProperty(n *CastNode, name CastPropertyName, index int) iCastProperty {
+	for _, p := range n.properties {
+		if p.Name() == name {
+			if index == 0 {
+				return p
+			}
+			index--
+		}
+	}
+	return nil
+}
+
+// diffProperty compares the values of two properties with the same name
+func diffProperty(a, b iCastProperty, tolerance float64) PropertyDiff {
+	diff := PropertyDiff{Kind: DiffChanged, Name: a.Name(), A: a, B: b, First: -1}
+	if a.Id() != b.Id() {
+		diff.Changed = max(a.Count(), b.Count(), 1)
+		diff.First = 0
+		return diff
+	}
+
+	switch a.(type) {
+	case *CastProperty[byte]:
+		diffValues[byte](&diff, nil, tolerance)
+	case *CastProperty[uint16]:
+		diffValues[uint16](&diff, nil, tolerance)
+	case *CastProperty[uint32]:
+		diffValues[uint32](&diff, nil, tolerance)
+	case *CastProperty[uint64]:
+		diffValues[uint64](&diff, nil, tolerance)
+	case *CastProperty[string]:
+		diffValues[string](&diff, nil, tolerance)
+	case *CastProperty[float32]:
+		diffValues(&diff, delta[float32], tolerance)
+	case *CastProperty[float64]:
+		diffValues(&diff, delta[float64], tolerance)
+	case *CastProperty[Vec2]:
+		diffValues(&diff, func(x, y Vec2) float64 {
+			return max(delta(x.X, y.X), delta(x.Y, y.Y))
+		}, tolerance)
+	case *CastProperty[Vec3]:
+		diffValues(&diff, func(x, y Vec3) float64 {
+			return max(delta(x.X, y.X), delta(x.Y, y.Y), delta(x.Z, y.Z))
+		}, tolerance)
+	case *CastProperty[Vec4]:
+		diffValues(&diff, func(x, y Vec4) float64 {
+			return max(delta(x.X, y.X), delta(x.Y, y.Y), delta(x.Z, y.Z), delta(x.W, y.W))
+		}, tolerance)
+	}
+	return diff
+}
+
+// delta returns the absolute difference of two float values, 0 if both are NaN
+func delta[F float32 | float64](x, y F) float64 {
+	if x != x && y != y {
+		return 0
+	}
+	return math.Abs(float64(x) - float64(y))
+}
+
+// diffValues counts the differing values of the properties of the diff. Values are equal if they are identical
+// or, when a distance function is given, their distance does not exceed the tolerance.
+func diffValues[T CastPropertyValueType](diff *PropertyDiff, distance func(x, y T) float64, tolerance float64) {
+	a, b := diff.A.(*CastProperty[T]).GetValues(), diff.B.(*CastProperty[T]).GetValues()
+	for i := range min(len(a), len(b)) {
+		if a[i] == b[i] {
+			continue
+		}
+		if distance != nil {
+			d := distance(a[i], b[i])
+			if d <= tolerance {
+				continue
+			}
+			if d > diff.MaxDelta {
+				diff.MaxDelta = d
+			}
+		}
+
+		if diff.First < 0 {
+			diff.First = i
+		}
+		diff.Changed++
+	}
+
+	if len(a) != len(b) {
+		if diff.First < 0 {
+			diff.First = min(len(a), len(b))
+		}
+		diff.Changed += max(len(a), len(b)) - min(len(a), len(b))
+	}
+}
@@ -0,0 +1,89 @@
+package cast
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := loadTestFile(t, "cube.cast")
+	b := a.Clone()
+	assertEqual(t, Diff(a, b, DiffOptions{}).Equal(), true)
+
+	// a reassigned hash still matches by type and name
+	b.RemapHashes()
+	assertEqual(t, Diff(a, b, DiffOptions{}).Equal(), true)
+
+	mesh := b.GetNodesOfType(NodeIdMesh)[0]
+	positions := must(GetPropertyValues[Vec3](mesh, PropNameVertexPositionBuffer))
+	positions[1].X += 0.0001
+	positions[2].Y += 0.5
+
+	diff := Diff(a, b, DiffOptions{FloatTolerance: 0.001})
+	assertEqual(t, len(diff.Nodes), 1)
+	assertEqual(t, diff.Nodes[0].Kind, DiffChanged)
+	assertEqual(t, diff.Nodes[0].B, mesh)
+
+	vp := diff.Nodes[0].Properties[0]
+	assertEqual(t, vp.Name, PropNameVertexPositionBuffer)
+	assertEqual(t, vp.Changed, 1)
+	assertEqual(t, vp.First, 2)
+	if vp.MaxDelta < 0.49 || vp.MaxDelta > 0.51 {
+		t.Errorf("unexpected max delta %v", vp.MaxDelta)
+	}
+	assertEqual(t, Diff(a, b, DiffOptions{}).Nodes[0].Properties[0].Changed, 2)
+
+	// added and removed properties and nodes
+	b = a.Clone()
+	mesh = b.GetNodesOfType(NodeIdMesh)[0]
+	CreateProperty(mesh, "extra", PropInteger32, uint32(1))
+	b.GetNodesOfType(NodeIdMaterial)[0].Remove()
+	added := b.Roots()[0].CreateChild(NodeIdMetadata)
+
+	diff = Diff(a, b, DiffOptions{})
+	kinds := map[DiffKind]int{}
+	for _, n := range diff.Nodes {
+		kinds[n.Kind]++
+		if n.Kind == DiffAdded {
+			assertEqual(t, n.B, added)
+			assertEqual(t, n.Path, "root[0]/meta[0]")
+		}
+	}
+	assertEqual(t, kinds[DiffAdded], 1)
+	assertEqual(t, kinds[DiffChanged], 1)
+	if kinds[DiffRemoved] == 0 {
+		t.Error("expected removed nodes")
+	}
+
+	report := diff.String()
+	for _, s := range []string{"+ root[0]/meta[0]", "+ extra (i, 1 values)", "- root[0]/modl[0]/matl[0]"} {
+		if !strings.Contains(report, s) {
+			t.Errorf("report does not contain %q:\n%s", s, report)
+		}
+	}
+}
+
+func TestDiffProperty(t *testing.T) {
+	a := &CastProperty[uint32]{id: PropInteger32, name: "p", values: []uint32{1, 2, 3}}
+	b := &CastProperty[uint32]{id: PropInteger32, name: "p", values: []uint32{1, 5, 3, 4}}
+	diff := diffProperty(a, b, 10)
+	assertEqual(t, diff.Changed, 2)
+	assertEqual(t, diff.First, 1)
+	assertEqual(t, diff.String(), "~ p: 2 of 4 values differ from index 1, count 3 -> 4")
+
+	c := &CastProperty[float32]{id: PropFloat, name: "p", values: []float32{1, 2, 3}}
+	diff = diffProperty(a, c, 0)
+	assertEqual(t, diff.Changed, 3)
+	assertEqual(t, diff.String(), "~ p: type i -> f")
+}
+
+func TestDiffNaN(t *testing.T) {
+	nan := float32(math.NaN())
+	a := &CastProperty[Vec2]{id: PropVector2, name: "p", values: []Vec2{{nan, 1}, {1, 1}}}
+	b := &CastProperty[Vec2]{id: PropVector2, name: "p", values: []Vec2{{nan, 1}, {nan, 1}}}
+	diff := diffProperty(a, b, 0)
+	assertEqual(t, diff.Changed, 1)
+	assertEqual(t, diff.First, 1)
+	assertEqual(t, diff.MaxDelta, 0)
+}
@@ -0,0 +1,133 @@
+package cast
+
+import "slices"
+
+// ----------------------- //
+//         EXTRACT         //
+// ----------------------- //
+
+// ExtractToFile returns a new [CastFile] holding a copy of the node and its descendants.
+// Nodes outside of the subtree that are referenced by hash from an Integer64 property (e.g. the
+// materials of a mesh) are copied along, transitively, and placed next to the node under the new root.
+// Hashes are preserved so the references stay valid. The original tree is left untouched.
+func (n *CastNode) ExtractToFile() *CastFile {
+	references := n.externalReferences()
+
+	castFile := New()
+	var root *CastNode
+	if n.id == NodeIdRoot {
+		root = copyNode(n, nil)
+	} else {
+		root = newCastNode(NodeIdRoot, castFile)
+		root.childNodes = append(root.childNodes, copyNode(n, root))
+	}
+
+	for _, ref := range references {
+		root.childNodes = append(root.childNodes, copyNode(ref, root))
+	}
+	castFile.addRoot(root, n.id != NodeIdRoot)
+
+	return castFile
+}
+
+// FileFromNode detaches the node from its tree and returns a new [CastFile] holding it. Nodes outside of the
+// subtree that are referenced by hash from an Integer64 property (e.g. the materials and files of a model)
+// are copied along, transitively, so the new file is self-contained. A root node becomes the root of the new
+// file, any other node is placed under a new root next to the copied references. Hashes are preserved.
+func FileFromNode(n *CastNode) *CastFile {
+	references := n.externalReferences()
+
+	oldFile := n.file
+	n.detach()
+	if oldFile != nil {
+		oldFile.invalidateIndex()
+	}
+
+	castFile := New()
+	root := n
+	if n.id != NodeIdRoot {
+		root = newCastNode(NodeIdRoot, castFile)
+		n.setParentNode(root)
+		root.childNodes = append(root.childNodes, n)
+	}
+
+	for _, ref := range references {
+		root.childNodes = append(root.childNodes, copyNode(ref, root))
+	}
+	castFile.addRoot(root, n.id != NodeIdRoot)
+
+	return castFile
+}
+
+// addRoot adds the given root node to the file. If the root was newly created for the file and its hash is
+// already used within its subtree, it is assigned another one.
+func (n *CastFile) addRoot(root *CastNode, created bool) {
+	if created {
+		for root.FindDescendantByHash(root.hash) != nil {
+			root.hash = n.nextHash()
+		}
+	}
+	n.rootNodes = append(n.rootNodes, root)
+	n.adopt(root)
+}
+
+// externalReferences returns the nodes outside of the subtree of the node and its ancestors that are
+// referenced by hash from within the subtree, transitively, in the order they are first referenced
+func (n *CastNode) externalReferences() []*CastNode {
+	// the subtree and its ancestors are never references
+	visited := make(map[*CastNode]struct{})
+	for p := n.parentNode; p != nil; p = p.parentNode {
+		visited[p] = struct{}{}
+	}
+	for c := range n.all() {
+		visited[c] = struct{}{}
+	}
+
+	var references []*CastNode
+	for pending := []*CastNode{n}; len(pending) > 0; {
+		node := pending[0]
+		pending = pending[1:]
+
+		for c := range node.all() {
+			for _, ref := range n.resolveHashes(hashReferences(c)...) {
+				if ref == nil {
+					continue
+				}
+				if _, ok := visited[ref]; ok {
+					continue
+				}
+
+				for rc := range ref.all() {
+					visited[rc] = struct{}{}
+				}
+				references = append(references, ref)
+				pending = append(pending, ref)
+			}
+		}
+	}
+
+	// a reference found before one of its ancestors is copied along with the ancestor
+	collected := make(map[*CastNode]struct{}, len(references))
+	for _, ref := range references {
+		collected[ref] = struct{}{}
+	}
+	return slices.DeleteFunc(references, func(ref *CastNode) bool {
+		for p := ref.parentNode; p != nil; p = p.parentNode {
+			if _, ok := collected[p]; ok {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// hashReferences returns the values of all Integer64 properties of the given node
+func hashReferences(n *CastNode) []uint64 {
+	var hashes []uint64
+	for _, p := range n.properties {
+		if p, ok := p.(*CastProperty[uint64]); ok {
+			hashes = append(hashes, p.GetValues()...)
+		}
+	}
+	return hashes
+}
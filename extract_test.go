@@ -0,0 +1,109 @@
+package cast
+
+import "testing"
+
+func TestExtractToFile(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	model := castFile.Roots()[0].GetChildrenOfType(NodeIdModel)[0]
+	mesh := model.GetChildrenOfType(NodeIdMesh)[0]
+	material := model.GetChildrenOfType(NodeIdMaterial)[0]
+
+	extracted := mesh.ExtractToFile()
+	assertEqual(t, len(extracted.Roots()), 1)
+
+	root := extracted.Roots()[0]
+	assertEqual(t, len(root.GetChildNodes()), 2)
+	assertEqual(t, root.GetChildNodes()[0].Hash(), mesh.Hash())
+	assertEqual(t, root.GetChildNodes()[0].GetParentNode(), root)
+	assertEqual(t, root.GetChildByHash(material.Hash()).Id(), NodeIdMaterial)
+
+	// the copy must not share data with the original
+	name, err := GetPropertyValue[string](root.GetChildNodes()[0], PropNameName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*name = "Changed"
+	original, err := GetPropertyValue[string](mesh, PropNameName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, *original, "Cube")
+	assertEqual(t, len(model.GetChildNodes()), 2)
+
+	whole := castFile.Roots()[0].ExtractToFile()
+	assertEqual(t, whole.Roots()[0].Hash(), castFile.Roots()[0].Hash())
+	assertEqual(t, whole.Roots()[0].len(), castFile.Roots()[0].len())
+}
+
+func TestFileFromNode(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	model := castFile.Roots()[0].GetChildrenOfType(NodeIdModel)[0]
+	mesh := model.GetChildrenOfType(NodeIdMesh)[0]
+	material := model.GetChildrenOfType(NodeIdMaterial)[0]
+
+	detached := FileFromNode(mesh)
+	assertEqual(t, len(model.GetChildNodes()), 1)
+	assertEqual(t, castFile.FindByHash(mesh.Hash()), nil)
+
+	root := detached.Roots()[0]
+	assertEqual(t, root.GetChildNodes()[0], mesh)
+	assertEqual(t, mesh.GetParentNode(), root)
+	assertEqual(t, detached.FindByHash(mesh.Hash()), mesh)
+
+	// the material is copied, the original stays in place
+	copied := mesh.ResolveReference(PropNameMaterial)
+	assertEqual(t, copied != nil, true)
+	assertEqual(t, copied != material, true)
+	assertEqual(t, copied.Hash(), material.Hash())
+	assertEqual(t, castFile.FindByHash(material.Hash()), material)
+
+	// root nodes become the root of the new file
+	whole := FileFromNode(castFile.Roots()[0])
+	assertEqual(t, len(castFile.Roots()), 0)
+	assertEqual(t, len(whole.Roots()), 1)
+	assertEqual(t, whole.Roots()[0].GetChildNodes()[0], model)
+}
+
+func TestExtractRootHash(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	skeleton := castFile.GetNodesOfType(NodeIdSkeleton)[0]
+	if err := castFile.Roots()[0].SetHash(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := skeleton.GetChildNodes()[0].SetHash(castHashBase); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new root must not reuse a hash of the extracted nodes
+	for _, f := range []*CastFile{skeleton.ExtractToFile(), FileFromNode(skeleton)} {
+		root := f.Roots()[0]
+		assertEqual(t, root.FindDescendantByHash(root.Hash()), nil)
+		assertEqual(t, f.FindByHash(root.Hash()), root)
+	}
+}
+
+func TestExtractNestedReferences(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	material := root.CreateChild(NodeIdMaterial)
+	file := material.CreateChild(NodeIdFile)
+	model := root.CreateChild(NodeIdModel)
+
+	// the file is referenced before the material holding it
+	CreateProperty(model, "a", PropInteger64, file.Hash(), material.Hash())
+
+	for _, f := range []*CastFile{model.ExtractToFile(), FileFromNode(model)} {
+		count := 0
+		for node := range f.AllNodes() {
+			if node.Hash() == file.Hash() {
+				count++
+			}
+		}
+		assertEqual(t, count, 1)
+
+		extracted := f.Roots()[0]
+		assertEqual(t, len(extracted.GetChildNodes()), 2)
+		assertEqual(t, extracted.GetChildNodes()[1].Hash(), material.Hash())
+		assertEqual(t, f.FindByHash(file.Hash()).GetParentNode().Hash(), material.Hash())
+	}
+}
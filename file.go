@@ -0,0 +1,91 @@
+package cast
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------- //
+//          FILE           //
+// ----------------------- //
+
+// File wraps a file node referencing an external file such as a texture. The contents of the file can be
+// embedded in the node to produce self-contained cast files, they are stored in the Byte property "d".
+type File struct {
+	*CastNode
+}
+
+// AsFile returns the node as a [File], nil if it is not a file node
+func AsFile(n *CastNode) *File {
+	if n == nil || n.id != NodeIdFile {
+		return nil
+	}
+	return &File{n}
+}
+
+// Path returns the path of the referenced file
+func (f *File) Path() string {
+	return GetPropertyValueOr(f.CastNode, PropNamePath, "")
+}
+
+// Data returns the embedded contents of the file, nil if nothing is embedded
+func (f *File) Data() []byte {
+	data, err := GetPropertyValues[byte](f.CastNode, PropNameData)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// SetData embeds the given contents in the node
+func (f *File) SetData(data []byte) error {
+	_, err := CreateProperty(f.CastNode, PropNameData, PropByte, data...)
+	return err
+}
+
+// Embed reads the file at the given path from disk and embeds its contents in the node. If the node has no
+// path yet, it is set to the base name of the file.
+func (f *File) Embed(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := f.SetData(data); err != nil {
+		return err
+	}
+
+	if f.Path() == "" {
+		_, err = CreateProperty(f.CastNode, PropNamePath, PropString, filepath.Base(path))
+	}
+	return err
+}
+
+// ExtractFiles writes the embedded contents of every file node of the file to the given directory, at the
+// path of the node relative to it. Backslashes in the paths are treated as separators. Files without embedded
+// contents are skipped, paths that are absolute or leave the directory are rejected.
+func (n *CastFile) ExtractFiles(dir string) error {
+	for _, node := range n.Find(ByType(NodeIdFile)) {
+		f := AsFile(node)
+		data := f.Data()
+		if data == nil {
+			continue
+		}
+
+		path := filepath.FromSlash(strings.ReplaceAll(f.Path(), `\`, "/"))
+		if !filepath.IsLocal(path) {
+			return fmt.Errorf("cast: invalid path of embedded file %q", f.Path())
+		}
+
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cast
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbedFiles(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "albedo.png")
+	if err := os.WriteFile(source, []byte("texture"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	castFile := New()
+	root := castFile.CreateRoot()
+	embedded := AsFile(root.CreateChild(NodeIdFile))
+	if err := embedded.Embed(source); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, embedded.Path(), "albedo.png")
+
+	nested := AsFile(root.CreateChild(NodeIdFile))
+	CreateProperty(nested.CastNode, PropNamePath, PropString, `_images\normal.png`)
+	if err := nested.Embed(source); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, nested.Path(), `_images\normal.png`)
+
+	external := AsFile(root.CreateChild(NodeIdFile))
+	CreateProperty(external.CastNode, PropNamePath, PropString, "external.png")
+	if external.Data() != nil {
+		t.Error("expected no embedded data")
+	}
+
+	var buf bytes.Buffer
+	if err := castFile.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := must(Load(&buf))
+	assertEqual(t, string(AsFile(loaded.Find(ByType(NodeIdFile))[0]).Data()), "texture")
+
+	dir := t.TempDir()
+	if err := loaded.ExtractFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(must(os.ReadFile(filepath.Join(dir, "albedo.png")))), "texture")
+	assertEqual(t, string(must(os.ReadFile(filepath.Join(dir, "_images", "normal.png")))), "texture")
+	if _, err := os.Stat(filepath.Join(dir, "external.png")); !os.IsNotExist(err) {
+		t.Errorf("expected no extracted external file, got: %v", err)
+	}
+
+	CreateProperty(embedded.CastNode, PropNamePath, PropString, "../escape.png")
+	if err := castFile.ExtractFiles(dir); err == nil {
+		t.Error("expected error for a path leaving the directory")
+	}
+}
@@ -0,0 +1,58 @@
+package cast
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+)
+
+// ----------------------- //
+//          FILES          //
+// ----------------------- //
+
+// LoadFile loads a [CastFile] from the file at the given path, see [Load]
+func LoadFile(path string, opts ...LoadOption) (*CastFile, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return Load(bufio.NewReader(r), opts...)
+}
+
+// LoadFS loads a [CastFile] from the file at the given path of the file system, e.g. an [embed.FS], see [Load]
+func LoadFS(fsys fs.FS, path string, opts ...LoadOption) (*CastFile, error) {
+	r, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return Load(bufio.NewReader(r), opts...)
+}
+
+// WriteFile writes the file to the given path, creating or truncating it. The written data is flushed to
+// stable storage before the file is closed, an error is returned if any of the steps fail.
+func WriteFile(path string, f *CastFile) error {
+	w, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := f.Write(bw); err != nil {
+		w.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Sync(); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
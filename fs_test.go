@@ -0,0 +1,41 @@
+package cast
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadWriteFile(t *testing.T) {
+	castFile := must(LoadFile("testdata/cube.cast"))
+
+	path := filepath.Join(t.TempDir(), "cube.cast")
+	if err := WriteFile(path, castFile); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := must(LoadFile(path))
+	assertEqual(t, len(loaded.Roots()), len(castFile.Roots()))
+	assertEqual(t, len(loaded.GetNodesOfType(NodeIdMesh)), len(castFile.GetNodesOfType(NodeIdMesh)))
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.cast")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	castFile := must(LoadFS(os.DirFS("testdata"), "cast_ik.cast"))
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdSkeleton)), 1)
+
+	data := must(os.ReadFile("testdata/cube.cast"))
+	fsys := fstest.MapFS{"models/cube.cast": &fstest.MapFile{Data: data}}
+	castFile = must(LoadFS(fsys, "models/cube.cast"))
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdMesh)), 1)
+
+	if _, err := LoadFS(fsys, "models/missing.cast"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+package cast
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// ----------------------- //
+//          HASH           //
+// ----------------------- //
+
+// HashGenerator generates the hashes assigned to new nodes. Hashes already used within the file are
+// skipped, so a generator must eventually produce an unused hash.
+type HashGenerator interface {
+	NextHash() uint64 // NextHash returns the next hash
+}
+
+// HashGeneratorFunc is an adapter to use an ordinary function as a [HashGenerator]
+type HashGeneratorFunc func() uint64
+
+// NextHash returns the next hash
+func (f HashGeneratorFunc) NextHash() uint64 {
+	return f()
+}
+
+// sequentialHashGenerator generates consecutive hashes
+type sequentialHashGenerator struct {
+	next atomic.Uint64
+}
+
+// NewSequentialHashGenerator returns a [HashGenerator] producing consecutive hashes starting at the given value
+func NewSequentialHashGenerator(start uint64) HashGenerator {
+	g := &sequentialHashGenerator{}
+	g.next.Store(start)
+	return g
+}
+
+// NextHash returns the next hash
+func (g *sequentialHashGenerator) NextHash() uint64 {
+	return g.next.Add(1) - 1
+}
+
+// NewRandomHashGenerator returns a [HashGenerator] producing random hashes
+func NewRandomHashGenerator() HashGenerator {
+	return HashGeneratorFunc(rand.Uint64)
+}
+
+// nextHash returns the next hash from the hash generator of the file
+func (n *CastFile) nextHash() uint64 {
+	if n.hashGenerator == nil {
+		return nextHash()
+	}
+	return n.hashGenerator.NextHash()
+}
+
+// FindByHash returns the node of the file with the given hash, or nil if there is none.
+// When multiple nodes share the hash the first one in depth-first order is returned.
+func (n *CastFile) FindByHash(hash uint64) *CastNode {
+	return n.hashIndex()[hash]
+}
+
+// FindDescendantByHash returns the descendant of the node with the given hash, or nil if there is none
+func (n *CastNode) FindDescendantByHash(hash uint64) *CastNode {
+	if n.file != nil {
+		if node := n.file.hashIndex()[hash]; node != nil && node.isDescendantOf(n) {
+			return node
+		}
+	}
+
+	// the index only holds the first node of a hash, fall back to searching the subtree
+	for c := range n.Descendants() {
+		if c.hash == hash {
+			return c
+		}
+	}
+	return nil
+}
+
+// ResolveReference returns the node referenced by the first value of the Integer64 property with the
+// given name (e.g. the material of a mesh), or nil if the property is missing or the hash is not found
+func (n *CastNode) ResolveReference(name CastPropertyName) *CastNode {
+	hash, err := GetPropertyValue[uint64](n, name)
+	if err != nil {
+		return nil
+	}
+	return n.resolveHashes(*hash)[0]
+}
+
+// ResolveReferences returns the nodes referenced by the values of the Integer64 property with the given
+// name (e.g. the target shapes of a blend shape). Hashes that are not found resolve to nil.
+func (n *CastNode) ResolveReferences(name CastPropertyName) []*CastNode {
+	hashes, err := GetPropertyValues[uint64](n, name)
+	if err != nil {
+		return nil
+	}
+	return n.resolveHashes(hashes...)
+}
+
+// resolveHashes looks up the given hashes in the file of the node. Nodes that do not belong to a
+// file are resolved against the tree they are part of.
+func (n *CastNode) resolveHashes(hashes ...uint64) []*CastNode {
+	index := map[uint64]*CastNode{}
+	if n.file != nil {
+		index = n.file.hashIndex()
+	} else {
+		top := n
+		for top.parentNode != nil {
+			top = top.parentNode
+		}
+		indexNodes(index, top)
+	}
+
+	nodes := make([]*CastNode, len(hashes))
+	for i, hash := range hashes {
+		nodes[i] = index[hash]
+	}
+	return nodes
+}
+
+// RemapHashes detects nodes sharing a hash and assigns a fresh hash to every occurrence but the first
+// in depth-first order. Integer64 properties referencing a remapped hash are rewritten within the root
+// of the remapped node when the first occurrence lives under a different root, as happens when combining
+// files; duplicates within a single root are ambiguous and their references keep resolving to the first
+// occurrence. Returns the number of nodes that were assigned a new hash.
+func (n *CastFile) RemapHashes() int {
+	used := make(map[uint64]struct{})
+	for node := range n.AllNodes() {
+		used[node.hash] = struct{}{}
+	}
+
+	remapped := 0
+	owners := make(map[uint64]*CastNode)
+	for _, root := range n.rootNodes {
+		remap := make(map[uint64]uint64)
+		walkNodes(root, func(node *CastNode) {
+			owner, ok := owners[node.hash]
+			if !ok {
+				owners[node.hash] = root
+				return
+			}
+
+			hash := n.nextHash()
+			for {
+				if _, ok := used[hash]; !ok {
+					break
+				}
+				hash = n.nextHash()
+			}
+
+			if _, ok := remap[node.hash]; owner != root && !ok {
+				remap[node.hash] = hash
+			}
+			node.hash = hash
+			owners[hash] = root
+			used[hash] = struct{}{}
+			remapped++
+		})
+
+		if len(remap) > 0 {
+			walkNodes(root, func(node *CastNode) {
+				remapHashReferences(node, remap)
+			})
+		}
+	}
+
+	if remapped > 0 {
+		n.invalidateIndex()
+	}
+	return remapped
+}
+
+// AssignContentHashes replaces the hash of every node with one derived from its content: the node type,
+// its name property, the hash of its parent and its position among the siblings sharing the same type
+// and name. Exporting the same source data therefore always yields the same hashes. Integer64 properties
+// referencing the previous hashes are rewritten.
+func (n *CastFile) AssignContentHashes() {
+	remap := make(map[uint64]uint64)
+	used := make(map[uint64]struct{})
+
+	var assign func(node *CastNode, parentHash uint64, occurrence uint32)
+	assign = func(node *CastNode, parentHash uint64, occurrence uint32) {
+		hash := contentHash(node, parentHash, occurrence)
+		for {
+			if _, ok := used[hash]; !ok {
+				break
+			}
+			hash++
+		}
+		used[hash] = struct{}{}
+
+		if _, ok := remap[node.hash]; !ok {
+			remap[node.hash] = hash
+		}
+		node.hash = hash
+
+		occurrences := make(map[string]uint32)
+		for _, c := range node.childNodes {
+			key := c.id.String() + contentName(c)
+			assign(c, hash, occurrences[key])
+			occurrences[key]++
+		}
+	}
+
+	for i, root := range n.rootNodes {
+		assign(root, 0, uint32(i))
+	}
+
+	for node := range n.AllNodes() {
+		remapHashReferences(node, remap)
+	}
+	n.invalidateIndex()
+}
+
+// ContentHashOption configures [CastFile.ContentHash]
+type ContentHashOption func(c *contentHashConfig)
+
+// contentHashConfig holds the settings of [CastFile.ContentHash]
+type contentHashConfig struct {
+	nodeHashes bool
+}
+
+// WithNodeHashes makes [CastFile.ContentHash] include the node hashes, so files differing only in their
+// hashes get different checksums
+func WithNodeHashes() ContentHashOption {
+	return func(c *contentHashConfig) {
+		c.nodeHashes = true
+	}
+}
+
+// ContentHash returns a SHA-256 checksum of the content of the file that is independent of how it was
+// encoded: the properties of each node are hashed in the order of their names, the header fields are
+// ignored and, unless [WithNodeHashes] is given, so are the node hashes. Integer64 values referencing nodes
+// of the file are hashed by the position of the referenced node in depth-first order instead, so files
+// exported twice with random hashes share the same checksum, while any change of a value, name, type or the
+// node structure changes it.
+func (n *CastFile) ContentHash(opts ...ContentHashOption) [32]byte {
+	var config contentHashConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var local map[uint64]uint32
+	if !config.nodeHashes {
+		local = make(map[uint64]uint32)
+		i := uint32(0)
+		for c := range n.AllNodes() {
+			if _, ok := local[c.hash]; !ok {
+				local[c.hash] = i
+			}
+			i++
+		}
+	}
+
+	h := sha256.New()
+	w := bufio.NewWriterSize(h, codecChunkSize)
+	w.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(n.rootNodes))))
+	for _, root := range n.rootNodes {
+		writeCanonicalContent(w, root, local)
+	}
+	w.Flush()
+
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// contentHash returns the content based hash of the given node
+func contentHash(n *CastNode, parentHash uint64, occurrence uint32) uint64 {
+	h := fnv.New64a()
+	h.Write(binary.LittleEndian.AppendUint64(nil, parentHash))
+	h.Write(binary.LittleEndian.AppendUint32(nil, uint32(n.id)))
+	h.Write([]byte(contentName(n)))
+	h.Write(binary.LittleEndian.AppendUint32(nil, occurrence))
+	return h.Sum64()
+}
+
+// contentName returns the name of the node used for content based hashing
+func contentName(n *CastNode) string {
+	name, err := GetPropertyValue[string](n, PropNameName)
+	if err != nil {
+		return ""
+	}
+	return *name
+}
+
+// remapHashReferences rewrites the hash references held by the Integer64 properties of the given node
+func remapHashReferences(n *CastNode, remap map[uint64]uint64) {
+	for _, p := range n.properties {
+		p, ok := p.(*CastProperty[uint64])
+		if !ok {
+			continue
+		}
+
+		values := p.GetValues()
+		for i, v := range values {
+			if hash, ok := remap[v]; ok {
+				values[i] = hash
+			}
+		}
+	}
+}
+
+// isDescendantOf reports whether the node is a descendant of the given node
+func (n *CastNode) isDescendantOf(ancestor *CastNode) bool {
+	for p := n.parentNode; p != nil; p = p.parentNode {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// hashIndex returns the hash index of the file, building it on first use
+func (n *CastFile) hashIndex() map[uint64]*CastNode {
+	if n.index == nil {
+		n.index = make(map[uint64]*CastNode)
+		for _, root := range n.rootNodes {
+			indexNodes(n.index, root)
+		}
+	}
+	return n.index
+}
+
+// invalidateIndex drops the hash index, it is rebuilt on next use
+func (n *CastFile) invalidateIndex() {
+	n.index = nil
+}
+
+// adopt attaches the given node and its descendants to the file and adds them to the hash index if it is built
+func (n *CastFile) adopt(node *CastNode) {
+	walkNodes(node, func(c *CastNode) {
+		c.file = n
+	})
+	if n.index != nil {
+		indexNodes(n.index, node)
+	}
+}
+
+// indexNodes adds the given node and its descendants to the index, keeping the first node of each hash
+func indexNodes(index map[uint64]*CastNode, node *CastNode) {
+	walkNodes(node, func(c *CastNode) {
+		if _, ok := index[c.hash]; !ok {
+			index[c.hash] = c
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package cast
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestFindByHash(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	root := castFile.Roots()[0]
+	model := root.GetChildNodes()[0]
+
+	spine := castFile.FindByHash(0x534e495752545260)
+	if spine == nil {
+		t.Fatal("node not found")
+	}
+	name, err := GetPropertyValue[string](spine, PropNameName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, *name, "spine")
+
+	assertEqual(t, castFile.FindByHash(1), nil)
+	assertEqual(t, root.FindDescendantByHash(spine.Hash()), spine)
+	assertEqual(t, model.FindDescendantByHash(spine.Hash()), spine)
+	assertEqual(t, spine.FindDescendantByHash(model.Hash()), nil)
+	assertEqual(t, model.FindDescendantByHash(model.Hash()), nil)
+
+	mesh := model.CreateChild(NodeIdMesh)
+	assertEqual(t, castFile.FindByHash(mesh.Hash()), mesh)
+	assertEqual(t, root.FindDescendantByHash(mesh.Hash()), mesh)
+
+	newRoot := castFile.CreateRoot()
+	assertEqual(t, castFile.FindByHash(newRoot.Hash()), newRoot)
+}
+
+func TestResolveReference(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	model := castFile.Roots()[0].GetChildNodes()[0]
+	mesh := model.GetChildrenOfType(NodeIdMesh)[0]
+	material := model.GetChildrenOfType(NodeIdMaterial)[0]
+
+	assertEqual(t, castFile.index == nil, true)
+	assertEqual(t, mesh.ResolveReference(PropNameMaterial), material)
+	assertEqual(t, castFile.index != nil, true)
+
+	references := mesh.ResolveReferences(PropNameMaterial)
+	assertEqual(t, len(references), 1)
+	assertEqual(t, references[0], material)
+
+	assertEqual(t, mesh.ResolveReference(PropNameName), nil)
+	assertEqual(t, mesh.ResolveReference(PropNameBaseShape), nil)
+	assertEqual(t, len(mesh.ResolveReferences(PropNameTargetShape)), 0)
+
+	// detached copies resolve against their own tree
+	detached := copyNode(model, nil)
+	assertEqual(t, detached.GetChildrenOfType(NodeIdMesh)[0].ResolveReference(PropNameMaterial), detached.GetChildrenOfType(NodeIdMaterial)[0])
+}
+
+func BenchmarkResolveReference(b *testing.B) {
+	castFile := loadTestFile(b, "pilot_medium_bangalore_LOD0.cast")
+	meshes := castFile.GetNodesOfType(NodeIdMesh)
+
+	b.ResetTimer()
+	for range b.N {
+		for _, mesh := range meshes {
+			if mesh.ResolveReference(PropNameMaterial) == nil {
+				b.Fatal("material not found")
+			}
+		}
+	}
+}
+
+func TestHashGenerator(t *testing.T) {
+	castFile := New(WithHashGenerator(NewSequentialHashGenerator(10)))
+	root := castFile.CreateRoot()
+	child := root.CreateChild(NodeIdModel)
+	assertEqual(t, root.Hash(), 10)
+	assertEqual(t, child.Hash(), 11)
+
+	next := uint64(100)
+	castFile.SetHashGenerator(HashGeneratorFunc(func() uint64 {
+		next += 2
+		return next
+	}))
+	assertEqual(t, root.CreateChild(NodeIdModel).Hash(), 102)
+
+	// generated hashes that are already used in the file are skipped
+	castFile.SetHashGenerator(NewSequentialHashGenerator(11))
+	assertEqual(t, root.CreateChild(NodeIdModel).Hash(), 12)
+
+	random := New(WithHashGenerator(NewRandomHashGenerator()))
+	a, b := random.CreateRoot(), random.CreateRoot()
+	assertEqual(t, a.Hash() != b.Hash(), true)
+}
+
+func TestRemapHashes(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	duplicate := copyNode(castFile.Roots()[0], nil)
+	castFile.rootNodes = append(castFile.rootNodes, duplicate)
+	castFile.adopt(duplicate)
+
+	assertEqual(t, castFile.RemapHashes(), 4)
+	assertEqual(t, castFile.RemapHashes(), 0)
+
+	hashes := map[uint64]bool{}
+	for n := range castFile.AllNodes() {
+		assertEqual(t, hashes[n.Hash()], false)
+		hashes[n.Hash()] = true
+		assertEqual(t, castFile.FindByHash(n.Hash()), n)
+	}
+
+	for _, root := range castFile.Roots() {
+		model := root.GetChildNodes()[0]
+		mesh := model.GetChildrenOfType(NodeIdMesh)[0]
+		assertEqual(t, mesh.ResolveReference(PropNameMaterial), model.GetChildrenOfType(NodeIdMaterial)[0])
+	}
+
+	// duplicates within a single root keep their references on the first occurrence
+	model := castFile.Roots()[0].GetChildNodes()[0]
+	material := model.GetChildrenOfType(NodeIdMaterial)[0]
+	model.CreateChild(NodeIdMaterial).hash = material.Hash()
+	assertEqual(t, castFile.RemapHashes(), 1)
+	assertEqual(t, model.GetChildrenOfType(NodeIdMesh)[0].ResolveReference(PropNameMaterial), material)
+}
+
+func TestSetHash(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	model := root.CreateChild(NodeIdModel)
+
+	if err := model.SetHash(0x1234); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, model.Hash(), 0x1234)
+	assertEqual(t, castFile.FindByHash(0x1234), model)
+	assertEqual(t, model.SetHash(0x1234), nil)
+
+	oldHash := root.Hash()
+	err := root.SetHash(0x1234)
+	assertEqual(t, errors.Is(err, ErrHashInUse), true)
+	assertEqual(t, root.Hash(), oldHash)
+
+	if err := root.SetHash(0x5678); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, castFile.FindByHash(oldHash), nil)
+	assertEqual(t, castFile.FindByHash(0x5678), root)
+}
+
+func TestAssignContentHashes(t *testing.T) {
+	a := loadTestFile(t, "cast_ik.cast")
+	b := loadTestFile(t, "cast_ik.cast")
+	b.SetHashGenerator(NewRandomHashGenerator())
+	for n := range b.AllNodes() {
+		n.hash = b.nextHash()
+	}
+
+	a.AssignContentHashes()
+	b.AssignContentHashes()
+
+	nodesA, nodesB := slices.Collect(a.AllNodes()), slices.Collect(b.AllNodes())
+	assertEqual(t, len(nodesA), len(nodesB))
+	for i := range nodesA {
+		assertEqual(t, nodesA[i].Hash(), nodesB[i].Hash())
+		assertEqual(t, a.FindByHash(nodesA[i].Hash()), nodesA[i])
+	}
+
+	// references follow the new hashes
+	ikHandle := a.GetNodesOfType(NodeIdIKHandle)[0]
+	start := ikHandle.ResolveReference(PropNameStartBone)
+	assertEqual(t, start != nil, true)
+	assertEqual(t, start.Id(), NodeIdBone)
+
+	castFile := New(WithContentHashes())
+	castFile.CreateRoot().CreateChild(NodeIdModel)
+	if err := castFile.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, castFile.Roots()[0].Hash(), contentHash(castFile.Roots()[0], 0, 0))
+}
+
+func TestContentHash(t *testing.T) {
+	a := loadTestFile(t, "cast_ik.cast")
+	b := loadTestFile(t, "cast_ik.cast")
+	b.AssignContentHashes()
+
+	assertEqual(t, a.ContentHash(), b.ContentHash())
+	assertEqual(t, a.ContentHash(WithNodeHashes()) == b.ContentHash(WithNodeHashes()), false)
+	assertEqual(t, a.ContentHash(WithNodeHashes()), a.Clone().ContentHash(WithNodeHashes()))
+
+	// the order of the properties does not matter
+	bone := b.GetNodesOfType(NodeIdBone)[0]
+	slices.Reverse(bone.properties)
+	assertEqual(t, a.ContentHash(), b.ContentHash())
+
+	// references to other nodes do matter
+	ikHandle := b.GetNodesOfType(NodeIdIKHandle)[0]
+	start := must(GetPropertyValues[uint64](ikHandle, PropNameStartBone))
+	for _, bone := range b.GetNodesOfType(NodeIdBone)[:2] {
+		if bone.Hash() != start[0] {
+			start[0] = bone.Hash()
+			break
+		}
+	}
+	assertEqual(t, a.ContentHash() == b.ContentHash(), false)
+
+	c := a.Clone()
+	SetProperty(c.GetNodesOfType(NodeIdBone)[0], PropNameName, "renamed")
+	assertEqual(t, a.ContentHash() == c.ContentHash(), false)
+}
+
+func TestConcurrentHashes(t *testing.T) {
+	const workers, count = 8, 1000
+
+	var wg sync.WaitGroup
+	hashes := make([][]uint64, workers)
+	files := make([]*CastFile, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			files[i] = New()
+			root := files[i].CreateRoot()
+			for range count {
+				root.CreateChild(NodeIdModel)
+				hashes[i] = append(hashes[i], nextHash())
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := map[uint64]bool{}
+	for i := range workers {
+		for _, hash := range hashes[i] {
+			assertEqual(t, seen[hash], false)
+			seen[hash] = true
+		}
+
+		// every file has its own counter
+		assertEqual(t, files[i].Roots()[0].Hash(), castHashBase)
+		assertEqual(t, files[i].Roots()[0].GetChildNodes()[count-1].Hash(), castHashBase+count)
+	}
+}
@@ -0,0 +1,207 @@
+package cast
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ----------------------- //
+//           IK            //
+// ----------------------- //
+
+// IKHandle wraps an ik handle node describing a chain of bones posed to reach a target
+type IKHandle struct {
+	*CastNode
+}
+
+// AsIKHandle returns the node as an [IKHandle], nil if it is not an ik handle node
+func AsIKHandle(n *CastNode) *IKHandle {
+	if n == nil || n.id != NodeIdIKHandle {
+		return nil
+	}
+	return &IKHandle{n}
+}
+
+// Name returns the name of the handle
+func (h *IKHandle) Name() string {
+	return GetPropertyValueOr(h.CastNode, PropNameName, "")
+}
+
+// UseTargetRotation reports whether the end bone takes the rotation of the target bone
+func (h *IKHandle) UseTargetRotation() bool {
+	v, err := GetPropertyValue[byte](h.CastNode, PropNameTargetRotation)
+	return err == nil && *v != 0
+}
+
+// IKHandles returns the ik handles of the skeleton
+func (s *Skeleton) IKHandles() []*IKHandle {
+	var handles []*IKHandle
+	for _, c := range s.GetChildrenOfType(NodeIdIKHandle) {
+		handles = append(handles, AsIKHandle(c))
+	}
+	return handles
+}
+
+// SolveIK poses the two bone chain of the handle so the end bone reaches the target bone, the way the
+// authoring application evaluates the handle. The chain is made up of the start bone, its child and the end
+// bone, a child of the latter. The bend plane follows the pole vector bone if the handle has one and the
+// current bend of the chain otherwise, the local X rotation of the pole bone twists the chain around the axis
+// to the target. Targets out of reach are approached along a straight chain.
+//
+// The local rotations of the chain are updated from the current local transforms of the skeleton, which
+// are posed beforehand (e.g. by evaluating an animation), and the world transforms of every bone are
+// recomputed, see [Skeleton.ComputeWorldTransforms].
+func (s *Skeleton) SolveIK(handle *IKHandle) error {
+	bones := s.Bones()
+	index := func(name CastPropertyName, required bool) (int, error) {
+		hash, err := GetPropertyValue[uint64](handle.CastNode, name)
+		if err != nil {
+			if required {
+				return -1, fmt.Errorf("cast: ik handle %q: missing %q", handle.Name(), name)
+			}
+			return -1, nil
+		}
+		for i, b := range bones {
+			if b.hash == *hash {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("cast: ik handle %q: bone %#x of %q not found", handle.Name(), *hash, name)
+	}
+
+	start, err := index(PropNameStartBone, true)
+	if err != nil {
+		return err
+	}
+	end, err := index(PropNameEndBone, true)
+	if err != nil {
+		return err
+	}
+	target, err := index(PropNameTargetBone, true)
+	if err != nil {
+		return err
+	}
+	poleVector, err := index(PropNamePoleVectorBone, false)
+	if err != nil {
+		return err
+	}
+	pole, err := index(PropNamePoleBone, false)
+	if err != nil {
+		return err
+	}
+
+	mid := bones[end].ParentIndex()
+	if mid < 0 || bones[mid].ParentIndex() != start {
+		return fmt.Errorf("cast: ik handle %q: not a two bone chain", handle.Name())
+	}
+
+	if err := s.ComputeWorldTransforms(); err != nil {
+		return err
+	}
+
+	a, b, c := bones[start].WorldPosition(), bones[mid].WorldPosition(), bones[end].WorldPosition()
+	t := bones[target].WorldPosition()
+	ab, cb, at := lenVec3(subVec3(b, a)), lenVec3(subVec3(c, b)), lenVec3(subVec3(t, a))
+	if ab == 0 || cb == 0 {
+		return fmt.Errorf("cast: ik handle %q: %w", handle.Name(), errZeroLengthBone)
+	}
+
+	const eps = 1e-4
+	at = min(max(at, eps), ab+cb-eps)
+
+	ac := subVec3(c, a).normalize()
+	bendAxis := crossVec3(ac, subVec3(b, a))
+	if lenVec3(bendAxis) < eps && poleVector >= 0 {
+		bendAxis = crossVec3(ac, subVec3(bones[poleVector].WorldPosition(), a))
+	}
+	if lenVec3(bendAxis) < eps {
+		bendAxis = perpendicular(ac)
+	}
+
+	// bend both joints within the bend plane to match the distance to the target, then swing the chain onto it
+	startBend := QuatFromAxisAngle(bendAxis, lawOfCosines(cb, ab, at)-angleBetween(ac, subVec3(b, a)))
+	midBend := QuatFromAxisAngle(bendAxis, lawOfCosines(at, ab, cb)-angleBetween(subVec3(a, b), subVec3(c, b)))
+	swing := rotationBetween(ac, subVec3(t, a)).Mul(startBend)
+
+	// turn the bend plane around the axis to the target towards the pole vector and apply the twist
+	axis := subVec3(t, a).normalize()
+	if poleVector >= 0 {
+		bend := reject(swing.Rotate(subVec3(b, a)), axis)
+		toPole := reject(subVec3(bones[poleVector].WorldPosition(), a), axis)
+		if lenVec3(bend) > eps && lenVec3(toPole) > eps {
+			angle := angleBetween(bend, toPole)
+			if dotVec3(crossVec3(bend, toPole), axis) < 0 {
+				angle = -angle
+			}
+			swing = QuatFromAxisAngle(axis, angle).Mul(swing)
+		}
+	}
+	if pole >= 0 {
+		x, _, _ := bones[pole].LocalRotation().Euler()
+		swing = QuatFromAxisAngle(axis, x).Mul(swing)
+	}
+
+	startRotation := swing.Mul(bones[start].WorldRotation())
+	midRotation := swing.Mul(midBend).Mul(bones[mid].WorldRotation())
+
+	parentRotation := QuatIdent()
+	if p := bones[start].ParentIndex(); p >= 0 {
+		parentRotation = bones[p].WorldRotation()
+	}
+	locals := map[int]Quat{
+		start: parentRotation.Conjugate().Mul(startRotation).Normalize(),
+		mid:   startRotation.Conjugate().Mul(midRotation).Normalize(),
+	}
+	if handle.UseTargetRotation() {
+		locals[end] = midRotation.Conjugate().Mul(bones[target].WorldRotation()).Normalize()
+	}
+
+	for i, r := range locals {
+		if _, err := CreateProperty(bones[i].CastNode, PropNameLocalRotation, PropVector4, Vec4(r)); err != nil {
+			return err
+		}
+	}
+	return s.ComputeWorldTransforms()
+}
+
+// errZeroLengthBone is returned when a bone of an ik chain has no length
+var errZeroLengthBone = errors.New("zero length bone in chain")
+
+// lawOfCosines returns the angle of a triangle opposite of side a between the sides b and c
+func lawOfCosines(a, b, c float32) float32 {
+	cos := (b*b + c*c - a*a) / (2 * b * c)
+	return float32(math.Acos(float64(min(max(cos, -1), 1))))
+}
+
+// angleBetween returns the angle between the vectors
+func angleBetween(a, b Vec3) float32 {
+	cos := dotVec3(a.normalize(), b.normalize())
+	return float32(math.Acos(float64(min(max(cos, -1), 1))))
+}
+
+// rotationBetween returns the shortest rotation turning the direction of a into the direction of b
+func rotationBetween(a, b Vec3) Quat {
+	axis := crossVec3(a, b)
+	if lenVec3(axis) < 1e-6 {
+		if dotVec3(a, b) >= 0 {
+			return QuatIdent()
+		}
+		axis = perpendicular(a)
+	}
+	return QuatFromAxisAngle(axis, angleBetween(a, b))
+}
+
+// reject returns the part of the vector perpendicular to the given unit axis
+func reject(v, axis Vec3) Vec3 {
+	d := dotVec3(v, axis)
+	return subVec3(v, scaleVec3(axis, d))
+}
+
+// perpendicular returns a unit vector perpendicular to the given one
+func perpendicular(v Vec3) Vec3 {
+	if math.Abs(float64(v.X)) < 0.9 {
+		return crossVec3(v, Vec3{X: 1}).normalize()
+	}
+	return crossVec3(v, Vec3{Y: 1}).normalize()
+}
@@ -0,0 +1,104 @@
+package cast
+
+import "testing"
+
+// createChain creates a skeleton with a root bone at the origin followed by bones at the given local positions,
+// each the child of the previous one
+func createChain(positions ...Vec3) *Skeleton {
+	skeleton := AsSkeleton(New().CreateRoot().CreateChild(NodeIdModel).CreateChild(NodeIdSkeleton))
+	for i, p := range positions {
+		bone := skeleton.CreateChild(NodeIdBone)
+		CreateProperty(bone, PropNameParentIndex, PropInteger32, uint32(i-1))
+		CreateProperty(bone, PropNameLocalPosition, PropVector3, p)
+		CreateProperty(bone, PropNameLocalRotation, PropVector4, Vec4(QuatIdent()))
+	}
+	return skeleton
+}
+
+// createIKHandle creates an ik handle on the skeleton referencing the bones with the given indices
+func createIKHandle(s *Skeleton, bones map[CastPropertyName]int) *IKHandle {
+	handle := AsIKHandle(s.CreateChild(NodeIdIKHandle))
+	all := s.Bones()
+	for name, i := range bones {
+		CreateProperty(handle.CastNode, name, PropInteger64, all[i].Hash())
+	}
+	return handle
+}
+
+func TestSolveIK(t *testing.T) {
+	// start, mid and end bone followed by a target and a pole vector root bone
+	skeleton := createChain(Vec3{}, Vec3{Y: 1}, Vec3{X: 1}, Vec3{})
+	target := skeleton.CreateChild(NodeIdBone)
+	CreateProperty(target, PropNameParentIndex, PropInteger32, uint32(0xFFFFFFFF))
+	CreateProperty(target, PropNameLocalPosition, PropVector3, Vec3{X: 0.5, Y: 1.2, Z: 0.4})
+	pole := skeleton.CreateChild(NodeIdBone)
+	CreateProperty(pole, PropNameParentIndex, PropInteger32, uint32(0xFFFFFFFF))
+	CreateProperty(pole, PropNameLocalPosition, PropVector3, Vec3{Z: 5})
+
+	handle := createIKHandle(skeleton, map[CastPropertyName]int{
+		PropNameStartBone:  0,
+		PropNameEndBone:    2,
+		PropNameTargetBone: 4,
+	})
+	if err := skeleton.SolveIK(handle); err != nil {
+		t.Fatal(err)
+	}
+
+	bones := skeleton.Bones()
+	assertWithinVec3(t, bones[2].WorldPosition(), bones[4].WorldPosition(), 1e-4)
+	assertWithinVec3(t, bones[0].WorldPosition(), Vec3{}, 1e-6)
+	assertNear(t, lenVec3(subVec3(bones[1].WorldPosition(), bones[0].WorldPosition())), 1)
+
+	// the mid joint bends towards the pole vector
+	CreateProperty(handle.CastNode, PropNamePoleVectorBone, PropInteger64, bones[5].Hash())
+	if err := skeleton.SolveIK(handle); err != nil {
+		t.Fatal(err)
+	}
+	axis := bones[4].WorldPosition().normalize()
+	bend := reject(bones[1].WorldPosition(), axis).normalize()
+	assertWithinVec3(t, bend, reject(bones[5].WorldPosition(), axis).normalize(), 1e-4)
+	assertWithinVec3(t, bones[2].WorldPosition(), bones[4].WorldPosition(), 1e-4)
+
+	// targets out of reach straighten the chain
+	CreateProperty(target, PropNameLocalPosition, PropVector3, Vec3{X: 10})
+	if err := skeleton.SolveIK(handle); err != nil {
+		t.Fatal(err)
+	}
+	assertWithinVec3(t, bones[2].WorldPosition(), Vec3{X: 2}, 1e-3)
+
+	invalid := createIKHandle(skeleton, map[CastPropertyName]int{
+		PropNameStartBone:  0,
+		PropNameEndBone:    3,
+		PropNameTargetBone: 4,
+	})
+	if err := skeleton.SolveIK(invalid); err == nil {
+		t.Error("expected error for a chain of three bones")
+	}
+}
+
+func TestSolveIKTestFile(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	skeleton := AsSkeleton(castFile.Find(ByType(NodeIdSkeleton))[0])
+	handles := skeleton.IKHandles()
+	assertEqual(t, len(handles), 2)
+
+	// the targets of the test file sit at the origin out of reach, so the legs straighten towards them
+	for _, h := range handles {
+		start := AsBone(h.ResolveReference(PropNameStartBone))
+		end := AsBone(h.ResolveReference(PropNameEndBone))
+		target := AsBone(h.ResolveReference(PropNameTargetBone))
+		mid := skeleton.Bones()[end.ParentIndex()]
+		length := lenVec3(mid.LocalPosition()) + lenVec3(end.LocalPosition())
+
+		if err := skeleton.SolveIK(h); err != nil {
+			t.Fatal(err)
+		}
+
+		toTarget := subVec3(target.WorldPosition(), start.WorldPosition())
+		want := addVec3(start.WorldPosition(), scaleVec3(toTarget.normalize(), length))
+		assertWithinVec3(t, end.WorldPosition(), want, 5e-2)
+		if d := end.WorldRotation().Dot(target.WorldRotation()); h.UseTargetRotation() && d < 0.999 && d > -0.999 {
+			t.Errorf("%s: got: %v != want: %v", h.Name(), end.WorldRotation(), target.WorldRotation())
+		}
+	}
+}
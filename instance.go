@@ -0,0 +1,129 @@
+package cast
+
+import "fmt"
+
+// ----------------------- //
+//        INSTANCE         //
+// ----------------------- //
+
+// Instance wraps an instance node placing the scene of an external cast file, referenced through a file node,
+// into the scene
+type Instance struct {
+	*CastNode
+}
+
+// AsInstance returns the node as an [Instance], nil if it is not an instance node
+func AsInstance(n *CastNode) *Instance {
+	if n == nil || n.id != NodeIdInstance {
+		return nil
+	}
+	return &Instance{n}
+}
+
+// Name returns the name of the instance
+func (i *Instance) Name() string {
+	return GetPropertyValueOr(i.CastNode, PropNameName, "")
+}
+
+// ReferenceFile returns the file node referencing the instanced cast file, nil if it is not found
+func (i *Instance) ReferenceFile() *File {
+	return AsFile(i.ResolveReference(PropNameReferenceFile))
+}
+
+// Position returns the position of the instance
+func (i *Instance) Position() Vec3 {
+	return GetPropertyValueOr(i.CastNode, PropNamePosition, Vec3{})
+}
+
+// Rotation returns the rotation of the instance
+func (i *Instance) Rotation() Quat {
+	return Quat(GetPropertyValueOr(i.CastNode, PropNameRotation, Vec4(QuatIdent())))
+}
+
+// Scale returns the scale of the instance
+func (i *Instance) Scale() Vec3 {
+	return GetPropertyValueOr(i.CastNode, PropNameScale, Vec3{1, 1, 1})
+}
+
+// Matrix returns the transform of the instance composed from its position, rotation and scale
+func (i *Instance) Matrix() Mat4 {
+	return Mat4FromTRS(i.Position(), i.Rotation(), i.Scale())
+}
+
+// Scene is a cast file linked to the scenes of the files referenced by its instances, see
+// [CastFile.ResolveExternal]
+type Scene struct {
+	File      *CastFile
+	Instances []*SceneInstance
+}
+
+// SceneInstance is an instance of a [Scene] linked to the scene of the file it references
+type SceneInstance struct {
+	*Instance
+	Scene *Scene
+}
+
+// ResolveExternal loads the cast files referenced by the instances of the file, and recursively the files
+// referenced by their instances, returning the linked scene graph. The loader is called with the path of the
+// referenced file node and only once per path, instances referencing the same path share their [Scene].
+// An error is returned if a reference can not be resolved, the loader fails or the files reference each
+// other in a cycle.
+func (n *CastFile) ResolveExternal(loader func(path string) (*CastFile, error)) (*Scene, error) {
+	r := externalResolver{
+		loader:  loader,
+		scenes:  make(map[string]*Scene),
+		loading: make(map[string]bool),
+	}
+	return r.link(n)
+}
+
+// externalResolver holds the state of [CastFile.ResolveExternal]
+type externalResolver struct {
+	loader  func(path string) (*CastFile, error)
+	scenes  map[string]*Scene // scenes by path
+	loading map[string]bool   // paths of the scenes being linked
+}
+
+// link returns the scene of the given file with the references of its instances resolved
+func (r *externalResolver) link(file *CastFile) (*Scene, error) {
+	scene := &Scene{File: file}
+	for _, node := range file.GetNodesOfType(NodeIdInstance) {
+		instance := AsInstance(node)
+		ref := instance.ReferenceFile()
+		if ref == nil {
+			return nil, fmt.Errorf("%w: instance %q references no file", ErrUnresolved, instance.Name())
+		}
+
+		s, err := r.scene(ref.Path())
+		if err != nil {
+			return nil, err
+		}
+		scene.Instances = append(scene.Instances, &SceneInstance{Instance: instance, Scene: s})
+	}
+	return scene, nil
+}
+
+// scene returns the linked scene of the file at the given path, loading it if necessary
+func (r *externalResolver) scene(path string) (*Scene, error) {
+	if r.loading[path] {
+		return nil, fmt.Errorf("cast: reference cycle through %q", path)
+	}
+	if s, ok := r.scenes[path]; ok {
+		return s, nil
+	}
+
+	file, err := r.loader(path)
+	if err != nil {
+		return nil, fmt.Errorf("cast: load %q: %w", path, err)
+	}
+
+	r.loading[path] = true
+	s, err := r.link(file)
+	delete(r.loading, path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.scenes[path] = s
+	return s, nil
+}
@@ -0,0 +1,91 @@
+package cast
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+// createSceneFile creates a file with an instance for each of the given referenced paths
+func createSceneFile(paths ...string) *CastFile {
+	castFile := New(WithHashGenerator(NewRandomHashGenerator()))
+	root := castFile.CreateRoot()
+	for i, path := range paths {
+		file := root.CreateChild(NodeIdFile)
+		CreateProperty(file, PropNamePath, PropString, path)
+
+		instance := root.CreateChild(NodeIdInstance)
+		CreateProperty(instance, PropNameName, PropString, fmt.Sprintf("instance%d", i))
+		CreateProperty(instance, PropNameReferenceFile, PropInteger64, file.Hash())
+		CreateProperty(instance, PropNamePosition, PropVector3, Vec3{float32(i), 0, 0})
+	}
+	return castFile
+}
+
+// mapLoader returns a loader for the given files counting the loads of each path
+func mapLoader(files map[string]*CastFile, loads map[string]int) func(path string) (*CastFile, error) {
+	return func(path string) (*CastFile, error) {
+		loads[path]++
+		f, ok := files[path]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return f, nil
+	}
+}
+
+func TestInstance(t *testing.T) {
+	castFile := createSceneFile("house.cast")
+	instance := AsInstance(castFile.GetNodesOfType(NodeIdInstance)[0])
+	assertEqual(t, instance.Name(), "instance0")
+	assertEqual(t, instance.ReferenceFile().Path(), "house.cast")
+	assertEqual(t, instance.Rotation(), QuatIdent())
+	assertEqual(t, instance.Scale(), Vec3{1, 1, 1})
+	assertEqual(t, instance.Matrix(), Ident4())
+	assertEqual(t, AsInstance(castFile.Roots()[0]), nil)
+}
+
+func TestResolveExternal(t *testing.T) {
+	files := map[string]*CastFile{
+		"house.cast": createSceneFile("door.cast", "door.cast"),
+		"door.cast":  createSceneFile(),
+	}
+	loads := map[string]int{}
+
+	world := createSceneFile("house.cast", "house.cast")
+	scene, err := world.ResolveExternal(mapLoader(files, loads))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, scene.File, world)
+	assertEqual(t, len(scene.Instances), 2)
+	assertEqual(t, scene.Instances[0].Name(), "instance0")
+	assertEqual(t, scene.Instances[1].Position(), Vec3{1, 0, 0})
+	assertEqual(t, scene.Instances[0].Scene, scene.Instances[1].Scene)
+
+	house := scene.Instances[0].Scene
+	assertEqual(t, house.File, files["house.cast"])
+	assertEqual(t, len(house.Instances), 2)
+	assertEqual(t, house.Instances[0].Scene.File, files["door.cast"])
+	assertEqual(t, len(house.Instances[0].Scene.Instances), 0)
+
+	assertEqual(t, loads["house.cast"], 1)
+	assertEqual(t, loads["door.cast"], 1)
+
+	files["door.cast"] = createSceneFile("house.cast")
+	if _, err := world.ResolveExternal(mapLoader(files, loads)); err == nil {
+		t.Error("expected error for reference cycle")
+	}
+
+	if _, err := createSceneFile("missing.cast").ResolveExternal(mapLoader(files, loads)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+
+	unresolved := createSceneFile("house.cast")
+	CreateProperty(unresolved.GetNodesOfType(NodeIdInstance)[0], PropNameReferenceFile, PropInteger64, uint64(0))
+	if _, err := unresolved.ResolveExternal(mapLoader(files, loads)); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("expected unresolved error, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+// Package cliutil holds helpers shared by the commands
+package cliutil
+
+import "fmt"
+
+// FormatSize returns the size in bytes in a human readable form, e.g. "512 B" or "1.5 MiB"
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 2 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMG"[exp])
+}
@@ -0,0 +1,18 @@
+package cliutil
+
+import "testing"
+
+func TestFormatSize(t *testing.T) {
+	for size, want := range map[int64]string{
+		0:         "0 B",
+		1023:      "1023 B",
+		1536:      "1.5 KiB",
+		734003200: "700.0 MiB",
+		3 << 30:   "3.0 GiB",
+		5 << 40:   "5120.0 GiB",
+	} {
+		if got := FormatSize(size); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cast
+
+import "iter"
+
+// ----------------------- //
+//        ITERATORS        //
+// ----------------------- //
+
+// AllNodes returns an iterator over every node of the file in depth-first order
+func (n *CastFile) AllNodes() iter.Seq[*CastNode] {
+	return func(yield func(*CastNode) bool) {
+		for _, root := range n.rootNodes {
+			if !yield(root) || !root.yieldDescendants(yield) {
+				return
+			}
+		}
+	}
+}
+
+// Descendants returns an iterator over the descendants of the node in depth-first order,
+// the node itself is not included
+func (n *CastNode) Descendants() iter.Seq[*CastNode] {
+	return func(yield func(*CastNode) bool) {
+		n.yieldDescendants(yield)
+	}
+}
+
+// Properties returns an iterator over the properties of the node keyed by their name
+func (n *CastNode) Properties() iter.Seq2[CastPropertyName, iCastProperty] {
+	return func(yield func(CastPropertyName, iCastProperty) bool) {
+		for _, p := range n.properties {
+			if !yield(p.Name(), p) {
+				return
+			}
+		}
+	}
+}
+
+// yieldDescendants yields the descendants of the node, reports whether the iteration should continue
+func (n *CastNode) yieldDescendants(yield func(*CastNode) bool) bool {
+	for _, c := range n.childNodes {
+		if !yield(c) || !c.yieldDescendants(yield) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package cast
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	root := castFile.Roots()[0]
+
+	nodes := slices.Collect(castFile.AllNodes())
+	assertEqual(t, len(nodes), 4)
+	assertEqual(t, nodes[0], root)
+
+	descendants := slices.Collect(root.Descendants())
+	assertEqual(t, len(descendants), 3)
+	assertEqual(t, descendants[0].Id(), NodeIdModel)
+
+	for n := range castFile.AllNodes() {
+		if n.Id() == NodeIdModel {
+			break
+		}
+		assertEqual(t, n, root)
+	}
+
+	material := root.GetChildNodes()[0].GetChildrenOfType(NodeIdMaterial)[0]
+	properties := maps.Collect(material.Properties())
+	assertEqual(t, len(properties), 2)
+	assertEqual(t, properties[PropNameType].Id(), PropString)
+}
@@ -0,0 +1,155 @@
+package cast
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ----------------------- //
+//        MATERIAL         //
+// ----------------------- //
+
+// MaterialSlots holds the names of the texture slots of a material defined by the spec in their canonical
+// order. Materials may additionally have any number of extra slots, see [ExtraSlot].
+var MaterialSlots = []CastPropertyName{
+	PropNameAlbedo,
+	PropNameDiffuse,
+	PropNameNormal,
+	PropNameSpecular,
+	PropNameEmissive,
+	PropNameGloss,
+	PropNameRoughness,
+	PropNameAmbientOcclusion,
+	PropNameCavity,
+	PropNameAnisotropy,
+}
+
+// ExtraSlot returns the property name of the extra texture slot with the given index
+func ExtraSlot(i int) CastPropertyName {
+	return CastPropertyName(fmt.Sprintf(string(PropNameExtra), i))
+}
+
+// Material wraps a material node
+type Material struct {
+	*CastNode
+}
+
+// AsMaterial returns the node as a [Material], nil if it is not a material node
+func AsMaterial(n *CastNode) *Material {
+	if n == nil || n.id != NodeIdMaterial {
+		return nil
+	}
+	return &Material{n}
+}
+
+// Name returns the name of the material
+func (m *Material) Name() string {
+	return GetPropertyValueOr(m.CastNode, PropNameName, "")
+}
+
+// Type returns the type of the material, e.g. "pbr"
+func (m *Material) Type() string {
+	return GetPropertyValueOr(m.CastNode, PropNameType, "")
+}
+
+// Slots returns the names of the texture slots the material has, the slots of [MaterialSlots] in their
+// canonical order followed by the extra slots in ascending order
+func (m *Material) Slots() []CastPropertyName {
+	var slots []CastPropertyName
+	for _, name := range MaterialSlots {
+		if m.hasSlot(name) {
+			slots = append(slots, name)
+		}
+	}
+
+	var extra []int
+	for _, p := range m.properties {
+		name := p.Name()
+		var i int
+		if _, err := fmt.Sscanf(string(name), string(PropNameExtra), &i); err != nil || ExtraSlot(i) != name {
+			continue
+		}
+		if m.hasSlot(name) {
+			extra = append(extra, i)
+		}
+	}
+	slices.Sort(extra)
+	for _, i := range extra {
+		slots = append(slots, ExtraSlot(i))
+	}
+	return slots
+}
+
+// Slot returns the file referenced by the texture slot with the given name, nil if the material does not
+// have the slot or the file is not found
+func (m *Material) Slot(name CastPropertyName) *File {
+	return AsFile(m.ResolveReference(name))
+}
+
+// SetSlot sets the texture slot with the given name to reference the given file
+func (m *Material) SetSlot(name CastPropertyName, file *File) error {
+	_, err := CreateProperty(m.CastNode, name, PropInteger64, file.hash)
+	return err
+}
+
+// Metalness returns the metalness of the material in the range [0, 1], 0 if it is not set
+func (m *Material) Metalness() float32 {
+	return GetPropertyValueOr(m.CastNode, PropNameMetalness, float32(0))
+}
+
+// SetMetalness sets the metalness of the material
+func (m *Material) SetMetalness(metalness float32) error {
+	_, err := CreateProperty(m.CastNode, PropNameMetalness, PropFloat, metalness)
+	return err
+}
+
+// RoughnessValue returns the roughness of the material in the range [0, 1], used when there is no roughness
+// texture or to scale it. It is 1 if it is not set.
+func (m *Material) RoughnessValue() float32 {
+	return GetPropertyValueOr(m.CastNode, PropNameRoughnessValue, float32(1))
+}
+
+// SetRoughnessValue sets the roughness of the material
+func (m *Material) SetRoughnessValue(roughness float32) error {
+	_, err := CreateProperty(m.CastNode, PropNameRoughnessValue, PropFloat, roughness)
+	return err
+}
+
+// AlbedoTint returns the linear RGBA color multiplied with the albedo, white if it is not set
+func (m *Material) AlbedoTint() Vec4 {
+	return GetPropertyValueOr(m.CastNode, PropNameAlbedoTint, Vec4{1, 1, 1, 1})
+}
+
+// SetAlbedoTint sets the linear RGBA color multiplied with the albedo
+func (m *Material) SetAlbedoTint(tint Vec4) error {
+	_, err := CreateProperty(m.CastNode, PropNameAlbedoTint, PropVector4, tint)
+	return err
+}
+
+// EmissiveTint returns the linear RGBA color multiplied with the emission, white if it is not set
+func (m *Material) EmissiveTint() Vec4 {
+	return GetPropertyValueOr(m.CastNode, PropNameEmissiveTint, Vec4{1, 1, 1, 1})
+}
+
+// SetEmissiveTint sets the linear RGBA color multiplied with the emission
+func (m *Material) SetEmissiveTint(tint Vec4) error {
+	_, err := CreateProperty(m.CastNode, PropNameEmissiveTint, PropVector4, tint)
+	return err
+}
+
+// UVTiling returns the factors the UV coordinates are scaled by when sampling the textures, 1 if it is not set
+func (m *Material) UVTiling() Vec2 {
+	return GetPropertyValueOr(m.CastNode, PropNameUVTiling, Vec2{1, 1})
+}
+
+// SetUVTiling sets the factors the UV coordinates are scaled by when sampling the textures
+func (m *Material) SetUVTiling(tiling Vec2) error {
+	_, err := CreateProperty(m.CastNode, PropNameUVTiling, PropVector2, tiling)
+	return err
+}
+
+// hasSlot reports whether the material has a texture slot property with the given name
+func (m *Material) hasSlot(name CastPropertyName) bool {
+	p, ok := m.GetProperty(name)
+	return ok && p.Id() == PropInteger64
+}
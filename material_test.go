@@ -0,0 +1,52 @@
+package cast
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMaterialSlots(t *testing.T) {
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	material := AsMaterial(castFile.Find(ByName("bangalore_base_head"))[0])
+	assertEqual(t, material.Type(), "pbr")
+	assertEqual(t, fmt.Sprint(material.Slots()), "[albedo normal specular emissive gloss ao cavity]")
+	assertEqual(t, material.Slot(PropNameAlbedo).Path(), `_images\bangalore_base_head_albedoTexture.png`)
+	if material.Slot(PropNameDiffuse) != nil {
+		t.Error("expected no diffuse slot")
+	}
+
+	model := material.GetParentNode()
+	file := AsFile(model.CreateChild(NodeIdFile))
+	CreateProperty(file.CastNode, PropNamePath, PropString, "extra.png")
+	material.SetSlot(ExtraSlot(10), file)
+	material.SetSlot(ExtraSlot(2), file)
+	assertEqual(t, fmt.Sprint(material.Slots()), "[albedo normal specular emissive gloss ao cavity extra2 extra10]")
+	assertEqual(t, material.Slot(ExtraSlot(10)).Path(), "extra.png")
+}
+
+func TestMaterialPBR(t *testing.T) {
+	castFile := New()
+	material := AsMaterial(castFile.CreateRoot().CreateChild(NodeIdModel).CreateChild(NodeIdMaterial))
+	assertEqual(t, material.Metalness(), 0)
+	assertEqual(t, material.RoughnessValue(), 1)
+	assertEqual(t, material.AlbedoTint(), Vec4{1, 1, 1, 1})
+	assertEqual(t, material.UVTiling(), Vec2{1, 1})
+
+	material.SetMetalness(0.75)
+	material.SetRoughnessValue(0.25)
+	material.SetAlbedoTint(Vec4{1, 0.5, 0.25, 1})
+	material.SetEmissiveTint(Vec4{0, 0, 1, 1})
+	material.SetUVTiling(Vec2{2, 4})
+
+	var buf bytes.Buffer
+	if err := castFile.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := AsMaterial(must(Load(&buf)).Find(ByType(NodeIdMaterial))[0])
+	assertEqual(t, loaded.Metalness(), 0.75)
+	assertEqual(t, loaded.RoughnessValue(), 0.25)
+	assertEqual(t, loaded.AlbedoTint(), Vec4{1, 0.5, 0.25, 1})
+	assertEqual(t, loaded.EmissiveTint(), Vec4{0, 0, 1, 1})
+	assertEqual(t, loaded.UVTiling(), Vec2{2, 4})
+}
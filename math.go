@@ -0,0 +1,181 @@
+package cast
+
+import "math"
+
+// ----------------------- //
+//          MATH           //
+// ----------------------- //
+
+// Mat4 is a 4x4 transformation matrix stored in column-major order, the element in row r and column c
+// is at index c*4+r
+type Mat4 [16]float32
+
+// Ident4 returns the identity matrix
+func Ident4() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Translate4 returns a matrix translating by the given vector
+func Translate4(v Vec3) Mat4 {
+	m := Ident4()
+	m[12], m[13], m[14] = v.X, v.Y, v.Z
+	return m
+}
+
+// Scale4 returns a matrix scaling by the given factors
+func Scale4(v Vec3) Mat4 {
+	m := Ident4()
+	m[0], m[5], m[10] = v.X, v.Y, v.Z
+	return m
+}
+
+// Mat4FromTRS returns the matrix applying the given scale, rotation and translation in that order, as stored
+// in the position, rotation and scale properties of bones and instances
+func Mat4FromTRS(t Vec3, r Quat, s Vec3) Mat4 {
+	return Translate4(t).Mul(r.Mat4()).Mul(Scale4(s))
+}
+
+// Decompose splits a matrix composed by [Mat4FromTRS] into its translation, rotation and scale. A mirroring
+// is returned as a negative X scale, shear is not representable and dropped.
+func (m Mat4) Decompose() (t Vec3, r Quat, s Vec3) {
+	t = Vec3{m[12], m[13], m[14]}
+	s = Vec3{
+		lenVec3(Vec3{m[0], m[1], m[2]}),
+		lenVec3(Vec3{m[4], m[5], m[6]}),
+		lenVec3(Vec3{m[8], m[9], m[10]}),
+	}
+	if m.det3() < 0 {
+		s.X = -s.X
+	}
+
+	rotation := Ident4()
+	for c, f := range [3]float32{s.X, s.Y, s.Z} {
+		if f == 0 {
+			continue
+		}
+		for row := range 3 {
+			rotation[c*4+row] = m[c*4+row] / f
+		}
+	}
+	return t, QuatFromMat4(rotation), s
+}
+
+// At returns the element in the given row and column
+func (m Mat4) At(row, col int) float32 {
+	return m[col*4+row]
+}
+
+// Mul returns the product m * o, which applies o first and m second
+func (m Mat4) Mul(o Mat4) Mat4 {
+	var r Mat4
+	for c := range 4 {
+		for row := range 4 {
+			var sum float32
+			for k := range 4 {
+				sum += m[k*4+row] * o[c*4+k]
+			}
+			r[c*4+row] = sum
+		}
+	}
+	return r
+}
+
+// TransformPoint returns the point transformed by the matrix, including the translation
+func (m Mat4) TransformPoint(p Vec3) Vec3 {
+	return Vec3{
+		X: m[0]*p.X + m[4]*p.Y + m[8]*p.Z + m[12],
+		Y: m[1]*p.X + m[5]*p.Y + m[9]*p.Z + m[13],
+		Z: m[2]*p.X + m[6]*p.Y + m[10]*p.Z + m[14],
+	}
+}
+
+// TransformDirection returns the direction transformed by the matrix, ignoring the translation
+func (m Mat4) TransformDirection(d Vec3) Vec3 {
+	return Vec3{
+		X: m[0]*d.X + m[4]*d.Y + m[8]*d.Z,
+		Y: m[1]*d.X + m[5]*d.Y + m[9]*d.Z,
+		Z: m[2]*d.X + m[6]*d.Y + m[10]*d.Z,
+	}
+}
+
+// Inverse returns the inverse of an affine matrix, i.e. one whose last row is 0 0 0 1. The zero matrix is
+// returned if the matrix is singular.
+func (m Mat4) Inverse() Mat4 {
+	det := m.det3()
+	if det == 0 {
+		return Mat4{}
+	}
+
+	// the inverse of the upper 3x3 part is its adjugate, the transposed cofactor matrix, divided by the determinant
+	inv := transpose(m.normalMatrix())
+	for i := range 12 {
+		inv[i] /= det
+	}
+
+	t := inv.TransformDirection(Vec3{m[12], m[13], m[14]})
+	inv[12], inv[13], inv[14] = -t.X, -t.Y, -t.Z
+	return inv
+}
+
+// det3 returns the determinant of the upper 3x3 part of the matrix
+func (m Mat4) det3() float32 {
+	return m[0]*(m[5]*m[10]-m[9]*m[6]) -
+		m[4]*(m[1]*m[10]-m[9]*m[2]) +
+		m[8]*(m[1]*m[6]-m[5]*m[2])
+}
+
+// normalMatrix returns a matrix transforming normals consistently with the matrix, i.e. the cofactor matrix
+// of its upper 3x3 part. It equals the inverse transpose scaled by the determinant, so the transformed normals
+// have to be normalized.
+func (m Mat4) normalMatrix() Mat4 {
+	return Mat4{
+		m[5]*m[10] - m[6]*m[9], m[8]*m[6] - m[4]*m[10], m[4]*m[9] - m[8]*m[5], 0,
+		m[9]*m[2] - m[1]*m[10], m[0]*m[10] - m[8]*m[2], m[8]*m[1] - m[0]*m[9], 0,
+		m[1]*m[6] - m[5]*m[2], m[4]*m[2] - m[0]*m[6], m[0]*m[5] - m[4]*m[1], 0,
+		0, 0, 0, 1,
+	}
+}
+
+// normalize returns the vector scaled to unit length, a zero vector is returned as is
+func (v Vec3) normalize() Vec3 {
+	l := float32(math.Sqrt(float64(v.X*v.X + v.Y*v.Y + v.Z*v.Z)))
+	if l == 0 {
+		return v
+	}
+	return Vec3{X: v.X / l, Y: v.Y / l, Z: v.Z / l}
+}
+
+// addVec3 returns the sum of the vectors
+func addVec3(a, b Vec3) Vec3 {
+	return Vec3{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
+}
+
+// subVec3 returns the difference of the vectors
+func subVec3(a, b Vec3) Vec3 {
+	return Vec3{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+}
+
+// scaleVec3 returns the vector scaled by the given factor
+func scaleVec3(v Vec3, f float32) Vec3 {
+	return Vec3{v.X * f, v.Y * f, v.Z * f}
+}
+
+// dotVec3 returns the dot product of the vectors
+func dotVec3(a, b Vec3) float32 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+// crossVec3 returns the cross product of the vectors
+func crossVec3(a, b Vec3) Vec3 {
+	return Vec3{a.Y*b.Z - a.Z*b.Y, a.Z*b.X - a.X*b.Z, a.X*b.Y - a.Y*b.X}
+}
+
+// lenVec3 returns the length of the vector
+func lenVec3(v Vec3) float32 {
+	return float32(math.Sqrt(float64(dotVec3(v, v))))
+}
@@ -0,0 +1,37 @@
+package cast
+
+import "testing"
+
+func TestMat4(t *testing.T) {
+	m := Translate4(Vec3{1, 2, 3}).Mul(Scale4(Vec3{2, 2, 2}))
+	assertEqual(t, m.TransformPoint(Vec3{1, 1, 1}), Vec3{3, 4, 5})
+	assertEqual(t, m.TransformDirection(Vec3{1, 1, 1}), Vec3{2, 2, 2})
+	assertEqual(t, m.At(0, 3), 1)
+	assertEqual(t, m.At(2, 2), 2)
+	assertEqual(t, Ident4().Mul(m), m)
+
+	// a non uniform scale keeps normals perpendicular to the transformed surface
+	s := Scale4(Vec3{1, 4, 1})
+	tangent := s.TransformDirection(Vec3{1, -1, 0})
+	normal := s.normalMatrix().TransformDirection(Vec3{1, 1, 0})
+	assertEqual(t, tangent.X*normal.X+tangent.Y*normal.Y+tangent.Z*normal.Z, 0)
+	assertEqual(t, s.det3(), 4)
+
+	inverse := m.Inverse()
+	assertEqual(t, inverse.TransformPoint(Vec3{3, 4, 5}), Vec3{1, 1, 1})
+	assertEqual(t, inverse.Mul(m), Ident4())
+	assertEqual(t, Scale4(Vec3{1, 0, 1}).Inverse(), Mat4{})
+
+	translation, rotation, scale := Vec3{1, 2, 3}, QuatFromEuler(0.3, 0.2, -1), Vec3{2, 0.5, 1}
+	trs := Mat4FromTRS(translation, rotation, scale)
+	assertNearVec3(t, trs.TransformPoint(Vec3{X: 1}), addVec3(translation, rotation.Rotate(Vec3{X: 2})))
+
+	tt, tr, ts := trs.Decompose()
+	assertNearVec3(t, tt, translation)
+	assertNear(t, tr.Dot(rotation), 1)
+	assertNearVec3(t, ts, scale)
+
+	_, tr, ts = Mat4FromTRS(translation, rotation, Vec3{-1, 1, 1}).Decompose()
+	assertNear(t, tr.Dot(rotation), 1)
+	assertNearVec3(t, ts, Vec3{-1, 1, 1})
+}
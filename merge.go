@@ -0,0 +1,53 @@
+package cast
+
+import "fmt"
+
+// ----------------------- //
+//          MERGE          //
+// ----------------------- //
+
+// Merge moves the root nodes of src into dst and resolves hash collisions with [CastFile.RemapHashes],
+// so nodes of src whose hash is already used in dst are assigned a fresh hash and the Integer64
+// properties within the moved nodes that reference them are updated accordingly. src is left without roots.
+func Merge(dst, src *CastFile) error {
+	if dst == src {
+		return ErrMergeSelf
+	}
+
+	for _, root := range src.rootNodes {
+		dst.rootNodes = append(dst.rootNodes, root)
+		dst.adopt(root)
+	}
+	src.rootNodes = make([]*CastNode, 0)
+	src.invalidateIndex()
+
+	dst.RemapHashes()
+	return nil
+}
+
+// MergeFiles returns a new [CastFile] holding copies of the root nodes of all given files in order. The header
+// of the first file is used for the merged file. Hash collisions between the files are resolved with
+// [CastFile.RemapHashes], then Material and File nodes that are identical by content are reduced to a single
+// node with [CastFile.Deduplicate]. The given files are left untouched.
+func MergeFiles(files ...*CastFile) (*CastFile, error) {
+	merged := New()
+	for i, f := range files {
+		if f == nil {
+			return nil, fmt.Errorf("cast: cannot merge nil file at index %d", i)
+		}
+
+		if i == 0 {
+			merged.SetVersion(f.version).SetFlags(f.flags)
+		}
+
+		for _, root := range f.rootNodes {
+			root := copyNode(root, nil)
+			merged.rootNodes = append(merged.rootNodes, root)
+			merged.adopt(root)
+		}
+	}
+
+	merged.RemapHashes()
+	merged.Deduplicate()
+	return merged, nil
+}
@@ -0,0 +1,73 @@
+package cast
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	dst := loadTestFile(t, "cube.cast")
+	src := loadTestFile(t, "cube.cast")
+
+	if err := Merge(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(dst.Roots()), 2)
+	assertEqual(t, len(src.Roots()), 0)
+
+	hashes := map[uint64]int{}
+	for _, root := range dst.Roots() {
+		walkNodes(root, func(n *CastNode) {
+			hashes[n.Hash()]++
+		})
+	}
+	for hash, count := range hashes {
+		if count != 1 {
+			t.Errorf("hash %#x is used by %d nodes", hash, count)
+		}
+	}
+
+	// the mesh of the merged model must reference the merged material
+	model := dst.Roots()[1].GetChildrenOfType(NodeIdModel)[0]
+	material := model.GetChildrenOfType(NodeIdMaterial)[0]
+	mesh := model.GetChildrenOfType(NodeIdMesh)[0]
+	ref, err := GetPropertyValue[uint64](mesh, PropNameMaterial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, *ref, material.Hash())
+
+	assertEqual(t, Merge(dst, dst), ErrMergeSelf)
+}
+
+func TestMergeFiles(t *testing.T) {
+	a := loadTestFile(t, "cube.cast")
+	b := loadTestFile(t, "cube.cast")
+	ik := loadTestFile(t, "cast_ik.cast")
+
+	merged, err := MergeFiles(a, b, ik)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(merged.Roots()), 3)
+	assertEqual(t, len(a.Roots()), 1)
+	assertEqual(t, len(b.Roots()), 1)
+
+	// the identical material of the second cube is dropped
+	assertEqual(t, len(merged.GetNodesOfType(NodeIdMaterial)), len(ik.GetNodesOfType(NodeIdMaterial))+1)
+	material := merged.GetNodesOfType(NodeIdMaterial)[0]
+	assertEqual(t, len(merged.Roots()[1].GetDescendantsOfType(NodeIdMaterial)), 0)
+	for _, mesh := range merged.GetNodesOfType(NodeIdMesh)[:2] {
+		assertEqual(t, mesh.ResolveReference(PropNameMaterial), material)
+	}
+
+	hashes := map[uint64]bool{}
+	for n := range merged.AllNodes() {
+		assertEqual(t, hashes[n.Hash()], false)
+		hashes[n.Hash()] = true
+	}
+
+	// ik handles keep resolving to the bones of their own skeleton
+	ikHandle := merged.GetNodesOfType(NodeIdIKHandle)[0]
+	assertEqual(t, ikHandle.ResolveReference(PropNameStartBone).GetParentNode(), ikHandle.GetParentNode())
+
+	_, err = MergeFiles(a, nil)
+	assertEqual(t, err != nil, true)
+}
@@ -0,0 +1,692 @@
+package cast
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ----------------------- //
+//          MESH           //
+// ----------------------- //
+
+// SkinningMethod is the way the bone weights of a mesh deform its vertices, stored in the skinning method
+// property
+type SkinningMethod int
+
+const (
+	SkinningMethodLinear     SkinningMethod = iota // Linear blend skinning
+	SkinningMethodQuaternion                       // Dual quaternion skinning
+)
+
+// skinningMethodNames holds the names of the skinning methods as stored in the skinning method property
+var skinningMethodNames = map[SkinningMethod]string{
+	SkinningMethodLinear:     "linear",
+	SkinningMethodQuaternion: "quaternion",
+}
+
+// String returns the name of the skinning method as stored in the skinning method property
+func (m SkinningMethod) String() string {
+	if name, ok := skinningMethodNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("SkinningMethod(%d)", int(m))
+}
+
+// ParseSkinningMethod parses a skinning method from its name as stored in the skinning method property
+func ParseSkinningMethod(s string) (SkinningMethod, error) {
+	for method, name := range skinningMethodNames {
+		if name == s {
+			return method, nil
+		}
+	}
+	return 0, fmt.Errorf("cast: invalid skinning method: %q", s)
+}
+
+// Mesh wraps a mesh node with helpers for its buffers
+type Mesh struct {
+	*CastNode
+}
+
+// AsMesh returns the node as a [Mesh], nil if it is not a mesh node
+func AsMesh(n *CastNode) *Mesh {
+	if n == nil || n.id != NodeIdMesh {
+		return nil
+	}
+	return &Mesh{n}
+}
+
+// VertexCount returns the amount of vertices of the mesh, given by its position buffer
+func (m *Mesh) VertexCount() int {
+	return propertyCount(m.CastNode, PropNameVertexPositionBuffer)
+}
+
+// Faces returns the face indices of the mesh widened to uint32, regardless of the width they are stored with
+func (m *Mesh) Faces() ([]uint32, error) {
+	return GetPropertyValuesAsUint32(m.CastNode, PropNameFaceBuffer)
+}
+
+// SetFaces stores the face indices, three per triangle, in the smallest width that can index every vertex
+// of the mesh: bytes for up to 256 vertices, shorts for up to 65536 vertices and integers otherwise. The
+// position buffer must be set beforehand, indices out of its range are an error.
+func (m *Mesh) SetFaces(indices ...uint32) error {
+	vertexCount := m.VertexCount()
+	if err := checkFaces(indices, vertexCount); err != nil {
+		return fmt.Errorf("cast: %w", err)
+	}
+
+	return setIndexValues(m.CastNode, PropNameFaceBuffer, indexPropertyId(max(vertexCount-1, 0)), indices)
+}
+
+// CheckIndices returns an error if the face buffer of the mesh does not hold whole triangles or one of its
+// indices is out of range of the vertices. Meshes without faces pass the check.
+func (m *Mesh) CheckIndices() error {
+	if err := m.checkIndices(); err != nil {
+		return fmt.Errorf("cast: %w", err)
+	}
+	return nil
+}
+
+// checkIndices checks the face buffer of the mesh, see [Mesh.CheckIndices]
+func (m *Mesh) checkIndices() error {
+	property, ok := m.GetProperty(PropNameFaceBuffer)
+	if !ok {
+		return nil
+	}
+
+	switch property.Id() {
+	case PropByte, PropShort, PropInteger32:
+	default:
+		return fmt.Errorf("face buffer has type %s instead of an index type", property.Id())
+	}
+
+	faces, err := m.Faces()
+	if err != nil {
+		return err
+	}
+	return checkFaces(faces, m.VertexCount())
+}
+
+// checkFaces returns an error if the face indices do not form whole triangles or one of them is out of range
+// of the given vertex count
+func checkFaces(indices []uint32, vertexCount int) error {
+	if len(indices)%3 != 0 {
+		return fmt.Errorf("face buffer length %d is not a multiple of 3", len(indices))
+	}
+
+	for i, index := range indices {
+		if int64(index) >= int64(vertexCount) {
+			return fmt.Errorf("face index %d of triangle %d out of range of %d vertices", index, i/3, vertexCount)
+		}
+	}
+	return nil
+}
+
+// SkinningMethod returns the skinning method of the mesh, [SkinningMethodLinear] if it is not set. Returns
+// an error if the skinning method property holds an unknown method.
+func (m *Mesh) SkinningMethod() (SkinningMethod, error) {
+	method, err := GetPropertyValue[string](m.CastNode, PropNameSkinningMethod)
+	if err != nil {
+		return SkinningMethodLinear, nil
+	}
+	return ParseSkinningMethod(*method)
+}
+
+// SetSkinningMethod sets the skinning method of the mesh
+func (m *Mesh) SetSkinningMethod(method SkinningMethod) error {
+	if _, ok := skinningMethodNames[method]; !ok {
+		return fmt.Errorf("cast: invalid skinning method: %d", method)
+	}
+	_, err := CreateProperty(m.CastNode, PropNameSkinningMethod, PropString, method.String())
+	return err
+}
+
+// UVLayerCount returns the amount of UV layers of the mesh
+func (m *Mesh) UVLayerCount() int {
+	return int(GetPropertyValueOr(m.CastNode, PropNameUVLayerCount, byte(0)))
+}
+
+// UVLayers returns the indices of the UV layers present on the mesh in ascending order
+func (m *Mesh) UVLayers() []int {
+	var layers []int
+	for _, p := range m.properties {
+		if i, ok := uvLayerIndex(p.Name()); ok && p.Id() == PropVector2 {
+			layers = append(layers, i)
+		}
+	}
+	slices.Sort(layers)
+	return slices.Compact(layers)
+}
+
+// UVLayer returns the UV coordinates of the layer with the given index
+func (m *Mesh) UVLayer(i int) ([]Vec2, error) {
+	return GetPropertyValues[Vec2](m.CastNode, uvLayerName(i))
+}
+
+// SetUVLayer sets the UV coordinates of the layer with the given index, one per vertex, and raises the UV
+// layer count if needed. Layers are added in order, the index can be at most the current layer count.
+func (m *Mesh) SetUVLayer(i int, uvs []Vec2) error {
+	count := m.UVLayerCount()
+	if i < 0 || i > count || i > math.MaxUint8-1 {
+		return fmt.Errorf("cast: UV layer %d out of range of %d layers", i, count)
+	}
+
+	if _, ok := m.GetProperty(PropNameVertexPositionBuffer); ok && len(uvs) != m.VertexCount() {
+		return fmt.Errorf("cast: %d UV coordinates for %d vertices", len(uvs), m.VertexCount())
+	}
+
+	if _, err := CreateProperty(m.CastNode, uvLayerName(i), PropVector2, uvs...); err != nil {
+		return err
+	}
+
+	if i == count {
+		_, err := CreateProperty(m.CastNode, PropNameUVLayerCount, PropByte, byte(count+1))
+		return err
+	}
+	return nil
+}
+
+// RemoveUVLayer removes the UV layer with the given index and lowers the UV layer count. The following
+// layers move down by one index, so the layers stay numbered from 0.
+func (m *Mesh) RemoveUVLayer(i int) error {
+	if !m.removeProperty(uvLayerName(i)) {
+		return fmt.Errorf("cast: UV layer %d not found", i)
+	}
+
+	for _, layer := range m.UVLayers() {
+		if layer > i {
+			if err := m.RenameProperty(uvLayerName(layer), uvLayerName(layer-1)); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := CreateProperty(m.CastNode, PropNameUVLayerCount, PropByte, byte(max(m.UVLayerCount()-1, 0)))
+	return err
+}
+
+// SyncUVLayerCount sets the UV layer count to the number of UV layers present on the mesh and returns it
+func (m *Mesh) SyncUVLayerCount() (int, error) {
+	count := min(len(m.UVLayers()), math.MaxUint8)
+	_, err := CreateProperty(m.CastNode, PropNameUVLayerCount, PropByte, byte(count))
+	return count, err
+}
+
+// checkUVLayerCount returns an error if the UV layer count of the mesh does not match its UV layers, which
+// must be numbered from 0
+func (m *Mesh) checkUVLayerCount() error {
+	layers := m.UVLayers()
+	if count := m.UVLayerCount(); count != len(layers) {
+		return fmt.Errorf("UV layer count %d does not match %d UV layers", count, len(layers))
+	}
+	for i, layer := range layers {
+		if layer != i {
+			return fmt.Errorf("UV layer %d is missing", i)
+		}
+	}
+	return nil
+}
+
+// uvLayerName returns the property name of the UV layer with the given index
+func uvLayerName(i int) CastPropertyName {
+	return CastPropertyName(fmt.Sprintf(string(PropNameVertexUVBuffer), i))
+}
+
+// uvLayerIndex returns the index of the UV layer with the given property name, reports whether the name is
+// the one of a UV layer
+func uvLayerIndex(name CastPropertyName) (int, bool) {
+	digits, ok := strings.CutPrefix(string(name), "u")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(digits)
+	if err != nil || i < 0 || strconv.Itoa(i) != digits {
+		return 0, false
+	}
+	return i, true
+}
+
+// ColorEncoding is the representation of the vertex color buffer
+type ColorEncoding int
+
+const (
+	ColorPacked ColorEncoding = iota // Integer32 values holding 8 bit RGBA channels, red in the lowest byte
+	ColorFloat                       // Vector4 values holding RGBA channels in the range [0, 1]
+)
+
+// Colors returns the vertex colors of the mesh as RGBA channels in the range [0, 1], regardless of their encoding
+func (m *Mesh) Colors() ([]Vec4, error) {
+	property, ok := m.GetProperty(PropNameVertexColorBuffer)
+	if !ok {
+		return nil, fmt.Errorf(`cast: property %s not found`, PropNameVertexColorBuffer)
+	}
+
+	switch p := property.(type) {
+	case *CastProperty[uint32]:
+		colors := make([]Vec4, len(p.GetValues()))
+		for i, c := range p.GetValues() {
+			colors[i] = UnpackColor(c)
+		}
+		return colors, nil
+	case *CastProperty[Vec4]:
+		return p.GetValues(), nil
+	default:
+		return nil, fmt.Errorf("cast: vertex color buffer has a type of %T", property)
+	}
+}
+
+// ColorEncoding returns the encoding of the vertex color buffer, false if the mesh has none
+func (m *Mesh) ColorEncoding() (ColorEncoding, bool) {
+	property, ok := m.GetProperty(PropNameVertexColorBuffer)
+	if !ok {
+		return 0, false
+	}
+
+	switch property.Id() {
+	case PropInteger32:
+		return ColorPacked, true
+	case PropVector4:
+		return ColorFloat, true
+	default:
+		return 0, false
+	}
+}
+
+// SetColors sets the vertex colors of the mesh, given as RGBA channels in the range [0, 1], using the given encoding
+func (m *Mesh) SetColors(colors []Vec4, encoding ColorEncoding) error {
+	switch encoding {
+	case ColorPacked:
+		packed := make([]uint32, len(colors))
+		for i, c := range colors {
+			packed[i] = PackColor(c)
+		}
+		_, err := CreateProperty(m.CastNode, PropNameVertexColorBuffer, PropInteger32, packed...)
+		return err
+	case ColorFloat:
+		_, err := CreateProperty(m.CastNode, PropNameVertexColorBuffer, PropVector4, colors...)
+		return err
+	default:
+		return fmt.Errorf("cast: invalid color encoding: %d", encoding)
+	}
+}
+
+// PackColor packs RGBA channels in the range [0, 1] into 8 bits each, red in the lowest byte
+func PackColor(c Vec4) uint32 {
+	return uint32(packChannel(c.X)) | uint32(packChannel(c.Y))<<8 | uint32(packChannel(c.Z))<<16 | uint32(packChannel(c.W))<<24
+}
+
+// UnpackColor unpacks 8 bit RGBA channels, red in the lowest byte, into the range [0, 1]
+func UnpackColor(c uint32) Vec4 {
+	return Vec4{
+		X: float32(c&0xFF) / 255,
+		Y: float32(c>>8&0xFF) / 255,
+		Z: float32(c>>16&0xFF) / 255,
+		W: float32(c>>24&0xFF) / 255,
+	}
+}
+
+// packChannel converts a channel in the range [0, 1] to 8 bits
+func packChannel(v float32) byte {
+	return byte(math.Round(float64(min(max(v, 0), 1) * 255)))
+}
+
+// ConvertColors converts the RGB channels of the vertex colors of the mesh from sRGB to linear if srgbToLinear
+// is set, from linear to sRGB otherwise, keeping their encoding. Alpha is left untouched. Packed colors are
+// rounded to 8 bits again, so converting them back and forth loses precision in the dark tones. Meshes
+// without vertex colors are left unchanged.
+func (m *Mesh) ConvertColors(srgbToLinear bool) error {
+	if !m.HasProperty(PropNameVertexColorBuffer) {
+		return nil
+	}
+
+	colors, err := m.Colors()
+	if err != nil {
+		return err
+	}
+	encoding, _ := m.ColorEncoding()
+
+	convert := LinearToSRGB
+	if srgbToLinear {
+		convert = SRGBToLinear
+	}
+
+	converted := make([]Vec4, len(colors))
+	for i, c := range colors {
+		converted[i] = Vec4{X: convert(c.X), Y: convert(c.Y), Z: convert(c.Z), W: c.W}
+	}
+	return m.SetColors(converted, encoding)
+}
+
+// SRGBToLinear converts a color channel in the range [0, 1] from the sRGB transfer function to linear
+func SRGBToLinear(v float32) float32 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return float32(math.Pow((float64(v)+0.055)/1.055, 2.4))
+}
+
+// LinearToSRGB converts a linear color channel in the range [0, 1] to the sRGB transfer function
+func LinearToSRGB(v float32) float32 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return float32(1.055*math.Pow(float64(v), 1/2.4) - 0.055)
+}
+
+// BakeTransform applies the transform to the vertex positions, normals and tangents of the mesh. Normals and
+// tangents are renormalized, and if the transform mirrors the mesh the winding order of the faces is reversed
+// so they keep facing outwards.
+func (m *Mesh) BakeTransform(transform Mat4) error {
+	if positions, err := GetPropertyValues[Vec3](m.CastNode, PropNameVertexPositionBuffer); err == nil {
+		for i, p := range positions {
+			positions[i] = transform.TransformPoint(p)
+		}
+	}
+
+	det := transform.det3()
+	normalMatrix := transform.normalMatrix()
+	if det < 0 {
+		for i := range normalMatrix {
+			normalMatrix[i] = -normalMatrix[i]
+		}
+	}
+	if normals, err := GetPropertyValues[Vec3](m.CastNode, PropNameVertexNormalBuffer); err == nil {
+		for i, n := range normals {
+			normals[i] = normalMatrix.TransformDirection(n).normalize()
+		}
+	}
+
+	if tangents, err := GetPropertyValues[Vec3](m.CastNode, PropNameVertexTangentBuffer); err == nil {
+		for i, t := range tangents {
+			tangents[i] = transform.TransformDirection(t).normalize()
+		}
+	}
+
+	p, ok := m.GetProperty(PropNameFaceBuffer)
+	if det >= 0 || !ok {
+		return nil
+	}
+
+	faces, err := GetPropertyValuesAsUint32(m.CastNode, PropNameFaceBuffer)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+2 < len(faces); i += 3 {
+		faces[i+1], faces[i+2] = faces[i+2], faces[i+1]
+	}
+	return setIndexValues(m.CastNode, PropNameFaceBuffer, p.Id(), faces)
+}
+
+// NormalizeWeights keeps the strongest maxInfluences bone weights of every vertex, or all of them if
+// maxInfluences is not positive, and rescales them to sum up to 1. The weight buffers are rewritten with
+// the new amount of influences per vertex, padded with zero weights, and the maximum weight influence
+// property is updated accordingly. Influence counts are never increased.
+func (m *Mesh) NormalizeWeights(maxInfluences int) error {
+	mi, err := GetPropertyValue[byte](m.CastNode, PropNameMaximumWeightInfluence)
+	if err != nil {
+		return err
+	}
+	influences := int(*mi)
+
+	bones, err := GetPropertyValuesAsUint32(m.CastNode, PropNameVertexWeightBoneBuffer)
+	if err != nil {
+		return err
+	}
+
+	weights, err := GetPropertyValues[float32](m.CastNode, PropNameVertexWeightValueBuffer)
+	if err != nil {
+		return err
+	}
+
+	vertexCount := m.VertexCount()
+	if len(bones) != vertexCount*influences || len(weights) != vertexCount*influences {
+		return fmt.Errorf("cast: weight buffers do not hold %d influences for %d vertices", influences, vertexCount)
+	}
+
+	trimmed := influences
+	if maxInfluences > 0 {
+		trimmed = min(influences, maxInfluences)
+	}
+
+	newBones := make([]uint32, vertexCount*trimmed)
+	newWeights := make([]float32, vertexCount*trimmed)
+	order := make([]int, influences)
+	for v := range vertexCount {
+		vertexBones := bones[v*influences : (v+1)*influences]
+		vertexWeights := weights[v*influences : (v+1)*influences]
+
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(vertexWeights[b], vertexWeights[a])
+		})
+
+		var sum float32
+		for _, i := range order[:trimmed] {
+			sum += max(vertexWeights[i], 0)
+		}
+
+		for j, i := range order[:trimmed] {
+			w := max(vertexWeights[i], 0)
+			if sum > 0 {
+				w /= sum
+			}
+			if w > 0 {
+				newBones[v*trimmed+j] = vertexBones[i]
+				newWeights[v*trimmed+j] = w
+			}
+		}
+	}
+
+	p, _ := m.GetProperty(PropNameVertexWeightBoneBuffer)
+	if err := setIndexValues(m.CastNode, PropNameVertexWeightBoneBuffer, p.Id(), newBones); err != nil {
+		return err
+	}
+	if _, err := CreateProperty(m.CastNode, PropNameVertexWeightValueBuffer, PropFloat, newWeights...); err != nil {
+		return err
+	}
+	_, err = CreateProperty(m.CastNode, PropNameMaximumWeightInfluence, PropByte, byte(trimmed))
+	return err
+}
+
+// ComputeMaxInfluence derives the maximum weight influence from the weight buffers instead of trusting the
+// stored value, writes it to the maximum weight influence property and returns it. The number of influences
+// stored per vertex is given by the length of the weight buffers, of which only the bones with positive
+// weights count. If no vertex uses all of them, the buffers are rewritten with the bones of positive weight
+// first and trimmed to the largest number used by a vertex, at least 1.
+func (m *Mesh) ComputeMaxInfluence() (int, error) {
+	bones, err := GetPropertyValuesAsUint32(m.CastNode, PropNameVertexWeightBoneBuffer)
+	if err != nil {
+		return 0, err
+	}
+
+	weights, err := GetPropertyValues[float32](m.CastNode, PropNameVertexWeightValueBuffer)
+	if err != nil {
+		return 0, err
+	}
+
+	vertexCount := m.VertexCount()
+	if vertexCount == 0 || len(bones) != len(weights) || len(weights)%vertexCount != 0 {
+		return 0, fmt.Errorf("cast: weight buffers of %d bones and %d weights do not match %d vertices", len(bones), len(weights), vertexCount)
+	}
+	stride := len(weights) / vertexCount
+
+	influences := 1
+	for v := range vertexCount {
+		used := 0
+		for _, w := range weights[v*stride : (v+1)*stride] {
+			if w > 0 {
+				used++
+			}
+		}
+		influences = max(influences, used)
+	}
+	if influences > math.MaxUint8 {
+		return 0, fmt.Errorf("cast: %d weight influences exceed the maximum of %d", influences, math.MaxUint8)
+	}
+
+	if influences < stride {
+		newBones := make([]uint32, vertexCount*influences)
+		newWeights := make([]float32, vertexCount*influences)
+		for v := range vertexCount {
+			j := v * influences
+			for i := v * stride; i < (v+1)*stride; i++ {
+				if weights[i] > 0 {
+					newBones[j] = bones[i]
+					newWeights[j] = weights[i]
+					j++
+				}
+			}
+		}
+
+		p, _ := m.GetProperty(PropNameVertexWeightBoneBuffer)
+		if err := setIndexValues(m.CastNode, PropNameVertexWeightBoneBuffer, p.Id(), newBones); err != nil {
+			return 0, err
+		}
+		if _, err := CreateProperty(m.CastNode, PropNameVertexWeightValueBuffer, PropFloat, newWeights...); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := CreateProperty(m.CastNode, PropNameMaximumWeightInfluence, PropByte, byte(influences)); err != nil {
+		return 0, err
+	}
+	return influences, nil
+}
+
+// SkinWeight is the influence of a bone on a vertex
+type SkinWeight struct {
+	Bone   uint32  // Bone is the index of the bone in the skeleton of the model
+	Weight float32 // Weight is the amount the bone moves the vertex
+}
+
+// SkinWeights returns the bone influences of every vertex, assembled from the weight bone and weight value
+// buffers which hold the maximum weight influence of entries per vertex. The returned slices hold that many
+// influences each, vertices influenced by fewer bones are padded with zero weights as stored in the buffers.
+func (m *Mesh) SkinWeights() ([][]SkinWeight, error) {
+	mi, err := GetPropertyValue[byte](m.CastNode, PropNameMaximumWeightInfluence)
+	if err != nil {
+		return nil, err
+	}
+	influences := int(*mi)
+
+	bones, err := GetPropertyValuesAsUint32(m.CastNode, PropNameVertexWeightBoneBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	weights, err := GetPropertyValues[float32](m.CastNode, PropNameVertexWeightValueBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	vertexCount := m.VertexCount()
+	if len(bones) != vertexCount*influences || len(weights) != vertexCount*influences {
+		return nil, fmt.Errorf("cast: weight buffers do not hold %d influences for %d vertices", influences, vertexCount)
+	}
+
+	pairs := make([]SkinWeight, len(weights))
+	for i := range pairs {
+		pairs[i] = SkinWeight{Bone: bones[i], Weight: weights[i]}
+	}
+
+	skinWeights := make([][]SkinWeight, vertexCount)
+	for v := range skinWeights {
+		skinWeights[v] = pairs[v*influences : (v+1)*influences : (v+1)*influences]
+	}
+	return skinWeights, nil
+}
+
+// SetSkinWeights writes the bone influences of every vertex to the weight bone and weight value buffers and
+// sets the maximum weight influence to the largest number of influences of a vertex, at least 1. Vertices
+// with fewer influences are padded with zero weights. The bone indices are stored in the smallest width that
+// holds them. If the position buffer is set, there must be influences for each of its vertices.
+func (m *Mesh) SetSkinWeights(skinWeights [][]SkinWeight) error {
+	if _, ok := m.GetProperty(PropNameVertexPositionBuffer); ok && len(skinWeights) != m.VertexCount() {
+		return fmt.Errorf("cast: skin weights of %d vertices for %d vertices", len(skinWeights), m.VertexCount())
+	}
+	if len(skinWeights) == 0 {
+		return ErrEmptyValues
+	}
+
+	influences := 1
+	var maxBone uint32
+	for _, vertex := range skinWeights {
+		influences = max(influences, len(vertex))
+		for _, w := range vertex {
+			maxBone = max(maxBone, w.Bone)
+		}
+	}
+	if influences > math.MaxUint8 {
+		return fmt.Errorf("cast: %d weight influences exceed the maximum of %d", influences, math.MaxUint8)
+	}
+
+	bones := make([]uint32, len(skinWeights)*influences)
+	weights := make([]float32, len(skinWeights)*influences)
+	for v, vertex := range skinWeights {
+		for i, w := range vertex {
+			bones[v*influences+i] = w.Bone
+			weights[v*influences+i] = w.Weight
+		}
+	}
+
+	id := indexPropertyId(int(min(maxBone, math.MaxUint16+1)))
+	if err := setIndexValues(m.CastNode, PropNameVertexWeightBoneBuffer, id, bones); err != nil {
+		return err
+	}
+	if _, err := CreateProperty(m.CastNode, PropNameVertexWeightValueBuffer, PropFloat, weights...); err != nil {
+		return err
+	}
+	_, err := CreateProperty(m.CastNode, PropNameMaximumWeightInfluence, PropByte, byte(influences))
+	return err
+}
+
+// setIndexValues stores the values in the index property with the given name using the given property id
+func setIndexValues(n *CastNode, name CastPropertyName, id CastPropertyId, values []uint32) error {
+	var err error
+	switch id {
+	case PropByte:
+		_, err = CreateProperty(n, name, id, narrowIndices[byte](values)...)
+	case PropShort:
+		_, err = CreateProperty(n, name, id, narrowIndices[uint16](values)...)
+	case PropInteger32:
+		_, err = CreateProperty(n, name, id, values...)
+	default:
+		err = fmt.Errorf("cast: invalid index property id: %v", id)
+	}
+	return err
+}
+
+// indexPropertyId returns the id of the smallest property type holding indices up to the given maximum
+func indexPropertyId(maxIndex int) CastPropertyId {
+	switch {
+	case maxIndex <= math.MaxUint8:
+		return PropByte
+	case maxIndex <= math.MaxUint16:
+		return PropShort
+	default:
+		return PropInteger32
+	}
+}
+
+// widenIndices converts the indices to uint32
+func widenIndices[T byte | uint16](indices []T) []uint32 {
+	widened := make([]uint32, len(indices))
+	for i, v := range indices {
+		widened[i] = uint32(v)
+	}
+	return widened
+}
+
+// narrowIndices converts the indices to a smaller width, they must fit into it
+func narrowIndices[T byte | uint16](indices []uint32) []T {
+	narrowed := make([]T, len(indices))
+	for i, v := range indices {
+		narrowed[i] = T(v)
+	}
+	return narrowed
+}
@@ -0,0 +1,321 @@
+package cast
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestMeshFaces(t *testing.T) {
+	for _, tc := range []struct {
+		vertices int
+		id       CastPropertyId
+	}{
+		{3, PropByte},
+		{256, PropByte},
+		{257, PropShort},
+		{65536, PropShort},
+		{65537, PropInteger32},
+	} {
+		mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdMesh))
+		CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, make([]Vec3, tc.vertices)...)
+
+		last := uint32(tc.vertices - 1)
+		if err := mesh.SetFaces(0, 1, last); err != nil {
+			t.Fatal(err)
+		}
+
+		p, _ := mesh.GetProperty(PropNameFaceBuffer)
+		assertEqual(t, p.Id(), tc.id)
+
+		faces, err := mesh.Faces()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, len(faces), 3)
+		assertEqual(t, faces[2], last)
+
+		if err := mesh.SetFaces(0, 1, uint32(tc.vertices)); err == nil {
+			t.Errorf("expected out of range error for %d vertices", tc.vertices)
+		}
+	}
+
+	if AsMesh(New().CreateRoot()) != nil {
+		t.Error("expected nil for a non mesh node")
+	}
+}
+
+func TestMeshComputeMaxInfluence(t *testing.T) {
+	mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, make([]Vec3, 2)...)
+	CreateProperty(mesh.CastNode, PropNameMaximumWeightInfluence, PropByte, byte(8))
+	CreateProperty(mesh.CastNode, PropNameVertexWeightBoneBuffer, PropByte, []byte{4, 5, 6, 7, 1, 2}...)
+	CreateProperty(mesh.CastNode, PropNameVertexWeightValueBuffer, PropFloat, []float32{0, 0.6, 0.4, 1, 0, 0}...)
+
+	assertEqual(t, must(mesh.ComputeMaxInfluence()), 2)
+	assertEqual(t, *must(GetPropertyValue[byte](mesh.CastNode, PropNameMaximumWeightInfluence)), 2)
+	assertEqual(t, [4]byte(must(GetPropertyValues[byte](mesh.CastNode, PropNameVertexWeightBoneBuffer))), [4]byte{5, 6, 7, 0})
+	assertEqual(t, [4]float32(must(GetPropertyValues[float32](mesh.CastNode, PropNameVertexWeightValueBuffer))), [4]float32{0.6, 0.4, 1, 0})
+
+	// buffers using all influences are kept
+	assertEqual(t, must(mesh.ComputeMaxInfluence()), 2)
+	assertEqual(t, len(must(GetPropertyValues[float32](mesh.CastNode, PropNameVertexWeightValueBuffer))), 4)
+
+	CreateProperty(mesh.CastNode, PropNameVertexWeightValueBuffer, PropFloat, []float32{1, 0, 0}...)
+	_, err := mesh.ComputeMaxInfluence()
+	assertEqual(t, err != nil, true)
+
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	for _, n := range castFile.Find(ByType(NodeIdMesh)) {
+		mi := int(*must(GetPropertyValue[byte](n, PropNameMaximumWeightInfluence)))
+		influences := must(AsMesh(n).ComputeMaxInfluence())
+		if influences > mi {
+			t.Errorf("computed %d influences for a maximum of %d", influences, mi)
+		}
+	}
+}
+
+func TestMeshSkinWeights(t *testing.T) {
+	mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, make([]Vec3, 3)...)
+
+	err := mesh.SetSkinWeights([][]SkinWeight{
+		{{Bone: 1, Weight: 1}},
+		{{Bone: 2, Weight: 0.25}, {Bone: 300, Weight: 0.75}},
+		{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, *must(GetPropertyValue[byte](mesh.CastNode, PropNameMaximumWeightInfluence)), 2)
+	assertEqual(t, [6]uint16(must(GetPropertyValues[uint16](mesh.CastNode, PropNameVertexWeightBoneBuffer))), [6]uint16{1, 0, 2, 300, 0, 0})
+	assertEqual(t, [6]float32(must(GetPropertyValues[float32](mesh.CastNode, PropNameVertexWeightValueBuffer))), [6]float32{1, 0, 0.25, 0.75, 0, 0})
+
+	skinWeights := must(mesh.SkinWeights())
+	assertEqual(t, len(skinWeights), 3)
+	assertEqual(t, [2]SkinWeight(skinWeights[1]), [2]SkinWeight{{2, 0.25}, {300, 0.75}})
+	assertEqual(t, [2]SkinWeight(skinWeights[2]), [2]SkinWeight{})
+	assertEqual(t, cap(skinWeights[0]), 2)
+
+	assertEqual(t, mesh.SetSkinWeights(make([][]SkinWeight, 2)) != nil, true)
+
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	for _, n := range castFile.Find(ByType(NodeIdMesh)) {
+		mesh := AsMesh(n)
+		bones := must(GetPropertyValuesAsUint32(n, PropNameVertexWeightBoneBuffer))
+		weights := must(GetPropertyValues[float32](n, PropNameVertexWeightValueBuffer))
+
+		skinWeights := must(mesh.SkinWeights())
+		if err := mesh.SetSkinWeights(skinWeights); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, slices.Equal(must(GetPropertyValuesAsUint32(n, PropNameVertexWeightBoneBuffer)), bones), true)
+		assertEqual(t, slices.Equal(must(GetPropertyValues[float32](n, PropNameVertexWeightValueBuffer)), weights), true)
+	}
+}
+
+func TestMeshCheckIndices(t *testing.T) {
+	mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdMesh))
+	assertEqual(t, mesh.CheckIndices(), nil)
+
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, make([]Vec3, 4)...)
+	CreateProperty(mesh.CastNode, PropNameFaceBuffer, PropShort, []uint16{0, 1, 2, 2, 3, 0}...)
+	assertEqual(t, mesh.CheckIndices(), nil)
+
+	for _, tc := range []struct {
+		faces func()
+		err   string
+	}{
+		{func() { CreateProperty(mesh.CastNode, PropNameFaceBuffer, PropShort, []uint16{0, 1, 2, 2, 3, 4}...) }, "cast: face index 4 of triangle 1 out of range of 4 vertices"},
+		{func() { CreateProperty(mesh.CastNode, PropNameFaceBuffer, PropByte, []byte{0, 1, 2, 3}...) }, "cast: face buffer length 4 is not a multiple of 3"},
+		{func() { CreateProperty(mesh.CastNode, PropNameFaceBuffer, PropFloat, []float32{0, 1, 2}...) }, "cast: face buffer has type f instead of an index type"},
+	} {
+		tc.faces()
+		err := mesh.CheckIndices()
+		if err == nil {
+			t.Fatalf("expected %q", tc.err)
+		}
+		assertEqual(t, err.Error(), tc.err)
+	}
+}
+
+func TestMeshNormalizeWeights(t *testing.T) {
+	mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, make([]Vec3, 2)...)
+	CreateProperty(mesh.CastNode, PropNameMaximumWeightInfluence, PropByte, byte(3))
+	CreateProperty(mesh.CastNode, PropNameVertexWeightBoneBuffer, PropShort, []uint16{4, 5, 6, 7, 0, 0}...)
+	CreateProperty(mesh.CastNode, PropNameVertexWeightValueBuffer, PropFloat, []float32{0.2, 0.6, 0.4, 2, 0, 0}...)
+
+	if err := mesh.NormalizeWeights(2); err != nil {
+		t.Fatal(err)
+	}
+
+	mi, _ := GetPropertyValue[byte](mesh.CastNode, PropNameMaximumWeightInfluence)
+	assertEqual(t, *mi, 2)
+
+	bones, _ := GetPropertyValues[uint16](mesh.CastNode, PropNameVertexWeightBoneBuffer)
+	assertEqual(t, len(bones), 4)
+	assertEqual(t, [4]uint16(bones), [4]uint16{5, 6, 7, 0})
+
+	weights, _ := GetPropertyValues[float32](mesh.CastNode, PropNameVertexWeightValueBuffer)
+	assertEqual(t, [4]float32(weights), [4]float32{0.6, 0.4, 1, 0})
+
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	for _, n := range castFile.Find(ByType(NodeIdMesh)) {
+		mesh := AsMesh(n)
+		if err := mesh.NormalizeWeights(4); err != nil {
+			t.Fatal(err)
+		}
+
+		mi, _ := GetPropertyValue[byte](n, PropNameMaximumWeightInfluence)
+		weights, _ := GetPropertyValues[float32](n, PropNameVertexWeightValueBuffer)
+		assertEqual(t, len(weights), mesh.VertexCount()*int(*mi))
+		for v := range mesh.VertexCount() {
+			var sum float32
+			for _, w := range weights[v*int(*mi) : (v+1)*int(*mi)] {
+				sum += w
+			}
+			if sum < 0.999 || sum > 1.001 {
+				t.Fatalf("weights of vertex %d sum up to %v", v, sum)
+			}
+		}
+	}
+}
+
+func TestMeshUVLayers(t *testing.T) {
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	mesh := AsMesh(castFile.Find(ByType(NodeIdMesh))[0])
+	assertEqual(t, mesh.UVLayerCount(), 2)
+	assertEqual(t, len(mesh.UVLayers()), 2)
+
+	uvs, err := mesh.UVLayer(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(uvs), mesh.VertexCount())
+
+	if err := mesh.SetUVLayer(2, uvs); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, mesh.UVLayerCount(), 3)
+	assertEqual(t, len(mesh.UVLayers()), 3)
+	assertEqual(t, mesh.UVLayers()[2], 2)
+
+	if err := mesh.SetUVLayer(0, uvs); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, mesh.UVLayerCount(), 3)
+
+	if err := mesh.SetUVLayer(4, uvs); err == nil {
+		t.Error("expected error for a layer after a gap")
+	}
+	if err := mesh.SetUVLayer(3, uvs[1:]); err == nil {
+		t.Error("expected error for a coordinate count mismatch")
+	}
+
+	// removing a layer moves the following ones down
+	if err := mesh.RemoveUVLayer(1); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, mesh.UVLayerCount(), 2)
+	assertEqual(t, slices.Equal(mesh.UVLayers(), []int{0, 1}), true)
+	assertEqual(t, len(must(mesh.UVLayer(1))), len(uvs))
+	assertEqual(t, mesh.checkUVLayerCount(), nil)
+	assertEqual(t, mesh.RemoveUVLayer(2) != nil, true)
+
+	SetProperty(mesh.CastNode, PropNameUVLayerCount, byte(5))
+	assertEqual(t, mesh.checkUVLayerCount() != nil, true)
+	assertEqual(t, must(mesh.SyncUVLayerCount()), 2)
+	assertEqual(t, mesh.UVLayerCount(), 2)
+}
+
+func TestMeshColors(t *testing.T) {
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+	mesh := AsMesh(castFile.Find(ByType(NodeIdMesh))[0])
+
+	encoding, ok := mesh.ColorEncoding()
+	assertEqual(t, ok, true)
+	assertEqual(t, encoding, ColorPacked)
+
+	packed, _ := GetPropertyValues[uint32](mesh.CastNode, PropNameVertexColorBuffer)
+	packed = slices.Clone(packed)
+
+	colors, err := mesh.Colors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(colors), mesh.VertexCount())
+
+	if err := mesh.SetColors(colors, ColorFloat); err != nil {
+		t.Fatal(err)
+	}
+	encoding, _ = mesh.ColorEncoding()
+	assertEqual(t, encoding, ColorFloat)
+
+	colors, _ = mesh.Colors()
+	if err := mesh.SetColors(colors, ColorPacked); err != nil {
+		t.Fatal(err)
+	}
+	repacked, _ := GetPropertyValues[uint32](mesh.CastNode, PropNameVertexColorBuffer)
+	assertEqual(t, slices.Equal(repacked, packed), true)
+
+	assertEqual(t, PackColor(Vec4{X: 1, Y: 0.5, Z: 0, W: 2}), 0xFF0080FF)
+	assertEqual(t, UnpackColor(0xFF0000FF), Vec4{X: 1, W: 1})
+}
+
+func TestMeshConvertColors(t *testing.T) {
+	assertEqual(t, SRGBToLinear(0), 0)
+	assertEqual(t, SRGBToLinear(1), 1)
+	assertEqual(t, math.Abs(float64(SRGBToLinear(0.5))-0.214041) < 1e-5, true)
+	assertEqual(t, math.Abs(float64(LinearToSRGB(SRGBToLinear(0.73)))-0.73) < 1e-5, true)
+	assertEqual(t, math.Abs(float64(LinearToSRGB(SRGBToLinear(0.02)))-0.02) < 1e-6, true)
+
+	mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdMesh))
+	if err := mesh.ConvertColors(true); err != nil {
+		t.Fatal(err)
+	}
+
+	mesh.SetColors([]Vec4{{0.5, 1, 0, 0.5}}, ColorFloat)
+	if err := mesh.ConvertColors(true); err != nil {
+		t.Fatal(err)
+	}
+	colors := must(mesh.Colors())
+	assertEqual(t, colors[0].X, SRGBToLinear(0.5))
+	assertEqual(t, colors[0].Y, 1)
+	assertEqual(t, colors[0].W, 0.5)
+
+	mesh.SetColors([]Vec4{{0.5, 1, 0, 0.5}}, ColorPacked)
+	if err := mesh.ConvertColors(true); err != nil {
+		t.Fatal(err)
+	}
+	encoding, _ := mesh.ColorEncoding()
+	assertEqual(t, encoding, ColorPacked)
+	assertEqual(t, must(GetPropertyValues[uint32](mesh.CastNode, PropNameVertexColorBuffer))[0], PackColor(Vec4{SRGBToLinear(128.0 / 255), 1, 0, 128.0 / 255}))
+
+	CreateProperty(mesh.CastNode, PropNameVertexColorBuffer, PropFloat, float32(1))
+	assertEqual(t, mesh.ConvertColors(false) != nil, true)
+}
+
+func TestMeshSkinningMethod(t *testing.T) {
+	mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdModel).CreateChild(NodeIdMesh))
+	assertEqual(t, must(mesh.SkinningMethod()), SkinningMethodLinear)
+
+	for _, method := range []SkinningMethod{SkinningMethodQuaternion, SkinningMethodLinear} {
+		if err := mesh.SetSkinningMethod(method); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, must(mesh.SkinningMethod()), method)
+		assertEqual(t, must(ParseSkinningMethod(method.String())), method)
+	}
+	assertEqual(t, GetPropertyValueOr(mesh.CastNode, PropNameSkinningMethod, ""), "linear")
+
+	if err := mesh.SetSkinningMethod(SkinningMethod(5)); err == nil {
+		t.Error("expected error for an invalid skinning method")
+	}
+	assertEqual(t, SkinningMethod(5).String(), "SkinningMethod(5)")
+
+	CreateProperty(mesh.CastNode, PropNameSkinningMethod, PropString, "dqs")
+	if _, err := mesh.SkinningMethod(); err == nil {
+		t.Error("expected error for an invalid skinning method property")
+	}
+}
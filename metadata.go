@@ -0,0 +1,152 @@
+package cast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ----------------------- //
+//        METADATA         //
+// ----------------------- //
+
+// Axis is the up axis stored in the metadata of a file
+type Axis string
+
+const (
+	AxisX Axis = "x"
+	AxisY Axis = "y"
+	AxisZ Axis = "z"
+)
+
+// Metadata wraps a metadata node describing the scene, such as its author, the software that created it and
+// its up axis
+type Metadata struct {
+	*CastNode
+}
+
+// AsMetadata returns the node as [Metadata], nil if it is not a metadata node
+func AsMetadata(n *CastNode) *Metadata {
+	if n == nil || n.id != NodeIdMetadata {
+		return nil
+	}
+	return &Metadata{n}
+}
+
+// Author returns the author of the scene
+func (m *Metadata) Author() string {
+	return GetPropertyValueOr(m.CastNode, PropNameAuthor, "")
+}
+
+// SetAuthor sets the author of the scene
+func (m *Metadata) SetAuthor(author string) error {
+	_, err := CreateProperty(m.CastNode, PropNameAuthor, PropString, author)
+	return err
+}
+
+// Software returns the software that created the scene
+func (m *Metadata) Software() string {
+	return GetPropertyValueOr(m.CastNode, PropNameSoftware, "")
+}
+
+// SetSoftware sets the software that created the scene
+func (m *Metadata) SetSoftware(software string) error {
+	_, err := CreateProperty(m.CastNode, PropNameSoftware, PropString, software)
+	return err
+}
+
+// UpAxis returns the up axis of the scene, empty if it is not set
+func (m *Metadata) UpAxis() Axis {
+	return Axis(GetPropertyValueOr(m.CastNode, PropNameUpAxis, ""))
+}
+
+// SetUpAxis sets the up axis of the scene
+func (m *Metadata) SetUpAxis(axis Axis) error {
+	if err := checkAxis(axis); err != nil {
+		return err
+	}
+
+	_, err := CreateProperty(m.CastNode, PropNameUpAxis, PropString, string(axis))
+	return err
+}
+
+// Metadata returns the metadata node of the first root node, nil if there is none
+func (n *CastFile) Metadata() *Metadata {
+	if len(n.rootNodes) == 0 {
+		return nil
+	}
+	metadata := n.rootNodes[0].GetChildrenOfType(NodeIdMetadata)
+	if len(metadata) == 0 {
+		return nil
+	}
+	return AsMetadata(metadata[0])
+}
+
+// CreatedBy returns the name and version of the tool that created the file, see [CastFile.SetCreatedBy]
+func (n *CastFile) CreatedBy() (tool, version string) {
+	m := n.Metadata()
+	if m == nil {
+		return "", ""
+	}
+
+	software := m.Software()
+	if i := strings.LastIndexByte(software, ' '); i >= 0 {
+		return software[:i], software[i+1:]
+	}
+	return software, ""
+}
+
+// SetCreatedBy records the name and version of the tool creating the file in the software property of the
+// metadata, joined by a space. The metadata node and the root node are created if necessary.
+func (n *CastFile) SetCreatedBy(tool, version string) error {
+	software := tool
+	if version != "" {
+		software += " " + version
+	}
+	return n.metadata().SetSoftware(software)
+}
+
+// UpAxis returns the up axis recorded in the metadata, empty if it is not set
+func (n *CastFile) UpAxis() Axis {
+	m := n.Metadata()
+	if m == nil {
+		return ""
+	}
+	return m.UpAxis()
+}
+
+// SetUpAxis records the up axis in the metadata. The metadata node and the root node are created if necessary.
+// The data of the file is not changed, see [ConvertAxes].
+func (n *CastFile) SetUpAxis(axis Axis) error {
+	// checked before the metadata node is created
+	if err := checkAxis(axis); err != nil {
+		return err
+	}
+	return n.metadata().SetUpAxis(axis)
+}
+
+// metadata returns the metadata node of the first root node, creating the nodes if necessary
+func (n *CastFile) metadata() *Metadata {
+	if m := n.Metadata(); m != nil {
+		return m
+	}
+
+	return AsMetadata(n.firstRoot().CreateChild(NodeIdMetadata))
+}
+
+// firstRoot returns the first root node, creating it if there is none
+func (n *CastFile) firstRoot() *CastNode {
+	if len(n.rootNodes) == 0 {
+		return n.CreateRoot()
+	}
+	return n.rootNodes[0]
+}
+
+// checkAxis returns an error if the axis is not one of [AxisX], [AxisY] or [AxisZ]
+func checkAxis(axis Axis) error {
+	switch axis {
+	case AxisX, AxisY, AxisZ:
+		return nil
+	default:
+		return fmt.Errorf("cast: invalid up axis: %q", axis)
+	}
+}
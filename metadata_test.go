@@ -0,0 +1,53 @@
+package cast
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	castFile := New()
+	assertEqual(t, castFile.Metadata(), nil)
+	assertEqual(t, castFile.UpAxis(), "")
+	tool, version := castFile.CreatedBy()
+	assertEqual(t, tool, "")
+	assertEqual(t, version, "")
+
+	if err := castFile.SetUpAxis("w"); err == nil {
+		t.Error("expected error for invalid axis")
+	}
+	assertEqual(t, len(castFile.Roots()), 0)
+
+	if err := castFile.SetCreatedBy("cast exporter", "1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := castFile.SetUpAxis(AxisZ); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(castFile.Roots()), 1)
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdMetadata)), 1)
+	if err := castFile.Metadata().SetAuthor("someone"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := castFile.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := must(Load(&buf))
+
+	tool, version = loaded.CreatedBy()
+	assertEqual(t, tool, "cast exporter")
+	assertEqual(t, version, "1.2.0")
+	assertEqual(t, loaded.UpAxis(), AxisZ)
+	assertEqual(t, loaded.Metadata().Software(), "cast exporter 1.2.0")
+	assertEqual(t, loaded.Metadata().Author(), "someone")
+
+	if err := loaded.SetCreatedBy("tool", ""); err != nil {
+		t.Fatal(err)
+	}
+	tool, version = loaded.CreatedBy()
+	assertEqual(t, tool, "tool")
+	assertEqual(t, version, "")
+	assertEqual(t, len(loaded.GetNodesOfType(NodeIdMetadata)), 1)
+}
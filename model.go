@@ -0,0 +1,67 @@
+package cast
+
+// ----------------------- //
+//          MODEL          //
+// ----------------------- //
+
+// Model wraps a model node with helpers for its meshes and skeleton
+type Model struct {
+	*CastNode
+}
+
+// AsModel returns the node as a [Model], nil if it is not a model node
+func AsModel(n *CastNode) *Model {
+	if n == nil || n.id != NodeIdModel {
+		return nil
+	}
+	return &Model{n}
+}
+
+// Meshes returns the meshes of the model
+func (m *Model) Meshes() []*Mesh {
+	var meshes []*Mesh
+	for _, c := range m.GetChildrenOfType(NodeIdMesh) {
+		meshes = append(meshes, AsMesh(c))
+	}
+	return meshes
+}
+
+// Skeletons returns the skeletons of the model
+func (m *Model) Skeletons() []*Skeleton {
+	var skeletons []*Skeleton
+	for _, c := range m.GetChildrenOfType(NodeIdSkeleton) {
+		skeletons = append(skeletons, AsSkeleton(c))
+	}
+	return skeletons
+}
+
+// BakeTransform applies the transform to the vertex data of all meshes of the model, see [Mesh.BakeTransform].
+// This flattens placed copies of a model into world space. The local transform of every root bone and the
+// world transforms of all bones are transformed as well, so the bind pose keeps matching the skinned vertices.
+// World transforms carry no scale, only the translation and rotation of the transform apply to them.
+func (m *Model) BakeTransform(transform Mat4) error {
+	for _, mesh := range m.Meshes() {
+		if err := mesh.BakeTransform(transform); err != nil {
+			return err
+		}
+	}
+
+	_, rotation, _ := transform.Decompose()
+	for _, skeleton := range m.Skeletons() {
+		for _, bone := range skeleton.Bones() {
+			if bone.ParentIndex() < 0 {
+				if err := bone.SetLocalMatrix(transform.Mul(bone.LocalMatrix())); err != nil {
+					return err
+				}
+			}
+			if !bone.HasProperty(PropNameWorldPosition) && !bone.HasProperty(PropNameWorldRotation) {
+				continue
+			}
+			position := transform.TransformPoint(bone.WorldPosition())
+			if err := bone.setTransform(PropNameWorldPosition, PropNameWorldRotation, position, rotation.Mul(bone.WorldRotation())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
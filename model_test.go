@@ -0,0 +1,81 @@
+package cast
+
+import (
+	"math"
+	"testing"
+)
+
+func TestModelBakeTransform(t *testing.T) {
+	model := AsModel(New().CreateRoot().CreateChild(NodeIdModel))
+	mesh := AsMesh(model.CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, Vec3{}, Vec3{X: 1}, Vec3{Y: 1})
+	CreateProperty(mesh.CastNode, PropNameVertexNormalBuffer, PropVector3, Vec3{Z: 1}, Vec3{Z: 1}, Vec3{Z: 1})
+	CreateProperty(mesh.CastNode, PropNameVertexTangentBuffer, PropVector3, Vec3{X: 1}, Vec3{X: 1}, Vec3{X: 1})
+	mesh.SetFaces(0, 1, 2)
+
+	if err := model.BakeTransform(Translate4(Vec3{Y: 5}).Mul(Scale4(Vec3{X: -2, Y: 1, Z: 3}))); err != nil {
+		t.Fatal(err)
+	}
+
+	positions, _ := GetPropertyValues[Vec3](mesh.CastNode, PropNameVertexPositionBuffer)
+	assertEqual(t, positions[1], Vec3{X: -2, Y: 5})
+	assertEqual(t, positions[2], Vec3{Y: 6})
+
+	normals, _ := GetPropertyValues[Vec3](mesh.CastNode, PropNameVertexNormalBuffer)
+	assertEqual(t, normals[0], Vec3{Z: 1})
+
+	tangents, _ := GetPropertyValues[Vec3](mesh.CastNode, PropNameVertexTangentBuffer)
+	assertEqual(t, tangents[0], Vec3{X: -1})
+
+	// the mirroring reverses the winding order
+	faces, _ := mesh.Faces()
+	assertEqual(t, [3]uint32(faces), [3]uint32{0, 2, 1})
+
+	assertEqual(t, len(model.Meshes()), 1)
+	if AsModel(mesh.CastNode) != nil {
+		t.Error("expected nil for a non model node")
+	}
+}
+
+func TestModelBakeTransformSkinned(t *testing.T) {
+	skeleton := createChain(Vec3{X: 1}, Vec3{Y: 2})
+	if err := skeleton.ComputeWorldTransforms(); err != nil {
+		t.Fatal(err)
+	}
+	model := AsModel(skeleton.GetParentNode())
+	mesh := AsMesh(model.CreateChild(NodeIdMesh))
+	vertices := []Vec3{{X: 1}, {X: 1, Y: 2}, {X: 2, Y: 3}}
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, vertices...)
+	if err := mesh.SetSkinWeights([][]SkinWeight{{{0, 1}}, {{1, 1}}, {{1, 1}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the vertices in the space of the bones they are bound to
+	bindSpace := func() []Vec3 {
+		inverse := skeleton.InverseBindMatrices()
+		positions, _ := GetPropertyValues[Vec3](mesh.CastNode, PropNameVertexPositionBuffer)
+		weights, _ := mesh.SkinWeights()
+		result := make([]Vec3, len(positions))
+		for i, p := range positions {
+			result[i] = inverse[weights[i][0].Bone].TransformPoint(p)
+		}
+		return result
+	}
+	before := bindSpace()
+
+	transform := Translate4(Vec3{Z: 4}).Mul(QuatFromAxisAngle(Vec3{Z: 1}, math.Pi/2).Mat4()).Mul(Scale4(Vec3{X: -1, Y: 1, Z: 1}))
+	if err := model.BakeTransform(transform); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, p := range bindSpace() {
+		assertWithinVec3(t, p, before[i], 1e-5)
+	}
+
+	bones := skeleton.Bones()
+	assertWithinVec3(t, bones[0].LocalPosition(), Vec3{Y: -1, Z: 4}, 1e-5)
+	assertWithinVec3(t, bones[1].LocalPosition(), Vec3{Y: 2}, 1e-5)
+	for i, b := range bones {
+		assertWithinVec3(t, b.WorldPosition(), transform.TransformPoint(vertices[i]), 1e-5)
+	}
+}
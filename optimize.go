@@ -0,0 +1,158 @@
+package cast
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+)
+
+// ----------------------- //
+//        OPTIMIZE         //
+// ----------------------- //
+
+// isVertexBuffer reports whether the property with the given name holds a fixed number of values per vertex
+func isVertexBuffer(name CastPropertyName) bool {
+	switch name {
+	case PropNameVertexPositionBuffer, PropNameVertexNormalBuffer, PropNameVertexTangentBuffer,
+		PropNameVertexColorBuffer, PropNameVertexWeightBoneBuffer, PropNameVertexWeightValueBuffer:
+		return true
+	}
+	_, ok := uvLayerIndex(name)
+	return ok
+}
+
+// WeldVertices merges the vertices of the mesh that are identical in every vertex buffer: positions, normals,
+// tangents, colors, UV layers and skin weights. The kept vertices keep the order of their first occurrence and
+// the face indices are rewritten to them. Welding renumbers the vertices, so blend shapes using the mesh as
+// their base or target shape no longer line up with it. Returns the number of removed vertices.
+func (m *Mesh) WeldVertices() (int, error) {
+	count := m.VertexCount()
+	if count == 0 {
+		return 0, nil
+	}
+
+	type vertexBuffer struct {
+		p      iCastProperty
+		raw    []byte // raw holds the encoded values
+		stride int    // stride is the number of encoded bytes per vertex
+	}
+	var buffers []vertexBuffer
+	for _, p := range m.properties {
+		if !isVertexBuffer(p.Name()) {
+			continue
+		}
+		if p.Count()%count != 0 {
+			return 0, fmt.Errorf("cast: buffer %s has %d values for %d vertices", p.Name(), p.Count(), count)
+		}
+
+		var buf bytes.Buffer
+		if err := p.write(&buf); err != nil {
+			return 0, err
+		}
+		raw := buf.Bytes()[castPropertyHeaderSize+len(p.Name()):]
+		buffers = append(buffers, vertexBuffer{p, raw, len(raw) / count})
+	}
+
+	faces, err := m.Faces()
+	if err != nil && m.HasProperty(PropNameFaceBuffer) {
+		return 0, err
+	}
+	if err := checkFaces(faces, count); err != nil {
+		return 0, fmt.Errorf("cast: %w", err)
+	}
+
+	remap := make([]uint32, count)
+	var keep []int
+	kept := make(map[string]uint32, count)
+	var key []byte
+	for i := range count {
+		key = key[:0]
+		for _, b := range buffers {
+			key = append(key, b.raw[i*b.stride:(i+1)*b.stride]...)
+		}
+
+		k, ok := kept[string(key)]
+		if !ok {
+			k = uint32(len(keep))
+			kept[string(key)] = k
+			keep = append(keep, i)
+		}
+		remap[i] = k
+	}
+
+	removed := count - len(keep)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	for _, b := range buffers {
+		b.p.gather(keep, b.p.Count()/count)
+	}
+	if faces != nil {
+		for i, index := range faces {
+			faces[i] = remap[index]
+		}
+		if err := m.SetFaces(faces...); err != nil {
+			return 0, err
+		}
+	}
+	return removed, nil
+}
+
+// NarrowIndices stores the index buffers of the file with the narrowest type holding their values: the face
+// buffers of meshes with the narrowest type indexing every vertex like [Mesh.SetFaces], the weight bone buffers
+// of meshes and the keyframe buffers of curves and notification tracks with the narrowest type holding their
+// largest value. Buffers are never widened. Returns the number of narrowed buffers.
+func (n *CastFile) NarrowIndices() int {
+	narrowed := 0
+	narrow := func(node *CastNode, name CastPropertyName, largest func(values []uint32) int) {
+		p, ok := node.GetProperty(name)
+		if !ok {
+			return
+		}
+		values, err := GetPropertyValuesAsUint32(node, name)
+		if err != nil {
+			return
+		}
+
+		id := indexPropertyId(largest(values))
+		if indexWidth(id) >= indexWidth(p.Id()) {
+			return
+		}
+		if setIndexValues(node, name, id, values) == nil {
+			narrowed++
+		}
+	}
+	largestValue := func(values []uint32) int {
+		if len(values) == 0 {
+			return 0
+		}
+		return int(min(slices.Max(values), 0x7fffffff))
+	}
+
+	for node := range n.AllNodes() {
+		switch node.id {
+		case NodeIdMesh:
+			vertexCount := AsMesh(node).VertexCount()
+			narrow(node, PropNameFaceBuffer, func(values []uint32) int {
+				return max(vertexCount-1, largestValue(values))
+			})
+			narrow(node, PropNameVertexWeightBoneBuffer, largestValue)
+		case NodeIdCurve, NodeIdNotificationTrack:
+			narrow(node, PropNameKeyFrameBuffer, largestValue)
+		}
+	}
+	return narrowed
+}
+
+// indexWidth returns the size in bytes of the values of the index property type
+func indexWidth(id CastPropertyId) int {
+	switch id {
+	case PropByte:
+		return 1
+	case PropShort:
+		return 2
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,60 @@
+package cast
+
+import "testing"
+
+func TestMeshWeldVertices(t *testing.T) {
+	mesh := AsMesh(New().CreateRoot().CreateChild(NodeIdModel).CreateChild(NodeIdMesh))
+	// a quad split along its diagonal with duplicated corners, the last vertex differs in its UV
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3,
+		Vec3{0, 0, 0}, Vec3{1, 0, 0}, Vec3{1, 1, 0}, Vec3{0, 0, 0}, Vec3{1, 1, 0}, Vec3{0, 1, 0}, Vec3{0, 1, 0})
+	CreateProperty(mesh.CastNode, PropNameVertexNormalBuffer, PropVector3,
+		Vec3{Z: 1}, Vec3{Z: 1}, Vec3{Z: 1}, Vec3{Z: 1}, Vec3{Z: 1}, Vec3{Z: 1}, Vec3{Z: 1})
+	mesh.SetUVLayer(0, []Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 0}, {1, 1}, {0, 1}, {0, 0.5}})
+	CreateProperty(mesh.CastNode, PropNameVertexWeightBoneBuffer, PropByte, []byte{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1}...)
+	CreateProperty(mesh.CastNode, PropNameVertexWeightValueBuffer, PropFloat, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, float32(0))
+	if err := mesh.SetFaces(0, 1, 2, 3, 4, 5, 3, 4, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := mesh.WeldVertices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, removed, 2)
+	assertEqual(t, mesh.VertexCount(), 5)
+	assertEqual(t, [9]uint32(must(mesh.Faces())), [9]uint32{0, 1, 2, 0, 2, 3, 0, 2, 4})
+	assertEqual(t, must(GetPropertyValues[Vec3](mesh.CastNode, PropNameVertexPositionBuffer))[3], Vec3{0, 1, 0})
+	assertEqual(t, len(must(mesh.UVLayer(0))), 5)
+	assertEqual(t, must(mesh.UVLayer(0))[4], Vec2{0, 0.5})
+	assertEqual(t, propertyCount(mesh.CastNode, PropNameVertexNormalBuffer), 5)
+	assertEqual(t, propertyCount(mesh.CastNode, PropNameVertexWeightBoneBuffer), 10)
+	assertEqual(t, propertyCount(mesh.CastNode, PropNameVertexWeightValueBuffer), 10)
+	assertEqual(t, must(mesh.WeldVertices()), 0)
+
+	CreateProperty(mesh.CastNode, PropNameVertexTangentBuffer, PropVector3, Vec3{})
+	_, err = mesh.WeldVertices()
+	assertEqual(t, err != nil, true)
+}
+
+func TestNarrowIndices(t *testing.T) {
+	castFile := New()
+	mesh := AsMesh(castFile.CreateRoot().CreateChild(NodeIdModel).CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, make([]Vec3, 300)...)
+	CreateProperty(mesh.CastNode, PropNameFaceBuffer, PropInteger32, uint32(0), uint32(1), uint32(2))
+	CreateProperty(mesh.CastNode, PropNameVertexWeightBoneBuffer, PropShort, make([]uint16, 300)...)
+	animation := castFile.Roots()[0].CreateChild(NodeIdAnimation)
+	curve := createCurve(animation, "j_root", KeyPropertyTranslationX, PropFloat, []uint16{0, 10}, float32(0), float32(1))
+	track := animation.CreateChild(NodeIdNotificationTrack)
+	CreateProperty(track, PropNameKeyFrameBuffer, PropByte, byte(5))
+
+	assertEqual(t, castFile.NarrowIndices(), 3)
+	f, _ := mesh.GetProperty(PropNameFaceBuffer)
+	assertEqual(t, f.Id(), PropShort)
+	assertEqual(t, [3]uint32(must(mesh.Faces())), [3]uint32{0, 1, 2})
+	wb, _ := mesh.GetProperty(PropNameVertexWeightBoneBuffer)
+	assertEqual(t, wb.Id(), PropByte)
+	kb, _ := curve.GetProperty(PropNameKeyFrameBuffer)
+	assertEqual(t, kb.Id(), PropByte)
+	assertEqual(t, [2]uint32(must(curve.KeyFrames())), [2]uint32{0, 10})
+	assertEqual(t, castFile.NarrowIndices(), 0)
+}
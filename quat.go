@@ -0,0 +1,156 @@
+package cast
+
+import "math"
+
+// ----------------------- //
+//          QUAT           //
+// ----------------------- //
+
+// Quat is a rotation quaternion. It has the same layout as [Vec4], which is how rotations are stored in
+// properties, so the types convert directly, e.g. Quat(v) and Vec4(q).
+type Quat struct {
+	X, Y, Z, W float32
+}
+
+// QuatIdent returns the identity rotation
+func QuatIdent() Quat {
+	return Quat{W: 1}
+}
+
+// QuatFromAxisAngle returns the rotation by the given angle in radians around the given axis
+func QuatFromAxisAngle(axis Vec3, angle float32) Quat {
+	axis = axis.normalize()
+	s, c := math.Sincos(float64(angle) / 2)
+	return Quat{
+		X: axis.X * float32(s),
+		Y: axis.Y * float32(s),
+		Z: axis.Z * float32(s),
+		W: float32(c),
+	}
+}
+
+// QuatFromEuler returns the rotation by the given angles in radians around the X, Y and Z axes, applied
+// in that order around the fixed axes
+func QuatFromEuler(x, y, z float32) Quat {
+	return QuatFromAxisAngle(Vec3{Z: 1}, z).
+		Mul(QuatFromAxisAngle(Vec3{Y: 1}, y)).
+		Mul(QuatFromAxisAngle(Vec3{X: 1}, x))
+}
+
+// QuatFromMat4 returns the rotation of the upper 3x3 part of the matrix, which must be a rotation matrix
+func QuatFromMat4(m Mat4) Quat {
+	m00, m11, m22 := m.At(0, 0), m.At(1, 1), m.At(2, 2)
+	var q Quat
+	switch {
+	case m00+m11+m22 > 0:
+		s := float32(math.Sqrt(float64(1+m00+m11+m22))) * 2
+		q = Quat{(m.At(2, 1) - m.At(1, 2)) / s, (m.At(0, 2) - m.At(2, 0)) / s, (m.At(1, 0) - m.At(0, 1)) / s, s / 4}
+	case m00 > m11 && m00 > m22:
+		s := float32(math.Sqrt(float64(1+m00-m11-m22))) * 2
+		q = Quat{s / 4, (m.At(0, 1) + m.At(1, 0)) / s, (m.At(0, 2) + m.At(2, 0)) / s, (m.At(2, 1) - m.At(1, 2)) / s}
+	case m11 > m22:
+		s := float32(math.Sqrt(float64(1+m11-m00-m22))) * 2
+		q = Quat{(m.At(0, 1) + m.At(1, 0)) / s, s / 4, (m.At(1, 2) + m.At(2, 1)) / s, (m.At(0, 2) - m.At(2, 0)) / s}
+	default:
+		s := float32(math.Sqrt(float64(1+m22-m00-m11))) * 2
+		q = Quat{(m.At(0, 2) + m.At(2, 0)) / s, (m.At(1, 2) + m.At(2, 1)) / s, s / 4, (m.At(1, 0) - m.At(0, 1)) / s}
+	}
+	return q.Normalize()
+}
+
+// Len returns the length of the quaternion
+func (q Quat) Len() float32 {
+	return float32(math.Sqrt(float64(q.Dot(q))))
+}
+
+// Dot returns the dot product of the quaternions
+func (q Quat) Dot(o Quat) float32 {
+	return q.X*o.X + q.Y*o.Y + q.Z*o.Z + q.W*o.W
+}
+
+// Normalize returns the quaternion scaled to unit length, a zero quaternion is returned as the identity
+func (q Quat) Normalize() Quat {
+	l := q.Len()
+	if l == 0 {
+		return QuatIdent()
+	}
+	return Quat{q.X / l, q.Y / l, q.Z / l, q.W / l}
+}
+
+// Conjugate returns the conjugate of the quaternion, which is the inverse rotation for unit quaternions
+func (q Quat) Conjugate() Quat {
+	return Quat{-q.X, -q.Y, -q.Z, q.W}
+}
+
+// Inverse returns the inverse of the quaternion
+func (q Quat) Inverse() Quat {
+	d := q.Dot(q)
+	if d == 0 {
+		return q
+	}
+	c := q.Conjugate()
+	return Quat{c.X / d, c.Y / d, c.Z / d, c.W / d}
+}
+
+// Mul returns the product q * o, the rotation applying o first and q second
+func (q Quat) Mul(o Quat) Quat {
+	return Quat{
+		X: q.W*o.X + q.X*o.W + q.Y*o.Z - q.Z*o.Y,
+		Y: q.W*o.Y - q.X*o.Z + q.Y*o.W + q.Z*o.X,
+		Z: q.W*o.Z + q.X*o.Y - q.Y*o.X + q.Z*o.W,
+		W: q.W*o.W - q.X*o.X - q.Y*o.Y - q.Z*o.Z,
+	}
+}
+
+// Rotate returns the vector rotated by the quaternion, which must be of unit length
+func (q Quat) Rotate(v Vec3) Vec3 {
+	r := q.Mul(Quat{v.X, v.Y, v.Z, 0}).Mul(q.Conjugate())
+	return Vec3{r.X, r.Y, r.Z}
+}
+
+// Slerp interpolates spherically from q to o along the shortest path, t ranges from 0 to 1
+func (q Quat) Slerp(o Quat, t float32) Quat {
+	dot := q.Dot(o)
+	if dot < 0 {
+		o = Quat{-o.X, -o.Y, -o.Z, -o.W}
+		dot = -dot
+	}
+
+	// fall back to a normalized linear interpolation for nearly identical rotations
+	wq, wo := 1-t, t
+	if dot < 0.9995 {
+		theta := math.Acos(float64(dot))
+		sin := math.Sin(theta)
+		wq = float32(math.Sin(float64(1-t)*theta) / sin)
+		wo = float32(math.Sin(float64(t)*theta) / sin)
+	}
+
+	return Quat{
+		X: wq*q.X + wo*o.X,
+		Y: wq*q.Y + wo*o.Y,
+		Z: wq*q.Z + wo*o.Z,
+		W: wq*q.W + wo*o.W,
+	}.Normalize()
+}
+
+// Euler returns the angles in radians around the X, Y and Z axes which applied in that order around the fixed
+// axes give the rotation, see [QuatFromEuler]. The angle around Y is in the range [-π/2, π/2].
+func (q Quat) Euler() (x, y, z float32) {
+	q = q.Normalize()
+	sinY := 2 * (q.W*q.Y - q.Z*q.X)
+	x = float32(math.Atan2(float64(2*(q.W*q.X+q.Y*q.Z)), float64(1-2*(q.X*q.X+q.Y*q.Y))))
+	y = float32(math.Asin(float64(min(max(sinY, -1), 1))))
+	z = float32(math.Atan2(float64(2*(q.W*q.Z+q.X*q.Y)), float64(1-2*(q.Y*q.Y+q.Z*q.Z))))
+	return x, y, z
+}
+
+// Mat4 returns the rotation matrix of the quaternion, which must be of unit length
+func (q Quat) Mat4() Mat4 {
+	x, y, z, w := q.X, q.Y, q.Z, q.W
+	return Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
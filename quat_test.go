@@ -0,0 +1,57 @@
+package cast
+
+import (
+	"math"
+	"testing"
+)
+
+// assertNear fails if the two values differ by more than 1e-5
+func assertNear(t testing.TB, got, want float32) {
+	t.Helper()
+	if math.Abs(float64(got-want)) > 1e-5 {
+		t.Errorf("got: %v != want: %v", got, want)
+	}
+}
+
+// assertNearVec3 fails if a component of the two vectors differs by more than 1e-5
+func assertNearVec3(t testing.TB, got, want Vec3) {
+	t.Helper()
+	assertNear(t, got.X, want.X)
+	assertNear(t, got.Y, want.Y)
+	assertNear(t, got.Z, want.Z)
+}
+
+func TestQuat(t *testing.T) {
+	rz := QuatFromAxisAngle(Vec3{Z: 1}, math.Pi/2)
+	assertNearVec3(t, rz.Rotate(Vec3{X: 1}), Vec3{Y: 1})
+	assertNearVec3(t, rz.Mat4().TransformDirection(Vec3{X: 1}), Vec3{Y: 1})
+	assertNearVec3(t, rz.Inverse().Rotate(Vec3{Y: 1}), Vec3{X: 1})
+
+	rx := QuatFromAxisAngle(Vec3{X: 1}, math.Pi/2)
+	v := Vec3{X: 1, Y: 2, Z: 3}
+	assertNearVec3(t, rz.Mul(rx).Rotate(v), rz.Rotate(rx.Rotate(v)))
+	assertNearVec3(t, rz.Mul(rx).Mat4().TransformDirection(v), rz.Mat4().Mul(rx.Mat4()).TransformDirection(v))
+
+	half := rz.Slerp(QuatIdent(), 0.5)
+	assertNearVec3(t, half.Rotate(Vec3{X: 1}), Vec3{X: math.Sqrt2 / 2, Y: math.Sqrt2 / 2})
+	assertNear(t, Quat{X: 3, W: 4}.Normalize().Len(), 1)
+
+	x, y, z := QuatFromEuler(0.1, -0.4, 1.2).Euler()
+	assertNear(t, x, 0.1)
+	assertNear(t, y, -0.4)
+	assertNear(t, z, 1.2)
+	assertNearVec3(t, QuatFromEuler(0, 0, math.Pi/2).Rotate(Vec3{X: 1}), Vec3{Y: 1})
+
+	assertEqual(t, Vec4(QuatIdent()), Vec4{W: 1})
+
+	for _, q := range []Quat{
+		QuatIdent(),
+		QuatFromEuler(0.1, -0.4, 1.2),
+		QuatFromAxisAngle(Vec3{X: 1}, math.Pi),
+		QuatFromAxisAngle(Vec3{Y: 1}, math.Pi),
+		QuatFromAxisAngle(Vec3{Z: 1}, math.Pi),
+	} {
+		r := QuatFromMat4(q.Mat4())
+		assertNear(t, float32(math.Abs(float64(r.Dot(q)))), 1)
+	}
+}
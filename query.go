@@ -0,0 +1,257 @@
+package cast
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ----------------------- //
+//          QUERY          //
+// ----------------------- //
+
+// Matcher reports whether a node matches a condition
+type Matcher func(n *CastNode) bool
+
+// Any matches every node
+func Any() Matcher {
+	return func(n *CastNode) bool {
+		return true
+	}
+}
+
+// ByType matches nodes with the given id
+func ByType(id CastNodeId) Matcher {
+	return func(n *CastNode) bool {
+		return n.id == id
+	}
+}
+
+// ByHash matches the node with the given hash
+func ByHash(hash uint64) Matcher {
+	return func(n *CastNode) bool {
+		return n.hash == hash
+	}
+}
+
+// ByName matches nodes with the given name property
+func ByName(name string) Matcher {
+	return ByProperty(PropNameName, name)
+}
+
+// ByProperty matches nodes having a property with the given name that holds the given value
+func ByProperty[T CastPropertyValueType](name CastPropertyName, value T) Matcher {
+	return func(n *CastNode) bool {
+		values, err := GetPropertyValues[T](n, name)
+		return err == nil && slices.Contains(values, value)
+	}
+}
+
+// HasProperty matches nodes having a property with the given name
+func HasProperty(name CastPropertyName) Matcher {
+	return func(n *CastNode) bool {
+		return n.HasProperty(name)
+	}
+}
+
+// AllOf matches nodes matching all of the given matchers
+func AllOf(matchers ...Matcher) Matcher {
+	return func(n *CastNode) bool {
+		for _, m := range matchers {
+			if !m(n) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Find returns every node of the file for which pred returns true in depth-first order
+func (n *CastFile) Find(pred Matcher) []*CastNode {
+	return collect(n.AllNodes(), pred)
+}
+
+// Find returns every descendant of the node for which pred returns true in depth-first order
+func (n *CastNode) Find(pred Matcher) []*CastNode {
+	return collect(n.Descendants(), pred)
+}
+
+// Select returns the nodes matching the given path of matchers. The first matcher is applied to
+// the children of the root nodes, every following matcher to the children of the previous matches.
+func (n *CastFile) Select(path ...Matcher) []*CastNode {
+	return selectNodes(n.rootNodes, path)
+}
+
+// Select returns the nodes matching the given path of matchers. The first matcher is applied to
+// the children of the node, every following matcher to the children of the previous matches.
+func (n *CastNode) Select(path ...Matcher) []*CastNode {
+	return selectNodes([]*CastNode{n}, path)
+}
+
+// Query returns the nodes matching the given path query, see [ParseQuery] for the syntax.
+// The first segment is matched against the children of the root nodes.
+func (n *CastFile) Query(query string) ([]*CastNode, error) {
+	path, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return n.Select(path...), nil
+}
+
+// Query returns the nodes matching the given path query, see [ParseQuery] for the syntax.
+// The first segment is matched against the children of the node.
+func (n *CastNode) Query(query string) ([]*CastNode, error) {
+	path, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return n.Select(path...), nil
+}
+
+// ParseQuery parses a path query into matchers usable with Select.
+//
+// A query consists of segments separated by slashes, each selecting a level of the tree, e.g.
+// "model/skel/bone[n=j_spine]". A segment starts with a node type given as a four character tag
+// or a descriptive name (see [ParseCastNodeId]), or "*" for any type, optionally followed by
+// property conditions in brackets: "[name=value]" matches nodes where the property holds the value
+// and "[name]" matches nodes having the property. Numeric values may be given in any base accepted
+// by [strconv.ParseUint], so hashes can be written in hexadecimal.
+func ParseQuery(query string) ([]Matcher, error) {
+	var path []Matcher
+	for segment := range splitQuery(query) {
+		m, err := parseQuerySegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("cast: invalid query %q: %w", query, err)
+		}
+		path = append(path, m)
+	}
+	return path, nil
+}
+
+// collect returns the nodes of the sequence for which pred returns true
+func collect(nodes iter.Seq[*CastNode], pred Matcher) []*CastNode {
+	matches := make([]*CastNode, 0)
+	for n := range nodes {
+		if pred(n) {
+			matches = append(matches, n)
+		}
+	}
+	return matches
+}
+
+// selectNodes applies the matchers of the path level by level starting from the children of the given nodes
+func selectNodes(nodes []*CastNode, path []Matcher) []*CastNode {
+	for _, m := range path {
+		var matches []*CastNode
+		for _, n := range nodes {
+			for _, c := range n.childNodes {
+				if m(c) {
+					matches = append(matches, c)
+				}
+			}
+		}
+		nodes = matches
+	}
+	return nodes
+}
+
+// splitQuery splits the query at the slashes outside of brackets
+func splitQuery(query string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		depth, start := 0, 0
+		for i, c := range query {
+			switch c {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			case '/':
+				if depth == 0 {
+					if !yield(query[start:i]) {
+						return
+					}
+					start = i + 1
+				}
+			}
+		}
+		yield(query[start:])
+	}
+}
+
+// parseQuerySegment parses a single segment of a query
+func parseQuerySegment(segment string) (Matcher, error) {
+	typ, conditions, _ := strings.Cut(segment, "[")
+	if typ == "" {
+		return nil, fmt.Errorf("missing node type in segment %q", segment)
+	}
+
+	matchers := []Matcher{}
+	if typ != "*" {
+		id, err := ParseCastNodeId(typ)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, ByType(id))
+	}
+
+	for conditions != "" {
+		condition, rest, ok := strings.Cut(conditions, "]")
+		if !ok {
+			return nil, fmt.Errorf("unterminated condition in segment %q", segment)
+		}
+
+		name, value, hasValue := strings.Cut(condition, "=")
+		if name == "" {
+			return nil, fmt.Errorf("missing property name in segment %q", segment)
+		}
+
+		if hasValue {
+			matchers = append(matchers, byPropertyString(CastPropertyName(name), value))
+		} else {
+			matchers = append(matchers, HasProperty(CastPropertyName(name)))
+		}
+
+		if rest != "" && !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("unexpected %q in segment %q", rest, segment)
+		}
+		conditions = strings.TrimPrefix(rest, "[")
+	}
+
+	return AllOf(matchers...), nil
+}
+
+// byPropertyString matches nodes having a property with the given name that holds the value
+// given in its textual form
+func byPropertyString(name CastPropertyName, value string) Matcher {
+	return func(n *CastNode) bool {
+		p, _ := n.GetProperty(name)
+		switch p := p.(type) {
+		case *CastProperty[string]:
+			return slices.Contains(p.GetValues(), value)
+		case *CastProperty[byte]:
+			return containsUint(p.GetValues(), value, 8)
+		case *CastProperty[uint16]:
+			return containsUint(p.GetValues(), value, 16)
+		case *CastProperty[uint32]:
+			return containsUint(p.GetValues(), value, 32)
+		case *CastProperty[uint64]:
+			return containsUint(p.GetValues(), value, 64)
+		case *CastProperty[float32]:
+			v, err := strconv.ParseFloat(value, 32)
+			return err == nil && slices.Contains(p.GetValues(), float32(v))
+		case *CastProperty[float64]:
+			v, err := strconv.ParseFloat(value, 64)
+			return err == nil && slices.Contains(p.GetValues(), v)
+		default:
+			return false
+		}
+	}
+}
+
+// containsUint reports whether the values contain the given textual unsigned integer
+func containsUint[T byte | uint16 | uint32 | uint64](values []T, value string, bitSize int) bool {
+	v, err := strconv.ParseUint(value, 0, bitSize)
+	return err == nil && slices.Contains(values, T(v))
+}
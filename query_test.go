@@ -0,0 +1,83 @@
+package cast
+
+import "testing"
+
+func TestParseCastNodeId(t *testing.T) {
+	for s, want := range map[string]CastNodeId{
+		"root":       NodeIdRoot,
+		"Model":      NodeIdModel,
+		"modl":       NodeIdModel,
+		"blsh":       NodeIdBlendShape,
+		"BlendShape": NodeIdBlendShape,
+	} {
+		id, err := ParseCastNodeId(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, id, want)
+		assertEqual(t, CastNodeId(id).String(), want.String())
+	}
+
+	_, err := ParseCastNodeId("invalid")
+	assertEqual(t, err != nil, true)
+}
+
+func TestQuery(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+
+	bones, err := castFile.Query("model/skel/bone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(bones) > 1, true)
+
+	spine, err := castFile.Query("model/skeleton/bone[n=spine]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(spine), 1)
+	assertEqual(t, spine[0].Hash(), uint64(0x534e495752545260))
+
+	children, err := castFile.Query("*/*/*[n][p=8][lp]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(children), 3)
+
+	selected := castFile.Select(Any(), ByType(NodeIdSkeleton), AllOf(ByName("spine"), ByProperty(PropNameParentIndex, uint32(8))))
+	assertEqual(t, len(selected), 1)
+	assertEqual(t, selected[0], spine[0])
+
+	model := castFile.Roots()[0].GetChildNodes()[0]
+	relative, err := model.Query("skel/bone[n=spine]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(relative), 1)
+
+	for _, query := range []string{"", "model//bone", "model[n", "model[=x]", "model[n=x]y", "invalid"} {
+		_, err := castFile.Query(query)
+		if err == nil {
+			t.Errorf("expected error for query %q", query)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	castFile := loadTestFile(t, "pilot_medium_bangalore_LOD0.cast")
+
+	large := castFile.Find(func(n *CastNode) bool {
+		if n.Id() != NodeIdMesh {
+			return false
+		}
+		positions, err := GetPropertyValues[Vec3](n, PropNameVertexPositionBuffer)
+		return err == nil && len(positions) > 1000
+	})
+	assertEqual(t, len(large) > 0, true)
+	assertEqual(t, len(large) < len(castFile.GetNodesOfType(NodeIdMesh)), true)
+
+	root := castFile.Roots()[0]
+	assertEqual(t, len(root.Find(ByType(NodeIdRoot))), 0)
+	assertEqual(t, len(castFile.Find(ByType(NodeIdRoot))), 1)
+	assertEqual(t, len(root.Find(AllOf(ByType(NodeIdMesh), HasProperty(PropNameVertexPositionBuffer)))), len(root.GetDescendantsOfType(NodeIdMesh)))
+}
@@ -0,0 +1,38 @@
+package cast
+
+// ----------------------- //
+//         RESCALE         //
+// ----------------------- //
+
+// Rescale scales the file uniformly by the given factor, e.g. 0.01 to convert from centimeters to meters.
+// Vertex positions, bone and instance translations and translation curves are scaled, rotations, scales,
+// normals and skin weights are left untouched.
+func Rescale(file *CastFile, factor float32) error {
+	scale := func(n *CastNode, name CastPropertyName) {
+		if values, err := GetPropertyValues[Vec3](n, name); err == nil {
+			for i, v := range values {
+				values[i] = Vec3{v.X * factor, v.Y * factor, v.Z * factor}
+			}
+		}
+	}
+
+	for n := range file.AllNodes() {
+		switch n.id {
+		case NodeIdMesh:
+			scale(n, PropNameVertexPositionBuffer)
+		case NodeIdBone:
+			scale(n, PropNameLocalPosition)
+			scale(n, PropNameWorldPosition)
+		case NodeIdInstance:
+			scale(n, PropNamePosition)
+		case NodeIdCurve:
+			switch AsCurve(n).KeyProperty() {
+			case KeyPropertyTranslationX, KeyPropertyTranslationY, KeyPropertyTranslationZ:
+				if err := scaleKeyValues(AsCurve(n), factor); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package cast
+
+import "testing"
+
+func TestRescale(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	bone := castFile.Find(ByType(NodeIdBone))[1]
+	lp := *must(GetPropertyValue[Vec3](bone, PropNameLocalPosition))
+	lr := *must(GetPropertyValue[Vec4](bone, PropNameLocalRotation))
+	mesh := castFile.Find(ByType(NodeIdMesh))[0]
+	vp := must(GetPropertyValues[Vec3](mesh, PropNameVertexPositionBuffer))[0]
+
+	root := castFile.Roots()[0]
+	curve := root.CreateChild(NodeIdAnimation).CreateChild(NodeIdCurve)
+	CreateProperty(curve, PropNameKeyProperty, PropString, KeyPropertyTranslationX)
+	CreateProperty(curve, PropNameKeyValueBuffer, PropDouble, 100.0, 250.0)
+
+	if err := Rescale(castFile, 0.01); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, *must(GetPropertyValue[Vec3](bone, PropNameLocalPosition)), Vec3{lp.X * 0.01, lp.Y * 0.01, lp.Z * 0.01})
+	assertEqual(t, *must(GetPropertyValue[Vec4](bone, PropNameLocalRotation)), lr)
+	assertEqual(t, must(GetPropertyValues[Vec3](mesh, PropNameVertexPositionBuffer))[0], Vec3{vp.X * 0.01, vp.Y * 0.01, vp.Z * 0.01})
+	assertEqual(t, must(GetPropertyValues[float64](curve, PropNameKeyValueBuffer))[1], 250.0*float64(float32(0.01)))
+}
@@ -0,0 +1,91 @@
+package cast
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------- //
+//         RESOLVE         //
+// ----------------------- //
+
+// PathResolver maps the path of a file node, often an absolute path on the machine that exported the file,
+// to a local file. Resolve returns an error wrapping [ErrUnresolved] if there is no such file.
+type PathResolver interface {
+	Resolve(path string) (string, error)
+}
+
+// PathResolverFunc is a function used as a [PathResolver]
+type PathResolverFunc func(path string) (string, error)
+
+// Resolve calls the function
+func (f PathResolverFunc) Resolve(path string) (string, error) {
+	return f(path)
+}
+
+// DirResolver returns a [PathResolver] looking for files in the given directory, usually the one holding the
+// cast file. A relative path is looked up relative to the directory first, then the base name of the path is
+// looked up within the directory. Backslashes in the paths are treated as separators.
+func DirResolver(dir string) PathResolver {
+	return PathResolverFunc(func(path string) (string, error) {
+		if p, ok := lookupInDir(dir, path); ok {
+			return p, nil
+		}
+		return "", fmt.Errorf("%w: %q", ErrUnresolved, path)
+	})
+}
+
+// SearchPathResolver returns a [PathResolver] using the path as is if it exists on this machine, and otherwise
+// looking for the file in each of the given directories in order, see [DirResolver]
+func SearchPathResolver(dirs ...string) PathResolver {
+	return PathResolverFunc(func(path string) (string, error) {
+		if isFile(path) {
+			return path, nil
+		}
+		for _, dir := range dirs {
+			if p, ok := lookupInDir(dir, path); ok {
+				return p, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %q", ErrUnresolved, path)
+	})
+}
+
+// Resolve returns the local path of the referenced file found by the given resolver
+func (f *File) Resolve(r PathResolver) (string, error) {
+	return r.Resolve(f.Path())
+}
+
+// ResolveSlot returns the local path of the file referenced by the texture slot with the given name found by
+// the given resolver
+func (m *Material) ResolveSlot(name CastPropertyName, r PathResolver) (string, error) {
+	file := m.Slot(name)
+	if file == nil {
+		return "", fmt.Errorf("%w: material %q has no file in slot %q", ErrUnresolved, m.Name(), name)
+	}
+	return file.Resolve(r)
+}
+
+// lookupInDir looks for the file with the given path relative to the directory, then for its base name
+// within the directory
+func lookupInDir(dir, path string) (string, bool) {
+	path = filepath.FromSlash(strings.ReplaceAll(path, `\`, "/"))
+	if filepath.IsLocal(path) {
+		if p := filepath.Join(dir, path); isFile(p) {
+			return p, true
+		}
+	}
+
+	if p := filepath.Join(dir, filepath.Base(path)); isFile(p) {
+		return p, true
+	}
+	return "", false
+}
+
+// isFile reports whether the path refers to an existing regular file
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
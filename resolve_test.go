@@ -0,0 +1,43 @@
+package cast
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathResolver(t *testing.T) {
+	dir := t.TempDir()
+	textures := filepath.Join(dir, "textures")
+	for _, p := range []string{filepath.Join(dir, "_images", "nested.png"), filepath.Join(textures, "albedo.png")} {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolver := DirResolver(dir)
+	assertEqual(t, must(resolver.Resolve(`_images\nested.png`)), filepath.Join(dir, "_images", "nested.png"))
+	if _, err := resolver.Resolve(`C:\export\albedo.png`); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("got: %v != want: %v", err, ErrUnresolved)
+	}
+
+	resolver = SearchPathResolver(dir, textures)
+	assertEqual(t, must(resolver.Resolve(`C:\export\albedo.png`)), filepath.Join(textures, "albedo.png"))
+	assertEqual(t, must(resolver.Resolve(filepath.Join(dir, "_images", "nested.png"))), filepath.Join(dir, "_images", "nested.png"))
+
+	castFile := New()
+	model := castFile.CreateRoot().CreateChild(NodeIdModel)
+	material := AsMaterial(model.CreateChild(NodeIdMaterial))
+	file := AsFile(model.CreateChild(NodeIdFile))
+	CreateProperty(file.CastNode, PropNamePath, PropString, `D:/work/textures/albedo.png`)
+	material.SetSlot(PropNameAlbedo, file)
+
+	assertEqual(t, must(material.ResolveSlot(PropNameAlbedo, resolver)), filepath.Join(textures, "albedo.png"))
+	if _, err := material.ResolveSlot(PropNameNormal, resolver); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("got: %v != want: %v", err, ErrUnresolved)
+	}
+}
@@ -0,0 +1,134 @@
+package cast
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ----------------------- //
+//          SCAN           //
+// ----------------------- //
+
+// Outline describes the structure of a file without its property values, see [Scan]
+type Outline struct {
+	Header Header
+	Nodes  []OutlineNode      // Nodes holds the nodes in depth-first order
+	Counts map[CastNodeId]int // Counts counts the nodes per type
+}
+
+// OutlineNode describes a node of an [Outline]
+type OutlineNode struct {
+	Id            CastNodeId
+	Hash          uint64
+	Name          string // Name is the name property of the node, empty if it has none
+	Parent        int    // Parent is the index of the parent node in [Outline.Nodes], -1 for root nodes
+	Index         int    // Index is the index of the node among its siblings of the same type
+	Depth         int    // Depth is the nesting level of the node, root nodes are at level 0
+	Offset        int64  // Offset is the offset of the node header in the stream
+	Size          uint32 // Size is the size of the node in bytes including its header, properties and children
+	PropertyCount uint32
+	ChildCount    uint32
+}
+
+// Path returns the path of the node with the given index, e.g. "root[0]/modl[0]/mesh[2]"
+func (o *Outline) Path(i int) string {
+	var elements []string
+	for ; i >= 0; i = o.Nodes[i].Parent {
+		elements = append(elements, fmt.Sprintf("%s[%d]", o.Nodes[i].Id, o.Nodes[i].Index))
+	}
+
+	for l, r := 0, len(elements)-1; l < r; l, r = l+1, r-1 {
+		elements[l], elements[r] = elements[r], elements[l]
+	}
+	return strings.Join(elements, "/")
+}
+
+// Scan reads the structure of a file from the given [io.Reader] without loading it. Only the headers of the
+// nodes and properties and the name properties are read, the values of all other properties are skipped,
+// which makes scanning much faster than [Load] when only the types, names and sizes of the nodes are of
+// interest. Failures are reported as a [*LoadError], the outline then holds the nodes scanned before.
+func Scan(r io.Reader) (Outline, error) {
+	d := decoderPool.Get().(*decoder)
+	defer decoderPool.Put(d)
+
+	d.reset(r, nil)
+	defer d.reset(nil, nil)
+	return d.scanFile()
+}
+
+// scanFile scans a cast file, see [Scan]
+func (d *decoder) scanFile() (Outline, error) {
+	var header castHeader
+	if err := d.readHeader(castHeaderSize, header.decode); err != nil {
+		return Outline{}, d.error(0, "", err)
+	}
+
+	outline := Outline{
+		Header: Header(header),
+		Counts: make(map[CastNodeId]int),
+	}
+	if header.Magic != castMagic {
+		return outline, d.error(0, "", fmt.Errorf("invalid cast file magic: %#x", header.Magic))
+	}
+
+	siblings := d.siblingCounts(0)
+	for range header.RootNodes {
+		if err := d.scanNode(&outline, -1, siblings); err != nil {
+			return outline, err
+		}
+	}
+	return outline, nil
+}
+
+// scanNode scans a node with the given parent index and its children into the outline
+func (d *decoder) scanNode(outline *Outline, parent int, siblings map[CastNodeId]int) error {
+	start := d.offset
+	if err := d.checkNode(); err != nil {
+		return d.error(start, "", err)
+	}
+
+	var header castNodeHeader
+	if err := d.readHeader(castNodeHeaderSize, header.decode); err != nil {
+		return d.error(start, "", err)
+	}
+	end := start + int64(header.NodeSize)
+
+	d.path = append(d.path, pathElement{header.Id, siblings[header.Id]})
+	siblings[header.Id]++
+	defer func() {
+		d.path = d.path[:len(d.path)-1]
+	}()
+
+	index := len(outline.Nodes)
+	outline.Nodes = append(outline.Nodes, OutlineNode{
+		Id:            header.Id,
+		Hash:          header.NodeHash,
+		Parent:        parent,
+		Index:         siblings[header.Id] - 1,
+		Depth:         len(d.path) - 1,
+		Offset:        start,
+		Size:          header.NodeSize,
+		PropertyCount: header.PropertyCount,
+		ChildCount:    header.ChildCount,
+	})
+	outline.Counts[header.Id]++
+
+	for range header.PropertyCount {
+		name, value, err := d.skipProperty(end)
+		if err != nil {
+			return err
+		}
+		if name == PropNameName && outline.Nodes[index].Name == "" {
+			outline.Nodes[index].Name = value
+		}
+	}
+
+	children := d.siblingCounts(len(d.path))
+	for range header.ChildCount {
+		if err := d.scanNode(outline, index, children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
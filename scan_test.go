@@ -0,0 +1,68 @@
+package cast
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	for _, f := range []string{"cube.cast", "cast_ik.cast", "pilot_medium_bangalore_LOD0.cast"} {
+		data := must(os.ReadFile("testdata/" + f))
+		castFile := must(Load(bytes.NewReader(data)))
+
+		outline, err := Scan(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, outline.Header.Valid(), true)
+		assertEqual(t, outline.Header.RootNodes, uint32(len(castFile.Roots())))
+		assertEqual(t, outline.Counts[NodeIdMesh], len(castFile.GetNodesOfType(NodeIdMesh)))
+		assertEqual(t, outline.Counts[NodeIdBone], len(castFile.GetNodesOfType(NodeIdBone)))
+
+		i := 0
+		castFile.Walk(func(path []*CastNode, n *CastNode) error {
+			node := outline.Nodes[i]
+			assertEqual(t, node.Id, n.id)
+			assertEqual(t, node.Hash, n.hash)
+			assertEqual(t, node.Name, contentName(n))
+			assertEqual(t, node.Depth, len(path))
+			assertEqual(t, int(node.Size), n.len())
+			assertEqual(t, int(node.ChildCount), len(n.childNodes))
+			assertEqual(t, outline.Path(i), nodePath(n))
+			i++
+			return nil
+		})
+		assertEqual(t, i, len(outline.Nodes))
+	}
+
+	// readers that cannot seek are read through
+	data := must(os.ReadFile("testdata/cast_ik.cast"))
+	outline := must(Scan(bytes.NewReader(data)))
+	assertEqual(t, len(must(Scan(struct{ io.Reader }{bytes.NewReader(data)})).Nodes), len(outline.Nodes))
+
+	outline, err := Scan(bytes.NewReader(data[:len(data)-4]))
+	var loadErr *LoadError
+	assertEqual(t, errors.As(err, &loadErr), true)
+	assertEqual(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+	assertEqual(t, len(outline.Nodes) > 0, true)
+
+	_, err = Scan(bytes.NewReader(append([]byte("GIF89a"), data[6:]...)))
+	assertEqual(t, errors.As(err, &loadErr), true)
+}
+
+func BenchmarkScan(b *testing.B) {
+	data, err := os.ReadFile("testdata/pilot_medium_bangalore_LOD0.cast")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		if _, err := Scan(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
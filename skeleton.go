@@ -0,0 +1,232 @@
+package cast
+
+import "fmt"
+
+// ----------------------- //
+//        SKELETON         //
+// ----------------------- //
+
+// Skeleton wraps a skeleton node with helpers for its bones
+type Skeleton struct {
+	*CastNode
+}
+
+// AsSkeleton returns the node as a [Skeleton], nil if it is not a skeleton node
+func AsSkeleton(n *CastNode) *Skeleton {
+	if n == nil || n.id != NodeIdSkeleton {
+		return nil
+	}
+	return &Skeleton{n}
+}
+
+// Bones returns the bones of the skeleton in the order their indices refer to
+func (s *Skeleton) Bones() []*Bone {
+	var bones []*Bone
+	for _, c := range s.GetChildrenOfType(NodeIdBone) {
+		bones = append(bones, AsBone(c))
+	}
+	return bones
+}
+
+// BoneByName returns the first bone with the given name and its index, nil and -1 if there is none.
+// The index is the one referenced by parent indices and weight buffers.
+func (s *Skeleton) BoneByName(name string) (*Bone, int) {
+	for i, b := range s.Bones() {
+		if b.Name() == name {
+			return b, i
+		}
+	}
+	return nil, -1
+}
+
+// BindPoseMatrices returns the world space matrix of every bone in bind pose, composed from the local
+// position, rotation and scale of the bone and its ancestors. Bones with a parent index out of range are
+// treated as root bones. Segment scale compensation is not applied.
+func (s *Skeleton) BindPoseMatrices() []Mat4 {
+	bones := s.Bones()
+	matrices := make([]Mat4, len(bones))
+	done := make([]bool, len(bones))
+
+	// depth bounds the recursion for cyclic hierarchies, see [CastFile.Validate]
+	var compute func(i, depth int) Mat4
+	compute = func(i, depth int) Mat4 {
+		if done[i] {
+			return matrices[i]
+		}
+
+		b := bones[i]
+		m := b.LocalMatrix()
+		if p := b.ParentIndex(); p >= 0 && p < len(bones) && depth < len(bones) {
+			m = compute(p, depth+1).Mul(m)
+		}
+
+		matrices[i], done[i] = m, true
+		return m
+	}
+
+	for i := range bones {
+		compute(i, 0)
+	}
+	return matrices
+}
+
+// InverseBindMatrices returns the inverses of the bind pose matrices, transforming from world space into the
+// space of each bone as needed for skinning, see [Skeleton.BindPoseMatrices]
+func (s *Skeleton) InverseBindMatrices() []Mat4 {
+	matrices := s.BindPoseMatrices()
+	for i, m := range matrices {
+		matrices[i] = m.Inverse()
+	}
+	return matrices
+}
+
+// ComputeWorldTransforms sets the world position and rotation of every bone from the local transforms of the
+// bone and its ancestors. Bone scales are not taken into account.
+func (s *Skeleton) ComputeWorldTransforms() error {
+	bones := s.Bones()
+	return s.eachParentFirst(bones, func(bone, parent *Bone) error {
+		position, rotation := bone.LocalPosition(), bone.LocalRotation()
+		if parent != nil {
+			parentRotation := parent.WorldRotation()
+			position = addVec3(parent.WorldPosition(), parentRotation.Rotate(position))
+			rotation = parentRotation.Mul(rotation)
+		}
+		return bone.setTransform(PropNameWorldPosition, PropNameWorldRotation, position, rotation)
+	})
+}
+
+// ComputeLocalTransforms sets the local position and rotation of every bone from the world transforms of the
+// bone and its parent, for data that only provides world space transforms. Bone scales are not taken into account.
+func (s *Skeleton) ComputeLocalTransforms() error {
+	bones := s.Bones()
+	return s.eachParentFirst(bones, func(bone, parent *Bone) error {
+		position, rotation := bone.WorldPosition(), bone.WorldRotation()
+		if parent != nil {
+			inverse := parent.WorldRotation().Conjugate()
+			position = inverse.Rotate(subVec3(position, parent.WorldPosition()))
+			rotation = inverse.Mul(rotation)
+		}
+		return bone.setTransform(PropNameLocalPosition, PropNameLocalRotation, position, rotation)
+	})
+}
+
+// eachParentFirst calls fn for every bone with its parent, nil for root bones, making sure parents are
+// visited before their children. Returns an error for parent indices out of range and cycles.
+func (s *Skeleton) eachParentFirst(bones []*Bone, fn func(bone, parent *Bone) error) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(bones))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("cast: bone %d is its own ancestor", i)
+		}
+		state[i] = visiting
+
+		var parent *Bone
+		if p := bones[i].ParentIndex(); p >= 0 {
+			if p >= len(bones) {
+				return fmt.Errorf("cast: parent index %d of bone %d out of range of %d bones", p, i, len(bones))
+			}
+			if err := visit(p); err != nil {
+				return err
+			}
+			parent = bones[p]
+		}
+
+		state[i] = visited
+		return fn(bones[i], parent)
+	}
+
+	for i := range bones {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bone wraps a bone node
+type Bone struct {
+	*CastNode
+}
+
+// AsBone returns the node as a [Bone], nil if it is not a bone node
+func AsBone(n *CastNode) *Bone {
+	if n == nil || n.id != NodeIdBone {
+		return nil
+	}
+	return &Bone{n}
+}
+
+// Name returns the name of the bone
+func (b *Bone) Name() string {
+	return GetPropertyValueOr(b.CastNode, PropNameName, "")
+}
+
+// ParentIndex returns the index of the parent bone within the skeleton, -1 if the bone is a root bone
+func (b *Bone) ParentIndex() int {
+	p, err := GetPropertyValue[uint32](b.CastNode, PropNameParentIndex)
+	if err != nil || int32(*p) < 0 {
+		return -1
+	}
+	return int(*p)
+}
+
+// LocalPosition returns the position of the bone relative to its parent
+func (b *Bone) LocalPosition() Vec3 {
+	return GetPropertyValueOr(b.CastNode, PropNameLocalPosition, Vec3{})
+}
+
+// LocalRotation returns the rotation of the bone relative to its parent
+func (b *Bone) LocalRotation() Quat {
+	return Quat(GetPropertyValueOr(b.CastNode, PropNameLocalRotation, Vec4(QuatIdent())))
+}
+
+// WorldPosition returns the position of the bone in world space
+func (b *Bone) WorldPosition() Vec3 {
+	return GetPropertyValueOr(b.CastNode, PropNameWorldPosition, Vec3{})
+}
+
+// WorldRotation returns the rotation of the bone in world space
+func (b *Bone) WorldRotation() Quat {
+	return Quat(GetPropertyValueOr(b.CastNode, PropNameWorldRotation, Vec4(QuatIdent())))
+}
+
+// Scale returns the scale of the bone
+func (b *Bone) Scale() Vec3 {
+	return GetPropertyValueOr(b.CastNode, PropNameScale, Vec3{1, 1, 1})
+}
+
+// LocalMatrix returns the transform of the bone relative to its parent composed from its local position,
+// rotation and scale
+func (b *Bone) LocalMatrix() Mat4 {
+	return Mat4FromTRS(b.LocalPosition(), b.LocalRotation(), b.Scale())
+}
+
+// SetLocalMatrix sets the local position, rotation and scale of the bone from the given transform relative
+// to its parent, see [Mat4.Decompose]
+func (b *Bone) SetLocalMatrix(m Mat4) error {
+	t, r, s := m.Decompose()
+	if err := b.setTransform(PropNameLocalPosition, PropNameLocalRotation, t, r); err != nil {
+		return err
+	}
+	_, err := CreateProperty(b.CastNode, PropNameScale, PropVector3, s)
+	return err
+}
+
+// setTransform sets the position and rotation properties with the given names
+func (b *Bone) setTransform(position, rotation CastPropertyName, p Vec3, r Quat) error {
+	if _, err := CreateProperty(b.CastNode, position, PropVector3, p); err != nil {
+		return err
+	}
+	_, err := CreateProperty(b.CastNode, rotation, PropVector4, Vec4(r))
+	return err
+}
@@ -0,0 +1,102 @@
+package cast
+
+import (
+	"math"
+	"testing"
+)
+
+// assertWithinVec3 fails if a component of the two vectors differs by more than the tolerance. The stored
+// transforms of the test files were computed separately, so they only agree up to float32 rounding.
+func assertWithinVec3(t testing.TB, got, want Vec3, tolerance float64) {
+	t.Helper()
+	d := subVec3(got, want)
+	if math.Abs(float64(d.X)) > tolerance || math.Abs(float64(d.Y)) > tolerance || math.Abs(float64(d.Z)) > tolerance {
+		t.Errorf("got: %v != want: %v", got, want)
+	}
+}
+
+func TestSkeletonTransforms(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	skeleton := AsSkeleton(castFile.Find(ByType(NodeIdSkeleton))[0])
+	bones := skeleton.Bones()
+
+	type transform struct {
+		position Vec3
+		rotation Quat
+	}
+	local := make([]transform, len(bones))
+	world := make([]transform, len(bones))
+	for i, b := range bones {
+		local[i] = transform{b.LocalPosition(), b.LocalRotation()}
+		world[i] = transform{b.WorldPosition(), b.WorldRotation()}
+	}
+
+	if err := skeleton.ComputeLocalTransforms(); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range bones {
+		assertWithinVec3(t, b.LocalPosition(), local[i].position, 1e-3)
+		if d := b.LocalRotation().Dot(local[i].rotation); d < 0.9999 && d > -0.9999 {
+			t.Errorf("bone %s: got: %v != want: %v", b.Name(), b.LocalRotation(), local[i].rotation)
+		}
+	}
+
+	if err := skeleton.ComputeWorldTransforms(); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range bones {
+		assertWithinVec3(t, b.WorldPosition(), world[i].position, 1e-3)
+	}
+
+	CreateProperty(bones[0].CastNode, PropNameParentIndex, PropInteger32, uint32(1))
+	CreateProperty(bones[1].CastNode, PropNameParentIndex, PropInteger32, uint32(0))
+	if err := skeleton.ComputeLocalTransforms(); err == nil {
+		t.Error("expected error for a cycle")
+	}
+}
+
+func TestBoneByName(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	skeleton := AsSkeleton(castFile.Find(ByType(NodeIdSkeleton))[0])
+	bones := skeleton.Bones()
+
+	last := bones[len(bones)-1]
+	bone, index := skeleton.BoneByName(last.Name())
+	assertEqual(t, index, len(bones)-1)
+	assertEqual(t, bone.CastNode, last.CastNode)
+
+	bone, index = skeleton.BoneByName("missing")
+	assertEqual(t, index, -1)
+	if bone != nil {
+		t.Errorf("got: %v != want: nil", bone)
+	}
+}
+
+func TestBindPoseMatrices(t *testing.T) {
+	castFile := loadTestFile(t, "cast_ik.cast")
+	skeleton := AsSkeleton(castFile.Find(ByType(NodeIdSkeleton))[0])
+	bones := skeleton.Bones()
+
+	matrices := skeleton.BindPoseMatrices()
+	inverse := skeleton.InverseBindMatrices()
+	assertEqual(t, len(matrices), len(bones))
+
+	for i, b := range bones {
+		assertWithinVec3(t, matrices[i].TransformPoint(Vec3{}), b.WorldPosition(), 1e-3)
+		assertWithinVec3(t, matrices[i].TransformDirection(Vec3{X: 1}), b.WorldRotation().Rotate(Vec3{X: 1}), 1e-4)
+		assertWithinVec3(t, inverse[i].TransformPoint(b.WorldPosition()), Vec3{}, 1e-3)
+	}
+}
+
+func TestBoneLocalMatrix(t *testing.T) {
+	skeleton := AsSkeleton(New().CreateRoot().CreateChild(NodeIdModel).CreateChild(NodeIdSkeleton))
+	bone := AsBone(skeleton.CreateChild(NodeIdBone))
+
+	m := Mat4FromTRS(Vec3{1, 2, 3}, QuatFromEuler(0, 0.5, 0), Vec3{2, 2, 2})
+	if err := bone.SetLocalMatrix(m); err != nil {
+		t.Fatal(err)
+	}
+	assertNearVec3(t, bone.LocalPosition(), Vec3{1, 2, 3})
+	assertNearVec3(t, bone.Scale(), Vec3{2, 2, 2})
+	assertNearVec3(t, bone.LocalMatrix().TransformPoint(Vec3{X: 1}), m.TransformPoint(Vec3{X: 1}))
+}
@@ -0,0 +1,54 @@
+package cast
+
+// ----------------------- //
+//          STATS          //
+// ----------------------- //
+
+// Stats holds statistics about the contents of a [CastFile]
+type Stats struct {
+	Nodes         map[CastNodeId]int     // Nodes counts the nodes per type
+	PropertyBytes map[CastPropertyId]int // PropertyBytes sums the encoded size of the properties, including their headers, per type
+	Vertices      int                    // Vertices is the total amount of mesh vertices
+	Triangles     int                    // Triangles is the total amount of mesh faces
+	Bones         int                    // Bones is the total amount of bones
+	Keyframes     int                    // Keyframes is the total amount of keyframes of all curves
+	MaxDepth      int                    // MaxDepth is the deepest nesting level of a node, root nodes are at level 0
+}
+
+// Stats returns statistics about the contents of the file
+func (n *CastFile) Stats() Stats {
+	stats := Stats{
+		Nodes:         make(map[CastNodeId]int),
+		PropertyBytes: make(map[CastPropertyId]int),
+	}
+
+	n.Walk(func(path []*CastNode, c *CastNode) error {
+		stats.Nodes[c.id]++
+		stats.MaxDepth = max(stats.MaxDepth, len(path))
+
+		for _, p := range c.properties {
+			stats.PropertyBytes[p.Id()] += p.len()
+		}
+
+		switch c.id {
+		case NodeIdMesh:
+			stats.Vertices += propertyCount(c, PropNameVertexPositionBuffer)
+			stats.Triangles += propertyCount(c, PropNameFaceBuffer) / 3
+		case NodeIdBone:
+			stats.Bones++
+		case NodeIdCurve:
+			stats.Keyframes += propertyCount(c, PropNameKeyFrameBuffer)
+		}
+		return nil
+	})
+
+	return stats
+}
+
+// propertyCount returns the amount of values of the property with the given name, 0 if the node does not have it
+func propertyCount(n *CastNode, name CastPropertyName) int {
+	if p, ok := n.GetProperty(name); ok {
+		return p.Count()
+	}
+	return 0
+}
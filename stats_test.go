@@ -0,0 +1,31 @@
+package cast
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	model := root.CreateChild(NodeIdModel)
+	mesh := model.CreateChild(NodeIdMesh)
+	CreateProperty(mesh, PropNameVertexPositionBuffer, PropVector3, Vec3{}, Vec3{X: 1}, Vec3{Y: 1}, Vec3{Z: 1})
+	CreateProperty(mesh, PropNameFaceBuffer, PropByte, []byte{0, 1, 2, 0, 2, 3}...)
+	skeleton := model.CreateChild(NodeIdSkeleton)
+	for range 3 {
+		skeleton.CreateChild(NodeIdBone)
+	}
+	curve := root.CreateChild(NodeIdAnimation).CreateChild(NodeIdCurve)
+	CreateProperty(curve, PropNameKeyFrameBuffer, PropShort, []uint16{0, 10, 20, 30, 40}...)
+
+	stats := castFile.Stats()
+	assertEqual(t, stats.Nodes[NodeIdRoot], 1)
+	assertEqual(t, stats.Nodes[NodeIdBone], 3)
+	assertEqual(t, stats.Nodes[NodeIdCurve], 1)
+	assertEqual(t, stats.Vertices, 4)
+	assertEqual(t, stats.Triangles, 2)
+	assertEqual(t, stats.Bones, 3)
+	assertEqual(t, stats.Keyframes, 5)
+	assertEqual(t, stats.MaxDepth, 3)
+	assertEqual(t, stats.PropertyBytes[PropVector3], 8+2+4*12)
+	assertEqual(t, stats.PropertyBytes[PropByte], 8+1+6)
+	assertEqual(t, stats.PropertyBytes[PropShort], 8+2+5*2)
+}
@@ -0,0 +1,47 @@
+package cast
+
+// ----------------------- //
+//          STRIP          //
+// ----------------------- //
+
+// StripOptions selects the data removed by [CastFile.Strip], the zero value removes nothing
+type StripOptions struct {
+	Tangents           bool // Tangents removes the vertex tangent buffers of meshes
+	Colors             bool // Colors removes the vertex color buffers of meshes
+	MaxUVLayers        int  // MaxUVLayers is the number of UV layers kept on meshes, 0 keeps all of them
+	NotificationTracks bool // NotificationTracks removes the notification tracks of animations
+}
+
+// Strip removes the data selected by the options, e.g. to produce a slimmed runtime variant of an authoring
+// file. UV layers beyond the kept ones are removed and the UV layer count is lowered accordingly. Returns the
+// number of removed properties and nodes.
+func (n *CastFile) Strip(opts StripOptions) (properties, nodes int) {
+	for _, node := range n.GetNodesOfType(NodeIdMesh) {
+		mesh := AsMesh(node)
+		if opts.Tangents && mesh.removeProperty(PropNameVertexTangentBuffer) {
+			properties++
+		}
+		if opts.Colors && mesh.removeProperty(PropNameVertexColorBuffer) {
+			properties++
+		}
+
+		if opts.MaxUVLayers > 0 {
+			for _, layer := range mesh.UVLayers() {
+				if layer >= opts.MaxUVLayers && mesh.removeProperty(uvLayerName(layer)) {
+					properties++
+				}
+			}
+			if mesh.UVLayerCount() > opts.MaxUVLayers {
+				CreateProperty(mesh.CastNode, PropNameUVLayerCount, PropByte, byte(min(opts.MaxUVLayers, 0xff)))
+			}
+		}
+	}
+
+	if opts.NotificationTracks {
+		for _, track := range n.GetNodesOfType(NodeIdNotificationTrack) {
+			track.Remove()
+			nodes++
+		}
+	}
+	return properties, nodes
+}
@@ -0,0 +1,39 @@
+package cast
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+	castFile := New()
+	model := castFile.CreateRoot().CreateChild(NodeIdModel)
+	mesh := AsMesh(model.CreateChild(NodeIdMesh))
+	CreateProperty(mesh.CastNode, PropNameVertexPositionBuffer, PropVector3, Vec3{}, Vec3{})
+	CreateProperty(mesh.CastNode, PropNameVertexTangentBuffer, PropVector3, Vec3{}, Vec3{})
+	CreateProperty(mesh.CastNode, PropNameVertexColorBuffer, PropInteger32, uint32(0), uint32(0))
+	for i := range 3 {
+		if err := mesh.SetUVLayer(i, []Vec2{{}, {}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	animation := castFile.Roots()[0].CreateChild(NodeIdAnimation)
+	animation.CreateChild(NodeIdNotificationTrack)
+	animation.CreateChild(NodeIdNotificationTrack)
+
+	properties, nodes := castFile.Strip(StripOptions{})
+	assertEqual(t, properties, 0)
+	assertEqual(t, nodes, 0)
+
+	properties, nodes = castFile.Strip(StripOptions{Tangents: true, MaxUVLayers: 1, NotificationTracks: true})
+	assertEqual(t, properties, 3)
+	assertEqual(t, nodes, 2)
+	assertEqual(t, mesh.HasProperty(PropNameVertexTangentBuffer), false)
+	assertEqual(t, mesh.HasProperty(PropNameVertexColorBuffer), true)
+	assertEqual(t, [1]int(mesh.UVLayers()), [1]int{0})
+	assertEqual(t, mesh.UVLayerCount(), 1)
+	assertEqual(t, len(animation.GetChildNodes()), 0)
+	assertEqual(t, len(castFile.GetNodesOfType(NodeIdNotificationTrack)), 0)
+
+	properties, _ = castFile.Strip(StripOptions{Tangents: true, Colors: true, MaxUVLayers: 1})
+	assertEqual(t, properties, 1)
+	assertEqual(t, mesh.HasProperty(PropNameVertexColorBuffer), false)
+	assertEqual(t, mesh.HasProperty(PropNameVertexPositionBuffer), true)
+}
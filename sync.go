@@ -0,0 +1,44 @@
+package cast
+
+import "sync"
+
+// ----------------------- //
+//          SYNC           //
+// ----------------------- //
+
+// SyncFile guards a [CastFile] for concurrent use. A CastFile and its nodes are not safe for concurrent use on
+// their own: even lookups such as [CastFile.FindByHash] and [CastNode.ResolveReference] build an index on
+// first use. Through a SyncFile any number of goroutines can read the file at the same time, while
+// modifications are exclusive.
+//
+// Subtrees that do not belong to the file, such as the nodes of a scratch file or copies made with
+// [CastNode.Clone], can be built by other goroutines without holding the lock and attached to the file within
+// [SyncFile.Update].
+type SyncFile struct {
+	mu   sync.RWMutex
+	file *CastFile
+}
+
+// NewSyncFile returns a [SyncFile] guarding the given file. The file must not be used directly afterwards.
+func NewSyncFile(file *CastFile) *SyncFile {
+	file.hashIndex()
+	return &SyncFile{file: file}
+}
+
+// Read calls fn with the file for reading, concurrently with other readers. fn must not modify the file or its
+// nodes, which includes writing it with content hashes enabled, see [WithContentHashes].
+func (s *SyncFile) Read(fn func(f *CastFile) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.file)
+}
+
+// Update calls fn with the file for modification, excluding every other reader and writer
+func (s *SyncFile) Update(fn func(f *CastFile) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// rebuild the lazy state before readers are let in again, so reading never modifies the file
+	defer s.file.hashIndex()
+	return fn(s.file)
+}
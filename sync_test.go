@@ -0,0 +1,54 @@
+package cast
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSyncFile(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	meshHash := castFile.Find(ByType(NodeIdMesh))[0].Hash()
+	s := NewSyncFile(castFile)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				s.Read(func(f *CastFile) error {
+					mesh := f.FindByHash(meshHash)
+					if mesh == nil || mesh.ResolveReference(PropNameMaterial) == nil {
+						t.Error("mesh or material not found")
+					}
+					return nil
+				})
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// the subtree is built outside of the file and attached while holding the lock
+			model := New(WithHashGenerator(NewRandomHashGenerator())).CreateRoot().CreateChild(NodeIdModel)
+			SetProperty(model, PropNameName, fmt.Sprintf("model%d", i))
+			model.CreateChild(NodeIdSkeleton)
+
+			if err := s.Update(func(f *CastFile) error {
+				return model.MoveTo(f.Roots()[0])
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := s.Read(func(f *CastFile) error {
+		assertEqual(t, len(f.Roots()[0].GetChildrenOfType(NodeIdModel)), 9)
+		return f.Validate()
+	}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,224 @@
+package cast
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// ----------------------- //
+//          TREE           //
+// ----------------------- //
+
+// MoveTo detaches the node from its current parent (or from the roots of its file) and attaches it as the
+// last child of the given parent, which may belong to a different file. Returns [ErrInvalidMove] if the new
+// parent is nil, the node itself or one of its descendants, and [ErrHashInUse] if the node is moved to another
+// file where one of the hashes of its subtree is already used. To make a node a root node, use
+// [CastFile.CreateRoot] or [CastFile.ReplaceRoot] instead.
+func (n *CastNode) MoveTo(newParent *CastNode) error {
+	if newParent == nil || newParent == n || newParent.isDescendantOf(n) {
+		return ErrInvalidMove
+	}
+
+	if newParent.file != n.file && newParent.file != nil {
+		index := newParent.file.hashIndex()
+		for c := range n.all() {
+			if index[c.hash] != nil {
+				return fmt.Errorf("%w: %#x", ErrHashInUse, c.hash)
+			}
+		}
+	}
+
+	oldFile := n.file
+	n.detach()
+	n.setParentNode(newParent)
+	newParent.childNodes = append(newParent.childNodes, n)
+
+	if newParent.file != oldFile {
+		if oldFile != nil {
+			oldFile.invalidateIndex()
+		}
+		if newParent.file != nil {
+			newParent.file.adopt(n)
+		} else {
+			for c := range n.all() {
+				c.file = nil
+			}
+		}
+	}
+	return nil
+}
+
+// Remove detaches the node from its parent, or from the roots of its file if it is a root node. The removed
+// node and its descendants no longer belong to the file.
+func (n *CastNode) Remove() {
+	oldFile := n.file
+	n.detach()
+	for c := range n.all() {
+		c.file = nil
+	}
+	if oldFile != nil {
+		oldFile.invalidateIndex()
+	}
+}
+
+// Clone returns a deep copy of the node, its properties and its descendants. The copy has no parent and
+// does not belong to a file until it is attached with [CastNode.MoveTo]. Unless keepHashes is set, the copied
+// nodes are assigned fresh hashes and the Integer64 properties referencing nodes within the copied subtree
+// are rewritten to the new hashes.
+func (n *CastNode) Clone(keepHashes bool) *CastNode {
+	c := copyNode(n, nil)
+	if keepHashes {
+		return c
+	}
+
+	var used map[uint64]*CastNode
+	if n.file != nil {
+		used = n.file.hashIndex()
+	}
+
+	remap := make(map[uint64]uint64)
+	for node := range c.all() {
+		hash := n.nextHash()
+		for used[hash] != nil {
+			hash = n.nextHash()
+		}
+
+		if _, ok := remap[node.hash]; !ok {
+			remap[node.hash] = hash
+		}
+		node.hash = hash
+	}
+
+	for node := range c.all() {
+		remapHashReferences(node, remap)
+	}
+	return c
+}
+
+// Clone returns a deep copy of the file keeping all hashes
+func (n *CastFile) Clone() *CastFile {
+	c := &CastFile{
+		flags:         n.flags,
+		version:       n.version,
+		rootNodes:     make([]*CastNode, 0, len(n.rootNodes)),
+		hashGenerator: n.hashGenerator,
+		contentHashes: n.contentHashes,
+	}
+
+	for _, root := range n.rootNodes {
+		root := copyNode(root, nil)
+		c.rootNodes = append(c.rootNodes, root)
+		c.adopt(root)
+	}
+	return c
+}
+
+// RemoveRoot removes the given root node from the file. The removed node and its descendants no longer
+// belong to the file. Returns [ErrNotRoot] if the node is not a root node of the file.
+func (n *CastFile) RemoveRoot(root *CastNode) error {
+	if !slices.Contains(n.rootNodes, root) {
+		return ErrNotRoot
+	}
+
+	root.detach()
+	for c := range root.all() {
+		c.file = nil
+	}
+	n.invalidateIndex()
+	return nil
+}
+
+// ReplaceRoot replaces the given root node with the replacement node at the same position. The replacement
+// is detached from wherever it is attached, the replaced node and its descendants no longer belong to the
+// file. Returns [ErrNotRoot] if old is not a root node of the file and [ErrHashInUse] if the replacement comes
+// from another file and one of the hashes of its subtree is already used by the remaining nodes.
+func (n *CastFile) ReplaceRoot(old, replacement *CastNode) error {
+	i := slices.Index(n.rootNodes, old)
+	if i < 0 {
+		return ErrNotRoot
+	}
+
+	if replacement == old {
+		return nil
+	}
+
+	if replacement.file != n {
+		index := n.hashIndex()
+		for c := range replacement.all() {
+			if other := index[c.hash]; other != nil && other != old && !other.isDescendantOf(old) {
+				return fmt.Errorf("%w: %#x", ErrHashInUse, c.hash)
+			}
+		}
+	}
+
+	oldFile := replacement.file
+	replacement.detach()
+	if oldFile != nil && oldFile != n {
+		oldFile.invalidateIndex()
+	}
+
+	// detaching the replacement may have shifted the position of old
+	i = slices.Index(n.rootNodes, old)
+	n.rootNodes[i] = replacement
+	for c := range old.all() {
+		c.file = nil
+	}
+
+	n.invalidateIndex()
+	n.adopt(replacement)
+	return nil
+}
+
+// nextHash returns the next hash from the generator of the file of the node
+func (n *CastNode) nextHash() uint64 {
+	if n.file == nil {
+		return nextHash()
+	}
+	return n.file.nextHash()
+}
+
+// copyNode returns a deep copy of the given node and its descendants, preserving hashes
+func copyNode(n *CastNode, parent *CastNode) *CastNode {
+	c := &CastNode{
+		id:         n.id,
+		hash:       n.hash,
+		properties: make([]iCastProperty, 0, len(n.properties)),
+		childNodes: make([]*CastNode, 0, len(n.childNodes)),
+		parentNode: parent,
+	}
+
+	for _, p := range n.properties {
+		c.properties = append(c.properties, p.clone())
+	}
+
+	for _, child := range n.childNodes {
+		c.childNodes = append(c.childNodes, copyNode(child, c))
+	}
+
+	return c
+}
+
+// detach removes the node from its parent or from the roots of its file. The node keeps its
+// file pointer and the hash index of the file is left untouched.
+func (n *CastNode) detach() {
+	if n.parentNode != nil {
+		n.parentNode.childNodes = slices.DeleteFunc(n.parentNode.childNodes, func(c *CastNode) bool {
+			return c == n
+		})
+		n.parentNode = nil
+	} else if n.file != nil {
+		n.file.rootNodes = slices.DeleteFunc(n.file.rootNodes, func(c *CastNode) bool {
+			return c == n
+		})
+	}
+}
+
+// all returns an iterator over the node and its descendants in depth-first order
+func (n *CastNode) all() iter.Seq[*CastNode] {
+	return func(yield func(*CastNode) bool) {
+		if yield(n) {
+			n.yieldDescendants(yield)
+		}
+	}
+}
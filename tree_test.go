@@ -0,0 +1,173 @@
+package cast
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoveTo(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	a := root.CreateChild(NodeIdModel)
+	b := root.CreateChild(NodeIdModel)
+	mesh := a.CreateChild(NodeIdMesh)
+
+	if err := mesh.MoveTo(b); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(a.GetChildNodes()), 0)
+	assertEqual(t, len(b.GetChildNodes()), 1)
+	assertEqual(t, mesh.GetParentNode(), b)
+	assertEqual(t, castFile.FindByHash(mesh.Hash()), mesh)
+
+	assertEqual(t, b.MoveTo(b), ErrInvalidMove)
+	assertEqual(t, b.MoveTo(mesh), ErrInvalidMove)
+	assertEqual(t, b.MoveTo(nil), ErrInvalidMove)
+
+	// moving a root node under another node
+	other := castFile.CreateRoot()
+	if err := other.MoveTo(a); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(castFile.Roots()), 1)
+	assertEqual(t, other.GetParentNode(), a)
+
+	// moving into another file
+	target := New(WithHashGenerator(NewSequentialHashGenerator(1)))
+	targetRoot := target.CreateRoot()
+	if err := b.MoveTo(targetRoot); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(root.GetChildNodes()), 1)
+	assertEqual(t, castFile.FindByHash(mesh.Hash()), nil)
+	assertEqual(t, target.FindByHash(mesh.Hash()), mesh)
+	assertEqual(t, mesh.file, target)
+
+	// colliding hashes are rejected
+	collision := castFile.Roots()[0].CreateChild(NodeIdModel)
+	if err := collision.SetHash(targetRoot.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	err := collision.MoveTo(targetRoot)
+	assertEqual(t, errors.Is(err, ErrHashInUse), true)
+	assertEqual(t, collision.GetParentNode(), castFile.Roots()[0])
+}
+
+func TestClone(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	model := castFile.Roots()[0].GetChildNodes()[0]
+
+	kept := model.Clone(true)
+	assertEqual(t, kept.Hash(), model.Hash())
+	assertEqual(t, kept.GetParentNode(), nil)
+	assertEqual(t, kept.len(), model.len())
+	assertEqual(t, errors.Is(kept.MoveTo(castFile.Roots()[0]), ErrHashInUse), true)
+
+	fresh := model.Clone(false)
+	assertEqual(t, fresh.Hash() != model.Hash(), true)
+	if err := fresh.MoveTo(castFile.Roots()[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// references within the clone point to the cloned nodes
+	mesh := fresh.GetChildrenOfType(NodeIdMesh)[0]
+	assertEqual(t, mesh.ResolveReference(PropNameMaterial), fresh.GetChildrenOfType(NodeIdMaterial)[0])
+
+	// values are not shared
+	positions, err := GetPropertyValues[Vec3](mesh, PropNameVertexPositionBuffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions[0].X = 100
+	original, err := GetPropertyValues[Vec3](model.GetChildrenOfType(NodeIdMesh)[0], PropNameVertexPositionBuffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, original[0].X != 100, true)
+
+	clone := castFile.Clone()
+	assertEqual(t, len(clone.Roots()), len(castFile.Roots()))
+	assertEqual(t, clone.Roots()[0].Hash(), castFile.Roots()[0].Hash())
+	assertEqual(t, clone.FindByHash(mesh.Hash()) != mesh, true)
+	assertEqual(t, clone.FindByHash(mesh.Hash()).Hash(), mesh.Hash())
+	assertEqual(t, clone.Roots()[0].len(), castFile.Roots()[0].len())
+}
+
+func TestRemoveRoot(t *testing.T) {
+	castFile := New()
+	a := castFile.CreateRoot()
+	b := castFile.CreateRoot()
+	model := b.CreateChild(NodeIdModel)
+
+	if err := castFile.RemoveRoot(b); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(castFile.Roots()), 1)
+	assertEqual(t, castFile.Roots()[0], a)
+	assertEqual(t, castFile.FindByHash(model.Hash()), nil)
+	assertEqual(t, model.file, nil)
+
+	assertEqual(t, castFile.RemoveRoot(b), ErrNotRoot)
+	assertEqual(t, castFile.RemoveRoot(model), ErrNotRoot)
+}
+
+func TestRemove(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	model := root.CreateChild(NodeIdModel)
+	mesh := model.CreateChild(NodeIdMesh)
+	assertEqual(t, castFile.FindByHash(mesh.Hash()), mesh)
+
+	model.Remove()
+	assertEqual(t, len(root.GetChildNodes()), 0)
+	assertEqual(t, model.GetParentNode(), nil)
+	assertEqual(t, castFile.FindByHash(mesh.Hash()), nil)
+	assertEqual(t, mesh.file, nil)
+
+	root.Remove()
+	assertEqual(t, len(castFile.Roots()), 0)
+}
+
+func TestReplaceRoot(t *testing.T) {
+	castFile := New()
+	a := castFile.CreateRoot()
+	b := castFile.CreateRoot()
+	c := castFile.CreateRoot()
+
+	other := New(WithHashGenerator(NewSequentialHashGenerator(1)))
+	replacement := other.CreateRoot()
+	model := replacement.CreateChild(NodeIdModel)
+
+	if err := castFile.ReplaceRoot(b, replacement); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(castFile.Roots()), 3)
+	assertEqual(t, castFile.Roots()[1], replacement)
+	assertEqual(t, len(other.Roots()), 0)
+	assertEqual(t, castFile.FindByHash(model.Hash()), model)
+	assertEqual(t, castFile.FindByHash(b.Hash()), nil)
+	assertEqual(t, b.file, nil)
+
+	// replacing with another root of the same file
+	if err := castFile.ReplaceRoot(c, a); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(castFile.Roots()), 2)
+	assertEqual(t, castFile.Roots()[0], replacement)
+	assertEqual(t, castFile.Roots()[1], a)
+
+	assertEqual(t, castFile.ReplaceRoot(c, a), ErrNotRoot)
+
+	collision := New()
+	colliding := collision.CreateRoot()
+	if err := colliding.SetHash(a.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, errors.Is(castFile.ReplaceRoot(replacement, colliding), ErrHashInUse), true)
+
+	// the hash of the replaced node itself may be reused
+	if err := castFile.ReplaceRoot(a, colliding); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, castFile.FindByHash(a.Hash()), colliding)
+}
@@ -0,0 +1,180 @@
+package cast
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// ----------------------- //
+//        VALIDATE         //
+// ----------------------- //
+
+// ValidationError describes a problem found by [CastFile.Validate]
+type ValidationError struct {
+	Path    string // Path of the offending node, e.g. "root[0]/skel[0]"
+	Message string // Message describing the problem
+}
+
+// Error returns the error message
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("cast: invalid %s: %s", e.Path, e.Message)
+}
+
+// validationRule checks a single node of a structurally sound tree, reporting every problem found
+type validationRule func(n *CastNode, report func(format string, args ...any))
+
+// validationRules holds the rules applied to every node by [CastFile.Validate]
+var validationRules = []validationRule{
+	validateBoneHierarchy,
+	validateSkinningMethod,
+	validateUVLayers,
+	validateFaceIndices,
+	validateSizes,
+}
+
+// Validate checks the file for inconsistencies and returns them joined into a single error, nil if there are
+// none. Every problem is reported as a [*ValidationError].
+//
+// The structure of the tree is checked first: parent pointers must match the node holding a child, nodes
+// must belong to the file and a node must not occur more than once, which would make writing the file
+// recurse endlessly. Then the bone parent indices of the skeletons are checked to be in range and acyclic,
+// the skinning methods of the meshes to be known, their UV layer counts to match the UV layers numbered from
+// 0 and their face buffers to hold whole triangles indexing existing vertices. Finally the sizes of the
+// nodes, the number of values of the properties and the lengths of their names are checked to fit their
+// header fields.
+func (n *CastFile) Validate() error {
+	var errs []error
+	visited := make(map[*CastNode]struct{})
+	path := make([]string, 0, 8)
+
+	// visit checks the node and its descendants and returns the size of the node, computed bottom-up so every
+	// node is measured once and nodes occurring more than once are not followed
+	var visit func(node, parent *CastNode, name string) int64
+	visit = func(node, parent *CastNode, name string) int64 {
+		path = append(path, name)
+		defer func() { path = path[:len(path)-1] }()
+
+		report := func(format string, args ...any) {
+			errs = append(errs, &ValidationError{
+				Path:    strings.Join(path, "/"),
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if _, ok := visited[node]; ok {
+			report("node %#x occurs more than once in the tree", node.hash)
+			return 0
+		}
+		visited[node] = struct{}{}
+
+		if node.parentNode != parent {
+			report("parent pointer does not match the parent node")
+		}
+		if node.file != n {
+			report("node does not belong to the file")
+		}
+
+		for _, rule := range validationRules {
+			rule(node, report)
+		}
+
+		size := int64(castNodeHeaderSize)
+		for _, p := range node.properties {
+			size += int64(p.len())
+		}
+
+		// the size is reported before the problems of the descendants
+		at := len(errs)
+		siblings := make(map[CastNodeId]int)
+		for _, c := range node.childNodes {
+			size += visit(c, node, fmt.Sprintf("%s[%d]", c.id, siblings[c.id]))
+			siblings[c.id]++
+		}
+
+		if size > math.MaxUint32 {
+			errs = slices.Insert(errs, at, error(&ValidationError{
+				Path:    strings.Join(path, "/"),
+				Message: fmt.Sprintf("node size %d exceeds the maximum of %d", size, int64(math.MaxUint32)),
+			}))
+		}
+		return size
+	}
+
+	siblings := make(map[CastNodeId]int)
+	for _, root := range n.rootNodes {
+		visit(root, nil, fmt.Sprintf("%s[%d]", root.id, siblings[root.id]))
+		siblings[root.id]++
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateBoneHierarchy checks that the parent indices of the bones of a skeleton are in range and acyclic
+func validateBoneHierarchy(n *CastNode, report func(format string, args ...any)) {
+	skeleton := AsSkeleton(n)
+	if skeleton == nil {
+		return
+	}
+
+	bones := skeleton.Bones()
+	parents := make([]int, len(bones))
+	for i, b := range bones {
+		parents[i] = b.ParentIndex()
+		if parents[i] >= len(bones) {
+			report("parent index %d of bone %d out of range of %d bones", parents[i], i, len(bones))
+			parents[i] = -1
+		}
+	}
+
+	for i := range bones {
+		p := parents[i]
+		for steps := 0; p >= 0 && steps < len(bones); steps++ {
+			if p == i {
+				report("bone %d is its own ancestor", i)
+				break
+			}
+			p = parents[p]
+		}
+	}
+}
+
+// validateSkinningMethod checks that the skinning method of a mesh is known
+func validateSkinningMethod(n *CastNode, report func(format string, args ...any)) {
+	if mesh := AsMesh(n); mesh != nil {
+		if _, err := mesh.SkinningMethod(); err != nil {
+			report("unknown skinning method %q", GetPropertyValueOr(n, PropNameSkinningMethod, ""))
+		}
+	}
+}
+
+// validateUVLayers checks that the UV layer count of a mesh matches its UV layers
+func validateUVLayers(n *CastNode, report func(format string, args ...any)) {
+	if mesh := AsMesh(n); mesh != nil {
+		if err := mesh.checkUVLayerCount(); err != nil {
+			report("%v", err)
+		}
+	}
+}
+
+// validateFaceIndices checks that the face buffer of a mesh holds whole triangles with indices in range of
+// its vertices
+func validateFaceIndices(n *CastNode, report func(format string, args ...any)) {
+	if mesh := AsMesh(n); mesh != nil {
+		if err := mesh.checkIndices(); err != nil {
+			report("%v", err)
+		}
+	}
+}
+
+// validateSizes checks that the number of values of the properties of a node and the lengths of their names
+// fit their header fields, see [OverflowError]. The size of the node is checked by [CastFile.Validate], which
+// computes it along the traversal.
+func validateSizes(n *CastNode, report func(format string, args ...any)) {
+	var overflow *OverflowError
+	if err := checkPropertySizes(n); errors.As(err, &overflow) {
+		report("%s %d exceeds the maximum of %d", overflow.Field, overflow.Value, overflow.Max)
+	}
+}
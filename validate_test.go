@@ -0,0 +1,89 @@
+package cast
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"cube.cast", "cast_ik.cast", "cast_constraints.cast"} {
+		if err := loadTestFile(t, name).Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	castFile := New()
+	root := castFile.CreateRoot()
+	model := root.CreateChild(NodeIdModel)
+	skeleton := model.CreateChild(NodeIdSkeleton)
+	for _, parent := range []uint32{0xFFFFFFFF, 2, 1, 7} {
+		bone := skeleton.CreateChild(NodeIdBone)
+		CreateProperty(bone, PropNameParentIndex, PropInteger32, parent)
+	}
+
+	// a node attached twice and a child with a stale parent pointer
+	model.childNodes = append(model.childNodes, skeleton)
+	stray := newCastNode(NodeIdMesh, castFile)
+	stray.file = castFile
+	root.childNodes = append(root.childNodes, stray)
+	CreateProperty(stray, PropNameSkinningMethod, PropString, "dqs")
+	CreateProperty(stray, uvLayerName(1), PropVector2, Vec2{})
+	CreateProperty(stray, PropNameFaceBuffer, PropByte, []byte{0, 1, 2}...)
+
+	err := castFile.Validate()
+	var messages []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("unexpected error type %T", err)
+		}
+		messages = append(messages, validationErr.Path+": "+validationErr.Message)
+	}
+
+	assertEqual(t, strings.Join(messages, "\n"), strings.Join([]string{
+		"root[0]/modl[0]/skel[0]: parent index 7 of bone 3 out of range of 4 bones",
+		"root[0]/modl[0]/skel[0]: bone 1 is its own ancestor",
+		"root[0]/modl[0]/skel[0]: bone 2 is its own ancestor",
+		fmt.Sprintf("root[0]/modl[0]/skel[1]: node %#x occurs more than once in the tree", skeleton.hash),
+		"root[0]/mesh[0]: parent pointer does not match the parent node",
+		`root[0]/mesh[0]: unknown skinning method "dqs"`,
+		"root[0]/mesh[0]: UV layer count 0 does not match 1 UV layers",
+		"root[0]/mesh[0]: face index 0 of triangle 0 out of range of 0 vertices",
+	}, "\n"))
+}
+
+func TestValidateCycle(t *testing.T) {
+	castFile := New()
+	root := castFile.CreateRoot()
+	model := root.CreateChild(NodeIdModel)
+	model.CreateChild(NodeIdSkeleton)
+	model.childNodes[0] = root
+
+	// the cycle is reported instead of being followed endlessly
+	err := castFile.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	assertEqual(t, validationErr.Path, "root[0]/modl[0]/root[0]")
+	assertEqual(t, validationErr.Message, fmt.Sprintf("node %#x occurs more than once in the tree", root.hash))
+	assertEqual(t, len(err.(interface{ Unwrap() []error }).Unwrap()), 1)
+}
+
+func TestValidateSizes(t *testing.T) {
+	castFile := New()
+	mesh := castFile.CreateRoot().CreateChild(NodeIdMesh)
+	mesh.properties = append(mesh.properties, hugeProperty("vp", 1<<28))
+
+	err := castFile.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	assertEqual(t, validationErr.Path, "root[0]")
+	assertEqual(t, validationErr.Message, fmt.Sprintf("node size %d exceeds the maximum of %d", int64(1<<32+castPropertyHeaderSize+2+2*castNodeHeaderSize), int64(math.MaxUint32)))
+	assertEqual(t, len(err.(interface{ Unwrap() []error }).Unwrap()), 2)
+}
@@ -0,0 +1,101 @@
+package cast
+
+import "errors"
+
+var (
+	// SkipChildren is used as a return value from walk functions to indicate that the children of the
+	// current node are to be skipped. It is not returned as an error by any function.
+	SkipChildren = errors.New("skip children")
+
+	// SkipAll is used as a return value from walk functions to indicate that all remaining nodes are to
+	// be skipped. It is not returned as an error by any function.
+	SkipAll = errors.New("skip everything")
+)
+
+// ----------------------- //
+//          WALK           //
+// ----------------------- //
+
+// WalkFunc is the type of the function called by [CastNode.Walk] for each visited node. The path holds
+// the ancestors of the node from the starting node down to its parent and must not be retained.
+type WalkFunc func(path []*CastNode, n *CastNode) error
+
+// WalkPropertiesFunc is the type of the function called by [CastNode.WalkProperties] for each visited
+// property. The path holds the ancestors of the node from the starting node down to its parent and must
+// not be retained.
+type WalkPropertiesFunc func(path []*CastNode, n *CastNode, p iCastProperty) error
+
+// Walk walks the node and its descendants in depth-first order, calling fn for each of them.
+// If fn returns [SkipChildren] the children of the node are skipped, if it returns [SkipAll]
+// the walk stops. Any other error stops the walk and is returned.
+func (n *CastNode) Walk(fn WalkFunc) error {
+	err := walk(make([]*CastNode, 0, 8), n, fn)
+	if err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// WalkProperties walks the properties of the node and its descendants in depth-first order, calling fn
+// for each of them. If fn returns [SkipChildren] the remaining properties and the children of the node
+// are skipped, if it returns [SkipAll] the walk stops. Any other error stops the walk and is returned.
+func (n *CastNode) WalkProperties(fn WalkPropertiesFunc) error {
+	return n.Walk(walkProperties(fn))
+}
+
+// Walk walks every root node of the file and their descendants, see [CastNode.Walk]
+func (n *CastFile) Walk(fn WalkFunc) error {
+	for _, root := range n.rootNodes {
+		if err := walk(make([]*CastNode, 0, 8), root, fn); err != nil {
+			if err == SkipAll {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkProperties walks the properties of every node of the file, see [CastNode.WalkProperties]
+func (n *CastFile) WalkProperties(fn WalkPropertiesFunc) error {
+	return n.Walk(walkProperties(fn))
+}
+
+// walk calls fn for the given node and recurses into its children
+func walk(path []*CastNode, n *CastNode, fn WalkFunc) error {
+	if err := fn(path, n); err != nil {
+		if err == SkipChildren {
+			return nil
+		}
+		return err
+	}
+
+	path = append(path, n)
+	for _, c := range n.childNodes {
+		if err := walk(path, c, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkProperties returns a [WalkFunc] calling fn for each property of the visited nodes
+func walkProperties(fn WalkPropertiesFunc) WalkFunc {
+	return func(path []*CastNode, n *CastNode) error {
+		for _, p := range n.properties {
+			if err := fn(path, n, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// walkNodes calls fn for the given node and all of its descendants in depth-first order
+func walkNodes(n *CastNode, fn func(n *CastNode)) {
+	fn(n)
+	for _, c := range n.childNodes {
+		walkNodes(c, fn)
+	}
+}
@@ -0,0 +1,72 @@
+package cast
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+	root := castFile.Roots()[0]
+
+	var ids []CastNodeId
+	var depths []int
+	err := root.Walk(func(path []*CastNode, n *CastNode) error {
+		ids = append(ids, n.Id())
+		depths = append(depths, len(path))
+		if len(path) > 0 {
+			assertEqual(t, path[len(path)-1], n.GetParentNode())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(ids), 4)
+	assertEqual(t, ids[0], NodeIdRoot)
+	assertEqual(t, ids[1], NodeIdModel)
+	assertEqual(t, depths[2], 2)
+
+	count := 0
+	err = castFile.Walk(func(path []*CastNode, n *CastNode) error {
+		count++
+		if n.Id() == NodeIdModel {
+			return SkipChildren
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, count, 2)
+
+	count = 0
+	err = castFile.Walk(func(path []*CastNode, n *CastNode) error {
+		count++
+		return SkipAll
+	})
+	assertEqual(t, err, nil)
+	assertEqual(t, count, 1)
+
+	errStop := errors.New("stop")
+	err = root.Walk(func(path []*CastNode, n *CastNode) error {
+		return errStop
+	})
+	assertEqual(t, err, errStop)
+}
+
+func TestWalkProperties(t *testing.T) {
+	castFile := loadTestFile(t, "cube.cast")
+
+	names := 0
+	err := castFile.WalkProperties(func(path []*CastNode, n *CastNode, p iCastProperty) error {
+		if p.Name() == PropNameName {
+			names++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, names, 3)
+}